@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// mutatingMethods are the GitClient methods that change the state of a repo,
+// which fail early for archived and disabled repos with WithArchivedCheck.
+//
+// SetRepositoryArchived is not included, so that archived repos can be
+// unarchived, nor are ForkRepository, Star and Unstar, which don't change the
+// repo.
+var mutatingMethods = map[string]bool{
+	"AddLabelsToMatching":          true,
+	"ClosePullRequestsOlderThan":   true,
+	"CommitDir":                    true,
+	"CommitTemplate":               true,
+	"CreateBranch":                 true,
+	"CreateBranchIfBaseMatches":    true,
+	"CreateDeploymentStatus":       true,
+	"CreateForkPullRequest":        true,
+	"CreateIssue":                  true,
+	"CreateIssueComment":           true,
+	"CreatePullRequest":            true,
+	"CreatePullRequestFromPatches": true,
+	"CreatePullRequestIfChanged":   true,
+	"CreateReviewComment":          true,
+	"CreateStatus":                 true,
+	"DeleteBranchesByPrefix":       true,
+	"DeleteFile":                   true,
+	"EnableAutoMerge":              true,
+	"LockPullRequest":              true,
+	"MergePullRequest":             true,
+	"RenameRepository":             true,
+	"ResolveReviewThread":          true,
+	"SetPullRequestBase":           true,
+	"SetRepositorySecret":          true,
+	"SetRepositoryTopics":          true,
+	"SetRepositoryVariable":        true,
+	"SyncDir":                      true,
+	"SyncFile":                     true,
+	"UnlockPullRequest":            true,
+	"UpdateFile":                   true,
+	"UpdateFileWithRetry":          true,
+	"UpdateFiles":                  true,
+	"UploadReleaseAsset":           true,
+}
+
+// IsMutatingMethod returns true if the GitClient method changes the state of
+// the repo it's called with, and is checked by WithArchivedCheck.
+func IsMutatingMethod(method string) bool {
+	return mutatingMethods[method]
+}
+
+// WithArchivedCheck is an option func that checks whether the repo is
+// archived or disabled before the first call that changes it, and fails the
+// call with an error wrapping ErrArchived or ErrRepoDisabled, rather than
+// with the permission error returned by the upstream service.
+//
+// The state of each repo is checked once for the lifetime of the client,
+// unless it's changed with SetRepositoryArchived. If the state can't be
+// read, the call is made anyway.
+//
+// Repos can only be disabled on GitHub.
+func WithArchivedCheck() ClientFunc {
+	return func(c *SCMClient) {
+		c.archivedRepos = &archivedCache{states: map[string]error{}}
+	}
+}
+
+// archivedCache holds the result of checking each repo, nil if the repo can
+// be changed.
+type archivedCache struct {
+	mu     sync.Mutex
+	states map[string]error
+}
+
+func (a *archivedCache) get(repo string) (error, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err, ok := a.states[repo]
+	return err, ok
+}
+
+func (a *archivedCache) set(repo string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.states[repo] = err
+}
+
+// invalidateFor drops the state of the repo if the call described by the
+// event may have changed it.
+func (a *archivedCache) invalidateFor(e Event) {
+	if a == nil || (e.Type != "SetRepositoryArchived" && e.Type != "RenameRepository") {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.states, e.Repo)
+}
+
+// checkArchived returns an error if the repo is archived or disabled, and the
+// method changes the repo.
+func (c *SCMClient) checkArchived(ctx context.Context, method, repo string) error {
+	if c.archivedRepos == nil || !mutatingMethods[method] {
+		return nil
+	}
+	if err, ok := c.archivedRepos.get(repo); ok {
+		return err
+	}
+	archived, disabled, err := c.repositoryState(ctx, repo)
+	if err != nil {
+		return nil
+	}
+	var state error
+	switch {
+	case disabled:
+		state = fmt.Errorf("repo %s: %w", repo, ErrRepoDisabled)
+	case archived:
+		state = fmt.Errorf("repo %s: %w", repo, ErrArchived)
+	}
+	c.archivedRepos.set(repo, state)
+	return state
+}
+
+// repositoryState returns whether the repo is archived or disabled.
+func (c *SCMClient) repositoryState(ctx context.Context, repo string) (archived, disabled bool, err error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		var out struct {
+			Archived bool `json:"archived"`
+			Disabled bool `json:"disabled"`
+		}
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s", repo), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return false, false, SCMError{Msg: fmt.Sprintf("failed to get repo %s", repo), Status: r.Status}
+		}
+		return out.Archived, out.Disabled, err
+	default:
+		r, res, err := c.scmClient.Repositories.Find(ctx, repo)
+		if res != nil && isErrorStatus(res.Status) {
+			return false, false, SCMError{Msg: fmt.Sprintf("failed to get repo %s", repo), Status: res.Status}
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return r.Archived, false, nil
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWithArchivedCheck(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Times(1).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"full_name": "Codertocat/Hello-World", "archived": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithArchivedCheck())
+
+	for i := 0; i < 2; i++ {
+		err := client.CreateBranch(context.Background(), "Codertocat/Hello-World", "new-feature", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+		if !errors.Is(err, ErrArchived) {
+			t.Fatalf("got %v, want ErrArchived", err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("the repo was not checked")
+	}
+}
+
+func TestWithArchivedCheckForDisabledRepo(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"full_name": "Codertocat/Hello-World", "disabled": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithArchivedCheck())
+
+	err = client.CreateBranch(context.Background(), "Codertocat/Hello-World", "new-feature", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+	if !errors.Is(err, ErrRepoDisabled) {
+		t.Fatalf("got %v, want ErrRepoDisabled", err)
+	}
+}
+
+func TestWithArchivedCheckAfterUnarchiving(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"full_name": "Codertocat/Hello-World", "archived": true})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World").
+		MatchType("json").
+		JSON(map[string]bool{"archived": false}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"full_name": "Codertocat/Hello-World", "archived": false})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"full_name": "Codertocat/Hello-World", "archived": false})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/created_ref.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithArchivedCheck())
+	ctx := context.Background()
+
+	err = client.CreateBranch(ctx, "Codertocat/Hello-World", "new-feature", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+	if !errors.Is(err, ErrArchived) {
+		t.Fatalf("got %v, want ErrArchived", err)
+	}
+	if err := client.SetRepositoryArchived(ctx, "Codertocat/Hello-World", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateBranch(ctx, "Codertocat/Hello-World", "new-feature", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the repo was not checked again")
+	}
+}
+
+func TestWithArchivedCheckInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"path_with_namespace": "Codertocat/Hello-World", "archived": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithArchivedCheck())
+
+	err = client.CreateBranch(context.Background(), "Codertocat/Hello-World", "new-feature", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+	if !errors.Is(err, ErrArchived) {
+		t.Fatalf("got %v, want ErrArchived", err)
+	}
+}
+
+func TestWithArchivedCheckSkipsReads(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/README.md").
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithArchivedCheck())
+
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutatingMethodsAreGitClientMethods(t *testing.T) {
+	iface := reflect.TypeOf((*GitClient)(nil)).Elem()
+	for method := range mutatingMethods {
+		if _, ok := iface.MethodByName(method); !ok {
+			t.Errorf("%s is not a GitClient method", method)
+		}
+	}
+}
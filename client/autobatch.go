@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// defaultAutoBatchMessage is the commit message of the flushes made by an
+// AutoBatcher, unless configured with WithFlushCommit.
+const defaultAutoBatchMessage = "Apply batched changes"
+
+// errAutoBatcherClosed is returned when changes are staged after the
+// AutoBatcher is closed.
+var errAutoBatcherClosed = errors.New("auto batcher is closed")
+
+// AutoBatcherFunc is an option for creating new AutoBatchers.
+type AutoBatcherFunc func(b *AutoBatcher)
+
+// WithFlushCommit is an option func that sets the message and signature of
+// the commits made by the flushes.
+func WithFlushCommit(message string, signature scm.Signature) AutoBatcherFunc {
+	return func(b *AutoBatcher) {
+		b.message = message
+		b.signature = signature
+	}
+}
+
+// WithFlushErrorHandler is an option func that calls the handler with the
+// errors of the flushes made when the window elapses, which have no caller to
+// return them to, the changes stay staged for the next flush.
+func WithFlushErrorHandler(handler func(error)) AutoBatcherFunc {
+	return func(b *AutoBatcher) {
+		b.onError = handler
+	}
+}
+
+// WithBatchClock is an option func that replaces the clock used to wait for
+// the window to elapse, by default this is the clock of the SCMClient, set
+// with WithClock.
+func WithBatchClock(clock Clock) AutoBatcherFunc {
+	return func(b *AutoBatcher) {
+		b.clock = clock
+	}
+}
+
+// NewAutoBatcher creates and returns a new AutoBatcher that commits the
+// changes staged for the branch of the repo once no changes have been staged
+// for the window.
+func NewAutoBatcher(u FilesUpdater, repo, branch string, window time.Duration, opts ...AutoBatcherFunc) *AutoBatcher {
+	b := &AutoBatcher{
+		batch:   NewBatcher(u),
+		repo:    repo,
+		branch:  branch,
+		window:  window,
+		message: defaultAutoBatchMessage,
+		clock:   realClock{},
+		done:    make(chan struct{}),
+	}
+	if c, ok := u.(*SCMClient); ok {
+		b.clock = c.getClock()
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// AutoBatcher stages file writes and deletions like a Batcher, and commits
+// them together once no changes have been staged for a window, so that many
+// small writes coalesce into a single commit.
+//
+// It's safe to use from several goroutines, staging changes blocks while a
+// commit is in progress.
+type AutoBatcher struct {
+	repo      string
+	branch    string
+	window    time.Duration
+	message   string
+	signature scm.Signature
+	clock     Clock
+	onError   func(error)
+
+	mu      sync.Mutex
+	batch   *Batcher
+	last    time.Time
+	waiting bool
+	closed  bool
+	done    chan struct{}
+}
+
+// Add stages the content to be written to the path.
+func (b *AutoBatcher) Add(path string, content []byte) error {
+	return b.stage(FileChange{Path: path, Content: content})
+}
+
+// Delete stages the removal of the path.
+func (b *AutoBatcher) Delete(path string) error {
+	return b.stage(FileChange{Path: path, Delete: true})
+}
+
+// Len returns the number of staged changes.
+func (b *AutoBatcher) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Len()
+}
+
+// Flush commits the staged changes now, and returns the SHA of the new
+// commit, if no changes are staged, no commit is made and the SHA is empty.
+//
+// The staged changes are only cleared if the commit succeeds.
+func (b *AutoBatcher) Flush(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+// Close commits the staged changes, after which no more changes can be
+// staged.
+func (b *AutoBatcher) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	_, err := b.flush(context.Background())
+	return err
+}
+
+func (b *AutoBatcher) stage(change FileChange) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errAutoBatcherClosed
+	}
+	b.batch.stage(change)
+	b.last = b.clock.Now()
+	if !b.waiting {
+		b.waiting = true
+		go b.wait()
+	}
+	return nil
+}
+
+// wait flushes the staged changes once the window has elapsed since the last
+// change was staged.
+func (b *AutoBatcher) wait() {
+	wait := b.window
+	for {
+		select {
+		case <-b.clock.After(wait):
+		case <-b.done:
+			return
+		}
+		b.mu.Lock()
+		if idle := b.clock.Now().Sub(b.last); idle < b.window {
+			wait = b.window - idle
+			b.mu.Unlock()
+			continue
+		}
+		b.waiting = false
+		_, err := b.flush(context.Background())
+		b.mu.Unlock()
+		if err != nil && b.onError != nil {
+			b.onError(err)
+		}
+		return
+	}
+}
+
+// flush commits the staged changes, the lock must be held.
+func (b *AutoBatcher) flush(ctx context.Context) (string, error) {
+	if b.batch.Len() == 0 {
+		return "", nil
+	}
+	sha, err := b.batch.Commit(ctx, b.repo, b.branch, b.message, b.signature)
+	if errors.Is(err, ErrNoChange) {
+		b.batch.changes = nil
+		return "", nil
+	}
+	return sha, err
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// manualClock is a Clock that only moves when it's advanced, the channels
+// returned by After receive once the clock is advanced past their deadline.
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []manualTimer
+	waiting chan struct{}
+}
+
+type manualTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC), waiting: make(chan struct{}, 10)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, manualTimer{deadline: c.now.Add(d), ch: ch})
+	c.waiting <- struct{}{}
+	return ch
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var pending []manualTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+type syncFilesUpdater struct {
+	mu        sync.Mutex
+	changes   [][]FileChange
+	messages  []string
+	committed chan struct{}
+}
+
+func (s *syncFilesUpdater) UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, changes)
+	s.messages = append(s.messages, message)
+	if s.committed != nil {
+		s.committed <- struct{}{}
+	}
+	return "commit-sha", nil
+}
+
+func TestAutoBatcherFlushesAfterWindow(t *testing.T) {
+	clock := newManualClock()
+	u := &syncFilesUpdater{committed: make(chan struct{}, 1)}
+	b := NewAutoBatcher(u, "my-org/my-repo", "main", time.Minute, WithBatchClock(clock), WithFlushCommit("sync", scm.Signature{}))
+
+	if err := b.Add("a.yaml", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	<-clock.waiting
+	clock.Advance(30 * time.Second)
+	if err := b.Add("b.yaml", []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(30 * time.Second)
+	<-clock.waiting
+	select {
+	case <-u.committed:
+		t.Fatal("changes were committed before the window elapsed since the last change")
+	default:
+	}
+	clock.Advance(30 * time.Second)
+	<-u.committed
+
+	want := [][]FileChange{{{Path: "a.yaml", Content: []byte("first")}, {Path: "b.yaml", Content: []byte("second")}}}
+	if diff := cmp.Diff(want, u.changes); diff != "" {
+		t.Fatalf("committed changes differ: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"sync"}, u.messages); diff != "" {
+		t.Fatalf("commit messages differ: %s", diff)
+	}
+	if l := b.Len(); l != 0 {
+		t.Fatalf("got %d staged changes after the flush, want 0", l)
+	}
+}
+
+func TestAutoBatcherClose(t *testing.T) {
+	u := &syncFilesUpdater{}
+	b := NewAutoBatcher(u, "my-org/my-repo", "main", time.Hour, WithBatchClock(newManualClock()))
+	if err := b.Delete("a.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]FileChange{{{Path: "a.yaml", Delete: true}}}
+	if diff := cmp.Diff(want, u.changes); diff != "" {
+		t.Fatalf("committed changes differ: %s", diff)
+	}
+	if err := b.Add("b.yaml", []byte("late")); !errors.Is(err, errAutoBatcherClosed) {
+		t.Fatalf("got %v staging a change after Close, want errAutoBatcherClosed", err)
+	}
+}
+
+func TestAutoBatcherFlush(t *testing.T) {
+	u := &syncFilesUpdater{}
+	b := NewAutoBatcher(u, "my-org/my-repo", "main", time.Hour, WithBatchClock(newManualClock()))
+	defer b.Close()
+
+	sha, err := b.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "" || len(u.changes) != 0 {
+		t.Fatalf("got sha %q and %d commits flushing no changes, want none", sha, len(u.changes))
+	}
+	if err := b.Add("a.yaml", []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+	sha, err = b.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "commit-sha" {
+		t.Fatalf("got sha %s, want commit-sha", sha)
+	}
+}
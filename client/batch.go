@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// FilesUpdater can apply several file changes in a single commit.
+type FilesUpdater interface {
+	UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error)
+}
+
+// NewBatcher creates and returns a new Batcher that commits the staged changes
+// with the FilesUpdater.
+func NewBatcher(u FilesUpdater) *Batcher {
+	return &Batcher{updater: u}
+}
+
+// Batcher stages file writes and deletions so that they can be committed
+// together.
+//
+// Staging the same path more than once replaces the earlier change.
+type Batcher struct {
+	updater FilesUpdater
+	changes []FileChange
+}
+
+// Batch returns a new Batcher that commits through this client.
+func (c *SCMClient) Batch() *Batcher {
+	return NewBatcher(c)
+}
+
+// Add stages the content to be written to the path.
+func (b *Batcher) Add(path string, content []byte) *Batcher {
+	b.stage(FileChange{Path: path, Content: content})
+	return b
+}
+
+// Delete stages the removal of the path.
+func (b *Batcher) Delete(path string) *Batcher {
+	b.stage(FileChange{Path: path, Delete: true})
+	return b
+}
+
+// Len returns the number of staged changes.
+func (b *Batcher) Len() int {
+	return len(b.changes)
+}
+
+// Commit applies the staged changes to the branch in a single commit, and
+// returns the SHA of the new commit.
+//
+// The staged changes are only cleared if the commit succeeds.
+func (b *Batcher) Commit(ctx context.Context, repo, branch, message string, signature scm.Signature, opts ...WriteOption) (string, error) {
+	if len(b.changes) == 0 {
+		return "", errors.New("no changes staged")
+	}
+	sha, err := b.updater.UpdateFiles(ctx, repo, branch, message, signature, b.changes, opts...)
+	if err != nil {
+		return "", err
+	}
+	b.changes = nil
+	return sha, nil
+}
+
+func (b *Batcher) stage(change FileChange) {
+	for i := range b.changes {
+		if b.changes[i].Path == change.Path {
+			b.changes[i] = change
+			return
+		}
+	}
+	b.changes = append(b.changes, change)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+type stubFilesUpdater struct {
+	changes [][]FileChange
+	err     error
+}
+
+func (s *stubFilesUpdater) UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.changes = append(s.changes, changes)
+	return "commit-sha", nil
+}
+
+func TestBatcherCommit(t *testing.T) {
+	u := &stubFilesUpdater{}
+	b := NewBatcher(u).
+		Add("a.yaml", []byte("first")).
+		Delete("b.yaml").
+		Add("a.yaml", []byte("second"))
+
+	sha, err := b.Commit(context.Background(), "my-org/my-repo", "main", "batched", scm.Signature{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "commit-sha" {
+		t.Fatalf("got sha %s, want commit-sha", sha)
+	}
+	want := [][]FileChange{{{Path: "a.yaml", Content: []byte("second")}, {Path: "b.yaml", Delete: true}}}
+	if diff := cmp.Diff(want, u.changes); diff != "" {
+		t.Fatalf("committed changes differ: %s", diff)
+	}
+	if l := b.Len(); l != 0 {
+		t.Fatalf("got %d staged changes after commit, want 0", l)
+	}
+}
+
+func TestBatcherCommitWithNoChanges(t *testing.T) {
+	u := &stubFilesUpdater{}
+
+	_, err := NewBatcher(u).Commit(context.Background(), "my-org/my-repo", "main", "batched", scm.Signature{})
+	if err == nil {
+		t.Fatal("expected an error committing no changes")
+	}
+	if len(u.changes) != 0 {
+		t.Fatal("expected no commit to be made")
+	}
+}
+
+func TestBatcherCommitKeepsChangesOnError(t *testing.T) {
+	u := &stubFilesUpdater{err: errors.New("failed")}
+	b := NewBatcher(u).Add("a.yaml", []byte("first"))
+
+	if _, err := b.Commit(context.Background(), "my-org/my-repo", "main", "batched", scm.Signature{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if l := b.Len(); l != 1 {
+		t.Fatalf("got %d staged changes, want 1", l)
+	}
+}
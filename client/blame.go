@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// BlameHunk is a range of lines of a file that were last changed by the same
+// commit.
+type BlameHunk struct {
+	StartLine int // the first line of the range, starting at 1
+	EndLine   int // the last line of the range, inclusive
+	Sha       string
+	Author    scm.Signature
+	Message   string
+}
+
+// GetBlame returns the hunks of the file at the ref, ordered by line, which
+// cover every line of the file.
+//
+// Concurrent calls for the same file are coalesced with WithSingleflight, and
+// on GitLab, where the blame is read with a GET request, the responses are
+// cached with WithETagCache.
+//
+// Blame is only supported on GitHub, where it uses the GraphQL API, and on
+// GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetBlame(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error) {
+	var out []*BlameHunk
+	err := c.callAt(ctx, "GetBlame", repo, ref, path, func(ctx context.Context) error {
+		ref, err := c.resolveRef(ctx, repo, ref)
+		if err != nil {
+			return err
+		}
+		v, err := c.flights.do(flightKey(ctx, "GetBlame", repo, ref, path), func() (interface{}, error) {
+			return c.getBlame(ctx, repo, ref, path)
+		})
+		out, _ = v.([]*BlameHunk)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getBlame(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return c.getBlameGitHub(ctx, repo, ref, path)
+	case scm.DriverGitlab:
+		return c.getBlameGitLab(ctx, repo, ref, path)
+	}
+	return nil, scm.ErrNotSupported
+}
+
+const blameQuery = `query($owner: String!, $name: String!, $ref: String!, $path: String!) {
+  repository(owner: $owner, name: $name) {
+    object(expression: $ref) {
+      ... on Commit {
+        blame(path: $path) {
+          ranges {
+            startingLine
+            endingLine
+            commit { oid message authoredDate author { name email user { login } } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type ghBlameRange struct {
+	StartingLine int `json:"startingLine"`
+	EndingLine   int `json:"endingLine"`
+	Commit       struct {
+		Oid          string    `json:"oid"`
+		Message      string    `json:"message"`
+		AuthoredDate time.Time `json:"authoredDate"`
+		Author       struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+			User  *struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+func (c *SCMClient) getBlameGitHub(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error) {
+	owner, name := scm.Split(repo)
+	msg := fmt.Sprintf("failed to get blame for file %s from repo %s ref %s", path, repo, ref)
+	var out struct {
+		Repository *struct {
+			Object *struct {
+				Blame *struct {
+					Ranges []ghBlameRange `json:"ranges"`
+				} `json:"blame"`
+			} `json:"object"`
+		} `json:"repository"`
+	}
+	variables := map[string]interface{}{"owner": owner, "name": name, "ref": ref, "path": path}
+	if err := c.doGraphQL(ctx, msg, blameQuery, variables, &out); err != nil {
+		return nil, err
+	}
+	if out.Repository == nil || out.Repository.Object == nil || out.Repository.Object.Blame == nil {
+		return nil, SCMError{Msg: msg, Status: http.StatusNotFound}
+	}
+	hunks := make([]*BlameHunk, 0, len(out.Repository.Object.Blame.Ranges))
+	for _, r := range out.Repository.Object.Blame.Ranges {
+		author := scm.Signature{Name: r.Commit.Author.Name, Email: r.Commit.Author.Email, Date: r.Commit.AuthoredDate}
+		if r.Commit.Author.User != nil {
+			author.Login = r.Commit.Author.User.Login
+		}
+		hunks = append(hunks, &BlameHunk{
+			StartLine: r.StartingLine,
+			EndLine:   r.EndingLine,
+			Sha:       r.Commit.Oid,
+			Author:    author,
+			Message:   r.Commit.Message,
+		})
+	}
+	sort.Slice(hunks, func(i, j int) bool { return hunks[i].StartLine < hunks[j].StartLine })
+	return hunks, nil
+}
+
+type glBlameRange struct {
+	Commit struct {
+		ID           string    `json:"id"`
+		Message      string    `json:"message"`
+		AuthorName   string    `json:"author_name"`
+		AuthorEmail  string    `json:"author_email"`
+		AuthoredDate time.Time `json:"authored_date"`
+	} `json:"commit"`
+	Lines []string `json:"lines"`
+}
+
+// getBlameGitLab reads the blame from GitLab, which returns the lines of each
+// range rather than the line numbers, so the ranges are numbered from the
+// number of lines in the ranges before them.
+func (c *SCMClient) getBlameGitLab(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error) {
+	var ranges []glBlameRange
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/projects/%s/repository/files/%s/blame?ref=%s", encodeRepo(repo), url.PathEscape(path), url.QueryEscape(ref)), nil, &ranges)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get blame for file %s from repo %s ref %s", path, repo, ref), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	hunks := make([]*BlameHunk, 0, len(ranges))
+	line := 1
+	for _, r := range ranges {
+		if len(r.Lines) == 0 {
+			continue
+		}
+		hunks = append(hunks, &BlameHunk{
+			StartLine: line,
+			EndLine:   line + len(r.Lines) - 1,
+			Sha:       r.Commit.ID,
+			Author:    scm.Signature{Name: r.Commit.AuthorName, Email: r.Commit.AuthorEmail, Date: r.Commit.AuthoredDate},
+			Message:   r.Commit.Message,
+		})
+		line += len(r.Lines)
+	}
+	return hunks, nil
+}
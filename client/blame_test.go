@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetBlame(t *testing.T) {
+	authored := time.Date(2021, time.March, 4, 10, 30, 0, 0, time.UTC)
+	commit := func(sha, login string) map[string]interface{} {
+		author := map[string]interface{}{"name": "Monalisa Octocat", "email": "mona@example.com", "user": nil}
+		if login != "" {
+			author["user"] = map[string]string{"login": login}
+		}
+		return map[string]interface{}{"oid": sha, "message": "Update config", "authoredDate": authored, "author": author}
+	}
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"path":"config/app.yaml","ref":"main"`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"object": map[string]interface{}{
+			"blame": map[string]interface{}{"ranges": []map[string]interface{}{
+				{"startingLine": 4, "endingLine": 4, "commit": commit("a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d", "")},
+				{"startingLine": 1, "endingLine": 3, "commit": commit("7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "monalisa")},
+			}},
+		}}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	hunks, err := client.GetBlame(context.Background(), "Codertocat/Hello-World", "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*BlameHunk{
+		{StartLine: 1, EndLine: 3, Sha: "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", Message: "Update config",
+			Author: scm.Signature{Name: "Monalisa Octocat", Email: "mona@example.com", Date: authored, Login: "monalisa"}},
+		{StartLine: 4, EndLine: 4, Sha: "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d", Message: "Update config",
+			Author: scm.Signature{Name: "Monalisa Octocat", Email: "mona@example.com", Date: authored}},
+	}
+	if diff := cmp.Diff(want, hunks); diff != "" {
+		t.Fatalf("hunks don't match:\n%s", diff)
+	}
+}
+
+func TestGetBlameWithUnknownRef(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"object": nil}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetBlame(context.Background(), "Codertocat/Hello-World", "unknown", "config/app.yaml")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetBlameInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml/blame").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"commit": map[string]interface{}{"id": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "author_name": "Monalisa Octocat"}, "lines": []string{"a: 1", "b: 2"}},
+			{"commit": map[string]interface{}{"id": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d", "author_name": "Hubot"}, "lines": []string{"c: 3"}},
+			{"commit": map[string]interface{}{"id": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "author_name": "Monalisa Octocat"}, "lines": []string{"d: 4", "e: 5", "f: 6"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	hunks, err := client.GetBlame(context.Background(), "Codertocat/Hello-World", "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{1, 2}, {3, 3}, {4, 6}}
+	if len(hunks) != len(want) {
+		t.Fatalf("got %d hunks, want %d", len(hunks), len(want))
+	}
+	for i, w := range want {
+		if hunks[i].StartLine != w[0] || hunks[i].EndLine != w[1] {
+			t.Fatalf("got hunk %d for lines %d-%d, want %d-%d", i, hunks[i].StartLine, hunks[i].EndLine, w[0], w[1])
+		}
+	}
+	if hunks[1].Author.Name != "Hubot" {
+		t.Fatalf("got author %q for the second hunk, want Hubot", hunks[1].Author.Name)
+	}
+}
@@ -0,0 +1,421 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// DeleteBranchesByPrefix deletes every branch in the repo whose name starts
+// with the prefix, and returns the number of branches that were deleted.
+//
+// A failure to delete a branch doesn't stop the remaining deletions, all the
+// failures are returned together in a BulkError.
+func (c *SCMClient) DeleteBranchesByPrefix(ctx context.Context, repo, prefix string) (int, error) {
+	var out int
+	err := c.call(ctx, "DeleteBranchesByPrefix", repo, func(ctx context.Context) (err error) {
+		out, err = c.deleteBranchesByPrefix(ctx, repo, prefix)
+		return err
+	})
+	c.emit(Event{Type: "DeleteBranchesByPrefix", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) deleteBranchesByPrefix(ctx context.Context, repo, prefix string) (int, error) {
+	if prefix == "" {
+		return 0, errors.New("a prefix is required to delete branches")
+	}
+	branches, err := c.listBranches(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		deleted int
+		errs    []error
+	)
+	for _, name := range matchingBranches(branches, prefix) {
+		if err := c.deleteBranch(ctx, repo, name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	if len(errs) > 0 {
+		return deleted, BulkError{Errs: errs}
+	}
+	return deleted, nil
+}
+
+// BranchesWithoutOpenPRs returns the branches in the repo whose name starts
+// with the prefix, and that are not the source of an open pull request,
+// sorted by name, e.g. to find the branches that are safe to delete.
+//
+// Pull requests from forks are matched by the name of their source branch,
+// so a branch with the same name as one in a fork is never returned.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) BranchesWithoutOpenPRs(ctx context.Context, repo, prefix string) ([]string, error) {
+	var out []string
+	err := c.call(ctx, "BranchesWithoutOpenPRs", repo, func(ctx context.Context) (err error) {
+		out, err = c.branchesWithoutOpenPRs(ctx, repo, prefix)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) branchesWithoutOpenPRs(ctx context.Context, repo, prefix string) ([]string, error) {
+	branches, err := c.listBranches(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	prs, err := c.listOpenPullRequests(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	heads := map[string]bool{}
+	for _, pr := range prs {
+		heads[pr.Source] = true
+	}
+	var names []string
+	for _, name := range matchingBranches(branches, prefix) {
+		if !heads[name] {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// IsBranchProtected returns true if the upstream service has protection rules
+// in place for the branch.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) IsBranchProtected(ctx context.Context, repo, branch string) (bool, error) {
+	var out bool
+	err := c.call(ctx, "IsBranchProtected", repo, func(ctx context.Context) (err error) {
+		if branch, err = c.resolveRef(ctx, repo, branch); err != nil {
+			return err
+		}
+		out, err = c.isBranchProtected(ctx, repo, branch)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) isBranchProtected(ctx context.Context, repo, branch string) (bool, error) {
+	var path string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/branches/%s", repo, branch)
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s/repository/branches/%s", encodeRepo(repo), url.PathEscape(branch))
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/branches/%s", repo, branch)
+	default:
+		return false, scm.ErrNotSupported
+	}
+	out := struct {
+		Protected bool `json:"protected"`
+	}{}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return false, SCMError{Msg: fmt.Sprintf("failed to get branch %s in repo %s", branch, repo), Status: r.Status}
+	}
+	if err != nil {
+		return false, err
+	}
+	return out.Protected, nil
+}
+
+// CreateBranchIfBaseMatches creates the branch from the head of the base
+// branch, only if the head is the expected SHA, and returns the SHA the branch
+// was created from.
+//
+// If the head of the base branch has moved, an error wrapping ErrConflict is
+// returned, and the branch is not created.
+func (c *SCMClient) CreateBranchIfBaseMatches(ctx context.Context, repo, branch, baseBranch, expectedBaseSHA string) (string, error) {
+	var out string
+	err := c.call(ctx, "CreateBranchIfBaseMatches", repo, func(ctx context.Context) (err error) {
+		out, err = c.createBranchIfBaseMatches(ctx, repo, branch, baseBranch, expectedBaseSHA)
+		return err
+	})
+	c.emit(Event{Type: "CreateBranchIfBaseMatches", Repo: repo, Branch: branch, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createBranchIfBaseMatches(ctx context.Context, repo, branch, baseBranch, expectedBaseSHA string) (string, error) {
+	head, err := c.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return "", err
+	}
+	if head != expectedBaseSHA {
+		return "", fmt.Errorf("head of branch %s in repo %s is %s, not %s: %w", baseBranch, repo, head, expectedBaseSHA, ErrConflict)
+	}
+	if err := c.CreateBranch(ctx, repo, branch, head); err != nil {
+		return "", err
+	}
+	return head, nil
+}
+
+// IsBranchMerged returns true if every commit on the branch is also on the
+// base branch, i.e. the branch is not ahead of the base branch.
+//
+// Comparing branches is only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) IsBranchMerged(ctx context.Context, repo, branch, baseBranch string) (bool, error) {
+	var out bool
+	err := c.call(ctx, "IsBranchMerged", repo, func(ctx context.Context) (err error) {
+		out, err = c.isBranchMerged(ctx, repo, branch, baseBranch)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) isBranchMerged(ctx context.Context, repo, branch, baseBranch string) (bool, error) {
+	ahead, err := c.commitsAhead(ctx, repo, branch, baseBranch)
+	if err != nil {
+		return false, err
+	}
+	return ahead == 0, nil
+}
+
+// commitsAhead returns the number of commits on the branch that are not on the
+// base branch.
+func (c *SCMClient) commitsAhead(ctx context.Context, repo, branch, baseBranch string) (int, error) {
+	var (
+		path string
+		out  struct {
+			AheadBy int               `json:"ahead_by"`
+			Commits []json.RawMessage `json:"commits"`
+		}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/compare/%s...%s", repo, url.PathEscape(baseBranch), url.PathEscape(branch))
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s/repository/compare?from=%s&to=%s&straight=false", encodeRepo(repo), url.QueryEscape(baseBranch), url.QueryEscape(branch))
+	default:
+		return 0, scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return 0, SCMError{Msg: fmt.Sprintf("failed to compare branch %s with %s in repo %s", branch, baseBranch, repo), Status: r.Status}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if c.scmClient.Driver == scm.DriverGitlab {
+		return len(out.Commits), nil
+	}
+	return out.AheadBy, nil
+}
+
+// MergeBase returns the SHA of the best common ancestor of the refs, which is
+// the base of the changes introduced on either ref, e.g. for a three-dot
+// diff.
+//
+// Merge bases are only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) MergeBase(ctx context.Context, repo, ref1, ref2 string) (string, error) {
+	var out string
+	err := c.call(ctx, "MergeBase", repo, func(ctx context.Context) (err error) {
+		out, err = c.mergeBase(ctx, repo, ref1, ref2)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) mergeBase(ctx context.Context, repo, ref1, ref2 string) (string, error) {
+	var (
+		path string
+		out  struct {
+			ID              string `json:"id"`
+			MergeBaseCommit struct {
+				Sha string `json:"sha"`
+			} `json:"merge_base_commit"`
+		}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/compare/%s...%s", repo, url.PathEscape(ref1), url.PathEscape(ref2))
+	case scm.DriverGitlab:
+		params := url.Values{"refs[]": {ref1, ref2}}
+		path = fmt.Sprintf("api/v4/projects/%s/repository/merge_base?%s", encodeRepo(repo), params.Encode())
+	default:
+		return "", scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: fmt.Sprintf("failed to get merge base of %s and %s in repo %s", ref1, ref2, repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	if c.scmClient.Driver == scm.DriverGitlab {
+		return out.ID, nil
+	}
+	return out.MergeBaseCommit.Sha, nil
+}
+
+// GetBranchHeads gets the head SHAs of the branches concurrently, bounded by
+// the configured concurrency, and returns them keyed by the branch name.
+//
+// Branches that don't exist have an empty head, other failures don't stop the
+// remaining branches from being fetched, they are returned together in a
+// BulkError, along with the heads that were fetched.
+//
+// Each head is fetched like with GetBranchHead, so empty branches are
+// resolved with WithDefaultRef, and requests are coalesced with
+// WithSingleflight.
+func (c *SCMClient) GetBranchHeads(ctx context.Context, repo string, branches []string) (map[string]string, error) {
+	var out map[string]string
+	err := c.call(ctx, "GetBranchHeads", repo, func(ctx context.Context) (err error) {
+		out, err = c.getBranchHeads(ctx, repo, branches)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getBranchHeads(ctx context.Context, repo string, branches []string) (map[string]string, error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		heads = make(map[string]string, len(branches))
+		sem   = make(chan struct{}, c.workers())
+	)
+	for _, branch := range branches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(branch string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sha, status, err := c.getBranchHead(ctx, repo, branch)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case status == http.StatusNotFound:
+				heads[branch] = ""
+			case isErrorStatus(status):
+				errs = append(errs, SCMError{Msg: fmt.Sprintf("failed to get branch %s in repo %s", branch, repo), Status: status})
+			case err != nil:
+				errs = append(errs, err)
+			default:
+				heads[branch] = sha
+			}
+		}(branch)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return heads, BulkError{Errs: errs}
+	}
+	return heads, nil
+}
+
+// ensureBranch creates the branch from the head of the default branch of the
+// repo if it doesn't already exist.
+func (c *SCMClient) ensureBranch(ctx context.Context, repo, branch string) error {
+	_, r, err := c.scmClient.Git.FindBranch(ctx, repo, branch)
+	if r == nil || r.Status != http.StatusNotFound {
+		if r != nil && isErrorStatus(r.Status) {
+			return SCMError{Msg: fmt.Sprintf("failed to get branch %s in repo %s", branch, repo), Status: r.Status}
+		}
+		return err
+	}
+	repository, r, err := c.scmClient.Repositories.Find(ctx, repo)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to get repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return err
+	}
+	head, err := c.GetBranchHead(ctx, repo, repository.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to get head of default branch %s: %w", repository.Branch, err)
+	}
+	return c.CreateBranch(ctx, repo, branch, head)
+}
+
+// listBranches pages through all the branches in the repo.
+func (c *SCMClient) listBranches(ctx context.Context, repo string) ([]*scm.Reference, error) {
+	var (
+		all  []*scm.Reference
+		opts = scm.ListOptions{Size: 100}
+	)
+	for {
+		branches, r, err := c.scmClient.Git.ListBranches(ctx, repo, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list branches in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, branches...)
+		if !nextPage(&opts, r) {
+			return all, nil
+		}
+	}
+}
+
+// deleteBranch deletes a single branch, go-scm provides no way to do this, so
+// the request is made directly to the driver's API.
+func (c *SCMClient) deleteBranch(ctx context.Context, repo, branch string) error {
+	var (
+		path string
+		in   interface{}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/git/refs/heads/%s", repo, escapePath(branch))
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s/repository/branches/%s", encodeRepo(repo), url.PathEscape(branch))
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/branches/%s", repo, escapePath(branch))
+	case scm.DriverBitbucket:
+		path = fmt.Sprintf("2.0/repositories/%s/refs/branches/%s", repo, branch)
+	case scm.DriverStash:
+		namespace, name := scm.Split(repo)
+		path = fmt.Sprintf("rest/branch-utils/1.0/projects/%s/repos/%s/branches", namespace, name)
+		in = map[string]string{"name": scm.ExpandRef(branch, "refs/heads")}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodDelete, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to delete branch %s in repo %s", branch, repo), Status: r.Status}
+	}
+	return err
+}
+
+// matchingBranches returns the sorted names of the branches that start with
+// the prefix.
+func matchingBranches(branches []*scm.Reference, prefix string) []string {
+	var names []string
+	for _, b := range branches {
+		if strings.HasPrefix(b.Name, prefix) {
+			names = append(names, b.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeRepo encodes the repo as a GitLab project ID.
+func encodeRepo(repo string) string {
+	return strings.Replace(repo, "/", "%2F", -1)
+}
@@ -0,0 +1,421 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestDeleteBranchesByPrefix(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_list_branches.json")
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/gitops-abcde").
+		Reply(http.StatusNoContent)
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/gitops-fghij").
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	deleted, err := client.DeleteBranchesByPrefix(context.Background(), "Codertocat/Hello-World", "gitops-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("got %d deleted branches, want 2", deleted)
+	}
+	if !gock.IsDone() {
+		t.Fatal("branches were not deleted")
+	}
+}
+
+func TestDeleteBranchesByPrefixWithFailures(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_list_branches.json")
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/gitops-abcde").
+		Reply(http.StatusUnprocessableEntity)
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/gitops-fghij").
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	deleted, err := client.DeleteBranchesByPrefix(context.Background(), "Codertocat/Hello-World", "gitops-")
+	if !test.MatchError(t, `1 errors occurred: failed to delete branch gitops-abcde.*\(422\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	var scmErr SCMError
+	if !errors.As(err, &scmErr) || scmErr.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("got %v, want the error of the failed deletion", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("got %d deleted branches, want 1", deleted)
+	}
+	if !gock.IsDone() {
+		t.Fatal("not all branch deletions were attempted")
+	}
+}
+
+func TestDeleteBranchesByPrefixEscapesBranches(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{{"name": "gitops/fix#1", "commit": map[string]string{"sha": "abc123"}}})
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/gitops/fix").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return req.URL.EscapedPath() == "/repos/Codertocat/Hello-World/git/refs/heads/gitops/fix%231", nil
+		}).
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	deleted, err := client.DeleteBranchesByPrefix(context.Background(), "Codertocat/Hello-World", "gitops/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("got %d deleted branches, want 1", deleted)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the branch was not deleted")
+	}
+}
+
+func TestDeleteBranchesByPrefixWithEmptyPrefix(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.DeleteBranchesByPrefix(context.Background(), "Codertocat/Hello-World", "")
+	if !test.MatchError(t, `prefix is required`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestIsBranchProtected(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"name": "master", "protected": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	protected, err := client.IsBranchProtected(context.Background(), "Codertocat/Hello-World", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !protected {
+		t.Fatal("expected the branch to be protected")
+	}
+}
+
+func TestIsBranchProtectedInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"name": "main", "protected": false})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	protected, err := client.IsBranchProtected(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protected {
+		t.Fatal("expected the branch not to be protected")
+	}
+	if !gock.IsDone() {
+		t.Fatal("branch was not fetched")
+	}
+}
+
+func TestIsBranchProtectedWithMissingBranch(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/unknown").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.IsBranchProtected(context.Background(), "Codertocat/Hello-World", "unknown")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCreateBranchIfBaseMatches(t *testing.T) {
+	sha := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		MatchType("json").
+		JSON(map[string]string{"ref": "refs/heads/new-feature", "sha": sha}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/created_ref.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	head, err := client.CreateBranchIfBaseMatches(context.Background(), "Codertocat/Hello-World", "new-feature", "master", sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != sha {
+		t.Fatalf("got head %s, want %s", head, sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("branch was not created")
+	}
+}
+
+func TestCreateBranchIfBaseMatchesWithMovedBase(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreateBranchIfBaseMatches(context.Background(), "Codertocat/Hello-World", "new-feature", "master", "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want %v", err, ErrConflict)
+	}
+}
+
+func TestIsBranchMerged(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/master...gitops-abcde").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"status": "behind", "ahead_by": 0, "behind_by": 2})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/master...gitops-fghij").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"status": "diverged", "ahead_by": 1, "behind_by": 2})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	merged, err := client.IsBranchMerged(context.Background(), "Codertocat/Hello-World", "gitops-abcde", "master")
+	if err != nil || !merged {
+		t.Fatalf("got %v, %v, want merged", merged, err)
+	}
+	merged, err = client.IsBranchMerged(context.Background(), "Codertocat/Hello-World", "gitops-fghij", "master")
+	if err != nil || merged {
+		t.Fatalf("got %v, %v, want not merged", merged, err)
+	}
+}
+
+func TestIsBranchMergedInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/compare").
+		MatchParam("from", "master").
+		MatchParam("to", "gitops-abcde").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"commits": []map[string]string{{"id": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	merged, err := client.IsBranchMerged(context.Background(), "Codertocat/Hello-World", "gitops-abcde", "master")
+	if err != nil || merged {
+		t.Fatalf("got %v, %v, want not merged", merged, err)
+	}
+}
+
+func TestGetBranchHeads(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/production").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/staging").
+		Reply(http.StatusInternalServerError)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithConcurrency(2))
+
+	heads, err := client.GetBranchHeads(context.Background(), "Codertocat/Hello-World", []string{"master", "production", "staging"})
+	if !test.MatchError(t, `failed to get branch staging.*\(500\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	want := map[string]string{"master": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "production": ""}
+	if diff := cmp.Diff(want, heads); diff != "" {
+		t.Fatalf("got different heads: %s", diff)
+	}
+}
+
+func TestGetBranchHeadsWithDefaultRef(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithDefaultRef("master"))
+
+	heads, err := client.GetBranchHeads(context.Background(), "Codertocat/Hello-World", []string{""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"}, heads); diff != "" {
+		t.Fatalf("got different heads: %s", diff)
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/main...feature").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"merge_base_commit": map[string]string{"sha": "base-sha"}, "ahead_by": 2})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.MergeBase(context.Background(), "Codertocat/Hello-World", "main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "base-sha" {
+		t.Fatalf("got merge base %s, want base-sha", sha)
+	}
+}
+
+func TestMergeBaseInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/merge_base").
+		MatchParam("refs[]", "main").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"id": "base-sha"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.MergeBase(context.Background(), "Codertocat/Hello-World", "main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "base-sha" {
+		t.Fatalf("got merge base %s, want base-sha", sha)
+	}
+}
+
+func TestBranchesWithoutOpenPRs(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_list_branches.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"number": 1, "state": "open", "head": map[string]string{"ref": "gitops-abcde"}, "base": map[string]string{"ref": "main"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	branches, err := client.BranchesWithoutOpenPRs(context.Background(), "Codertocat/Hello-World", "gitops-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"gitops-fghij"}, branches); diff != "" {
+		t.Fatalf("got different branches: %s", diff)
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// callKey is the context key that marks a call to a GitClient method in
+// progress.
+type callKey struct{}
+
+// orgMethods are the methods that are called with an org, or without a repo,
+// rather than a repo.
+var orgMethods = map[string]bool{"ListRepositories": true, "GetTokenScopes": true, "HasScope": true, "CreateGist": true, "GetGist": true}
+
+// call runs the implementation of a GitClient method, with the behaviour
+// configured for the client, e.g. retries and slow call logging.
+//
+// Methods that the driver doesn't support fail without making any requests,
+// methods called by other methods run the implementation directly, so that
+// they are not retried separately from the method that called them.
+func (c *SCMClient) call(ctx context.Context, method, repo string, fn func(ctx context.Context) error) error {
+	return c.callAt(ctx, method, repo, "", "", fn)
+}
+
+// callAt runs the implementation of a GitClient method like call, for methods
+// that act on the file at the path and ref, which are included in the errors
+// wrapped for WithErrorContext.
+func (c *SCMClient) callAt(ctx context.Context, method, repo, ref, path string, fn func(ctx context.Context) error) error {
+	if ctx.Value(callKey{}) != nil {
+		return fn(ctx)
+	}
+	err := c.runCall(context.WithValue(ctx, callKey{}, method), method, repo, fn)
+	if err != nil && c.errorContext {
+		return WrapErrorContext(method, repo, ref, path, err)
+	}
+	return err
+}
+
+func (c *SCMClient) runCall(ctx context.Context, method, repo string, fn func(ctx context.Context) error) error {
+	start := c.getClock().Now()
+	err := c.runCallChecked(ctx, method, repo, fn)
+	c.getMetrics().ObserveCall(method, c.getClock().Now().Sub(start), err)
+	return err
+}
+
+// runCallChecked runs the call once the method and repo are checked.
+func (c *SCMClient) runCallChecked(ctx context.Context, method, repo string, fn func(ctx context.Context) error) error {
+	if !c.capable(method) {
+		return scm.ErrNotSupported
+	}
+	if c.validateRepos && !orgMethods[method] {
+		if err := c.validateRepo(repo); err != nil {
+			return err
+		}
+	}
+	if err := c.checkArchived(ctx, method, repo); err != nil {
+		return err
+	}
+	return c.observeSlowCall(ctx, method, repo, func(ctx context.Context) error {
+		return c.retryCall(ctx, method, fn)
+	})
+}
+
+// WrapErrorContext wraps the error returned by the method with the repo, and
+// the path and ref of the file if the method acts on one, e.g.
+// "GetFile org/repo/README.md@main: ...", the error can still be matched with
+// errors.Is and errors.As.
+func WrapErrorContext(method, repo, ref, path string, err error) error {
+	location := repo
+	if path != "" {
+		location += "/" + strings.TrimPrefix(path, "/")
+	}
+	if ref != "" {
+		location += "@" + ref
+	}
+	if location == "" {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return fmt.Errorf("%s %s: %w", method, location, err)
+}
+
+// streamingMethods are the methods that consume a stream, which can't be
+// replayed, so they are never retried.
+var streamingMethods = map[string]bool{"UploadReleaseAsset": true}
+
+// retryCall runs the implementation, and retries it if it fails with an error
+// that is retryable, when retries are configured.
+func (c *SCMClient) retryCall(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	attempts := 1
+	if c.retry.attempts > 1 && !c.retry.noRetry[method] && !streamingMethods[method] {
+		attempts = c.retry.attempts
+	}
+	backoff := c.retry.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= attempts || !isRetryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-c.getClock().After(backoff):
+		}
+		if isRateLimited(err) {
+			c.getMetrics().ObserveRateLimitWait(backoff)
+		}
+		c.getMetrics().IncRetry(method)
+		backoff *= 2
+	}
+}
+
+// isRetryable returns true for errors that are likely to be transient, server
+// errors, rate limiting and network timeouts.
+func isRetryable(err error) bool {
+	var scmErr SCMError
+	if errors.As(err, &scmErr) {
+		return scmErr.Status >= http.StatusInternalServerError || scmErr.Status == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isRateLimited returns true for errors from responses with the Too Many
+// Requests status.
+func isRateLimited(err error) bool {
+	var scmErr SCMError
+	return errors.As(err, &scmErr) && scmErr.Status == http.StatusTooManyRequests
+}
+
+// retryPolicy configures the retries of failed calls.
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+	noRetry  map[string]bool
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetFileWithRetry(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusBadGateway)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock), WithRetry(3, time.Second))
+
+	if _, err := client.GetFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{time.Second}; !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("got waits %v, want %v", clock.waits, want)
+	}
+}
+
+func TestGetFileWithRetryExhausted(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Times(3).
+		Reply(http.StatusInternalServerError)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock), WithRetry(3, time.Second))
+
+	_, err = client.GetFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if !test.MatchError(t, `failed to get file.*(500)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("got waits %v, want %v", clock.waits, want)
+	}
+}
+
+func TestGetFileWithRetryNotRetryable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock), WithRetry(3, time.Second))
+
+	_, err = client.GetFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if !test.MatchError(t, `failed to get file.*(404)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if len(clock.waits) != 0 {
+		t.Fatalf("got waits %v, want none", clock.waits)
+	}
+}
+
+func TestGetFileWithNoRetryMethods(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusInternalServerError)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{}), WithRetry(3, time.Second), WithNoRetryMethods("GetFile"))
+
+	_, err = client.GetFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if !test.MatchError(t, `failed to get file.*(500)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if !gock.IsPending() {
+		t.Fatal("the request was retried")
+	}
+}
+
+func TestGetFileWithErrorContext(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithErrorContext())
+
+	_, err = client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if !test.MatchError(t, `^GetFile Codertocat/Hello-World/config/my/file.yaml@master: failed to get file`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestErrorContextWithoutPath(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithErrorContext())
+
+	err = client.EnableAutoMerge(context.Background(), "Codertocat/Hello-World", 1, MergeMethodMerge)
+	if !errors.Is(err, scm.ErrNotSupported) {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+	if want := "EnableAutoMerge Codertocat/Hello-World: " + scm.ErrNotSupported.Error(); err.Error() != want {
+		t.Fatalf("got error %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CassetteMode controls whether a cassette records or replays interactions.
+type CassetteMode int
+
+const (
+	// CassetteRecordOnce records interactions if the cassette file doesn't
+	// exist, and replays them if it does.
+	CassetteRecordOnce CassetteMode = iota
+	// CassetteRecord always makes requests upstream, and overwrites the
+	// cassette file with the recorded interactions.
+	CassetteRecord
+	// CassetteReplay only replays recorded interactions, requests that were
+	// not recorded fail.
+	CassetteReplay
+)
+
+// redactedHeaders are the request headers that are not stored in cassettes.
+var redactedHeaders = []string{"Authorization", "Private-Token", "Cookie"}
+
+// WithCassette is an option func that records the HTTP interactions with the
+// upstream service to a file, and replays them, which allows tests against the
+// SCMClient to run without access to the service.
+//
+// Credentials in the request headers are redacted before they are stored,
+// credentials added by the transport of the scm.Client are added after the
+// cassette, and are never seen.
+func WithCassette(path string, mode CassetteMode) ClientFunc {
+	return func(c *SCMClient) {
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return newCassette(path, mode, rt)
+		})
+	}
+}
+
+type cassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type interaction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+	played   bool
+}
+
+type cassette struct {
+	path         string
+	recording    bool
+	next         http.RoundTripper
+	mu           sync.Mutex
+	interactions []*interaction
+	loadErr      error
+}
+
+func newCassette(path string, mode CassetteMode, next http.RoundTripper) *cassette {
+	c := &cassette{path: path, next: next}
+	switch mode {
+	case CassetteRecord:
+		c.recording = true
+	case CassetteRecordOnce:
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			c.recording = true
+		}
+	}
+	if !c.recording {
+		c.loadErr = c.load()
+	}
+	return c
+}
+
+func (c *cassette) load() error {
+	b, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return fmt.Errorf("failed to parse cassette %s: %w", c.path, err)
+	}
+	return nil
+}
+
+func (c *cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.recording {
+		return c.record(req, body)
+	}
+	return c.replay(req, body)
+}
+
+// record makes the request upstream, and stores the interaction once the
+// response is read, the lock is only held while storing it, so concurrent
+// requests are not serialized.
+func (c *cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := transportOrDefault(c.next).RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	header := req.Header.Clone()
+	for _, h := range redactedHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, "REDACTED")
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, &interaction{
+		Request:  cassetteRequest{Method: req.Method, URL: req.URL.String(), Header: header, Body: string(body)},
+		Response: cassetteResponse{Status: res.StatusCode, Header: res.Header, Body: string(resBody)},
+	})
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(c.path, b, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return res, nil
+}
+
+// replay returns the first recorded response for a matching request that has
+// not already been played, so that repeated requests are replayed in order.
+func (c *cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	if c.loadErr != nil {
+		return nil, c.loadErr
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	url := req.URL.String()
+	for _, i := range c.interactions {
+		if i.played || i.Request.Method != req.Method || i.Request.URL != url || i.Request.Body != string(body) {
+			continue
+		}
+		i.played = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.Status, http.StatusText(i.Response.Status)),
+			StatusCode:    i.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Response.Header,
+			Body:          ioutil.NopCloser(bytes.NewBufferString(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction in cassette %s for %s %s", c.path, req.Method, url)
+}
+
+// readRequestBody reads the request body, and replaces it so that it can be
+// sent upstream.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/README.md").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+
+	recording := makeCassetteClient(t, path, CassetteRecordOnce)
+	file, err := recording.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("request was not made upstream")
+	}
+	gock.Off()
+
+	replaying := makeCassetteClient(t, path, CassetteRecordOnce)
+	replayed, err := replaying.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(replayed.Data) != string(file.Data) {
+		t.Fatalf("got %q, want %q", replayed.Data, file.Data)
+	}
+}
+
+func TestCassetteRedactsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"name": "Hello-World"})
+	defer gock.Off()
+
+	client := makeCassetteClient(t, path, CassetteRecord)
+	_, _, _, err := client.doRaw(context.Background(), http.MethodGet, "repos/Codertocat/Hello-World", http.Header{"Authorization": {"token secret-token"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret-token") {
+		t.Fatalf("cassette contains the token: %s", b)
+	}
+	if !strings.Contains(string(b), "REDACTED") {
+		t.Fatalf("cassette has no redacted header: %s", b)
+	}
+}
+
+func TestCassetteReplayWithUnrecordedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := ioutil.WriteFile(path, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := makeCassetteClient(t, path, CassetteReplay)
+	_, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md")
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Fatalf("got %v, want an unrecorded interaction error", err)
+	}
+}
+
+func TestCassetteRecordsConcurrentRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	release := make(chan struct{})
+	upstream := &blockingTransport{blocked: "/slow", release: release, started: make(chan struct{})}
+	c := newCassette(path, CassetteRecord, upstream)
+
+	slow := make(chan error)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/slow", nil)
+		_, err := c.RoundTrip(req)
+		slow <- err
+	}()
+	<-upstream.started
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/fast", nil)
+	if _, err := c.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "/slow") || !strings.Contains(string(b), "/fast") {
+		t.Fatalf("cassette is missing interactions: %s", b)
+	}
+}
+
+// blockingTransport blocks requests for the blocked path until it's released.
+type blockingTransport struct {
+	blocked string
+	release chan struct{}
+	started chan struct{}
+}
+
+func (b *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == b.blocked {
+		close(b.started)
+		<-b.release
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func makeCassetteClient(t *testing.T, path string, mode CassetteMode) *SCMClient {
+	t.Helper()
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(scmClient, WithCassette(path, mode))
+}
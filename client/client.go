@@ -1,28 +1,88 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/ocraviotto/go-scm/scm"
 )
 
 // New creates and returns a new SCMClient.
-func New(c *scm.Client) *SCMClient {
-	return &SCMClient{scmClient: c}
+//
+// The SCMClient makes requests with a copy of the scm.Client, so the options
+// that change how requests are made don't affect the scm.Client, or other
+// SCMClients created with it.
+func New(c *scm.Client, opts ...ClientFunc) *SCMClient {
+	client := &SCMClient{scmClient: copyClient(c)}
+	client.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+		return &contentLengthTransport{next: &contextTokenTransport{next: rt}}
+	})
+	if c.Driver == scm.DriverGitlab {
+		client.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &sudoTransport{next: rt}
+		})
+	}
+	for _, o := range opts {
+		o(client)
+	}
+	return client
 }
 
 // SCMClient is a wrapper for the go-scm scm.Client with a simplified API.
 type SCMClient struct {
-	scmClient *scm.Client
+	scmClient     *scm.Client
+	maxDiffSize   int
+	concurrency   int
+	clock         Clock
+	lineEnding    LineEnding
+	retry         retryPolicy
+	flights       *flightGroup
+	events        *eventSink
+	graphQL       bool
+	validateRepos bool
+	defaultRef    *defaultRef
+	slowCalls     *slowCallLogger
+	messageFormat CommitMessageFormat
+	maxItems      int
+	errorContext  bool
+	symlinkDepth  int
+	metrics       MetricsRecorder
+	trees         *treeCache
+	archivedRepos *archivedCache
 }
 
 // GetFile reads the specific revision of a file from a repository.
 //
+// Symlinks are returned with the path of their target as the data, unless
+// the client is created with WithFollowSymlinks.
+//
 // If an HTTP error is returned by the upstream service, an error with the
 // response status code is returned.
 func (c *SCMClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+	var out *scm.Content
+	err := c.callAt(ctx, "GetFile", repo, ref, path, func(ctx context.Context) error {
+		ref, err := c.resolveRef(ctx, repo, ref)
+		if err != nil {
+			return err
+		}
+		v, err := c.flights.do(flightKey(ctx, "GetFile", repo, ref, path), func() (interface{}, error) {
+			content, err := c.getFilePrefetched(ctx, repo, ref, path)
+			if err != nil || c.symlinkDepth == 0 || c.scmClient.Driver == scm.DriverGithub {
+				return content, err
+			}
+			return c.followSymlinks(ctx, repo, ref, path, content)
+		})
+		out, _ = v.(*scm.Content)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
 	content, r, err := c.scmClient.Contents.Find(ctx, repo, path, ref)
 	if r != nil && isErrorStatus(r.Status) {
 		e := SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: r.Status}
@@ -39,6 +99,14 @@ func (c *SCMClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.C
 
 // CreateBranch will create a new branch in the repo from the SHA.
 func (c *SCMClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	err := c.call(ctx, "CreateBranch", repo, func(ctx context.Context) error {
+		return c.createBranch(ctx, repo, branch, sha)
+	})
+	c.emit(Event{Type: "CreateBranch", Repo: repo, Branch: branch, Err: err})
+	return err
+}
+
+func (c *SCMClient) createBranch(ctx context.Context, repo, branch, sha string) error {
 	params := &scm.CreateBranch{Name: branch, Sha: sha}
 	_, err := c.scmClient.Git.CreateBranch(ctx, repo, params)
 	return err
@@ -46,29 +114,282 @@ func (c *SCMClient) CreateBranch(ctx context.Context, repo, branch, sha string)
 
 // CreatePullRequest creates a PullRequest with the provided input.
 //
+// The source can be a branch in a fork in the "owner:branch" format, on
+// GitLab the fork is assumed to have the same name as the repo, use
+// CreateForkPullRequest if it's named differently.
+//
+// With the EnsureBase option, the target branch is created from the default
+// branch of the repository if it doesn't exist.
+//
 // If an HTTP error is returned by the upstream service, an error with the
 // response status code is returned.
-func (c *SCMClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+func (c *SCMClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput, opts ...PullRequestOption) (*scm.PullRequest, error) {
+	var out *scm.PullRequest
+	err := c.call(ctx, "CreatePullRequest", repo, func(ctx context.Context) (err error) {
+		out, err = c.createPullRequest(ctx, repo, inp, opts...)
+		return err
+	})
+	c.emit(Event{Type: "CreatePullRequest", Repo: repo, Branch: inp.Source, Number: prNumber(out), Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createPullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput, opts ...PullRequestOption) (*scm.PullRequest, error) {
+	if makePullRequestOptions(opts).EnsureBase {
+		if err := c.ensureBranch(ctx, repo, inp.Target); err != nil {
+			return nil, err
+		}
+	}
+	if owner, branch, ok := splitForkSource(inp.Source); ok && c.scmClient.Driver == scm.DriverGitlab {
+		_, name := scm.Split(repo)
+		return c.CreateForkPullRequest(ctx, repo, scm.Join(owner, name), branch, inp.Target, inp)
+	}
 	pr, _, err := c.scmClient.PullRequests.Create(ctx, repo, inp)
 	return pr, err
 }
 
+// CreatePullRequestIfChanged creates a pull request like CreatePullRequest,
+// unless the source branch has no commits that are not on the target branch,
+// in which case no pull request is created, and false is returned.
+//
+// Comparing branches is only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreatePullRequestIfChanged(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, bool, error) {
+	var (
+		out     *scm.PullRequest
+		created bool
+	)
+	err := c.call(ctx, "CreatePullRequestIfChanged", repo, func(ctx context.Context) (err error) {
+		out, created, err = c.createPullRequestIfChanged(ctx, repo, inp)
+		return err
+	})
+	c.emit(Event{Type: "CreatePullRequestIfChanged", Repo: repo, Branch: inp.Source, Number: prNumber(out), Err: err})
+	return out, created, err
+}
+
+func (c *SCMClient) createPullRequestIfChanged(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, bool, error) {
+	ahead, err := c.commitsAhead(ctx, repo, inp.Source, inp.Target)
+	if err != nil {
+		return nil, false, err
+	}
+	if ahead == 0 {
+		return nil, false, nil
+	}
+	pr, err := c.createPullRequest(ctx, repo, inp)
+	if err != nil {
+		return nil, false, err
+	}
+	return pr, true, nil
+}
+
 // UpdateFile updates an existing file in a repository.
 //
 // If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, if the write was rejected because the
+// branch is protected, the error wraps ErrProtectedBranch, and if the
+// previousSHA doesn't match the file, the error wraps ErrConflict.
+//
+// If the file on the branch already has the content, no commit is made and
+// ErrNoChange is returned, unless the AllowEmpty option is provided.
+func (c *SCMClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte, opts ...WriteOption) error {
+	err := c.callAt(ctx, "UpdateFile", repo, branch, path, func(ctx context.Context) error {
+		return c.updateFile(ctx, repo, branch, path, message, previousSHA, signature, content, opts...)
+	})
+	c.emit(Event{Type: "UpdateFile", Repo: repo, Branch: branch, Path: path, Err: err})
+	return err
+}
+
+func (c *SCMClient) updateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte, opts ...WriteOption) error {
+	o := makeWriteOptions(opts)
+	content = normalizeLineEndings(content, c.lineEnding)
+	sha, blobID := previousSHA, previousSHA
+	if !o.AllowEmpty || o.Force {
+		current := c.currentContent(ctx, repo, branch, path)
+		if !o.AllowEmpty && current != nil && bytes.Equal(current.Data, content) {
+			return ErrNoChange
+		}
+		if o.Force && current != nil {
+			sha, blobID = current.Sha, current.BlobID
+		}
+	}
+	return c.writeFile(ctx, repo, branch, path, message, sha, blobID, signature, content)
+}
+
+// UpdateFileWithRetry applies the transform to the current content of the file
+// on the branch, and writes the result, returning the SHA of the branch head
+// after the write.
+//
+// The existing content is nil if the file doesn't exist, and the file is
+// created with the transformed content. If the file changes between the read
+// and the write, the read, transform and write are retried, up to 3 times
+// before the error wrapping ErrConflict is returned.
+//
+// If the transform returns the content the file already has, no commit is
+// made, and the SHA of the branch head is returned along with ErrNoChange.
+func (c *SCMClient) UpdateFileWithRetry(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) (string, error) {
+	var out string
+	err := c.callAt(ctx, "UpdateFileWithRetry", repo, branch, path, func(ctx context.Context) (err error) {
+		out, err = c.updateFileWithRetry(ctx, repo, branch, path, message, signature, transform)
+		return err
+	})
+	c.emit(Event{Type: "UpdateFileWithRetry", Repo: repo, Branch: branch, Path: path, Err: err})
+	return out, err
+}
+
+// conflictRetries is the number of times UpdateFileWithRetry retries a write
+// that conflicts with a concurrent change to the file.
+const conflictRetries = 3
+
+func (c *SCMClient) updateFileWithRetry(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) (string, error) {
+	for attempt := 0; ; attempt++ {
+		err := c.transformFile(ctx, repo, branch, path, message, signature, transform)
+		if errors.Is(err, ErrConflict) && attempt < conflictRetries {
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrNoChange) {
+			return "", err
+		}
+		head, headErr := c.GetBranchHead(ctx, repo, branch)
+		if headErr != nil {
+			return "", fmt.Errorf("failed to get branch head: %w", headErr)
+		}
+		return head, err
+	}
+}
+
+// transformFile makes a single read, transform and write of the file, the
+// write fails with ErrConflict if the file changed after it was read.
+//
+// A file that doesn't exist is created, as GitLab rejects updates to missing
+// files.
+func (c *SCMClient) transformFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) error {
+	current, err := c.getFile(ctx, repo, branch, path)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	missing := err != nil || current == nil
+	var existing []byte
+	if !missing {
+		existing = current.Data
+	}
+	content, err := transform(existing)
+	if err != nil {
+		return fmt.Errorf("failed to transform file %s: %w", path, err)
+	}
+	content = normalizeLineEndings(content, c.lineEnding)
+	if missing {
+		return c.createFile(ctx, repo, branch, path, message, signature, content)
+	}
+	if bytes.Equal(existing, content) {
+		return ErrNoChange
+	}
+	return c.writeFile(ctx, repo, branch, path, message, current.Sha, current.BlobID, signature, content)
+}
+
+// SyncFile makes the file on the branch have the wanted content, committing
+// only if it doesn't already, and returns whether a commit was made, and the
+// SHA of the file.
+//
+// A file that doesn't exist is treated as empty, and is created if the wanted
+// content is not empty. With the DeleteIfEmpty option, empty wanted content
+// means the file should not exist, an existing file is deleted, and an empty
+// SHA is returned.
+//
+// If an HTTP error is returned by the upstream service, an error with the
 // response status code is returned.
-func (c *SCMClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
+func (c *SCMClient) SyncFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, want []byte, opts ...WriteOption) (changed bool, sha string, err error) {
+	err = c.callAt(ctx, "SyncFile", repo, branch, path, func(ctx context.Context) (err error) {
+		changed, sha, err = c.syncFile(ctx, repo, branch, path, message, signature, want, makeWriteOptions(opts))
+		return err
+	})
+	c.emit(Event{Type: "SyncFile", Repo: repo, Branch: branch, Path: path, Err: err})
+	return changed, sha, err
+}
+
+func (c *SCMClient) syncFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, want []byte, o WriteOptions) (bool, string, error) {
+	want = normalizeLineEndings(want, c.lineEnding)
+	current, err := c.getFile(ctx, repo, branch, path)
+	if err != nil && !IsNotFound(err) {
+		return false, "", err
+	}
+	if err != nil {
+		if len(want) == 0 {
+			return false, "", nil
+		}
+		if err := c.createFile(ctx, repo, branch, path, message, signature, want); err != nil {
+			return false, "", err
+		}
+	} else {
+		if len(want) == 0 && o.DeleteIfEmpty {
+			if err := c.deleteFile(ctx, repo, branch, path, message, previousSHA(current), signature, nil); err != nil {
+				return false, "", err
+			}
+			return true, "", nil
+		}
+		if bytes.Equal(current.Data, want) {
+			return false, fileSHA(current), nil
+		}
+		if err := c.writeFile(ctx, repo, branch, path, message, current.Sha, current.BlobID, signature, want); err != nil {
+			return false, "", err
+		}
+	}
+	written, err := c.getFile(ctx, repo, branch, path)
+	if err != nil {
+		return true, "", fmt.Errorf("failed to get SHA of file %s: %w", path, err)
+	}
+	return true, fileSHA(written), nil
+}
+
+// fileSHA returns the SHA of the blob for the file, falling back to the SHA
+// reported by the driver, which is the last commit on GitLab.
+func fileSHA(content *scm.Content) string {
+	if content.BlobID != "" {
+		return content.BlobID
+	}
+	return content.Sha
+}
+
+// previousSHA returns the SHA that identifies the content of the file when it's
+// replaced or deleted, the SHA reported by the driver, which is the last commit
+// on GitLab, falling back to the SHA of the blob on GitHub, which doesn't
+// report one.
+func previousSHA(content *scm.Content) string {
+	if content.Sha != "" {
+		return content.Sha
+	}
+	return content.BlobID
+}
+
+// createFile creates a file that doesn't exist on the branch.
+func (c *SCMClient) createFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, content []byte) error {
 	params := scm.ContentParams{
-		Message:   message,
+		Message:   c.messageFormat.Apply(message),
 		Data:      content,
 		Branch:    branch,
-		Sha:       previousSHA,
-		BlobID:    previousSHA,
+		Signature: signature,
+	}
+	r, err := c.scmClient.Contents.Create(ctx, repo, path, &params)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to create file %s in repo %s branch %s", path, repo, branch), Status: r.Status, Err: writeErrorCause(r, err)}
+	}
+	return err
+}
+
+// writeFile writes the content of the file, the sha and blobID identify the
+// content being replaced, depending on the driver.
+func (c *SCMClient) writeFile(ctx context.Context, repo, branch, path, message, sha, blobID string, signature scm.Signature, content []byte) error {
+	params := scm.ContentParams{
+		Message:   c.messageFormat.Apply(message),
+		Data:      content,
+		Branch:    branch,
+		Sha:       sha,
+		BlobID:    blobID,
 		Signature: signature,
 	}
 	r, err := c.scmClient.Contents.Update(ctx, repo, path, &params)
 	if r != nil && isErrorStatus(r.Status) {
-		return SCMError{Msg: fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch), Status: r.Status}
+		return SCMError{Msg: fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch), Status: r.Status, Err: writeErrorCause(r, err)}
 	}
 	if err != nil {
 		return err
@@ -79,10 +400,19 @@ func (c *SCMClient) UpdateFile(ctx context.Context, repo, branch, path, message,
 // DeleteFile deletes a file in a repository
 //
 // If an HTTP error is returned by the upstream service, an error with the
-// response status code is returned.
+// response status code is returned, if the write was rejected because the
+// branch is protected, the error wraps ErrProtectedBranch.
 func (c *SCMClient) DeleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
+	err := c.callAt(ctx, "DeleteFile", repo, branch, path, func(ctx context.Context) error {
+		return c.deleteFile(ctx, repo, branch, path, message, previousSHA, signature, content)
+	})
+	c.emit(Event{Type: "DeleteFile", Repo: repo, Branch: branch, Path: path, Err: err})
+	return err
+}
+
+func (c *SCMClient) deleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
 	params := scm.ContentParams{
-		Message:   message,
+		Message:   c.messageFormat.Apply(message),
 		Data:      content,
 		Branch:    branch,
 		Sha:       previousSHA,
@@ -91,7 +421,7 @@ func (c *SCMClient) DeleteFile(ctx context.Context, repo, branch, path, message,
 	}
 	r, err := c.scmClient.Contents.Delete(ctx, repo, path, &params)
 	if r != nil && isErrorStatus(r.Status) {
-		return SCMError{Msg: fmt.Sprintf("failed to delete file %s in repo %s branch %s", path, repo, branch), Status: r.Status}
+		return SCMError{Msg: fmt.Sprintf("failed to delete file %s in repo %s branch %s", path, repo, branch), Status: r.Status, Err: writeErrorCause(r, err)}
 	}
 	if err != nil {
 		return err
@@ -104,8 +434,93 @@ func (c *SCMClient) DeleteFile(ctx context.Context, repo, branch, path, message,
 // If an HTTP error is returned by the upstream service, an error with the
 // response status code is returned.
 func (c *SCMClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
-	ref, _, err := c.scmClient.Git.FindBranch(ctx, repo, branch)
-	return ref.Sha, err
+	var out string
+	err := c.call(ctx, "GetBranchHead", repo, func(ctx context.Context) (err error) {
+		out, _, err = c.getBranchHead(ctx, repo, branch)
+		return err
+	})
+	return out, err
+}
+
+// getBranchHead gets the head of the branch, resolved with the default ref,
+// along with the status of the response, identical calls are coalesced with
+// WithSingleflight.
+func (c *SCMClient) getBranchHead(ctx context.Context, repo, branch string) (string, int, error) {
+	branch, err := c.resolveRef(ctx, repo, branch)
+	if err != nil {
+		return "", 0, err
+	}
+	type result struct {
+		sha    string
+		status int
+	}
+	v, err := c.flights.do(flightKey(ctx, "GetBranchHead", repo, branch), func() (interface{}, error) {
+		ref, r, err := c.scmClient.Git.FindBranch(ctx, repo, branch)
+		var res result
+		if ref != nil {
+			res.sha = ref.Sha
+		}
+		if r != nil {
+			res.status = r.Status
+		}
+		return res, err
+	})
+	res, _ := v.(result)
+	return res.sha, res.status, err
+}
+
+// currentContent returns the file on the branch, or nil if it can't be
+// fetched.
+//
+// Failing to fetch the file is not treated as an error, the write that follows
+// will report any real problem with the repository.
+func (c *SCMClient) currentContent(ctx context.Context, repo, branch, path string) *scm.Content {
+	current, r, err := c.scmClient.Contents.Find(ctx, repo, path, branch)
+	if err != nil || (r != nil && isErrorStatus(r.Status)) {
+		return nil
+	}
+	return current
+}
+
+// writeErrorCause identifies the reason an upstream service rejected a write
+// to a branch, falling back to the driver error.
+//
+// Protected branch rejections are reported with different statuses (GitHub
+// uses 409 or 422, GitLab uses 403), so the message is used to tell them
+// apart from other failures with the same status.
+func writeErrorCause(r *scm.Response, err error) error {
+	if err != nil && r.Status == http.StatusForbidden && isArchivedMessage(err.Error()) {
+		return ErrArchived
+	}
+	if err != nil && isProtectedBranchMessage(err.Error()) {
+		switch r.Status {
+		case http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity:
+			return ErrProtectedBranch
+		}
+	}
+	if r.Status == http.StatusConflict || (err != nil && isConflictMessage(err.Error())) {
+		return ErrConflict
+	}
+	return err
+}
+
+// isConflictMessage matches GitLab's rejection of a write when the file has
+// changed since the last commit ID that was provided, and GitHub's rejection
+// of a branch update that isn't a fast-forward.
+func isConflictMessage(s string) bool {
+	s = strings.ToLower(s)
+	return strings.Contains(s, "has changed since") || strings.Contains(s, "not a fast forward")
+}
+
+// isArchivedMessage matches GitHub's rejection of a write to an archived
+// repository.
+func isArchivedMessage(s string) bool {
+	return strings.Contains(strings.ToLower(s), "archived")
+}
+
+func isProtectedBranchMessage(s string) bool {
+	s = strings.ToLower(s)
+	return strings.Contains(s, "protected branch") || strings.Contains(s, "not allowed to push")
 }
 
 func isErrorStatus(i int) bool {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -142,6 +143,416 @@ func TestUpdateFile(t *testing.T) {
 	}
 }
 
+func TestUpdateFileWithNoChange(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte("body:\n  key:\n    env:\n      val: testing\n"))
+	if !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got %v, want ErrNoChange", err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not fetched")
+	}
+}
+
+func TestUpdateFileAllowingEmpty(t *testing.T) {
+	content := "body:\n  key:\n    env:\n      val: testing\n"
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte(content), AllowEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not updated")
+	}
+}
+
+func TestUpdateFileWithConflict(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusConflict).
+		Type("application/json").
+		JSON(map[string]string{"message": "config/my/file.yaml does not match 6113728f27ae82c7b1a177c8d03f9e96e0adf246"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte("testing"), AllowEmpty())
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want ErrConflict", err)
+	}
+}
+
+func TestUpdateFileWithRetry(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Times(2).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusConflict).
+		Type("application/json").
+		JSON(map[string]string{"message": "config/my/file.yaml does not match 980a0d5f19a64b4b30a87d4206aade58726b60e3"})
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		BodyString(`"sha":"980a0d5f19a64b4b30a87d4206aade58726b60e3"`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	var reads int
+	sha, err := client.UpdateFileWithRetry(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", scm.Signature{Name: "John Doe", Email: "john.doe@example.com"},
+		func(existing []byte) ([]byte, error) {
+			reads++
+			return append(existing, "other: value\n"...), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads != 2 {
+		t.Fatalf("got %d reads, want 2", reads)
+	}
+	if sha != "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d" {
+		t.Fatalf("got sha %s, want the branch head", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not updated")
+	}
+}
+
+func TestUpdateFileWithRetryGivingUp(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Times(conflictRetries + 1).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Times(conflictRetries + 1).
+		Reply(http.StatusConflict).
+		Type("application/json").
+		JSON(map[string]string{"message": "config/my/file.yaml does not match 980a0d5f19a64b4b30a87d4206aade58726b60e3"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdateFileWithRetry(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", scm.Signature{},
+		func(existing []byte) ([]byte, error) { return []byte("testing"), nil })
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want ErrConflict", err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the write was not retried")
+	}
+}
+
+func TestUpdateFileWithRetryCreatingFileInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/new.yaml").
+		MatchParam("ref", "main").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "404 File Not Found"})
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/files/config/new.yaml").
+		BodyString(`"content":"bmV3"`).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"file_path": "config/new.yaml", "branch": "main"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "commit": map[string]string{"id": "aa218f56b14c9653891f9e74264a383fa43fefbd"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.UpdateFileWithRetry(context.TODO(), "Codertocat/Hello-World", "main",
+		"config/new.yaml", "just a test message", scm.Signature{},
+		func(existing []byte) ([]byte, error) {
+			if existing != nil {
+				t.Fatalf("got existing content %q, want none", existing)
+			}
+			return []byte("new"), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "aa218f56b14c9653891f9e74264a383fa43fefbd" {
+		t.Fatalf("got sha %s, want the branch head", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not created")
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		BodyString(`"sha":"980a0d5f19a64b4b30a87d4206aade58726b60e3"`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"path": "config/my/file.yaml", "sha": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d", "content": "dGVzdGluZw==", "encoding": "base64"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changed, sha, err := client.SyncFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml",
+		"just a test message", scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte("testing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || sha != "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d" {
+		t.Fatalf("got changed %v and sha %s, want the SHA of the new content", changed, sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not updated")
+	}
+}
+
+func TestSyncFileCreatingTheFile(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/new.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/new.yaml").
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/new.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"path": "config/new.yaml", "sha": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d", "content": "dGVzdGluZw==", "encoding": "base64"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changed, sha, err := client.SyncFile(context.TODO(), "Codertocat/Hello-World", "master", "config/new.yaml",
+		"just a test message", scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte("testing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || sha != "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d" {
+		t.Fatalf("got changed %v and sha %s, want the SHA of the new file", changed, sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not created")
+	}
+}
+
+func TestSyncFileInSync(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changed, sha, err := client.SyncFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml",
+		"just a test message", scm.Signature{}, []byte("body:\n  key:\n    env:\n      val: testing\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || sha != "980a0d5f19a64b4b30a87d4206aade58726b60e3" {
+		t.Fatalf("got changed %v and sha %s, want the existing SHA", changed, sha)
+	}
+}
+
+func TestSyncFileDeletingIfEmpty(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		BodyString(`"sha":"980a0d5f19a64b4b30a87d4206aade58726b60e3"`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changed, sha, err := client.SyncFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml",
+		"just a test message", scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, nil, DeleteIfEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || sha != "" {
+		t.Fatalf("got changed %v and sha %s, want the file deleted", changed, sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not deleted")
+	}
+}
+
+func TestSyncFileDeletingIfEmptyAlreadyAbsent(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/new.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changed, sha, err := client.SyncFile(context.TODO(), "Codertocat/Hello-World", "master", "config/new.yaml",
+		"just a test message", scm.Signature{}, []byte{}, DeleteIfEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || sha != "" {
+		t.Fatalf("got changed %v and sha %s, want no change", changed, sha)
+	}
+}
+
+func TestUpdateFileForcingTheSHA(t *testing.T) {
+	message := "just a test message"
+	content := []byte("testing")
+	branch := "master"
+	signature := scm.Signature{
+		Name:  "John Doe",
+		Email: "john.doe@example.com",
+	}
+	c := base64.StdEncoding.EncodeToString(content)
+	r := ghContentS{
+		Branch:    branch,
+		Message:   message,
+		Content:   &c,
+		Sha:       "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		Author:    ghCommitAuthor{Name: signature.Name, Email: signature.Email},
+		Committer: ghCommitAuthor{Name: signature.Name, Email: signature.Email},
+	}
+
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", branch).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchType("json").
+		JSON(r).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", branch,
+		"config/my/file.yaml", message, "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+		signature, content, Force())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not updated")
+	}
+}
+
 func TestDeleteFile(t *testing.T) {
 	message := "just another message"
 	branch := "my-test-branch"
@@ -234,6 +645,75 @@ func TestUpdateFileToPrivateRepositoryWithBadCreds(t *testing.T) {
 	}
 }
 
+func TestUpdateFileToProtectedBranch(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusUnprocessableEntity).
+		Type("application/json").
+		JSON(map[string]string{"message": "Protected branch update failed for refs/heads/master."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte(`testing`))
+	if !errors.Is(err, ErrProtectedBranch) {
+		t.Fatalf("got %v, want ErrProtectedBranch", err)
+	}
+	if !test.MatchError(t, `failed to update file.*\(422\)$`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUpdateFileToArchivedRepository(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusForbidden).
+		Type("application/json").
+		JSON(map[string]string{"message": "Repository was archived so is read-only."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte(`testing`))
+	if !errors.Is(err, ErrArchived) {
+		t.Fatalf("got %v, want ErrArchived", err)
+	}
+}
+
+func TestUpdateFileWithValidationFailure(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusUnprocessableEntity).
+		Type("application/json").
+		JSON(map[string]string{"message": "Invalid request."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte(`testing`))
+	if errors.Is(err, ErrProtectedBranch) {
+		t.Fatalf("got ErrProtectedBranch for a non-protection failure: %s", err)
+	}
+}
+
 func TestCreateBranchInGitHub(t *testing.T) {
 	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
 
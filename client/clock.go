@@ -0,0 +1,35 @@
+package client
+
+import "time"
+
+// Clock provides the time to methods that wait, so that tests can control
+// the passing of time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithClock is an option func that replaces the clock used by methods that
+// wait.
+func WithClock(clock Clock) ClientFunc {
+	return func(c *SCMClient) {
+		c.clock = clock
+	}
+}
+
+func (c *SCMClient) getClock() Clock {
+	if c.clock != nil {
+		return c.clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// codeOwnersPaths are the locations that CODEOWNERS files are read from, in
+// the order that GitHub looks for them.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners is a parsed CODEOWNERS file.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// GetCodeOwners reads and parses the CODEOWNERS file from the first of the
+// locations that GitHub supports that has one, .github/CODEOWNERS, CODEOWNERS
+// or docs/CODEOWNERS.
+//
+// If none of the locations has a CODEOWNERS file, an error with a NotFound
+// status is returned.
+func (c *SCMClient) GetCodeOwners(ctx context.Context, repo, ref string) (*CodeOwners, error) {
+	var out *CodeOwners
+	err := c.call(ctx, "GetCodeOwners", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getCodeOwners(ctx, repo, ref)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getCodeOwners(ctx context.Context, repo, ref string) (*CodeOwners, error) {
+	for _, path := range codeOwnersPaths {
+		content, err := c.GetFile(ctx, repo, ref, path)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ParseCodeOwners(content.Data), nil
+	}
+	return nil, SCMError{Msg: fmt.Sprintf("failed to find CODEOWNERS in repo %s ref %s", repo, ref), Status: http.StatusNotFound}
+}
+
+// SuggestReviewers returns the owners of the files changed by the pull
+// request, from the CODEOWNERS file in its target branch, sorted and without
+// duplicates. Renamed files are also matched at their previous path.
+//
+// If the target branch has no CODEOWNERS file, no reviewers are returned.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, IsNotFound returns true for an unknown
+// pull request.
+func (c *SCMClient) SuggestReviewers(ctx context.Context, repo string, number int) ([]string, error) {
+	var out []string
+	err := c.call(ctx, "SuggestReviewers", repo, func(ctx context.Context) (err error) {
+		out, err = c.suggestReviewers(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) suggestReviewers(ctx context.Context, repo string, number int) ([]string, error) {
+	pr, err := c.getPullRequest(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := c.listPullRequestChanges(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := c.getCodeOwners(ctx, repo, pr.Target)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return SuggestedReviewers(owners, changes), nil
+}
+
+// SuggestedReviewers returns the owners of the changed files, sorted and
+// without duplicates, as returned by SuggestReviewers.
+func SuggestedReviewers(owners *CodeOwners, changes []*scm.Change) []string {
+	seen := map[string]bool{}
+	var reviewers []string
+	add := func(path string) {
+		for _, owner := range owners.OwnersFor(path) {
+			if !seen[owner] {
+				seen[owner] = true
+				reviewers = append(reviewers, owner)
+			}
+		}
+	}
+	for _, change := range changes {
+		add(change.Path)
+		if change.Renamed && change.PrevFilePath != "" {
+			add(change.PrevFilePath)
+		}
+	}
+	sort.Strings(reviewers)
+	return reviewers
+}
+
+func (c *SCMClient) listPullRequestChanges(ctx context.Context, repo string, number int) ([]*scm.Change, error) {
+	var all []*scm.Change
+	opts := scm.ListOptions{Size: 100}
+	for {
+		changes, r, err := c.scmClient.PullRequests.ListChanges(ctx, repo, number, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list files changed by pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, changes...)
+		if !nextPage(&opts, r) {
+			return all, nil
+		}
+	}
+}
+
+// ParseCodeOwners parses the rules in a CODEOWNERS file, comments and blank
+// lines are ignored.
+func ParseCodeOwners(b []byte) *CodeOwners {
+	co := &CodeOwners{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		co.rules = append(co.rules, codeOwnersRule{
+			pattern: compileCodeOwnersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+	return co
+}
+
+// OwnersFor returns the owners of the path, from the last rule that matches
+// it, which is nil if no rule matches, or the rule has no owners.
+func (co *CodeOwners) OwnersFor(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.MatchString(path) {
+			if len(co.rules[i].owners) == 0 {
+				return nil
+			}
+			return append([]string(nil), co.rules[i].owners...)
+		}
+	}
+	return nil
+}
+
+// compileCodeOwnersPattern converts a CODEOWNERS pattern, which follows the
+// gitignore rules, to a regular expression that matches the paths it applies
+// to.
+//
+// Patterns that start with, or contain a "/" are relative to the root of the
+// repository, other patterns match at any depth. A pattern that matches a
+// directory matches everything in it, except for patterns that end with "/*",
+// which only match the files directly in the directory.
+func compileCodeOwnersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		expr.WriteString("$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(expr.String())
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testCodeOwners = `# Default owners
+*       @org/maintainers
+
+*.go    @org/go-reviewers # Go code
+/docs/  @org/docs
+apps/   @org/apps
+config/* @org/config
+**/deploy/*.yaml @org/ops
+/vendor/
+`
+
+func TestCodeOwnersOwnersFor(t *testing.T) {
+	co := ParseCodeOwners([]byte(testCodeOwners))
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@org/maintainers"}},
+		{"main.go", []string{"@org/go-reviewers"}},
+		{"pkg/client/client.go", []string{"@org/go-reviewers"}},
+		{"docs/index.md", []string{"@org/docs"}},
+		{"src/docs/index.md", []string{"@org/maintainers"}},
+		{"apps/web/app.yaml", []string{"@org/apps"}},
+		{"services/apps/api.yaml", []string{"@org/apps"}},
+		{"config/app.yaml", []string{"@org/config"}},
+		{"config/nested/app.yaml", []string{"@org/maintainers"}},
+		{"services/deploy/app.yaml", []string{"@org/ops"}},
+		{"deploy/app.yaml", []string{"@org/ops"}},
+		{"vendor/lib/lib.go", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := co.OwnersFor(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCodeOwners(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/.github/CODEOWNERS").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/CODEOWNERS").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON(map[string]string{
+			"path":     "CODEOWNERS",
+			"content":  base64.StdEncoding.EncodeToString([]byte(testCodeOwners)),
+			"encoding": "base64",
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	co, err := client.GetCodeOwners(context.Background(), "Codertocat/Hello-World", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := co.OwnersFor("main.go"), []string{"@org/go-reviewers"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetCodeOwnersWithNoFile(t *testing.T) {
+	for _, path := range codeOwnersPaths {
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/"+path).
+			MatchParam("ref", "master").
+			Reply(http.StatusNotFound)
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetCodeOwners(context.Background(), "Codertocat/Hello-World", "master")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestSuggestReviewers(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"number": 1,
+			"base":   map[string]string{"ref": "main"},
+			"head":   map[string]string{"ref": "feature"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1/files").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"filename": "main.go", "status": "modified"},
+			{"filename": "docs/index.md", "status": "renamed", "previous_filename": "apps/index.md"},
+			{"filename": "vendor/lib.go", "status": "added"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/.github/CODEOWNERS").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		JSON(map[string]string{
+			"path":     ".github/CODEOWNERS",
+			"content":  base64.StdEncoding.EncodeToString([]byte(testCodeOwners)),
+			"encoding": "base64",
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	reviewers, err := client.SuggestReviewers(context.Background(), "Codertocat/Hello-World", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"@org/apps", "@org/docs", "@org/go-reviewers"}; !reflect.DeepEqual(reviewers, want) {
+		t.Fatalf("got %v, want %v", reviewers, want)
+	}
+}
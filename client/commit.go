@@ -0,0 +1,587 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// FileChange is a change to a single file in a multi-file commit.
+type FileChange struct {
+	Path    string // relative path to the file in the repository
+	Content []byte // the new content of the file, ignored when deleting
+	Delete  bool   // whether to remove the file
+}
+
+// GetCommitFiles returns the changes to files made by the commit, paging
+// through the changes from the page in the options.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, IsNotFound returns true for an unknown
+// SHA.
+func (c *SCMClient) GetCommitFiles(ctx context.Context, repo, sha string, opts scm.ListOptions) ([]*scm.Change, error) {
+	var out []*scm.Change
+	err := c.call(ctx, "GetCommitFiles", repo, func(ctx context.Context) (err error) {
+		out, err = c.getCommitFiles(ctx, repo, sha, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getCommitFiles(ctx context.Context, repo, sha string, opts scm.ListOptions) ([]*scm.Change, error) {
+	var all []*scm.Change
+	for {
+		changes, r, err := c.scmClient.Git.ListChanges(ctx, repo, sha, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list files changed by commit %s in repo %s", sha, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, changes...)
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// GetDiff returns the unified diff of the changes between the base and head
+// commits, truncated like pull request diffs if WithMaxDiffSize is used.
+//
+// Like pull request diffs, and "git diff base...head", the diff is of the
+// changes on head since its merge base with base, so changes made on base
+// since then are not included.
+//
+// Diffs are only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetDiff(ctx context.Context, repo, base, head string) (string, error) {
+	var out string
+	err := c.call(ctx, "GetDiff", repo, func(ctx context.Context) (err error) {
+		out, err = c.getDiff(ctx, repo, base, head)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getDiff(ctx context.Context, repo, base, head string) (string, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		header := http.Header{"Accept": {"application/vnd.github.v3.diff"}}
+		r, body, truncated, err := c.doRaw(ctx, http.MethodGet, fmt.Sprintf("repos/%s/compare/%s...%s", repo, url.PathEscape(base), url.PathEscape(head)), header, c.maxDiffSize)
+		if r != nil && isErrorStatus(r.Status) {
+			return "", SCMError{Msg: fmt.Sprintf("failed to get diff between %s and %s in repo %s", base, head, repo), Status: r.Status}
+		}
+		if err != nil {
+			return "", err
+		}
+		diff := string(body)
+		if truncated {
+			diff += DiffTruncatedMarker
+		}
+		return diff, nil
+	case scm.DriverGitlab:
+		out := struct {
+			Diffs []glDiff `json:"diffs"`
+		}{}
+		path := fmt.Sprintf("api/v4/projects/%s/repository/compare?from=%s&to=%s&straight=false", encodeRepo(repo), url.QueryEscape(base), url.QueryEscape(head))
+		r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return "", SCMError{Msg: fmt.Sprintf("failed to get diff between %s and %s in repo %s", base, head, repo), Status: r.Status}
+		}
+		if err != nil {
+			return "", err
+		}
+		return c.joinDiffsGitLab(out.Diffs), nil
+	default:
+		return "", scm.ErrNotSupported
+	}
+}
+
+// ChangedFilesBetween returns the sorted paths of the files changed between
+// the base and head commits, like GetDiff. Renamed files are returned with both
+// their old and new paths.
+//
+// GitHub returns at most 300 files from the compare API, if there are more,
+// the files changed by each of the commits between base and head are returned
+// instead, which includes files that were changed and then changed back.
+//
+// Comparisons are only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ChangedFilesBetween(ctx context.Context, repo, base, head string) ([]string, error) {
+	var out []string
+	err := c.call(ctx, "ChangedFilesBetween", repo, func(ctx context.Context) (err error) {
+		out, err = c.changedFilesBetween(ctx, repo, base, head)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) changedFilesBetween(ctx context.Context, repo, base, head string) ([]string, error) {
+	var paths []string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return c.changedFilesBetweenGitHub(ctx, repo, base, head)
+	case scm.DriverGitlab:
+		out := struct {
+			Diffs []glDiff `json:"diffs"`
+		}{}
+		path := fmt.Sprintf("api/v4/projects/%s/repository/compare?from=%s&to=%s&straight=false", encodeRepo(repo), url.QueryEscape(base), url.QueryEscape(head))
+		r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to compare %s with %s in repo %s", head, base, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range out.Diffs {
+			paths = append(paths, d.NewPath, d.OldPath)
+		}
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	return uniquePaths(paths), nil
+}
+
+// compareFilesLimitGitHub is the maximum number of files that GitHub returns
+// from the compare API.
+const compareFilesLimitGitHub = 300
+
+type ghChangedFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// changedFilesBetweenGitHub returns the files from the compare API, or the
+// files changed by each commit if there are too many to be compared.
+//
+// Only the commits of the comparison are paged, each page has the same files.
+func (c *SCMClient) changedFilesBetweenGitHub(ctx context.Context, repo, base, head string) ([]string, error) {
+	var (
+		paths []string
+		shas  []string
+	)
+	opts := scm.ListOptions{Size: 100}
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var out struct {
+			Commits []struct {
+				Sha string `json:"sha"`
+			} `json:"commits"`
+			Files []ghChangedFile `json:"files"`
+		}
+		path := fmt.Sprintf("repos/%s/compare/%s...%s?%s", repo, url.PathEscape(base), url.PathEscape(head), params.Encode())
+		r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to compare %s with %s in repo %s", head, base, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if opts.Page == 0 && len(out.Files) < compareFilesLimitGitHub {
+			for _, f := range out.Files {
+				paths = append(paths, f.Filename, f.PreviousFilename)
+			}
+			return uniquePaths(paths), nil
+		}
+		for _, commit := range out.Commits {
+			shas = append(shas, commit.Sha)
+		}
+		if !nextPage(&opts, r) {
+			break
+		}
+	}
+	for _, sha := range shas {
+		files, err := c.commitFilesGitHub(ctx, repo, sha)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			paths = append(paths, f.Filename, f.PreviousFilename)
+		}
+	}
+	return uniquePaths(paths), nil
+}
+
+// commitFilesGitHub returns the files changed by the commit, paging through
+// the files of the commit.
+func (c *SCMClient) commitFilesGitHub(ctx context.Context, repo, sha string) ([]ghChangedFile, error) {
+	var files []ghChangedFile
+	opts := scm.ListOptions{Size: 100}
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var out struct {
+			Files []ghChangedFile `json:"files"`
+		}
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/commits/%s?%s", repo, sha, params.Encode()), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to get commit %s in repo %s", sha, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, out.Files...)
+		if !nextPage(&opts, r) {
+			return files, nil
+		}
+	}
+}
+
+// uniquePaths returns the sorted non-empty paths without duplicates.
+func uniquePaths(paths []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, path := range paths {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// UpdateFiles applies all the changes to the branch in a single commit, and
+// returns the SHA of the new commit.
+//
+// Changes that would write a file with the content it already has are
+// dropped, if no changes remain, the SHA of the branch head is returned along
+// with ErrNoChange. With the AllowEmpty option, the changes are committed as
+// provided, and an empty commit can be made with no changes on GitHub.
+//
+// The branch is only moved to the new commit if it hasn't moved since the
+// commit was started, unless the Force option is provided.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, if the write was rejected because the
+// branch is protected, the error wraps ErrProtectedBranch, if the repository
+// is archived, it wraps ErrArchived, and if the branch moved, it wraps
+// ErrConflict.
+func (c *SCMClient) UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error) {
+	var out string
+	err := c.call(ctx, "UpdateFiles", repo, func(ctx context.Context) (err error) {
+		out, err = c.updateFiles(ctx, repo, branch, message, signature, changes, opts...)
+		return err
+	})
+	c.emit(Event{Type: "UpdateFiles", Repo: repo, Branch: branch, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) updateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error) {
+	o := makeWriteOptions(opts)
+	if len(changes) == 0 && !o.AllowEmpty {
+		return "", errors.New("no changes to commit")
+	}
+	changes = c.normalizeChanges(changes)
+	if !o.AllowEmpty {
+		changes = c.withoutUnchanged(ctx, repo, branch, changes)
+		if len(changes) == 0 {
+			head, err := c.GetBranchHead(ctx, repo, branch)
+			if err != nil {
+				return "", fmt.Errorf("failed to get branch head: %w", err)
+			}
+			return head, ErrNoChange
+		}
+	}
+	message = c.messageFormat.Apply(message)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return c.updateFilesGitHub(ctx, repo, branch, message, signature, changes, o)
+	case scm.DriverGitlab:
+		if len(changes) == 0 {
+			return "", scm.ErrNotSupported
+		}
+		return c.updateFilesGitLab(ctx, repo, branch, message, signature, changes)
+	default:
+		return "", scm.ErrNotSupported
+	}
+}
+
+// normalizeChanges returns a copy of the changes with the line endings of the
+// content normalized.
+func (c *SCMClient) normalizeChanges(changes []FileChange) []FileChange {
+	if c.lineEnding == LineEndingNone {
+		return changes
+	}
+	normalized := make([]FileChange, len(changes))
+	for i, change := range changes {
+		change.Content = normalizeLineEndings(change.Content, c.lineEnding)
+		normalized[i] = change
+	}
+	return normalized
+}
+
+// withoutUnchanged returns the changes that would modify the branch.
+func (c *SCMClient) withoutUnchanged(ctx context.Context, repo, branch string, changes []FileChange) []FileChange {
+	var filtered []FileChange
+	for _, change := range changes {
+		if !change.Delete {
+			if current := c.currentContent(ctx, repo, branch, change.Path); current != nil && bytes.Equal(current.Data, change.Content) {
+				continue
+			}
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered
+}
+
+type ghAuthor struct {
+	Name  string     `json:"name"`
+	Email string     `json:"email"`
+	Date  *time.Time `json:"date,omitempty"`
+}
+
+type ghTreeEntry struct {
+	Path string  `json:"path"`
+	Mode string  `json:"mode"`
+	Type string  `json:"type"`
+	Sha  *string `json:"sha"`
+}
+
+type ghObject struct {
+	Sha  string `json:"sha"`
+	Tree struct {
+		Sha string `json:"sha"`
+	} `json:"tree"`
+	Message string `json:"message"` // only set for error responses
+}
+
+// writeGitHub makes a request with the Git data API like doWithStatus, but
+// reads the message of error responses to identify why the write was rejected.
+func (c *SCMClient) writeGitHub(ctx context.Context, method, path string, in interface{}, out *ghObject) error {
+	r, err := c.doWithErrorBody(ctx, method, path, in, out)
+	if err != nil {
+		return err
+	}
+	if !isErrorStatus(r.Status) {
+		return nil
+	}
+	var cause error
+	if out.Message != "" {
+		cause = errors.New(out.Message)
+	}
+	return SCMError{Msg: fmt.Sprintf("failed to %s %s", method, path), Status: r.Status, Err: writeErrorCause(r, cause)}
+}
+
+// updateFilesGitHub uses the Git data API to create the blobs, a tree and a
+// commit, before moving the branch to the new commit.
+//
+// GitHub rejects moving the branch with a 422 if it's not a fast-forward,
+// which means the branch moved after the head was read, but also if the branch
+// is protected, so the cause is identified from the message.
+func (c *SCMClient) updateFilesGitHub(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, o WriteOptions) (string, error) {
+	head, err := c.GetBranchHead(ctx, repo, branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch head: %w", err)
+	}
+	parent := ghObject{}
+	if err := c.doWithStatus(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/commits/%s", repo, head), nil, &parent); err != nil {
+		return "", err
+	}
+
+	tree, err := c.createTreeGitHub(ctx, repo, parent.Tree.Sha, changes)
+	if err != nil {
+		return "", err
+	}
+
+	author := ghAuthor{Name: signature.Name, Email: signature.Email}
+	if !signature.Date.IsZero() {
+		author.Date = &signature.Date
+	}
+	commit := ghObject{}
+	err = c.writeGitHub(ctx, http.MethodPost, fmt.Sprintf("repos/%s/git/commits", repo), map[string]interface{}{
+		"message":   message,
+		"tree":      tree,
+		"parents":   []string{head},
+		"author":    author,
+		"committer": author,
+	}, &commit)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.writeGitHub(ctx, http.MethodPatch, fmt.Sprintf("repos/%s/git/refs/heads/%s", repo, branch), map[string]interface{}{
+		"sha":   commit.Sha,
+		"force": o.Force,
+	}, &ghObject{})
+	if err != nil {
+		return "", err
+	}
+	return commit.Sha, nil
+}
+
+// createTreeGitHub creates a blob for each of the updated files, and a tree
+// from the base tree with the changes applied, returning the SHA of the tree.
+//
+// Updated files keep their mode in the base tree, e.g. executable files stay
+// executable, and new files are created as regular files. The modes can't be
+// kept if the base tree is too large to be read in full.
+func (c *SCMClient) createTreeGitHub(ctx context.Context, repo, baseTree string, changes []FileChange) (string, error) {
+	if len(changes) == 0 {
+		return baseTree, nil
+	}
+	existing, err := c.getTreeGitHub(ctx, repo, baseTree)
+	if err != nil {
+		return "", err
+	}
+	entries := make([]ghTreeEntry, len(changes))
+	for i, change := range changes {
+		entries[i] = ghTreeEntry{Path: change.Path, Mode: "100644", Type: "blob"}
+		if change.Delete {
+			continue
+		}
+		if e, ok := existing[change.Path]; ok && e.Type == "blob" {
+			entries[i].Mode = e.Mode
+		}
+		blob := ghObject{}
+		err := c.writeGitHub(ctx, http.MethodPost, fmt.Sprintf("repos/%s/git/blobs", repo), map[string]string{
+			"content":  base64.StdEncoding.EncodeToString(change.Content),
+			"encoding": "base64",
+		}, &blob)
+		if err != nil {
+			return "", err
+		}
+		entries[i].Sha = &blob.Sha
+	}
+
+	tree := ghObject{}
+	err = c.writeGitHub(ctx, http.MethodPost, fmt.Sprintf("repos/%s/git/trees", repo), map[string]interface{}{
+		"base_tree": baseTree,
+		"tree":      entries,
+	}, &tree)
+	if err != nil {
+		return "", err
+	}
+	return tree.Sha, nil
+}
+
+// CommitPreview is the result of a commit prepared with PreviewCommit.
+type CommitPreview struct {
+	TreeSha string       // the SHA of the tree that the commit would have
+	Files   []string     // the sorted paths of the files in the tree
+	Changes []FileChange // the changes that would modify the branch
+}
+
+// PreviewCommit prepares the tree that committing the changes to the base
+// branch with UpdateFiles would produce, without committing it, so the branch
+// is left untouched.
+//
+// Changes that would write a file with the content it already has are
+// dropped from the preview like they are by UpdateFiles. If the tree is too
+// large to be listed in a single response, Files is nil.
+//
+// Previews are only supported on GitHub, where the tree is created with the
+// Git data API.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) PreviewCommit(ctx context.Context, repo, baseBranch string, changes []FileChange) (*CommitPreview, error) {
+	var out *CommitPreview
+	err := c.call(ctx, "PreviewCommit", repo, func(ctx context.Context) (err error) {
+		out, err = c.previewCommit(ctx, repo, baseBranch, changes)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) previewCommit(ctx context.Context, repo, baseBranch string, changes []FileChange) (*CommitPreview, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	changes = c.withoutUnchanged(ctx, repo, baseBranch, c.normalizeChanges(changes))
+	head, err := c.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch head: %w", err)
+	}
+	parent := ghObject{}
+	if err := c.doWithStatus(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/commits/%s", repo, head), nil, &parent); err != nil {
+		return nil, err
+	}
+	tree, err := c.createTreeGitHub(ctx, repo, parent.Tree.Sha, changes)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.getTreeGitHub(ctx, repo, tree)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for path, e := range entries {
+		if e.Type == "blob" {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return &CommitPreview{TreeSha: tree, Files: files, Changes: changes}, nil
+}
+
+// updateFilesGitLab uses the commits API which accepts multiple actions, GitLab
+// needs to know whether each file is being created or updated.
+func (c *SCMClient) updateFilesGitLab(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange) (string, error) {
+	actions := make([]map[string]string, len(changes))
+	for i, change := range changes {
+		action := map[string]string{"file_path": change.Path}
+		switch {
+		case change.Delete:
+			action["action"] = "delete"
+		default:
+			_, err := c.GetFile(ctx, repo, branch, change.Path)
+			switch {
+			case IsNotFound(err):
+				action["action"] = "create"
+			case err != nil:
+				return "", err
+			default:
+				action["action"] = "update"
+			}
+			action["content"] = base64.StdEncoding.EncodeToString(change.Content)
+			action["encoding"] = "base64"
+		}
+		actions[i] = action
+	}
+
+	out := struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}{}
+	r, err := c.doWithErrorBody(ctx, http.MethodPost, fmt.Sprintf("api/v4/projects/%s/repository/commits", encodeRepo(repo)), map[string]interface{}{
+		"branch":         branch,
+		"commit_message": message,
+		"author_name":    signature.Name,
+		"author_email":   signature.Email,
+		"actions":        actions,
+	}, &out)
+	if err != nil {
+		return "", err
+	}
+	if isErrorStatus(r.Status) {
+		var cause error
+		if out.Message != "" {
+			cause = errors.New(out.Message)
+		}
+		return "", SCMError{Msg: fmt.Sprintf("failed to commit files in repo %s branch %s", repo, branch), Status: r.Status, Err: writeErrorCause(r, cause)}
+	}
+	return out.ID, nil
+}
@@ -0,0 +1,643 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestUpdateFilesInGitHub(t *testing.T) {
+	head := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/commits/" + head).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": head, "tree": map[string]string{"sha": "base-tree"}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/base-tree").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"tree": []map[string]string{
+				{"path": "config/a.yaml", "mode": "100755", "type": "blob", "sha": "old-blob"},
+				{"path": "config/b.yaml", "mode": "100644", "type": "blob", "sha": "b-blob"},
+			},
+		})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/blobs").
+		JSON(map[string]string{"content": "dGVzdGluZw==", "encoding": "base64"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-blob"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/trees").
+		JSON(map[string]interface{}{
+			"base_tree": "base-tree",
+			"tree": []map[string]interface{}{
+				{"path": "config/a.yaml", "mode": "100755", "type": "blob", "sha": "new-blob"},
+				{"path": "config/b.yaml", "mode": "100644", "type": "blob", "sha": nil},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-tree"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/commits").
+		JSON(map[string]interface{}{
+			"message":   "update files",
+			"tree":      "new-tree",
+			"parents":   []string{head},
+			"author":    map[string]string{"name": "John Doe", "email": "john.doe@example.com"},
+			"committer": map[string]string{"name": "John Doe", "email": "john.doe@example.com"},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-commit"})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/git/refs/heads/master").
+		JSON(map[string]interface{}{"sha": "new-commit", "force": false}).
+		Reply(http.StatusOK)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "master", "update files",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"},
+		[]FileChange{{Path: "config/a.yaml", Content: []byte("testing")}, {Path: "config/b.yaml", Delete: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "new-commit" {
+		t.Fatalf("got sha %s, want new-commit", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("commit was not created")
+	}
+}
+
+func TestUpdateFilesInGitHubWithRejectedBranchUpdate(t *testing.T) {
+	updateTests := []struct {
+		message string
+		wantErr error
+	}{
+		{"Update is not a fast forward", ErrConflict},
+		{"Protected branch update failed for refs/heads/master.", ErrProtectedBranch},
+	}
+
+	for _, tt := range updateTests {
+		t.Run(tt.message, func(t *testing.T) {
+			mockCommitGitHub()
+			gock.New("https://api.github.com").
+				Patch("/repos/Codertocat/Hello-World/git/refs/heads/master").
+				Reply(http.StatusUnprocessableEntity).
+				JSON(map[string]string{"message": tt.message})
+			defer gock.Off()
+
+			scmClient, err := factory.NewClient("github", "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := New(scmClient)
+
+			_, err = client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "master", "update files",
+				scm.Signature{}, []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			var e SCMError
+			if !errors.As(err, &e) || e.Status != http.StatusUnprocessableEntity {
+				t.Fatalf("got %v, want a 422 error", err)
+			}
+		})
+	}
+}
+
+func TestUpdateFilesInGitHubWithArchivedRepository(t *testing.T) {
+	head := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/commits/" + head).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": head, "tree": map[string]string{"sha": "base-tree"}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/base-tree").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"tree": []map[string]string{}})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/blobs").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "Repository was archived so is read-only."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "master", "update files",
+		scm.Signature{}, []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+	if !errors.Is(err, ErrArchived) {
+		t.Fatalf("got %v, want ErrArchived", err)
+	}
+}
+
+// mockCommitGitHub mocks the requests to create a commit on the master branch
+// with the Git data API, without moving the branch.
+func mockCommitGitHub() {
+	head := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/commits/" + head).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": head, "tree": map[string]string{"sha": "base-tree"}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/base-tree").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"tree": []map[string]string{}})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/blobs").
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-blob"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/trees").
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-tree"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/commits").
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-commit"})
+}
+
+func TestUpdateFilesInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/a.yaml").
+		Times(2).
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/a.yaml", "content": "dGVzdA==", "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/new.yaml").
+		Times(2).
+		Reply(http.StatusNotFound)
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		JSON(map[string]interface{}{
+			"branch":         "main",
+			"commit_message": "update files",
+			"author_name":    "John Doe",
+			"author_email":   "john.doe@example.com",
+			"actions": []map[string]string{
+				{"action": "update", "file_path": "config/a.yaml", "content": "dGVzdGluZw==", "encoding": "base64"},
+				{"action": "create", "file_path": "config/new.yaml", "content": "bmV3", "encoding": "base64"},
+				{"action": "delete", "file_path": "config/b.yaml"},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"id": "new-commit"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "main", "update files",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"},
+		[]FileChange{
+			{Path: "config/a.yaml", Content: []byte("testing")},
+			{Path: "config/new.yaml", Content: []byte("new")},
+			{Path: "config/b.yaml", Delete: true},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "new-commit" {
+		t.Fatalf("got sha %s, want new-commit", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("commit was not created")
+	}
+}
+
+func TestUpdateFilesInGitLabWithProtectedBranch(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/a.yaml").
+		Times(2).
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/a.yaml", "content": "dGVzdA==", "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "You are not allowed to push into this branch"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "main", "update files",
+		scm.Signature{}, []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+	if !errors.Is(err, ErrProtectedBranch) {
+		t.Fatalf("got %v, want ErrProtectedBranch", err)
+	}
+}
+
+func TestUpdateFilesWithNoChanges(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	sha, err := client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "master", "update files",
+		scm.Signature{}, []FileChange{{Path: "config/my/file.yaml", Content: []byte("body:\n  key:\n    env:\n      val: testing\n")}})
+	if !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got %v, want ErrNoChange", err)
+	}
+	if sha != "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d" {
+		t.Fatalf("got sha %s, want the branch head", sha)
+	}
+}
+
+func TestUpdateFilesWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("gogs", "https://gogs.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "main", "update files",
+		scm.Signature{}, []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+	if !test.MatchError(t, scm.ErrNotSupported.Error(), err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestPreviewCommit(t *testing.T) {
+	head := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/commits/" + head).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": head, "tree": map[string]string{"sha": "base-tree"}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/base-tree").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"tree": []map[string]string{
+				{"path": "README.md", "mode": "100644", "type": "blob", "sha": "readme-blob"},
+			},
+		})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/blobs").
+		JSON(map[string]string{"content": "dGVzdGluZw==", "encoding": "base64"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-blob"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/trees").
+		JSON(map[string]interface{}{
+			"base_tree": "base-tree",
+			"tree": []map[string]interface{}{
+				{"path": "config/a.yaml", "mode": "100644", "type": "blob", "sha": "new-blob"},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-tree"})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/new-tree").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"tree": []map[string]string{
+				{"path": "README.md", "type": "blob", "sha": "readme-blob"},
+				{"path": "config", "type": "tree", "sha": "config-tree"},
+				{"path": "config/a.yaml", "type": "blob", "sha": "new-blob"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	preview, err := client.PreviewCommit(context.Background(), "Codertocat/Hello-World", "master",
+		[]FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CommitPreview{
+		TreeSha: "new-tree",
+		Files:   []string{"README.md", "config/a.yaml"},
+		Changes: []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}},
+	}
+	if diff := cmp.Diff(want, preview); diff != "" {
+		t.Fatalf("got different preview: %s", diff)
+	}
+	if !gock.IsDone() {
+		t.Fatal("tree was not created")
+	}
+}
+
+func TestPreviewCommitWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.PreviewCommit(context.Background(), "Codertocat/Hello-World", "main", []FileChange{{Path: "config/a.yaml", Content: []byte("testing")}})
+	if !test.MatchError(t, scm.ErrNotSupported.Error(), err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetCommitFiles(t *testing.T) {
+	sha := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/" + sha).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"sha": sha,
+			"files": []map[string]interface{}{
+				{"filename": "config/a.yaml", "status": "modified"},
+				{"filename": "config/b.yaml", "status": "added"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	changes, err := client.GetCommitFiles(context.Background(), "Codertocat/Hello-World", sha, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(changes); l != 2 {
+		t.Fatalf("got %d changes, want 2", l)
+	}
+	if changes[0].Path != "config/a.yaml" || !changes[1].Added {
+		t.Fatalf("got unexpected changes %#v, %#v", changes[0], changes[1])
+	}
+}
+
+func TestGetCommitFilesWithUnknownSHA(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/unknown").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetCommitFiles(context.Background(), "Codertocat/Hello-World", "unknown", scm.ListOptions{})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetDiff(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/7fd1a60...a84d88e").
+		MatchHeader("Accept", "application/vnd.github.v3.diff").
+		Reply(http.StatusOK).
+		BodyString(testDiff)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	diff, err := client.GetDiff(context.Background(), "Codertocat/Hello-World", "7fd1a60", "a84d88e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != testDiff {
+		t.Fatalf("got diff %q, want %q", diff, testDiff)
+	}
+}
+
+func TestGetDiffWithMaxDiffSize(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/7fd1a60...a84d88e").
+		Reply(http.StatusOK).
+		BodyString(testDiff)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithMaxDiffSize(20))
+
+	diff, err := client.GetDiff(context.Background(), "Codertocat/Hello-World", "7fd1a60", "a84d88e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testDiff[:20] + DiffTruncatedMarker; diff != want {
+		t.Fatalf("got diff %q, want %q", diff, want)
+	}
+}
+
+func TestGetDiffInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/compare").
+		MatchParam("from", "7fd1a60").
+		MatchParam("to", "a84d88e").
+		MatchParam("straight", "false").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"diffs": []map[string]string{
+				{"old_path": "README.md", "new_path": "README.md", "diff": "@@ -1 +1 @@\n-hello world\n+hello there\n"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	diff, err := client.GetDiff(context.Background(), "Codertocat/Hello-World", "7fd1a60", "a84d88e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-hello world\n+hello there\n"
+	if diff != want {
+		t.Fatalf("got diff %q, want %q", diff, want)
+	}
+}
+
+func TestChangedFilesBetween(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"files": []map[string]string{{"filename": "b.yaml"}, {"filename": "a.yaml"}, {"filename": "c.yaml", "previous_filename": "old.yaml"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	paths, err := client.ChangedFilesBetween(context.Background(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a.yaml", "b.yaml", "c.yaml", "old.yaml"}, paths); diff != "" {
+		t.Fatalf("got different paths: %s", diff)
+	}
+}
+
+func TestChangedFilesBetweenWithTooManyFiles(t *testing.T) {
+	files := make([]map[string]string, compareFilesLimitGitHub)
+	for i := range files {
+		files[i] = map[string]string{"filename": fmt.Sprintf("config/%03d.yaml", i)}
+	}
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0?per_page=100&page=2>; rel="next"`).
+		JSON(map[string]interface{}{"commits": []map[string]string{{"sha": "abc123"}}, "files": files})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"commits": []map[string]string{{"sha": "def456"}}, "files": files})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/abc123").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/commits/abc123?per_page=100&page=2>; rel="next"`).
+		JSON(map[string]interface{}{"files": []map[string]string{{"filename": "a.yaml"}}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/abc123").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"files": []map[string]string{{"filename": "b.yaml"}}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/def456").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"files": []map[string]string{{"filename": "c.yaml", "previous_filename": "a.yaml"}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	paths, err := client.ChangedFilesBetween(context.Background(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a.yaml", "b.yaml", "c.yaml"}, paths); diff != "" {
+		t.Fatalf("got different paths: %s", diff)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the files of the commits were not read")
+	}
+}
+
+func TestChangedFilesBetweenInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/compare").
+		MatchParam("from", "v1.0.0").
+		MatchParam("to", "v1.1.0").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"diffs": []map[string]string{
+				{"old_path": "b.yaml", "new_path": "b.yaml"},
+				{"old_path": "a.yaml", "new_path": "a.yaml"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	paths, err := client.ChangedFilesBetween(context.Background(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a.yaml", "b.yaml"}, paths); diff != "" {
+		t.Fatalf("got different paths: %s", diff)
+	}
+}
+
+func TestGetDiffWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/7fd1a60...unknown").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetDiff(context.Background(), "Codertocat/Hello-World", "7fd1a60", "unknown")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
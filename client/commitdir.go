@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// CommitDir commits every file in the local directory, and its
+// subdirectories, to the branch in a single commit, with the paths relative to
+// the directory under repoPrefix, returning the SHA of the commit.
+//
+// The files are committed with UpdateFiles, so binary files are written
+// unchanged, and if every file already has its content, no commit is made and
+// the SHA of the branch head is returned along with ErrNoChange.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CommitDir(ctx context.Context, repo, branch, message string, signature scm.Signature, localDir, repoPrefix string) (string, error) {
+	var out string
+	err := c.call(ctx, "CommitDir", repo, func(ctx context.Context) (err error) {
+		out, err = c.commitDir(ctx, repo, branch, message, signature, localDir, repoPrefix)
+		return err
+	})
+	c.emit(Event{Type: "CommitDir", Repo: repo, Branch: branch, Path: repoPrefix, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) commitDir(ctx context.Context, repo, branch, message string, signature scm.Signature, localDir, repoPrefix string) (string, error) {
+	changes, err := DirChanges(localDir, repoPrefix)
+	if err != nil {
+		return "", err
+	}
+	return c.UpdateFiles(ctx, repo, branch, message, signature, changes)
+}
+
+// DirChanges returns a change writing each regular file in the local directory,
+// and its subdirectories, to its path relative to the directory under
+// repoPrefix, in lexical order of the paths.
+func DirChanges(localDir, repoPrefix string) ([]FileChange, error) {
+	var changes []FileChange
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, FileChange{Path: path.Join(repoPrefix, filepath.ToSlash(rel)), Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", localDir, err)
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no files to commit in directory %s", localDir)
+	}
+	return changes, nil
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// binaryContent has CRLF line endings and a NUL byte, so it would be mangled
+// if it were normalized as text.
+var binaryContent = []byte("\x89PNG\r\n\x00\r\n")
+
+func TestDirChanges(t *testing.T) {
+	dir := writeDir(t, map[string][]byte{
+		"README.md":      []byte("# service\n"),
+		"config/app.yml": []byte("name: service\n"),
+		"logo.png":       binaryContent,
+	})
+
+	changes, err := DirChanges(dir, "services/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{
+		{Path: "services/new/README.md", Content: []byte("# service\n")},
+		{Path: "services/new/config/app.yml", Content: []byte("name: service\n")},
+		{Path: "services/new/logo.png", Content: binaryContent},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("got changes %v, want %v", changes, want)
+	}
+}
+
+func TestDirChangesWithEmptyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DirChanges(dir, ""); err == nil {
+		t.Fatal("expected an error for a directory without files")
+	}
+}
+
+func TestCommitDir(t *testing.T) {
+	dir := writeDir(t, map[string][]byte{
+		"app.yml":  []byte("name: service\r\n"),
+		"logo.png": binaryContent,
+	})
+	for _, name := range []string{"app.yml", "logo.png"} {
+		gock.New("https://gitlab.com").
+			Get("/api/v4/projects/Codertocat/Hello-World/repository/files/services/new/" + name).
+			Times(2).
+			Reply(http.StatusNotFound)
+	}
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		JSON(map[string]interface{}{
+			"branch":         "main",
+			"commit_message": "scaffold service",
+			"author_name":    "John Doe",
+			"author_email":   "john.doe@example.com",
+			"actions": []map[string]string{
+				{"action": "create", "file_path": "services/new/app.yml", "content": base64.StdEncoding.EncodeToString([]byte("name: service\n")), "encoding": "base64"},
+				{"action": "create", "file_path": "services/new/logo.png", "content": base64.StdEncoding.EncodeToString(binaryContent), "encoding": "base64"},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"id": "new-commit"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithLineEndingNormalization(LineEndingLF))
+
+	sha, err := client.CommitDir(context.Background(), "Codertocat/Hello-World", "main", "scaffold service",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, dir, "services/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "new-commit" {
+		t.Fatalf("got sha %s, want new-commit", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("files were not committed")
+	}
+}
+
+// writeDir writes the files to a temporary directory, creating the
+// directories in their paths, and returns the directory.
+func writeDir(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommitMessageFormat is the decoration applied to the messages of the commits
+// made by the client.
+type CommitMessageFormat struct {
+	Prefix   string
+	Trailers []CommitMessageTrailer
+}
+
+// CommitMessageTrailer is a "Key: value" line added to the end of commit
+// messages, e.g. "Ticket: OPS-123".
+type CommitMessageTrailer struct {
+	Key   string
+	Value string
+}
+
+// WithCommitMessagePrefix is an option func that starts the message of every
+// commit made with UpdateFile, UpdateFiles and DeleteFile, and the methods
+// built on them, with the prefix, e.g. "[bot]", followed by a space.
+func WithCommitMessagePrefix(prefix string) ClientFunc {
+	return func(c *SCMClient) {
+		c.messageFormat.Prefix = prefix
+	}
+}
+
+// WithCommitMessageTrailer is an option func that ends the message of every
+// commit made with UpdateFile, UpdateFiles and DeleteFile, and the methods
+// built on them, with a "key: value" trailer, it can be provided more than
+// once to add several trailers.
+func WithCommitMessageTrailer(key, value string) ClientFunc {
+	return func(c *SCMClient) {
+		c.messageFormat.Trailers = append(c.messageFormat.Trailers, CommitMessageTrailer{Key: key, Value: value})
+	}
+}
+
+// Apply returns the message decorated with the prefix and trailers.
+//
+// The prefix is not added again to a message that already starts with it, and
+// the trailers are separated from the message by a blank line, as git expects.
+func (f CommitMessageFormat) Apply(message string) string {
+	if f.Prefix != "" && !strings.HasPrefix(message, f.Prefix) {
+		message = f.Prefix + " " + message
+	}
+	if len(f.Trailers) == 0 {
+		return message
+	}
+	lines := make([]string, len(f.Trailers))
+	for i, t := range f.Trailers {
+		lines[i] = fmt.Sprintf("%s: %s", t.Key, t.Value)
+	}
+	return strings.TrimRight(message, "\n") + "\n\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCommitMessageFormat(t *testing.T) {
+	trailers := []CommitMessageTrailer{{Key: "Ticket", Value: "OPS-123"}, {Key: "Signed-off-by", Value: "bot"}}
+	tests := []struct {
+		name    string
+		format  CommitMessageFormat
+		message string
+		want    string
+	}{
+		{"no decoration", CommitMessageFormat{}, "update", "update"},
+		{"prefix", CommitMessageFormat{Prefix: "[bot]"}, "update", "[bot] update"},
+		{"prefix already present", CommitMessageFormat{Prefix: "[bot]"}, "[bot] update", "[bot] update"},
+		{"trailers", CommitMessageFormat{Trailers: trailers}, "update\n", "update\n\nTicket: OPS-123\nSigned-off-by: bot"},
+		{"prefix and trailer", CommitMessageFormat{Prefix: "[bot]", Trailers: trailers[:1]}, "update", "[bot] update\n\nTicket: OPS-123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format.Apply(tt.message); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateFileWithCommitMessageDecoration(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchType("json").
+		BodyString(regexp.QuoteMeta(`"message":"[bot] update\n\nTicket: OPS-123"`)).
+		Reply(http.StatusCreated).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithCommitMessagePrefix("[bot]"), WithCommitMessageTrailer("Ticket", "OPS-123"))
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml", "update", "", scm.Signature{}, []byte("a: 1\n"), AllowEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("commit message was not decorated")
+	}
+}
+
+func TestDeleteFileWithCommitMessageDecoration(t *testing.T) {
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchType("json").
+		BodyString(regexp.QuoteMeta(`"message":"[bot] delete"`)).
+		Reply(http.StatusOK)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithCommitMessagePrefix("[bot]"))
+
+	err = client.DeleteFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml", "delete", "abc123", scm.Signature{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("commit message was not decorated")
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WithDefaultRef is an option func that substitutes the ref for empty refs and
+// branches in the methods that read from a repository, e.g. GetFile and
+// GetBranchHead.
+//
+// If the ref is empty, the default branch of each repository is used instead,
+// it's looked up the first time it's needed, and cached for the lifetime of
+// the client.
+func WithDefaultRef(ref string) ClientFunc {
+	return func(c *SCMClient) {
+		c.defaultRef = &defaultRef{ref: ref, branches: map[string]string{}}
+	}
+}
+
+// defaultRef is the ref substituted for empty refs, and the cache of default
+// branches if there is no ref.
+type defaultRef struct {
+	ref      string
+	mu       sync.Mutex
+	branches map[string]string
+}
+
+// resolveRef returns the ref, or the configured default if the ref is empty.
+func (c *SCMClient) resolveRef(ctx context.Context, repo, ref string) (string, error) {
+	if ref != "" || c.defaultRef == nil {
+		return ref, nil
+	}
+	if c.defaultRef.ref != "" {
+		return c.defaultRef.ref, nil
+	}
+	c.defaultRef.mu.Lock()
+	branch, ok := c.defaultRef.branches[repo]
+	c.defaultRef.mu.Unlock()
+	if ok {
+		return branch, nil
+	}
+	repository, r, err := c.scmClient.Repositories.Find(ctx, repo)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: fmt.Sprintf("failed to get default branch of repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	c.defaultRef.mu.Lock()
+	c.defaultRef.branches[repo] = repository.Branch
+	c.defaultRef.mu.Unlock()
+	return repository.Branch, nil
+}
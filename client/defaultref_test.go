@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWithDefaultRef(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "release").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithDefaultRef("release"))
+
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "", "config/my/file.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("file was not read from the default ref")
+	}
+}
+
+func TestWithDefaultRefResolvingTheDefaultBranch(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "Hello-World", "default_branch": "trunk"})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "trunk").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/trunk").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithDefaultRef(""))
+
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "", "config/my/file.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	// The default branch is cached, so the repo is only fetched once.
+	if _, err := client.GetBranchHead(context.Background(), "Codertocat/Hello-World", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("default branch was not used")
+	}
+}
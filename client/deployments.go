@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// Deployment is a request to deploy a ref of a repository to an environment.
+//
+// go-scm has no deployment API, so deployments are read directly from the
+// upstream service.
+type Deployment struct {
+	ID          int
+	Sha         string
+	Ref         string
+	Task        string
+	Environment string
+	Description string
+	Creator     string
+	Created     time.Time
+	Updated     time.Time
+}
+
+// DeploymentStatusInput is the status to record for a deployment.
+type DeploymentStatusInput struct {
+	// State is one of the states accepted by GitHub, e.g. "in_progress",
+	// "success", "failure" or "inactive".
+	State          string `json:"state"`
+	Description    string `json:"description,omitempty"`
+	Environment    string `json:"environment,omitempty"`
+	EnvironmentURL string `json:"environment_url,omitempty"`
+	LogURL         string `json:"log_url,omitempty"`
+}
+
+// ListDeployments lists the deployments of the repo, paging through the
+// deployments from the page in the options.
+//
+// Deployments are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListDeployments(ctx context.Context, repo string, opts scm.ListOptions) ([]*Deployment, error) {
+	var out []*Deployment
+	err := c.call(ctx, "ListDeployments", repo, func(ctx context.Context) (err error) {
+		out, err = c.listDeployments(ctx, repo, opts)
+		return err
+	})
+	return out, err
+}
+
+type ghDeployment struct {
+	ID          int    `json:"id"`
+	Sha         string `json:"sha"`
+	Ref         string `json:"ref"`
+	Task        string `json:"task"`
+	Environment string `json:"environment"`
+	Description string `json:"description"`
+	Creator     struct {
+		Login string `json:"login"`
+	} `json:"creator"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *SCMClient) listDeployments(ctx context.Context, repo string, opts scm.ListOptions) ([]*Deployment, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	if opts.Size == 0 {
+		opts.Size = 100
+	}
+	var all []*Deployment
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var deployments []ghDeployment
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/deployments?%s", repo, params.Encode()), nil, &deployments)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list deployments in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deployments {
+			all = append(all, &Deployment{
+				ID:          d.ID,
+				Sha:         d.Sha,
+				Ref:         d.Ref,
+				Task:        d.Task,
+				Environment: d.Environment,
+				Description: d.Description,
+				Creator:     d.Creator.Login,
+				Created:     d.CreatedAt,
+				Updated:     d.UpdatedAt,
+			})
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// CreateDeploymentStatus records a new status for the deployment.
+//
+// Deployments are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateDeploymentStatus(ctx context.Context, repo string, id int, inp *DeploymentStatusInput) error {
+	err := c.call(ctx, "CreateDeploymentStatus", repo, func(ctx context.Context) error {
+		return c.createDeploymentStatus(ctx, repo, id, inp)
+	})
+	c.emit(Event{Type: "CreateDeploymentStatus", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) createDeploymentStatus(ctx context.Context, repo string, id int, inp *DeploymentStatusInput) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodPost, fmt.Sprintf("repos/%s/deployments/%d/statuses", repo, id), inp, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to create status for deployment %d in repo %s", id, repo), Status: r.Status}
+	}
+	return err
+}
+
+// Environment is a deployment environment of a repository, with the
+// protection rules that gate deployments to it.
+type Environment struct {
+	Name string
+	// WaitTimer is how long deployments wait before they start, zero if no
+	// wait timer is configured.
+	WaitTimer time.Duration
+	// Reviewers are the logins of the users and the slugs of the teams that
+	// must approve deployments, empty if no reviewers are required.
+	Reviewers []string
+	Created   time.Time
+	Updated   time.Time
+}
+
+// ListEnvironments lists the deployment environments of the repo, with their
+// wait timers and required reviewers.
+//
+// Environments are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListEnvironments(ctx context.Context, repo string) ([]*Environment, error) {
+	var out []*Environment
+	err := c.call(ctx, "ListEnvironments", repo, func(ctx context.Context) (err error) {
+		out, err = c.listEnvironments(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+type ghEnvironment struct {
+	Name            string `json:"name"`
+	ProtectionRules []struct {
+		Type      string `json:"type"`
+		WaitTimer int    `json:"wait_timer"`
+		Reviewers []struct {
+			Type     string `json:"type"`
+			Reviewer struct {
+				Login string `json:"login"`
+				Slug  string `json:"slug"`
+			} `json:"reviewer"`
+		} `json:"reviewers"`
+	} `json:"protection_rules"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e ghEnvironment) convert() *Environment {
+	out := &Environment{Name: e.Name, Created: e.CreatedAt, Updated: e.UpdatedAt}
+	for _, rule := range e.ProtectionRules {
+		switch rule.Type {
+		case "wait_timer":
+			out.WaitTimer = time.Duration(rule.WaitTimer) * time.Minute
+		case "required_reviewers":
+			for _, r := range rule.Reviewers {
+				if r.Type == "Team" {
+					out.Reviewers = append(out.Reviewers, r.Reviewer.Slug)
+				} else {
+					out.Reviewers = append(out.Reviewers, r.Reviewer.Login)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (c *SCMClient) listEnvironments(ctx context.Context, repo string) ([]*Environment, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	opts := scm.ListOptions{Size: 100}
+	var all []*Environment
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var out struct {
+			Environments []ghEnvironment `json:"environments"`
+		}
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/environments?%s", repo, params.Encode()), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list environments in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range out.Environments {
+			all = append(all, e.convert())
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListDeployments(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/deployments").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/deployments?per_page=100&page=2>; rel="next"`).
+		JSON([]map[string]interface{}{
+			{"id": 2, "sha": "a84d88e", "ref": "main", "environment": "production", "creator": map[string]string{"login": "octocat"}},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/deployments").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 1, "sha": "7fd1a60", "ref": "main", "environment": "staging"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	deployments, err := client.ListDeployments(context.Background(), "Codertocat/Hello-World", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Deployment{
+		{ID: 2, Sha: "a84d88e", Ref: "main", Environment: "production", Creator: "octocat"},
+		{ID: 1, Sha: "7fd1a60", Ref: "main", Environment: "staging"},
+	}
+	if len(deployments) != len(want) {
+		t.Fatalf("got %d deployments, want %d", len(deployments), len(want))
+	}
+	for i := range want {
+		if *deployments[i] != *want[i] {
+			t.Fatalf("got deployment %#v, want %#v", deployments[i], want[i])
+		}
+	}
+}
+
+func TestCreateDeploymentStatus(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/deployments/2/statuses").
+		MatchType("json").
+		JSON(map[string]string{"state": "success", "environment_url": "https://example.com"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": 5, "state": "success"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateDeploymentStatus(context.Background(), "Codertocat/Hello-World", 2, &DeploymentStatusInput{State: "success", EnvironmentURL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("status was not created")
+	}
+}
+
+func TestCreateDeploymentStatusWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/deployments/2/statuses").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateDeploymentStatus(context.Background(), "Codertocat/Hello-World", 2, &DeploymentStatusInput{State: "success"})
+	if !test.MatchError(t, `failed to create status for deployment 2.*\(404\)$`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestListDeploymentsWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if _, err := client.ListDeployments(context.Background(), "Codertocat/Hello-World", scm.ListOptions{}); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want %v", err, scm.ErrNotSupported)
+	}
+}
+
+func TestListEnvironments(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/environments").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"total_count": 2,
+			"environments": []map[string]interface{}{
+				{
+					"name": "production",
+					"protection_rules": []map[string]interface{}{
+						{"type": "wait_timer", "wait_timer": 30},
+						{"type": "required_reviewers", "reviewers": []map[string]interface{}{
+							{"type": "User", "reviewer": map[string]string{"login": "octocat"}},
+							{"type": "Team", "reviewer": map[string]string{"slug": "release-team"}},
+						}},
+						{"type": "branch_policy"},
+					},
+				},
+				{"name": "staging"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	environments, err := client.ListEnvironments(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Environment{
+		{Name: "production", WaitTimer: 30 * time.Minute, Reviewers: []string{"octocat", "release-team"}},
+		{Name: "staging"},
+	}
+	if diff := cmp.Diff(want, environments); diff != "" {
+		t.Fatalf("got different environments: %s", diff)
+	}
+}
+
+func TestListEnvironmentsWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if _, err := client.ListEnvironments(context.Background(), "Codertocat/Hello-World"); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want %v", err, scm.ErrNotSupported)
+	}
+}
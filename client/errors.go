@@ -1,22 +1,112 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
+// ErrProtectedBranch is the error wrapped by an SCMError when the upstream
+// service rejects a write because the branch is protected.
+var ErrProtectedBranch = errors.New("branch is protected")
+
+// ErrNoChange is returned when a write is skipped because the file already has
+// the content being written.
+var ErrNoChange = errors.New("no change to commit")
+
+// ErrConflict is the error wrapped by an SCMError when a write is rejected
+// because the resource changed since it was read.
+var ErrConflict = errors.New("conflicting change")
+
+// ErrMergeInProgress is the error wrapped by an SCMError when the upstream
+// service rejects a merge because another merge into the target branch is in
+// progress, or the target branch was modified while merging.
+var ErrMergeInProgress = errors.New("merge in progress")
+
+// ErrArchived is the error wrapped by an SCMError when the upstream service
+// rejects a write because the repository is archived.
+var ErrArchived = errors.New("repository is archived")
+
+// ErrRepoDisabled is the error wrapped by the errors returned by
+// WithArchivedCheck for writes to a repository that is disabled.
+var ErrRepoDisabled = errors.New("repository is disabled")
+
+// ErrUnauthorized is the error wrapped by an SCMError when the upstream
+// service rejects a request because the token lacks the required permissions.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrNotFound is the error wrapped by an SCMError when a resource that is
+// located by the client, rather than by the upstream service, doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrTruncated is returned along with the items collected by a list method
+// when the limit set with WithMaxItems was reached before every item was
+// listed.
+var ErrTruncated = errors.New("list truncated")
+
+// ErrInvalidRepo is the error wrapped by the errors returned for malformed
+// repo names.
+var ErrInvalidRepo = errors.New("invalid repo name")
+
+// ErrTooManySymlinks is the error wrapped by the errors returned when a file
+// read with WithFollowSymlinks is a chain of symlinks that is longer than the
+// depth, or that loops.
+var ErrTooManySymlinks = errors.New("too many levels of symlinks")
+
 // IsNotFound returns true if the error represents a NotFound response from an
 // upstream service.
 func IsNotFound(err error) bool {
-	e, ok := err.(SCMError)
-	return ok && e.Status == http.StatusNotFound
+	var e SCMError
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
 }
 
 type SCMError struct {
 	Msg    string
 	Status int
+	Err    error
 }
 
 func (s SCMError) Error() string {
 	return fmt.Sprintf("%s: (%d)", s.Msg, s.Status)
 }
+
+// Unwrap returns the underlying cause of the error, if it was identified.
+func (s SCMError) Unwrap() error {
+	return s.Err
+}
+
+// BulkError is returned by operations that act on several resources and
+// carry on past individual failures, it collects each of the failures.
+type BulkError struct {
+	Errs []error
+}
+
+func (b BulkError) Error() string {
+	msgs := make([]string, len(b.Errs))
+	for i, err := range b.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(b.Errs), strings.Join(msgs, "; "))
+}
+
+// Is returns true if any of the collected errors matches the target.
+func (b BulkError) Is(target error) bool {
+	for _, err := range b.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the collected errors that matches the target, and if
+// one does, sets the target to it and returns true.
+func (b BulkError) As(target interface{}) bool {
+	for _, err := range b.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
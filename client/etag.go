@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WithETagCache is an option func that caches the responses to GET requests,
+// e.g. from GetFile and GetBranchHead, that have an ETag, up to size
+// responses, discarding the least recently used.
+//
+// Cached responses are always revalidated, the ETag is sent in an
+// If-None-Match header, and the cached response is only used if the upstream
+// service responds that it's not modified, which GitHub doesn't count against
+// the rate limit.
+//
+// The responses streamed by GetFileStream are not cached.
+func WithETagCache(size int) ClientFunc {
+	return func(c *SCMClient) {
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return newETagCache(size, rt)
+		})
+	}
+}
+
+type etagEntry struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// etagCache is a transport that makes conditional requests for the responses
+// it has cached.
+type etagCache struct {
+	size    int
+	next    http.RoundTripper
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newETagCache(size int, next http.RoundTripper) *etagCache {
+	return &etagCache{size: size, next: next, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *etagCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || c.size <= 0 || req.Context().Value(noETagCacheKey{}) != nil {
+		return transportOrDefault(c.next).RoundTrip(req)
+	}
+	key := etagKey(req)
+	cached := c.get(key)
+	if cached != nil && req.Header.Get("If-None-Match") != "" {
+		cached = nil
+	}
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	res, err := transportOrDefault(c.next).RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		res.Body.Close()
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         res.Proto,
+			ProtoMajor:    res.ProtoMajor,
+			ProtoMinor:    res.ProtoMinor,
+			Header:        cached.header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       req,
+		}, nil
+	}
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" {
+		return res, nil
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.put(&etagEntry{key: key, etag: etag, header: res.Header.Clone(), body: body})
+	return res, nil
+}
+
+func (c *etagCache) get(key string) *etagEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*etagEntry)
+}
+
+func (c *etagCache) put(entry *etagEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[entry.key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagEntry).key)
+	}
+}
+
+// etagKey identifies the request by its URL, and the credentials that are
+// visible to the cache, so that responses are not shared between callers with
+// different credentials.
+func etagKey(req *http.Request) string {
+	parts := []string{req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization"), req.Header.Get("Private-Token"), req.Header.Get("Sudo"), ContextSudo(req.Context())}
+	if t, ok := req.Context().Value(scm.TokenKey{}).(*scm.Token); ok && t != nil {
+		parts = append(parts, t.Token)
+	}
+	return strings.Join(parts, "\x00")
+}
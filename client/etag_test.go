@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetFileWithETagCache(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		SetHeader("ETag", `"abc123"`).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		MatchHeader("If-None-Match", `"abc123"`).
+		Reply(http.StatusNotModified)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithETagCache(10))
+
+	first, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Fatalf("got a different file from the cache: %s", diff)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the cached file was not revalidated")
+	}
+}
+
+func TestETagCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newETagCache(2, nil)
+	for _, k := range []string{"a", "b"} {
+		c.put(&etagEntry{key: k, etag: k})
+	}
+	c.get("a")
+	c.put(&etagEntry{key: "c", etag: "c"})
+
+	if c.get("b") != nil {
+		t.Fatal("the least recently used entry was not evicted")
+	}
+	if c.get("a") == nil || c.get("c") == nil {
+		t.Fatal("recently used entries were evicted")
+	}
+}
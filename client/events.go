@@ -0,0 +1,71 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// Event describes a call to a GitClient method that changes the state of a
+// repository.
+type Event struct {
+	Type   string // the name of the method, e.g. "UpdateFile"
+	Repo   string
+	Branch string
+	Path   string
+	Number int // the number of the pull request
+	Time   time.Time
+	Err    error
+}
+
+// WithEventChannel is an option func that sends an Event to the channel when
+// each call that changes a repository completes.
+//
+// Events are dropped if the channel is full, so that a slow consumer doesn't
+// delay the calls, the number of dropped events is reported by DroppedEvents.
+func WithEventChannel(ch chan<- Event) ClientFunc {
+	return func(c *SCMClient) {
+		c.events = &eventSink{ch: ch}
+	}
+}
+
+// DroppedEvents returns the number of events that were not sent to the
+// channel because it was full.
+func (c *SCMClient) DroppedEvents() uint64 {
+	if c.events == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.events.dropped)
+}
+
+type eventSink struct {
+	dropped uint64 // accessed atomically, first for 64-bit alignment
+	ch      chan<- Event
+}
+
+// emit sends the event, if an event channel is configured, without blocking.
+//
+// As every call that changes a repository emits an event, the prefetched
+// trees that the call may have changed are dropped here too.
+func (c *SCMClient) emit(e Event) {
+	c.trees.invalidateFor(e)
+	c.archivedRepos.invalidateFor(e)
+	if c.events == nil {
+		return
+	}
+	e.Time = c.getClock().Now()
+	select {
+	case c.events.ch <- e:
+	default:
+		atomic.AddUint64(&c.events.dropped, 1)
+	}
+}
+
+// prNumber returns the number of the pull request, or zero if it's nil.
+func prNumber(pr *scm.PullRequest) int {
+	if pr == nil {
+		return 0
+	}
+	return pr.Number
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCreateBranchWithEventChannel(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/created_ref.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+	events := make(chan Event, 1)
+	client := New(scmClient, WithEventChannel(events), WithClock(&fakeClock{now: now}))
+
+	if err := client.CreateBranch(context.Background(), "Codertocat/Hello-World", "new-feature", "aa218f56b14c9653891f9e74264a383fa43fefbd"); err != nil {
+		t.Fatal(err)
+	}
+	want := Event{Type: "CreateBranch", Repo: "Codertocat/Hello-World", Branch: "new-feature", Time: now}
+	if e := <-events; e != want {
+		t.Fatalf("got event %#v, want %#v", e, want)
+	}
+}
+
+func TestEventChannelDropsEventsWhenFull(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/user/starred/Codertocat/Hello-World").
+		Times(2).
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan Event, 1)
+	client := New(scmClient, WithEventChannel(events))
+
+	for i := 0; i < 2; i++ {
+		if err := client.Star(context.Background(), "Codertocat/Hello-World"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l := len(events); l != 1 {
+		t.Fatalf("got %d events, want 1", l)
+	}
+	if d := client.DroppedEvents(); d != 1 {
+		t.Fatalf("got %d dropped events, want 1", d)
+	}
+}
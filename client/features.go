@@ -0,0 +1,142 @@
+package client
+
+import "github.com/ocraviotto/go-scm/scm"
+
+// Feature is a capability that only some drivers support.
+type Feature int
+
+const (
+	// FeatureSquashMerge is squashing the commits of a pull request when it's
+	// merged.
+	FeatureSquashMerge Feature = iota
+	// FeatureDraftPullRequests is opening pull requests as drafts.
+	FeatureDraftPullRequests
+	// FeatureDeployments is listing deployments and environments, and
+	// creating deployment statuses.
+	FeatureDeployments
+	// FeatureMultiFileCommits is committing several changes at once with
+	// UpdateFiles.
+	FeatureMultiFileCommits
+	// FeatureCompare is comparing refs with GetDiff, ChangedFilesBetween,
+	// IsBranchMerged and MergeBase.
+	FeatureCompare
+	// FeatureRepositoryTopics is reading and writing the topics of a repo.
+	FeatureRepositoryTopics
+)
+
+// driverFeatures are the drivers that support each feature.
+var driverFeatures = map[Feature][]scm.Driver{
+	FeatureSquashMerge:       {scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea, scm.DriverBitbucket},
+	FeatureDraftPullRequests: {scm.DriverGithub, scm.DriverGitlab},
+	FeatureDeployments:       {scm.DriverGithub},
+	FeatureMultiFileCommits:  {scm.DriverGithub, scm.DriverGitlab},
+	FeatureCompare:           {scm.DriverGithub, scm.DriverGitlab},
+	FeatureRepositoryTopics:  {scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea},
+}
+
+var (
+	githubOnly        = []scm.Driver{scm.DriverGithub}
+	githubGitLab      = []scm.Driver{scm.DriverGithub, scm.DriverGitlab}
+	githubGitLabGitea = []scm.Driver{scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea}
+
+	// listRepositoriesDrivers are the drivers that ListRepositories supports,
+	// the drivers other than GitHub and GitLab only list the repositories of
+	// the authenticated user, without excluding forks.
+	listRepositoriesDrivers = []scm.Driver{scm.DriverGithub, scm.DriverGitlab, scm.DriverGogs, scm.DriverGitea, scm.DriverBitbucket, scm.DriverStash, scm.DriverGitee}
+)
+
+// methodDrivers are the drivers that support the GitClient methods that are
+// only implemented for some drivers, methods that are not listed are
+// supported by every driver.
+var methodDrivers = map[string][]scm.Driver{
+	"AddLabelsToMatching":        githubGitLab,
+	"ChangedFilesBetween":        githubGitLab,
+	"CommitDir":                  githubGitLab,
+	"CreateDeploymentStatus":     githubOnly,
+	"CreateForkPullRequest":      githubGitLabGitea,
+	"CreateGist":                 githubGitLab,
+	"CreatePullRequestIfChanged": githubGitLab,
+	"CreateReviewComment":        githubOnly,
+	"DeleteBranchesByPrefix":     {scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea, scm.DriverBitbucket, scm.DriverStash},
+	"DownloadReleaseAsset":       githubOnly,
+	"EnableAutoMerge":            githubOnly,
+	"ForkRepository":             githubGitLabGitea,
+	"GetBlame":                   githubGitLab,
+	"GetBranchProtection":        githubGitLabGitea,
+	"GetDiff":                    githubGitLab,
+	"GetFilePermalink":           {scm.DriverGithub, scm.DriverGitlab, scm.DriverGitea, scm.DriverBitbucket},
+	"GetGist":                    githubGitLab,
+	"GetLanguages":               {scm.DriverGithub, scm.DriverGitea},
+	"GetPullRequestDiff":         githubGitLab,
+	"GetRepositoryTopics":        githubGitLabGitea,
+	"GetTokenScopes":             githubGitLab,
+	"HasScope":                   githubGitLab,
+	"IsBranchMerged":             githubGitLab,
+	"IsBranchProtected":          githubGitLabGitea,
+	"IsPullRequestMergeable":     githubGitLab,
+	"IsStarred":                  {scm.DriverGithub, scm.DriverGitea},
+	"ListDeployments":            githubOnly,
+	"ListEnvironments":           githubOnly,
+	"ListReviewComments":         githubOnly,
+	"ListReviews":                githubGitLabGitea,
+	"ListReleaseAssets":          githubOnly,
+	"ListRepositories":           listRepositoriesDrivers,
+	"ListRepositoryVariables":    githubGitLabGitea,
+	"ListReviewThreads":          githubOnly,
+	"ListWorkflowRuns":           githubOnly,
+	"LockPullRequest":            githubGitLab,
+	"MergeBase":                  githubGitLab,
+	"MergePullRequest":           githubGitLab,
+	"PreviewCommit":              githubOnly,
+	"RenameRepository":           githubGitLabGitea,
+	"ResolveReviewThread":        githubOnly,
+	"SetPullRequestBase":         githubGitLabGitea,
+	"SetRepositoryArchived":      githubGitLabGitea,
+	"SetRepositoryTopics":        githubGitLabGitea,
+	"SetRepositorySecret":        {scm.DriverGithub, scm.DriverGitea},
+	"SetRepositoryVariable":      githubGitLabGitea,
+	"SyncDir":                    githubGitLab,
+	"Star":                       githubGitLabGitea,
+	"SuggestReviewers":           githubGitLab,
+	"UnlockPullRequest":          githubGitLab,
+	"Unstar":                     githubGitLabGitea,
+	"UpdateFiles":                githubGitLab,
+	"UploadReleaseAsset":         githubOnly,
+	"WaitForMergeable":           githubGitLab,
+}
+
+// Supports returns true if the driver of the client supports the feature, so
+// that callers can avoid the methods that would fail with ErrNotSupported.
+func (c *SCMClient) Supports(feature Feature) bool {
+	return hasDriver(driverFeatures[feature], c.scmClient.Driver)
+}
+
+// Capabilities returns whether the driver of the client supports each of the
+// GitClient methods that are only implemented for some drivers, keyed by the
+// method name.
+//
+// Calls to the methods that are not supported fail with ErrNotSupported
+// without making any requests.
+func (c *SCMClient) Capabilities() map[string]bool {
+	capabilities := make(map[string]bool, len(methodDrivers))
+	for method, drivers := range methodDrivers {
+		capabilities[method] = hasDriver(drivers, c.scmClient.Driver)
+	}
+	return capabilities
+}
+
+// capable returns false if the method is not supported by the driver of the
+// client.
+func (c *SCMClient) capable(method string) bool {
+	drivers, ok := methodDrivers[method]
+	return !ok || hasDriver(drivers, c.scmClient.Driver)
+}
+
+func hasDriver(drivers []scm.Driver, driver scm.Driver) bool {
+	for _, d := range drivers {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
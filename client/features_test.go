@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		driver  string
+		feature Feature
+		want    bool
+	}{
+		{"github", FeatureDeployments, true},
+		{"gitlab", FeatureDeployments, false},
+		{"gitlab", FeatureDraftPullRequests, true},
+		{"gitea", FeatureDraftPullRequests, false},
+		{"gitea", FeatureRepositoryTopics, true},
+		{"bitbucket", FeatureSquashMerge, true},
+		{"bitbucket", FeatureMultiFileCommits, false},
+		{"github", Feature(-1), false},
+	}
+	for _, tt := range tests {
+		serverURL := ""
+		if tt.driver == "gitea" {
+			serverURL = "https://gitea.example.com"
+		}
+		scmClient, err := factory.NewClient(tt.driver, serverURL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := New(scmClient).Supports(tt.feature); got != tt.want {
+			t.Errorf("%s: Supports(%d) got %v, want %v", tt.driver, tt.feature, got, tt.want)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	capabilities := New(scmClient).Capabilities()
+
+	if !capabilities["UpdateFiles"] {
+		t.Error("UpdateFiles is not supported on GitLab")
+	}
+	if capabilities["EnableAutoMerge"] {
+		t.Error("EnableAutoMerge is supported on GitLab")
+	}
+	if !capabilities["ListRepositories"] {
+		t.Error("ListRepositories is not supported on GitLab")
+	}
+	if _, ok := capabilities["GetFile"]; ok {
+		t.Error("GetFile is listed as a method supported only by some drivers")
+	}
+}
+
+func TestUnsupportedMethodMakesNoRequests(t *testing.T) {
+	gock.New("https://gitea.example.com").
+		Get("/api/v1/repos/Codertocat/Hello-World/contents/README.md").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"content": "", "sha": "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.UpdateFiles(context.Background(), "Codertocat/Hello-World", "main", "Update README", scm.Signature{}, []FileChange{{Path: "README.md", Content: []byte("hi")}})
+	if err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+	if !gock.IsPending() {
+		t.Fatal("a request was made for an unsupported method")
+	}
+}
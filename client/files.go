@@ -0,0 +1,482 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"strings"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// defaultConcurrency is the number of concurrent requests made by methods
+// that fan out, unless configured with WithConcurrency.
+const defaultConcurrency = 8
+
+// GetFileNormalized reads a file like GetFile, and replaces the data with the
+// result of applying normalize to it, this allows callers to compare files
+// without reporting changes that are only formatting, e.g. YAML key order.
+//
+// The SHA of the returned content is unchanged, and is the SHA of the file as
+// stored in the repository.
+func (c *SCMClient) GetFileNormalized(ctx context.Context, repo, ref, path string, normalize func([]byte) ([]byte, error)) (*scm.Content, error) {
+	var out *scm.Content
+	err := c.callAt(ctx, "GetFileNormalized", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getFileNormalized(ctx, repo, ref, path, normalize)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFileNormalized(ctx context.Context, repo, ref, path string, normalize func([]byte) ([]byte, error)) (*scm.Content, error) {
+	content, err := c.GetFile(ctx, repo, ref, path)
+	if err != nil {
+		return content, err
+	}
+	return normalizeContent(content, normalize)
+}
+
+// normalizeContent returns a copy of the content with the normalized data.
+func normalizeContent(content *scm.Content, normalize func([]byte) ([]byte, error)) (*scm.Content, error) {
+	b, err := normalize(content.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize file %s: %w", content.Path, err)
+	}
+	normalized := *content
+	normalized.Data = b
+	return &normalized, nil
+}
+
+// GetFileAtCommit reads a file as of the commit with the SHA, which unlike the
+// ref accepted by GetFile, must be a commit SHA, abbreviated or in full, and
+// never a branch or tag name.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetFileAtCommit(ctx context.Context, repo, sha, path string) (*scm.Content, error) {
+	var out *scm.Content
+	err := c.callAt(ctx, "GetFileAtCommit", repo, sha, path, func(ctx context.Context) (err error) {
+		if !IsCommitSHA(sha) {
+			return fmt.Errorf("failed to get file %s from repo %s: %q is not a commit SHA", path, repo, sha)
+		}
+		out, err = c.getFile(ctx, repo, sha, path)
+		return err
+	})
+	return out, err
+}
+
+// IsCommitSHA returns true if s is a hex commit SHA, either abbreviated to at
+// least 7 characters, or a full SHA-1 or SHA-256.
+func IsCommitSHA(s string) bool {
+	if len(s) < 7 || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetFileRaw reads the specific revision of a file from a repository, and
+// returns the bytes of the file without the metadata.
+//
+// On GitHub, files that are too large for the contents API are read from the
+// blob API.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetFileRaw(ctx context.Context, repo, ref, path string) ([]byte, error) {
+	var out []byte
+	err := c.callAt(ctx, "GetFileRaw", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getFileRaw(ctx, repo, ref, path)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFileRaw(ctx context.Context, repo, ref, path string) ([]byte, error) {
+	var urlPath string
+	header := http.Header{}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		urlPath = fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, path, url.QueryEscape(ref))
+		header.Set("Accept", rawMediaTypeGitHub)
+	case scm.DriverGitlab:
+		urlPath = fmt.Sprintf("api/v4/projects/%s/repository/files/%s/raw?ref=%s", encodeRepo(repo), url.PathEscape(path), url.QueryEscape(ref))
+	default:
+		content, err := c.GetFile(ctx, repo, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		return content.Data, nil
+	}
+	r, body, _, err := c.doRaw(ctx, http.MethodGet, urlPath, header, 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.scmClient.Driver == scm.DriverGithub && isTooLarge(r.Status, body) {
+		return c.getBlobGitHub(ctx, repo, ref, path)
+	}
+	if isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: r.Status}
+	}
+	return body, nil
+}
+
+// rawMediaTypeGitHub is the media type that GitHub returns the bytes of files
+// and blobs for.
+const rawMediaTypeGitHub = "application/vnd.github.raw"
+
+// getBlobGitHub finds the blob for the file in its directory listing, and
+// reads the blob, which is not limited in size like the contents API.
+func (c *SCMClient) getBlobGitHub(ctx context.Context, repo, ref, path string) ([]byte, error) {
+	dir := pathpkg.Dir(path)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := c.listFiles(ctx, repo, ref, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Path == path {
+			return c.getBlobByIDGitHub(ctx, repo, ref, path, e.BlobID)
+		}
+	}
+	return nil, SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: http.StatusNotFound}
+}
+
+// getBlobByIDGitHub reads the blob with the SHA, which is the content of the
+// file at the path in the ref.
+func (c *SCMClient) getBlobByIDGitHub(ctx context.Context, repo, ref, path, sha string) ([]byte, error) {
+	r, body, _, err := c.doRaw(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/blobs/%s", repo, sha), http.Header{"Accept": {rawMediaTypeGitHub}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get blob for file %s from repo %s ref %s", path, repo, ref), Status: r.Status}
+	}
+	return body, nil
+}
+
+// FilesEqual returns true if the file at the path has the same content in
+// both refs.
+//
+// On GitHub and GitLab, the SHAs of the blobs are compared, without reading
+// the content, on other drivers the content is read from both refs.
+//
+// If the file doesn't exist in one of the refs, an error that wraps
+// ErrNotFound is returned, which names the ref.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) FilesEqual(ctx context.Context, repo, path, refA, refB string) (bool, error) {
+	var out bool
+	err := c.call(ctx, "FilesEqual", repo, func(ctx context.Context) (err error) {
+		if refA, err = c.resolveRef(ctx, repo, refA); err != nil {
+			return err
+		}
+		if refB, err = c.resolveRef(ctx, repo, refB); err != nil {
+			return err
+		}
+		out, err = c.filesEqual(ctx, repo, path, refA, refB)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) filesEqual(ctx context.Context, repo, path, refA, refB string) (bool, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub, scm.DriverGitlab:
+		a, err := c.fileBlobID(ctx, repo, refA, path)
+		if err != nil {
+			return false, err
+		}
+		b, err := c.fileBlobID(ctx, repo, refB, path)
+		if err != nil {
+			return false, err
+		}
+		return a == b, nil
+	default:
+		a, err := c.getFileRaw(ctx, repo, refA, path)
+		if err != nil {
+			return false, fileMissing(err, repo, refA, path)
+		}
+		b, err := c.getFileRaw(ctx, repo, refB, path)
+		if err != nil {
+			return false, fileMissing(err, repo, refB, path)
+		}
+		return bytes.Equal(a, b), nil
+	}
+}
+
+// fileBlobID returns the SHA of the blob for the file at the path in the ref,
+// from the directory listing on GitHub, and the headers of the file on GitLab.
+func (c *SCMClient) fileBlobID(ctx context.Context, repo, ref, path string) (string, error) {
+	if c.scmClient.Driver == scm.DriverGitlab {
+		r, err := c.do(ctx, http.MethodHead, fmt.Sprintf("api/v4/projects/%s/repository/files/%s?ref=%s", encodeRepo(repo), url.PathEscape(path), url.QueryEscape(ref)), nil, nil)
+		if r != nil && isErrorStatus(r.Status) {
+			return "", fileMissing(SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: r.Status}, repo, ref, path)
+		}
+		if err != nil {
+			return "", err
+		}
+		return r.Header.Get("X-Gitlab-Blob-Id"), nil
+	}
+	dir := pathpkg.Dir(path)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := c.listFiles(ctx, repo, ref, dir, 0)
+	if err != nil {
+		return "", fileMissing(err, repo, ref, path)
+	}
+	for _, e := range entries {
+		if e.Path == path && e.Kind != scm.ContentKindDirectory {
+			return e.BlobID, nil
+		}
+	}
+	return "", fileMissing(SCMError{Status: http.StatusNotFound}, repo, ref, path)
+}
+
+// fileMissing converts a not found error for the file to an error that names
+// the ref it's missing from, other errors are returned unchanged.
+func fileMissing(err error, repo, ref, path string) error {
+	if !IsNotFound(err) {
+		return err
+	}
+	return SCMError{Msg: fmt.Sprintf("file %s not found in repo %s ref %s", path, repo, ref), Status: http.StatusNotFound, Err: ErrNotFound}
+}
+
+// isTooLarge returns true if GitHub rejected a request to the contents API
+// because the file is too large, which is reported with a 403 response that
+// has the reason in the body, or a 413 response.
+func isTooLarge(status int, body []byte) bool {
+	switch status {
+	case http.StatusRequestEntityTooLarge:
+		return true
+	case http.StatusForbidden:
+		return bytes.Contains(body, []byte("too_large")) || bytes.Contains(body, []byte("This API returns blobs up to"))
+	}
+	return false
+}
+
+// GetFilePermalink returns the URL of the page for the file on the upstream
+// service, at the commit that the ref currently resolves to, so that the link
+// continues to show the same content when the ref moves.
+//
+// Permalinks are only supported on GitHub, GitLab, Gitea and Bitbucket.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetFilePermalink(ctx context.Context, repo, ref, path string) (string, error) {
+	var out string
+	err := c.callAt(ctx, "GetFilePermalink", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getFilePermalink(ctx, repo, ref, path)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFilePermalink(ctx context.Context, repo, ref, path string) (string, error) {
+	var format string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		format = "%s/blob/%s/%s"
+	case scm.DriverGitlab:
+		format = "%s/-/blob/%s/%s"
+	case scm.DriverGitea:
+		format = "%s/src/commit/%s/%s"
+	case scm.DriverBitbucket:
+		format = "%s/src/%s/%s"
+	default:
+		return "", scm.ErrNotSupported
+	}
+	commit, r, err := c.scmClient.Git.FindCommit(ctx, repo, ref)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: fmt.Sprintf("failed to get commit for ref %s in repo %s", ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	repository, r, err := c.scmClient.Repositories.Find(ctx, repo)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: fmt.Sprintf("failed to get repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(format, strings.TrimSuffix(repository.Link, "/"), commit.Sha, escapePath(path)), nil
+}
+
+// escapePath escapes each of the segments of the path for use in a URL.
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// ListFiles lists the entries in a directory of a repository.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListFiles(ctx context.Context, repo, ref, path string) ([]*scm.ContentInfo, error) {
+	var out []*scm.ContentInfo
+	err := c.callAt(ctx, "ListFiles", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.listFiles(ctx, repo, ref, path, c.maxItems)
+		return err
+	})
+	return out, err
+}
+
+// listFiles pages through the entries in the directory, stopping once limit
+// entries have been collected if the limit is not 0.
+func (c *SCMClient) listFiles(ctx context.Context, repo, ref, path string, limit int) ([]*scm.ContentInfo, error) {
+	var (
+		all  []*scm.ContentInfo
+		opts = scm.ListOptions{Size: 100}
+	)
+	for {
+		entries, r, err := c.scmClient.Contents.List(ctx, repo, path, ref, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list files in %s from repo %s ref %s", path, repo, ref), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+		more := nextPage(&opts, r)
+		if exceedsLimit(limit, len(all), more) {
+			return all[:limit], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// ReadDir reads all the files in a directory of a repository, and returns
+// them keyed by their path.
+//
+// Subdirectories are not read, and the files are fetched concurrently.
+func (c *SCMClient) ReadDir(ctx context.Context, repo, ref, path string) (map[string]*scm.Content, error) {
+	var out map[string]*scm.Content
+	err := c.callAt(ctx, "ReadDir", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.readDir(ctx, repo, ref, path)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) readDir(ctx context.Context, repo, ref, path string) (map[string]*scm.Content, error) {
+	entries, err := c.listFiles(ctx, repo, ref, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Kind == scm.ContentKindFile {
+			paths = append(paths, e.Path)
+		}
+	}
+	return c.getFiles(ctx, repo, ref, paths)
+}
+
+// GetFilesAtPaths reads the files at the paths from a repository, and returns
+// them keyed by their path.
+//
+// With the WithGraphQL option, files are fetched from GitHub in batches with a
+// single request for each, otherwise they are fetched concurrently.
+//
+// If any of the files can't be read, the error is returned, if an HTTP error
+// is returned by the upstream service, an error with the response status code
+// is returned.
+func (c *SCMClient) GetFilesAtPaths(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	var out map[string]*scm.Content
+	err := c.call(ctx, "GetFilesAtPaths", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getFilesAtPaths(ctx, repo, ref, paths)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFilesAtPaths(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	if c.graphQL && c.scmClient.Driver == scm.DriverGithub && len(paths) > 0 {
+		return c.getFilesGraphQL(ctx, repo, ref, paths)
+	}
+	return c.getFiles(ctx, repo, ref, paths)
+}
+
+// getFiles fetches the files concurrently, bounded by the configured
+// concurrency, and stops at the first failure.
+func (c *SCMClient) getFiles(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		files    = make(map[string]*scm.Content, len(paths))
+		sem      = make(chan struct{}, c.workers())
+	)
+	for _, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			content, err := c.GetFile(ctx, repo, ref, path)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			files[path] = content
+		}(path)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return files, nil
+}
+
+func (c *SCMClient) workers() int {
+	if c.concurrency > 0 {
+		return c.concurrency
+	}
+	return defaultConcurrency
+}
@@ -0,0 +1,410 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestReadDir(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"name": "a.yaml", "path": "config/a.yaml", "sha": "sha-a", "type": "file"},
+			{"name": "b.yaml", "path": "config/b.yaml", "sha": "sha-b", "type": "file"},
+			{"name": "nested", "path": "config/nested", "sha": "sha-c", "type": "dir"},
+		})
+	for _, name := range []string{"a", "b"} {
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/config/"+name+".yaml").
+			MatchParam("ref", "master").
+			Reply(http.StatusOK).
+			JSON(map[string]string{
+				"path":    "config/" + name + ".yaml",
+				"sha":     "sha-" + name,
+				"content": base64.StdEncoding.EncodeToString([]byte("file " + name)),
+				"type":    "file",
+			})
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithConcurrency(2))
+
+	files, err := client.ReadDir(context.Background(), "Codertocat/Hello-World", "master", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(files); l != 2 {
+		t.Fatalf("got %d files, want 2", l)
+	}
+	for _, name := range []string{"a", "b"} {
+		f := files["config/"+name+".yaml"]
+		if f == nil || string(f.Data) != "file "+name {
+			t.Fatalf("got %#v for %s", f, name)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("files were not read")
+	}
+}
+
+func TestReadDirWithFailedRead(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"name": "a.yaml", "path": "config/a.yaml", "sha": "sha-a", "type": "file"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/a.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusInternalServerError)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ReadDir(context.Background(), "Codertocat/Hello-World", "master", "config")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestGetFileNormalized(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Times(2).
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	file, err := client.GetFileNormalized(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml", func(b []byte) ([]byte, error) {
+		return bytes.ToUpper(b), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "BODY:\n  KEY:\n    ENV:\n      VAL: TESTING\n"; string(file.Data) != want {
+		t.Fatalf("got %q, want %q", file.Data, want)
+	}
+
+	failure := errors.New("invalid YAML")
+	_, err = client.GetFileNormalized(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml", func(b []byte) ([]byte, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+}
+
+func TestGetFileRaw(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/badge.svg").
+		MatchParam("ref", "master").
+		MatchHeader("Accept", "application/vnd.github.raw").
+		Reply(http.StatusOK).
+		BodyString("<svg></svg>")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	b, err := client.GetFileRaw(context.Background(), "Codertocat/Hello-World", "master", "badge.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "<svg></svg>" {
+		t.Fatalf("got %q, want %q", s, "<svg></svg>")
+	}
+}
+
+func TestGetFileRawFallsBackToBlob(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data/large.bin").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "This API returns blobs up to 1 MB in size."})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"name": "large.bin", "path": "data/large.bin", "sha": "blob-sha", "type": "file"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/blobs/blob-sha").
+		MatchHeader("Accept", "application/vnd.github.raw").
+		Reply(http.StatusOK).
+		BodyString("large content")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	b, err := client.GetFileRaw(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "large content" {
+		t.Fatalf("got %q, want %q", s, "large content")
+	}
+}
+
+func TestGetFileRawForbidden(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data/large.bin").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "Resource not accessible by integration"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetFileRaw(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	var scmErr SCMError
+	if !errors.As(err, &scmErr) || scmErr.Status != http.StatusForbidden {
+		t.Fatalf("got %v, want a forbidden error", err)
+	}
+}
+
+func TestGetFileRawFromGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml/raw").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		BodyString("key: value\n")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	b, err := client.GetFileRaw(context.Background(), "Codertocat/Hello-World", "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "key: value\n" {
+		t.Fatalf("got %q, want %q", s, "key: value\n")
+	}
+}
+
+func TestGetFilePermalink(t *testing.T) {
+	sha := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/master").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": sha})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "Hello-World", "html_url": "https://github.com/Codertocat/Hello-World"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	link, err := client.GetFilePermalink(context.Background(), "Codertocat/Hello-World", "master", "config/my file.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://github.com/Codertocat/Hello-World/blob/" + sha + "/config/my%20file.yaml"; link != want {
+		t.Fatalf("got %s, want %s", link, want)
+	}
+}
+
+func TestGetFilePermalinkWithUnknownRef(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/unknown").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetFilePermalink(context.Background(), "Codertocat/Hello-World", "unknown", "config/my/file.yaml")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetFileAtCommit(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "6dcb09b5b57875f334f61aebed695e2e4193db5e").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	body, err := client.GetFileAtCommit(context.Background(), "Codertocat/Hello-World", "6dcb09b5b57875f334f61aebed695e2e4193db5e", "config/my/file.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mustParseJSONAsContent(t, "testdata/content.json")
+	if diff := cmp.Diff(want, body); diff != "" {
+		t.Fatalf("got a different body back: %s\n", diff)
+	}
+}
+
+func TestGetFileAtCommitWithBranch(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetFileAtCommit(context.Background(), "Codertocat/Hello-World", "main", "config/my/file.yaml")
+	if !test.MatchError(t, `"main" is not a commit SHA`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"6dcb09b5b57875f334f61aebed695e2e4193db5e", true},
+		{"6dcb09b", true},
+		{"6DCB09B", true},
+		{"6dcb09", false},
+		{"main", false},
+		{"feature/deadbeef", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsCommitSHA(tt.s); got != tt.want {
+			t.Errorf("IsCommitSHA(%q) got %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFilesEqual(t *testing.T) {
+	for _, ref := range []string{"staging", "prod"} {
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/config").
+			MatchParam("ref", ref).
+			Reply(http.StatusOK).
+			JSON([]map[string]string{
+				{"name": "app.yaml", "path": "config/app.yaml", "sha": "blob-sha", "type": "file"},
+				{"name": "other.yaml", "path": "config/other.yaml", "sha": ref + "-sha", "type": "file"},
+			})
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	equal, err := client.FilesEqual(context.Background(), "Codertocat/Hello-World", "config/app.yaml", "staging", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Fatal("got different files, want equal files")
+	}
+}
+
+func TestFilesEqualInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Head("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		MatchParam("ref", "staging").
+		Reply(http.StatusOK).
+		SetHeader("X-Gitlab-Blob-Id", "staging-sha")
+	gock.New("https://gitlab.com").
+		Head("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		MatchParam("ref", "prod").
+		Reply(http.StatusOK).
+		SetHeader("X-Gitlab-Blob-Id", "prod-sha")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	equal, err := client.FilesEqual(context.Background(), "Codertocat/Hello-World", "config/app.yaml", "staging", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal {
+		t.Fatal("got equal files, want different files")
+	}
+}
+
+func TestFilesEqualWithMissingFile(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Head("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		MatchParam("ref", "staging").
+		Reply(http.StatusOK).
+		SetHeader("X-Gitlab-Blob-Id", "staging-sha")
+	gock.New("https://gitlab.com").
+		Head("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		MatchParam("ref", "prod").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.FilesEqual(context.Background(), "Codertocat/Hello-World", "config/app.yaml", "staging", "prod")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	if !test.MatchError(t, "file config/app.yaml not found in repo Codertocat/Hello-World ref prod", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
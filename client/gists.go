@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// Gist is a GitHub gist, or a GitLab personal snippet.
+//
+// go-scm has no gist API, so gists are read and written directly with the
+// upstream service.
+type Gist struct {
+	ID          string
+	Description string
+	Public      bool
+	// Files is the content of the files in the gist, keyed by their name.
+	Files   map[string]string
+	URL     string
+	Created time.Time
+}
+
+// GistInput provides the input fields required for creating a gist.
+type GistInput struct {
+	// Description is the description of a gist, and the title of a snippet.
+	Description string
+	Public      bool
+	// Files is the content of the files to create, keyed by their name.
+	Files map[string]string
+}
+
+// CreateGist creates a gist with the files, owned by the user that the client
+// authenticates as.
+//
+// Gists are only supported on GitHub, and as snippets on GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateGist(ctx context.Context, inp *GistInput) (*Gist, error) {
+	var out *Gist
+	err := c.call(ctx, "CreateGist", "", func(ctx context.Context) (err error) {
+		out, err = c.createGist(ctx, inp)
+		return err
+	})
+	return out, err
+}
+
+// GetGist gets the gist with the ID, with the content of its files.
+//
+// Gists are only supported on GitHub, and as snippets on GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetGist(ctx context.Context, id string) (*Gist, error) {
+	var out *Gist
+	err := c.call(ctx, "GetGist", "", func(ctx context.Context) (err error) {
+		out, err = c.getGist(ctx, id)
+		return err
+	})
+	return out, err
+}
+
+type ghGistFile struct {
+	Content string `json:"content"`
+}
+
+type ghGist struct {
+	ID          string                `json:"id"`
+	Description string                `json:"description"`
+	Public      bool                  `json:"public"`
+	Files       map[string]ghGistFile `json:"files"`
+	HTMLURL     string                `json:"html_url"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+func (g ghGist) convert() *Gist {
+	out := &Gist{
+		ID:          g.ID,
+		Description: g.Description,
+		Public:      g.Public,
+		Files:       make(map[string]string, len(g.Files)),
+		URL:         g.HTMLURL,
+		Created:     g.CreatedAt,
+	}
+	for name, f := range g.Files {
+		out.Files[name] = f.Content
+	}
+	return out
+}
+
+type glSnippet struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	Visibility string `json:"visibility"`
+	WebURL     string `json:"web_url"`
+	Files      []struct {
+		Path   string `json:"path"`
+		RawURL string `json:"raw_url"`
+	} `json:"files"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *SCMClient) createGist(ctx context.Context, inp *GistInput) (*Gist, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		in := struct {
+			Description string                `json:"description,omitempty"`
+			Public      bool                  `json:"public"`
+			Files       map[string]ghGistFile `json:"files"`
+		}{Description: inp.Description, Public: inp.Public, Files: map[string]ghGistFile{}}
+		for name, content := range inp.Files {
+			in.Files[name] = ghGistFile{Content: content}
+		}
+		var out ghGist
+		r, err := c.do(ctx, http.MethodPost, "gists", in, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: "failed to create gist", Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out.convert(), nil
+	case scm.DriverGitlab:
+		type glSnippetFile struct {
+			FilePath string `json:"file_path"`
+			Content  string `json:"content"`
+		}
+		in := struct {
+			Title      string          `json:"title"`
+			Visibility string          `json:"visibility"`
+			Files      []glSnippetFile `json:"files"`
+		}{Title: inp.Description, Visibility: "private"}
+		if inp.Public {
+			in.Visibility = "public"
+		}
+		for name, content := range inp.Files {
+			in.Files = append(in.Files, glSnippetFile{FilePath: name, Content: content})
+		}
+		var out glSnippet
+		r, err := c.do(ctx, http.MethodPost, "api/v4/snippets", in, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: "failed to create snippet", Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		gist := convertSnippet(out)
+		for name, content := range inp.Files {
+			gist.Files[name] = content
+		}
+		return gist, nil
+	default:
+		return nil, scm.ErrNotSupported
+	}
+}
+
+func (c *SCMClient) getGist(ctx context.Context, id string) (*Gist, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		var out ghGist
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("gists/%s", url.PathEscape(id)), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to get gist %s", id), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out.convert(), nil
+	case scm.DriverGitlab:
+		var out glSnippet
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/snippets/%s", url.PathEscape(id)), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to get snippet %s", id), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		gist := convertSnippet(out)
+		for _, f := range out.Files {
+			path := fmt.Sprintf("api/v4/snippets/%d/files/%s/%s/raw", out.ID, url.PathEscape(snippetRef(f.RawURL, f.Path)), url.PathEscape(f.Path))
+			r, body, _, err := c.doRaw(ctx, http.MethodGet, path, nil, 0)
+			if r != nil && isErrorStatus(r.Status) {
+				return nil, SCMError{Msg: fmt.Sprintf("failed to get file %s of snippet %s", f.Path, id), Status: r.Status}
+			}
+			if err != nil {
+				return nil, err
+			}
+			gist.Files[f.Path] = string(body)
+		}
+		return gist, nil
+	default:
+		return nil, scm.ErrNotSupported
+	}
+}
+
+// convertSnippet returns the gist for the snippet, without the content of its
+// files, which GitLab doesn't return with the snippet.
+func convertSnippet(s glSnippet) *Gist {
+	return &Gist{
+		ID:          strconv.Itoa(s.ID),
+		Description: s.Title,
+		Public:      s.Visibility == "public",
+		Files:       map[string]string{},
+		URL:         s.WebURL,
+		Created:     s.CreatedAt,
+	}
+}
+
+// snippetRef returns the ref of the snippet repository from the raw URL of
+// one of its files, e.g. "main" from
+// "https://gitlab.com/-/snippets/1/raw/main/a.yaml".
+func snippetRef(rawURL, path string) string {
+	prefix := strings.TrimSuffix(rawURL, "/"+path)
+	if i := strings.LastIndex(prefix, "/"); i >= 0 && prefix != rawURL {
+		return prefix[i+1:]
+	}
+	return "main"
+}
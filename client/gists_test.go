@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCreateGist(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/gists").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"description": "scratch output",
+			"public":      false,
+			"files":       map[string]interface{}{"output.txt": map[string]string{"content": "done"}},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{
+			"id":          "aa5a315d61ae9438b18d",
+			"description": "scratch output",
+			"public":      false,
+			"html_url":    "https://gist.github.com/aa5a315d61ae9438b18d",
+			"files":       map[string]interface{}{"output.txt": map[string]string{"filename": "output.txt", "content": "done"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	gist, err := client.CreateGist(context.Background(), &GistInput{Description: "scratch output", Files: map[string]string{"output.txt": "done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Gist{
+		ID:          "aa5a315d61ae9438b18d",
+		Description: "scratch output",
+		Files:       map[string]string{"output.txt": "done"},
+		URL:         "https://gist.github.com/aa5a315d61ae9438b18d",
+	}
+	if diff := cmp.Diff(want, gist); diff != "" {
+		t.Fatalf("got different gist: %s", diff)
+	}
+}
+
+func TestGetGist(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/gists/aa5a315d61ae9438b18d").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"id":     "aa5a315d61ae9438b18d",
+			"public": true,
+			"files":  map[string]interface{}{"output.txt": map[string]string{"filename": "output.txt", "content": "done"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	gist, err := client.GetGist(context.Background(), "aa5a315d61ae9438b18d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Gist{ID: "aa5a315d61ae9438b18d", Public: true, Files: map[string]string{"output.txt": "done"}}
+	if diff := cmp.Diff(want, gist); diff != "" {
+		t.Fatalf("got different gist: %s", diff)
+	}
+}
+
+func TestGetGistNotFound(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/gists/unknown").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetGist(context.Background(), "unknown")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetGistWithRepoValidation(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/gists/aa5a315d61ae9438b18d").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": "aa5a315d61ae9438b18d"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithRepoValidation())
+
+	if _, err := client.GetGist(context.Background(), "aa5a315d61ae9438b18d"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateGistInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/snippets").
+		MatchType("json").
+		JSON(map[string]interface{}{
+			"title":      "scratch output",
+			"visibility": "public",
+			"files":      []map[string]string{{"file_path": "output.txt", "content": "done"}},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{
+			"id":         1,
+			"title":      "scratch output",
+			"visibility": "public",
+			"web_url":    "https://gitlab.com/-/snippets/1",
+			"files":      []map[string]string{{"path": "output.txt", "raw_url": "https://gitlab.com/-/snippets/1/raw/main/output.txt"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	gist, err := client.CreateGist(context.Background(), &GistInput{Description: "scratch output", Public: true, Files: map[string]string{"output.txt": "done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Gist{
+		ID:          "1",
+		Description: "scratch output",
+		Public:      true,
+		Files:       map[string]string{"output.txt": "done"},
+		URL:         "https://gitlab.com/-/snippets/1",
+	}
+	if diff := cmp.Diff(want, gist); diff != "" {
+		t.Fatalf("got different gist: %s", diff)
+	}
+}
+
+func TestGetGistInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/snippets/1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"id":         1,
+			"title":      "scratch output",
+			"visibility": "private",
+			"files":      []map[string]string{{"path": "output.txt", "raw_url": "https://gitlab.com/-/snippets/1/raw/main/output.txt"}},
+		})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/snippets/1/files/main/output.txt/raw").
+		Reply(http.StatusOK).
+		BodyString("done")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	gist, err := client.GetGist(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Gist{ID: "1", Description: "scratch output", Files: map[string]string{"output.txt": "done"}}
+	if diff := cmp.Diff(want, gist); diff != "" {
+		t.Fatalf("got different gist: %s", diff)
+	}
+}
+
+func TestGetGistWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("gitea", "https://try.gitea.io", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if _, err := client.GetGist(context.Background(), "1"); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want %v", err, scm.ErrNotSupported)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// graphQLBatchSize is the number of files fetched in each GraphQL query.
+const graphQLBatchSize = 100
+
+// WithGraphQL is an option func that enables the use of GitHub's GraphQL API
+// for methods that can fetch several resources in a single request, e.g.
+// GetFilesAtPaths.
+//
+// If a GraphQL request fails, the REST API is used instead, so the option has
+// no effect on the results, or on drivers other than GitHub.
+func WithGraphQL() ClientFunc {
+	return func(c *SCMClient) {
+		c.graphQL = true
+	}
+}
+
+// graphQLPath returns the path of the GraphQL endpoint relative to the base
+// URL, on GitHub Enterprise, the REST API is under /api/v3/, and the GraphQL
+// endpoint is /api/graphql.
+func (c *SCMClient) graphQLPath() string {
+	if c.scmClient.BaseURL != nil && strings.HasSuffix(c.scmClient.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
+
+type graphQLBlob struct {
+	Oid      string  `json:"oid"`
+	Text     *string `json:"text"`
+	IsBinary bool    `json:"isBinary"`
+}
+
+// getFilesGraphQL fetches the files with a GraphQL query for each batch of
+// paths, falling back to fetching them from the REST API if a query fails.
+//
+// Binary files are not returned by the GraphQL API as text, they are fetched
+// from the REST API.
+func (c *SCMClient) getFilesGraphQL(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	files := make(map[string]*scm.Content, len(paths))
+	var binary []string
+	for start := 0; start < len(paths); start += graphQLBatchSize {
+		end := start + graphQLBatchSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+		blobs, err := c.queryBlobsGraphQL(ctx, repo, ref, paths[start:end])
+		if err != nil {
+			return c.getFiles(ctx, repo, ref, paths)
+		}
+		for i, path := range paths[start:end] {
+			blob := blobs[fmt.Sprintf("f%d", i)]
+			if blob == nil {
+				return nil, SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: http.StatusNotFound}
+			}
+			if blob.IsBinary || blob.Text == nil {
+				binary = append(binary, path)
+				continue
+			}
+			files[path] = &scm.Content{Path: path, Data: []byte(*blob.Text), Sha: blob.Oid}
+		}
+	}
+	if len(binary) > 0 {
+		rest, err := c.getFiles(ctx, repo, ref, binary)
+		if err != nil {
+			return nil, err
+		}
+		for path, content := range rest {
+			files[path] = content
+		}
+	}
+	return files, nil
+}
+
+// graphQLError is an error returned in the body of a GraphQL response.
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// doGraphQL makes a GraphQL request, and decodes the data of the response into
+// out, the msg describes the request in the errors that are returned.
+func (c *SCMClient) doGraphQL(ctx context.Context, msg, query string, variables map[string]interface{}, out interface{}) error {
+	res := struct {
+		Data   interface{}    `json:"data"`
+		Errors []graphQLError `json:"errors"`
+	}{Data: out}
+	r, err := c.do(ctx, http.MethodPost, c.graphQLPath(), map[string]interface{}{"query": query, "variables": variables}, &res)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: msg, Status: r.Status}
+	}
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("%s: %s", msg, res.Errors[0].Message)
+	}
+	return nil
+}
+
+// queryBlobsGraphQL fetches the blobs for the paths in a single query, the
+// blobs are returned keyed by the alias "f<index of the path>", and are nil
+// for paths that don't exist.
+func (c *SCMClient) queryBlobsGraphQL(ctx context.Context, repo, ref string, paths []string) (map[string]*graphQLBlob, error) {
+	owner, name := scm.Split(repo)
+	var (
+		query     strings.Builder
+		variables = map[string]interface{}{"owner": owner, "name": name}
+	)
+	query.WriteString("query($owner: String!, $name: String!")
+	for i := range paths {
+		fmt.Fprintf(&query, ", $e%d: String!", i)
+	}
+	query.WriteString(") { repository(owner: $owner, name: $name) {")
+	for i, path := range paths {
+		fmt.Fprintf(&query, " f%d: object(expression: $e%d) { ... on Blob { oid text isBinary } }", i, i)
+		variables[fmt.Sprintf("e%d", i)] = ref + ":" + path
+	}
+	query.WriteString(" } }")
+
+	out := struct {
+		Data struct {
+			Repository map[string]*graphQLBlob `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	r, err := c.do(ctx, http.MethodPost, c.graphQLPath(), map[string]interface{}{"query": query.String(), "variables": variables}, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to query files from repo %s ref %s", repo, ref), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Errors) > 0 {
+		return nil, fmt.Errorf("failed to query files from repo %s ref %s: %s", repo, ref, out.Errors[0].Message)
+	}
+	if out.Data.Repository == nil {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to query files from repo %s ref %s", repo, ref), Status: http.StatusNotFound}
+	}
+	return out.Data.Repository, nil
+}
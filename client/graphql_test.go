@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetFilesAtPathsWithGraphQL(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"e0":"master:config/a.yaml","e1":"master:config/b.yaml"`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"data": map[string]interface{}{
+				"repository": map[string]interface{}{
+					"f0": map[string]interface{}{"oid": "sha-a", "text": "a: 1\n"},
+					"f1": map[string]interface{}{"oid": "sha-b", "text": "b: 2\n"},
+				},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithGraphQL())
+
+	files, err := client.GetFilesAtPaths(context.Background(), "Codertocat/Hello-World", "master", []string{"config/a.yaml", "config/b.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(files); l != 2 {
+		t.Fatalf("got %d files, want 2", l)
+	}
+	if f := files["config/b.yaml"]; string(f.Data) != "b: 2\n" || f.Sha != "sha-b" {
+		t.Fatalf("got file %#v, want the content of config/b.yaml", f)
+	}
+}
+
+func TestGetFilesAtPathsWithGraphQLMissingFile(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"data": map[string]interface{}{
+				"repository": map[string]interface{}{"f0": nil},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithGraphQL())
+
+	_, err = client.GetFilesAtPaths(context.Background(), "Codertocat/Hello-World", "master", []string{"config/missing.yaml"})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetFilesAtPathsWithGraphQLFailure(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusBadGateway)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithGraphQL())
+
+	files, err := client.GetFilesAtPaths(context.Background(), "Codertocat/Hello-World", "master", []string{"config/my/file.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["config/my/file.yaml"]; !ok {
+		t.Fatalf("got files %v, want config/my/file.yaml", files)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the file was not fetched from the REST API")
+	}
+}
+
+func TestGetFilesAtPathsWithGraphQLBinaryFile(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"data": map[string]interface{}{
+				"repository": map[string]interface{}{
+					"f0": map[string]interface{}{"oid": "980a0d5f19a64b4b30a87d4206aade58726b60e3", "text": nil, "isBinary": true},
+				},
+			},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithGraphQL())
+
+	files, err := client.GetFilesAtPaths(context.Background(), "Codertocat/Hello-World", "master", []string{"config/my/file.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustParseJSONAsContent(t, "testdata/content.json"); string(files["config/my/file.yaml"].Data) != string(want.Data) {
+		t.Fatalf("got %q, want %q", files["config/my/file.yaml"].Data, want.Data)
+	}
+}
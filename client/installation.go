@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// installationTokenLeeway is how long before they expire installation tokens
+// are replaced, so that requests aren't made with tokens about to expire.
+const installationTokenLeeway = time.Minute
+
+type installationKey struct{}
+
+// WithInstallationContext returns a copy of the context that carries the
+// GitHub App installation, the requests made with the context by a client
+// created with WithInstallationTokens are authenticated with a token for the
+// installation.
+func WithInstallationContext(ctx context.Context, installationID int64) context.Context {
+	return context.WithValue(ctx, installationKey{}, installationID)
+}
+
+// ContextInstallation returns the installation from WithInstallationContext,
+// or zero if there's none.
+func ContextInstallation(ctx context.Context) int64 {
+	id, _ := ctx.Value(installationKey{}).(int64)
+	return id
+}
+
+// WithInstallationTokens is an option func that authenticates the requests
+// made with a context from WithInstallationContext with a token for the
+// installation, so a single client can be shared by the installations of a
+// GitHub App.
+//
+// The tokens are created with the app JWT from the TokenSource, and cached
+// until shortly before they expire. Requests without an installation in the
+// context, or with a token from WithContextToken, use the credentials of the
+// client as usual.
+//
+// On other drivers this is a no-op.
+func WithInstallationTokens(app TokenSource) ClientFunc {
+	return func(c *SCMClient) {
+		if c.scmClient.Driver != scm.DriverGithub {
+			return
+		}
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &installationTransport{client: c, app: app, tokens: map[int64]installationToken{}, next: rt}
+		})
+	}
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// installationTransport puts the token for the installation from the request
+// context in the context, where it's used by the contextTokenTransport.
+type installationTransport struct {
+	client *SCMClient
+	app    TokenSource
+	next   http.RoundTripper
+
+	mu      sync.Mutex
+	tokens  map[int64]installationToken
+	minting flightGroup
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := ContextInstallation(req.Context())
+	if _, ok := req.Context().Value(scm.TokenKey{}).(*scm.Token); ok || id == 0 {
+		return transportOrDefault(t.next).RoundTrip(req)
+	}
+	token, err := t.token(req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return transportOrDefault(t.next).RoundTrip(req.WithContext(WithContextToken(req.Context(), token)))
+}
+
+// token returns the cached token for the installation, or creates a new one if
+// there's none or it's about to expire, concurrent requests for the same
+// installation share the token that's created.
+func (t *installationTransport) token(ctx context.Context, id int64) (string, error) {
+	now := t.client.getClock().Now()
+	t.mu.Lock()
+	cached, ok := t.tokens[id]
+	t.mu.Unlock()
+	if ok && now.Add(installationTokenLeeway).Before(cached.ExpiresAt) {
+		return cached.Token, nil
+	}
+	v, err := t.minting.do(strconv.FormatInt(id, 10), func() (interface{}, error) {
+		return t.createToken(ctx, id)
+	})
+	token, _ := v.(string)
+	return token, err
+}
+
+// createToken creates a token for the installation, and caches it.
+func (t *installationTransport) createToken(ctx context.Context, id int64) (string, error) {
+	jwt, err := t.app.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get app token: %w", err)
+	}
+	u, err := t.client.scmClient.BaseURL.Parse(fmt.Sprintf("app/installations/%d/access_tokens", id))
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(WithContextToken(ctx, jwt), http.MethodPost, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := transportOrDefault(t.next).RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.StatusCode) {
+		e := SCMError{Msg: fmt.Sprintf("failed to create token for installation %d", id), Status: res.StatusCode}
+		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+			e.Err = ErrUnauthorized
+		}
+		return "", e
+	}
+	var created installationToken
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode token for installation %d: %w", id, err)
+	}
+	t.mu.Lock()
+	t.tokens[id] = created
+	t.mu.Unlock()
+	return created.Token, nil
+}
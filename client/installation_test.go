@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWithInstallationTokens(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"1", "2"} {
+		gock.New("https://api.github.com").
+			Post("/app/installations/"+id+"/access_tokens").
+			MatchHeader("Authorization", "Bearer app-jwt").
+			Reply(http.StatusCreated).
+			JSON(map[string]interface{}{"token": "installation-" + id, "expires_at": now.Add(time.Hour)})
+	}
+	for _, token := range []string{"installation-1", "installation-1", "installation-2", "default-token"} {
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/README.md").
+			MatchHeader("Authorization", "Bearer "+token).
+			Reply(http.StatusOK).
+			File("testdata/content.json")
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "default-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{now: now}), WithInstallationTokens(StaticTokenSource("app-jwt")))
+
+	for _, ctx := range []context.Context{
+		WithInstallationContext(context.Background(), 1),
+		WithInstallationContext(context.Background(), 1),
+		WithInstallationContext(context.Background(), 2),
+		context.Background(),
+	} {
+		if _, err := client.GetFile(ctx, "Codertocat/Hello-World", "master", "README.md"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("requests were not made with the installation tokens")
+	}
+}
+
+func TestWithInstallationTokensRefreshesExpiringTokens(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)}
+	for _, token := range []string{"token-1", "token-2"} {
+		gock.New("https://api.github.com").
+			Post("/app/installations/1/access_tokens").
+			Reply(http.StatusCreated).
+			JSON(map[string]interface{}{"token": token, "expires_at": clock.now.Add(time.Hour)})
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/README.md").
+			MatchHeader("Authorization", "Bearer "+token).
+			Reply(http.StatusOK).
+			File("testdata/content.json")
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(clock), WithInstallationTokens(StaticTokenSource("app-jwt")))
+
+	ctx := WithInstallationContext(context.Background(), 1)
+	if _, err := client.GetFile(ctx, "Codertocat/Hello-World", "master", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(time.Hour - 30*time.Second)
+	if _, err := client.GetFile(ctx, "Codertocat/Hello-World", "master", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the expiring token was not replaced")
+	}
+}
+
+func TestWithInstallationTokensCoalescesConcurrentRequests(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	gock.New("https://api.github.com").
+		Post("/app/installations/1/access_tokens").
+		Reply(http.StatusCreated).
+		Delay(100 * time.Millisecond).
+		JSON(map[string]interface{}{"token": "installation-1", "expires_at": now.Add(time.Hour)})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/README.md").
+		MatchHeader("Authorization", "Bearer installation-1").
+		Times(3).
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{now: now}), WithInstallationTokens(StaticTokenSource("app-jwt")))
+
+	ctx := WithInstallationContext(context.Background(), 1)
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := client.GetFile(ctx, "Codertocat/Hello-World", "master", "README.md")
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("requests were not made with the installation token")
+	}
+}
+
+func TestWithInstallationTokensFailure(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/app/installations/1/access_tokens").
+		Reply(http.StatusUnauthorized)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithInstallationTokens(StaticTokenSource("bad-jwt")))
+
+	_, err = client.GetFile(WithInstallationContext(context.Background(), 1), "Codertocat/Hello-World", "master", "README.md")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v, want ErrUnauthorized", err)
+	}
+}
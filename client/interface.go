@@ -2,6 +2,9 @@ package client
 
 import (
 	"context"
+	"io"
+	"text/template"
+	"time"
 
 	"github.com/ocraviotto/go-scm/scm"
 )
@@ -9,9 +12,95 @@ import (
 // GitClient wraps go-scm's Client with a simplified API.
 type GitClient interface {
 	GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error)
-	UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error
+	GetFileNormalized(ctx context.Context, repo, ref, path string, normalize func([]byte) ([]byte, error)) (*scm.Content, error)
+	GetFileAtCommit(ctx context.Context, repo, sha, path string) (*scm.Content, error)
+	GetFileRaw(ctx context.Context, repo, ref, path string) ([]byte, error)
+	GetFileStream(ctx context.Context, repo, ref, path string) (io.ReadCloser, error)
+	FilesEqual(ctx context.Context, repo, path, refA, refB string) (bool, error)
+	GetFilesAtPaths(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error)
+	GetReadme(ctx context.Context, repo, ref string) (*scm.Content, error)
+	GetFilePermalink(ctx context.Context, repo, ref, path string) (string, error)
+	GetBlame(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error)
+	ListFiles(ctx context.Context, repo, ref, path string) ([]*scm.ContentInfo, error)
+	ReadDir(ctx context.Context, repo, ref, path string) (map[string]*scm.Content, error)
+	UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte, opts ...WriteOption) error
+	UpdateFileWithRetry(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) (string, error)
+	SyncFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, want []byte, opts ...WriteOption) (changed bool, sha string, err error)
+	UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error)
+	PreviewCommit(ctx context.Context, repo, baseBranch string, changes []FileChange) (*CommitPreview, error)
+	CommitTemplate(ctx context.Context, repo, branch, path, message string, signature scm.Signature, tmpl *template.Template, data interface{}) (string, error)
+	SyncDir(ctx context.Context, repo, branch, dir string, desired map[string][]byte, signature scm.Signature, message string) (created, updated, deleted int, sha string, err error)
+	CommitDir(ctx context.Context, repo, branch, message string, signature scm.Signature, localDir, repoPrefix string) (string, error)
+	Batch() *Batcher
+	Supports(feature Feature) bool
+	Capabilities() map[string]bool
+	GetTokenScopes(ctx context.Context) ([]string, error)
+	HasScope(ctx context.Context, scope string) (bool, error)
+	CreateGist(ctx context.Context, inp *GistInput) (*Gist, error)
+	GetGist(ctx context.Context, id string) (*Gist, error)
 	DeleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error
-	CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+	CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput, opts ...PullRequestOption) (*scm.PullRequest, error)
+	CreatePullRequestIfChanged(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, bool, error)
+	CreatePullRequestFromPatches(ctx context.Context, repo, baseBranch, newBranch string, patches [][]byte, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+	CreateForkPullRequest(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+	GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error)
+	ListPullRequestCommits(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Commit, error)
+	EnableAutoMerge(ctx context.Context, repo string, number int, method MergeMethod) error
+	MergePullRequest(ctx context.Context, repo string, number int, method MergeMethod, opts ...MergeOption) error
+	SetPullRequestBase(ctx context.Context, repo string, number int, newBase string) error
+	LockPullRequest(ctx context.Context, repo string, number int, reason string) error
+	UnlockPullRequest(ctx context.Context, repo string, number int) error
+	ListReviews(ctx context.Context, repo string, number int) ([]*Review, error)
+	ListReviewThreads(ctx context.Context, repo string, number int) ([]*ReviewThread, error)
+	ResolveReviewThread(ctx context.Context, repo, threadID string) error
+	ListReviewComments(ctx context.Context, repo string, number int) ([]*scm.Review, error)
+	CreateReviewComment(ctx context.Context, repo string, number int, inp *scm.ReviewInput) error
+	IsPullRequestMergeable(ctx context.Context, repo string, number int) (bool, error)
+	WaitForMergeable(ctx context.Context, repo string, number int, poll time.Duration) error
+	GetPullRequestDiff(ctx context.Context, repo string, number int) (string, error)
+	CountOpenPullRequests(ctx context.Context, repo string) (int, error)
+	ClosePullRequestsOlderThan(ctx context.Context, repo string, d time.Duration, filter func(*scm.PullRequest) bool) (int, error)
+	AddLabelsToMatching(ctx context.Context, repo string, match func(*scm.PullRequest) bool, labels []string) (int, error)
+	GetCodeOwners(ctx context.Context, repo, ref string) (*CodeOwners, error)
+	SuggestReviewers(ctx context.Context, repo string, number int) ([]string, error)
+	CreateIssue(ctx context.Context, repo string, inp *scm.IssueInput) (*scm.Issue, error)
+	CreateIssueComment(ctx context.Context, repo string, number int, body string) (*scm.Comment, error)
+	GetCombinedStatus(ctx context.Context, repo, ref string) (*CombinedStatus, error)
+	GetChecksStatus(ctx context.Context, repo, ref string) (*ChecksSummary, error)
+	ListReleaseAssets(ctx context.Context, repo string, releaseID int) ([]*ReleaseAsset, error)
+	DownloadReleaseAsset(ctx context.Context, repo string, assetID int, w io.Writer) error
+	UploadReleaseAsset(ctx context.Context, repo string, releaseID int, name, contentType string, r io.Reader) (*ReleaseAsset, error)
+	LatestRelease(ctx context.Context, repo string, includePrereleases bool, opts ...ReleaseOption) (*scm.Release, error)
+	ListTagsWithCommits(ctx context.Context, repo string, opts scm.ListOptions) ([]*TagInfo, error)
+	CreateStatus(ctx context.Context, repo, ref string, inp *scm.StatusInput) (*scm.Status, error)
+	GetDiff(ctx context.Context, repo, base, head string) (string, error)
+	ChangedFilesBetween(ctx context.Context, repo, base, head string) ([]string, error)
+	GetCommitFiles(ctx context.Context, repo, sha string, opts scm.ListOptions) ([]*scm.Change, error)
 	CreateBranch(ctx context.Context, repo, branch, sha string) error
+	CreateBranchIfBaseMatches(ctx context.Context, repo, branch, baseBranch, expectedBaseSHA string) (string, error)
 	GetBranchHead(ctx context.Context, repo, branch string) (string, error)
+	GetBranchHeads(ctx context.Context, repo string, branches []string) (map[string]string, error)
+	DeleteBranchesByPrefix(ctx context.Context, repo, prefix string) (int, error)
+	BranchesWithoutOpenPRs(ctx context.Context, repo, prefix string) ([]string, error)
+	MergeBase(ctx context.Context, repo, ref1, ref2 string) (string, error)
+	IsBranchMerged(ctx context.Context, repo, branch, baseBranch string) (bool, error)
+	IsBranchProtected(ctx context.Context, repo, branch string) (bool, error)
+	GetBranchProtection(ctx context.Context, repo, branch string) (*BranchProtection, error)
+	ListRepositories(ctx context.Context, org string, opts RepositoryListOptions) ([]*scm.Repository, error)
+	ListDeployments(ctx context.Context, repo string, opts scm.ListOptions) ([]*Deployment, error)
+	CreateDeploymentStatus(ctx context.Context, repo string, id int, inp *DeploymentStatusInput) error
+	ListEnvironments(ctx context.Context, repo string) ([]*Environment, error)
+	ListWorkflowRuns(ctx context.Context, repo, ref string, opts scm.ListOptions) ([]*WorkflowRun, error)
+	SetRepositoryArchived(ctx context.Context, repo string, archived bool) error
+	RenameRepository(ctx context.Context, repo, newName string) (*scm.Repository, error)
+	ForkRepository(ctx context.Context, repo, org string, opts ...ForkOption) (*scm.Repository, error)
+	GetLanguages(ctx context.Context, repo string) (map[string]int, error)
+	GetRepositoryTopics(ctx context.Context, repo string) ([]string, error)
+	SetRepositoryTopics(ctx context.Context, repo string, topics []string) error
+	SetRepositoryVariable(ctx context.Context, repo, name, value string) error
+	ListRepositoryVariables(ctx context.Context, repo string) (map[string]string, error)
+	SetRepositorySecret(ctx context.Context, repo, name, value string) error
+	Star(ctx context.Context, repo string) error
+	Unstar(ctx context.Context, repo string) error
+	IsStarred(ctx context.Context, repo string) (bool, error)
 }
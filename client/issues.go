@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// CreateIssue creates an issue with the provided input.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateIssue(ctx context.Context, repo string, inp *scm.IssueInput) (*scm.Issue, error) {
+	var out *scm.Issue
+	err := c.call(ctx, "CreateIssue", repo, func(ctx context.Context) (err error) {
+		out, err = c.createIssue(ctx, repo, inp)
+		return err
+	})
+	c.emit(Event{Type: "CreateIssue", Repo: repo, Number: issueNumber(out), Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createIssue(ctx context.Context, repo string, inp *scm.IssueInput) (*scm.Issue, error) {
+	issue, r, err := c.scmClient.Issues.Create(ctx, repo, inp)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to create issue in repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// CreateIssueComment adds a comment with the body to the issue.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateIssueComment(ctx context.Context, repo string, number int, body string) (*scm.Comment, error) {
+	var out *scm.Comment
+	err := c.call(ctx, "CreateIssueComment", repo, func(ctx context.Context) (err error) {
+		out, err = c.createIssueComment(ctx, repo, number, body)
+		return err
+	})
+	c.emit(Event{Type: "CreateIssueComment", Repo: repo, Number: number, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createIssueComment(ctx context.Context, repo string, number int, body string) (*scm.Comment, error) {
+	comment, r, err := c.scmClient.Issues.CreateComment(ctx, repo, number, &scm.CommentInput{Body: body})
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to comment on issue %d in repo %s", number, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// issueNumber returns the number of the issue, or zero if it's nil.
+func issueNumber(issue *scm.Issue) int {
+	if issue == nil {
+		return 0
+	}
+	return issue.Number
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCreateIssue(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues").
+		MatchType("json").
+		JSON(map[string]string{"title": "Manual step required", "body": "Please approve the migration."}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"number": 7, "title": "Manual step required", "html_url": "https://github.com/Codertocat/Hello-World/issues/7"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	issue, err := client.CreateIssue(context.Background(), "Codertocat/Hello-World", &scm.IssueInput{Title: "Manual step required", Body: "Please approve the migration."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Number != 7 {
+		t.Fatalf("got issue %d, want 7", issue.Number)
+	}
+}
+
+func TestCreateIssueWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues").
+		Reply(http.StatusGone).
+		JSON(map[string]string{"message": "Issues are disabled for this repo"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreateIssue(context.Background(), "Codertocat/Hello-World", &scm.IssueInput{Title: "Manual step required"})
+	if !test.MatchError(t, `failed to create issue.*\(410\)$`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCreateIssueComment(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/7/comments").
+		MatchType("json").
+		JSON(map[string]string{"body": "Done."}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": 12, "body": "Done."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	comment, err := client.CreateIssueComment(context.Background(), "Codertocat/Hello-World", 7, "Done.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.ID != 12 {
+		t.Fatalf("got comment %d, want 12", comment.ID)
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import "bytes"
+
+// LineEnding is the style of line endings that text content is written with.
+type LineEnding int
+
+const (
+	// LineEndingNone writes content unchanged.
+	LineEndingNone LineEnding = iota
+	// LineEndingLF writes text content with "\n" line endings.
+	LineEndingLF
+	// LineEndingCRLF writes text content with "\r\n" line endings.
+	LineEndingCRLF
+)
+
+// binarySniffLen is the number of bytes checked for NUL bytes when detecting
+// binary content, this is the same heuristic that git uses.
+const binarySniffLen = 8000
+
+// WithLineEndingNormalization is an option func that converts the line
+// endings of text content written with UpdateFile and UpdateFiles to the
+// style.
+//
+// Content that contains a NUL byte in the first 8000 bytes is treated as
+// binary and written unchanged.
+func WithLineEndingNormalization(style LineEnding) ClientFunc {
+	return func(c *SCMClient) {
+		c.lineEnding = style
+	}
+}
+
+// normalizeLineEndings returns the content with the line endings converted to
+// the style, lone "\r" characters are not treated as line endings.
+func normalizeLineEndings(b []byte, style LineEnding) []byte {
+	if style == LineEndingNone || isBinary(b) {
+		return b
+	}
+	lf := bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	if style == LineEndingCRLF {
+		return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))
+	}
+	return lf
+}
+
+func isBinary(b []byte) bool {
+	if len(b) > binarySniffLen {
+		b = b[:binarySniffLen]
+	}
+	return bytes.IndexByte(b, 0) >= 0
+}
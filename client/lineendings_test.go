@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	mixed := "a\r\nb\nc\rd\r\n"
+	binary := "a\r\n\x00b\r\n"
+	tests := []struct {
+		name    string
+		content string
+		style   LineEnding
+		want    string
+	}{
+		{"none leaves content", mixed, LineEndingNone, mixed},
+		{"mixed to LF", mixed, LineEndingLF, "a\nb\nc\rd\n"},
+		{"mixed to CRLF", mixed, LineEndingCRLF, "a\r\nb\r\nc\rd\r\n"},
+		{"CRLF is idempotent", "a\r\nb\r\n", LineEndingCRLF, "a\r\nb\r\n"},
+		{"binary is unchanged", binary, LineEndingLF, binary},
+		{"empty content", "", LineEndingCRLF, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeLineEndings([]byte(tt.content), tt.style)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateFileNormalizingLineEndings(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchType("json").
+		BodyString(base64.StdEncoding.EncodeToString([]byte("a: 1\nb: 2\n"))).
+		Reply(http.StatusCreated).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithLineEndingNormalization(LineEndingLF))
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml", "update", "", scm.Signature{}, []byte("a: 1\r\nb: 2\r\n"), AllowEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("content was not normalized")
+	}
+}
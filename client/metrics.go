@@ -0,0 +1,74 @@
+package client
+
+import "time"
+
+// MetricsRecorder records metrics about the calls to the GitClient methods.
+//
+// To export the metrics to Prometheus, implement the methods with the
+// collectors of the process, e.g.
+//
+//	type promRecorder struct {
+//		calls   *prometheus.HistogramVec // labels: method, result
+//		retries *prometheus.CounterVec   // labels: method
+//		waits   prometheus.Histogram
+//	}
+//
+//	func (r promRecorder) ObserveCall(method string, dur time.Duration, err error) {
+//		result := "success"
+//		if err != nil {
+//			result = "error"
+//		}
+//		r.calls.WithLabelValues(method, result).Observe(dur.Seconds())
+//	}
+//
+//	func (r promRecorder) IncRetry(method string) {
+//		r.retries.WithLabelValues(method).Inc()
+//	}
+//
+//	func (r promRecorder) ObserveRateLimitWait(dur time.Duration) {
+//		r.waits.Observe(dur.Seconds())
+//	}
+//
+// The recorder is called from the goroutines making the calls, so it must be
+// safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveCall records a call to the method that completed after the
+	// duration, with the error it returned, including calls that failed
+	// without making any requests.
+	ObserveCall(method string, dur time.Duration, err error)
+	// IncRetry records a retry of a failed call to the method.
+	IncRetry(method string)
+	// ObserveRateLimitWait records the time waited before retrying a call
+	// that was rate limited.
+	ObserveRateLimitWait(dur time.Duration)
+}
+
+// NopMetricsRecorder is a MetricsRecorder that discards the metrics, it's
+// used unless a recorder is configured with WithMetrics.
+type NopMetricsRecorder struct{}
+
+// ObserveCall implements the MetricsRecorder interface.
+func (NopMetricsRecorder) ObserveCall(method string, dur time.Duration, err error) {}
+
+// IncRetry implements the MetricsRecorder interface.
+func (NopMetricsRecorder) IncRetry(method string) {}
+
+// ObserveRateLimitWait implements the MetricsRecorder interface.
+func (NopMetricsRecorder) ObserveRateLimitWait(dur time.Duration) {}
+
+// WithMetrics is an option func that records metrics about every call to a
+// GitClient method with the recorder.
+//
+// Calls made by other methods are recorded as part of the calling method.
+func WithMetrics(r MetricsRecorder) ClientFunc {
+	return func(c *SCMClient) {
+		c.metrics = r
+	}
+}
+
+func (c *SCMClient) getMetrics() MetricsRecorder {
+	if c.metrics != nil {
+		return c.metrics
+	}
+	return NopMetricsRecorder{}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+var _ MetricsRecorder = NopMetricsRecorder{}
+
+type observedCall struct {
+	method string
+	dur    time.Duration
+	err    error
+}
+
+type stubMetricsRecorder struct {
+	calls   []observedCall
+	retries []string
+	waits   []time.Duration
+}
+
+func (s *stubMetricsRecorder) ObserveCall(method string, dur time.Duration, err error) {
+	s.calls = append(s.calls, observedCall{method: method, dur: dur, err: err})
+}
+
+func (s *stubMetricsRecorder) IncRetry(method string) {
+	s.retries = append(s.retries, method)
+}
+
+func (s *stubMetricsRecorder) ObserveRateLimitWait(dur time.Duration) {
+	s.waits = append(s.waits, dur)
+}
+
+func TestWithMetrics(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusTooManyRequests)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &stubMetricsRecorder{}
+	client := New(scmClient, WithClock(&fakeClock{}), WithRetry(3, time.Second), WithMetrics(recorder))
+
+	if _, err := client.GetFile(context.TODO(), "Codertocat/Hello-World", "master", "config/my/file.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []observedCall{{method: "GetFile", dur: time.Second}}; !reflect.DeepEqual(recorder.calls, want) {
+		t.Fatalf("got calls %v, want %v", recorder.calls, want)
+	}
+	if want := []string{"GetFile"}; !reflect.DeepEqual(recorder.retries, want) {
+		t.Fatalf("got retries %v, want %v", recorder.retries, want)
+	}
+	if want := []time.Duration{time.Second}; !reflect.DeepEqual(recorder.waits, want) {
+		t.Fatalf("got rate limit waits %v, want %v", recorder.waits, want)
+	}
+}
+
+func TestWithMetricsRecordsUnsupportedCalls(t *testing.T) {
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &stubMetricsRecorder{}
+	client := New(scmClient, WithMetrics(recorder))
+
+	_, err = client.GetDiff(context.TODO(), "Codertocat/Hello-World", "main", "feature")
+	if err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+	if len(recorder.calls) != 1 || recorder.calls[0].method != "GetDiff" || recorder.calls[0].err != scm.ErrNotSupported {
+		t.Fatalf("got calls %v, want the unsupported GetDiff call", recorder.calls)
+	}
+	if len(recorder.retries) != 0 {
+		t.Fatalf("got retries %v, want none", recorder.retries)
+	}
+}
@@ -0,0 +1,256 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// GetCommitFiles implements the client.GitClient interface.
+//
+// The changes are the ones added with AddCommitFiles, all of them are returned
+// regardless of the page in the options.
+func (m *MockClient) GetCommitFiles(ctx context.Context, repo, sha string, opts scm.ListOptions) ([]*scm.Change, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	changes, ok := m.commitFiles[key(repo, sha)]
+	if !ok {
+		return nil, notFound("failed to list files changed by commit %s in repo %s", sha, repo)
+	}
+	n, err := m.limitItems(len(changes))
+	return append([]*scm.Change(nil), changes[:n]...), err
+}
+
+// AddCommitFiles sets the changes to files returned by GetCommitFiles for the
+// commit.
+func (m *MockClient) AddCommitFiles(repo, sha string, changes []*scm.Change) {
+	m.commitFiles[key(repo, sha)] = changes
+}
+
+// GetDiff implements the client.GitClient interface.
+//
+// The diff set with SetDiff for the base and head is returned.
+func (m *MockClient) GetDiff(ctx context.Context, repo, base, head string) (string, error) {
+	if err := m.checkMethod("GetDiff", repo); err != nil {
+		return "", err
+	}
+	diff, ok := m.diffs[key(repo, base, head)]
+	if !ok {
+		return "", notFound("failed to get diff between %s and %s in repo %s", base, head, repo)
+	}
+	return diff, nil
+}
+
+// CommitDir implements the client.GitClient interface.
+//
+// The files in the directory are committed with UpdateFiles, so a single
+// commit is recorded.
+func (m *MockClient) CommitDir(ctx context.Context, repo, branch, message string, signature scm.Signature, localDir, repoPrefix string) (string, error) {
+	if err := m.checkMethod("CommitDir", repo); err != nil {
+		return "", err
+	}
+	changes, err := client.DirChanges(localDir, repoPrefix)
+	if err != nil {
+		return "", err
+	}
+	return m.UpdateFiles(ctx, repo, branch, message, signature, changes)
+}
+
+// SyncDir implements the client.GitClient interface.
+//
+// The changes are worked out from the files currently in the directory of the
+// branch, and committed with UpdateFiles, so a single commit is recorded.
+func (m *MockClient) SyncDir(ctx context.Context, repo, branch, dir string, desired map[string][]byte, signature scm.Signature, message string) (created, updated, deleted int, sha string, err error) {
+	if err := m.checkMethod("SyncDir", repo); err != nil {
+		return 0, 0, 0, "", err
+	}
+	current := map[string][]byte{}
+	for _, p := range m.filesUnder(repo, branch, dir) {
+		if b, ok := m.currentContents(repo, p, branch); ok {
+			current[p] = b
+		}
+	}
+	changes, created, updated, deleted := client.SyncChanges(dir, current, desired)
+	if len(changes) == 0 {
+		return 0, 0, 0, m.branchHeads[key(repo, branch)], client.ErrNoChange
+	}
+	sha, err = m.UpdateFiles(ctx, repo, branch, message, signature, changes)
+	if err != nil {
+		return 0, 0, 0, sha, err
+	}
+	return created, updated, deleted, sha, nil
+}
+
+// PreviewCommit implements the client.GitClient interface.
+//
+// The changes are applied to a copy of the files of the base branch, and the
+// tree SHA is derived from the paths and contents of the resulting files.
+func (m *MockClient) PreviewCommit(ctx context.Context, repo, baseBranch string, changes []client.FileChange) (*client.CommitPreview, error) {
+	if err := m.checkMethod("PreviewCommit", repo); err != nil {
+		return nil, err
+	}
+	contents := map[string][]byte{}
+	for _, path := range m.filesUnder(repo, baseBranch, "") {
+		if b, ok := m.currentContents(repo, path, baseBranch); ok {
+			contents[path] = b
+		}
+	}
+	var effective []client.FileChange
+	for _, change := range changes {
+		if current, ok := contents[change.Path]; ok && !change.Delete && bytes.Equal(current, change.Content) {
+			continue
+		}
+		effective = append(effective, change)
+		if change.Delete {
+			delete(contents, change.Path)
+			continue
+		}
+		contents[change.Path] = change.Content
+	}
+	files := make([]string, 0, len(contents))
+	for path := range contents {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	var tree strings.Builder
+	for _, path := range files {
+		fmt.Fprintf(&tree, "%s:%s\n", path, bytesSha1(contents[path]))
+	}
+	return &client.CommitPreview{TreeSha: bytesSha1([]byte(tree.String())), Files: files, Changes: effective}, nil
+}
+
+// filesUnder returns the paths of the files added or committed under the
+// directory of the branch, including files that have since been deleted.
+func (m *MockClient) filesUnder(repo, branch, dir string) []string {
+	prefix := dirPrefix(dir)
+	var paths []string
+	for _, state := range []map[string][]byte{m.files, m.updatedFiles} {
+		for k := range state {
+			if parts := splitKey(k); parts[0] == repo && parts[2] == branch && strings.HasPrefix(parts[1], prefix) {
+				paths = append(paths, parts[1])
+			}
+		}
+	}
+	return paths
+}
+
+// ChangedFilesBetween implements the client.GitClient interface.
+//
+// The paths are the ones added with AddCommitFiles for the commits that are
+// reachable from the head but not from the base, in the commit graph added
+// with AddCommit. Branches are resolved like they are by MergeBase.
+func (m *MockClient) ChangedFilesBetween(ctx context.Context, repo, base, head string) ([]string, error) {
+	if err := m.checkMethod("ChangedFilesBetween", repo); err != nil {
+		return nil, err
+	}
+	excluded := m.ancestors(repo, m.commitSHA(repo, base))
+	seen := map[string]bool{}
+	paths := []string{}
+	for sha := range m.ancestors(repo, m.commitSHA(repo, head)) {
+		if excluded[sha] {
+			continue
+		}
+		for _, change := range m.commitFiles[key(repo, sha)] {
+			for _, path := range []string{change.Path, change.PrevFilePath} {
+				if path != "" && !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// SetDiff sets the diff returned by GetDiff for the base and head.
+func (m *MockClient) SetDiff(repo, base, head, diff string) {
+	m.diffs[key(repo, base, head)] = diff
+}
+
+// CommitTemplate implements the client.GitClient interface.
+//
+// The rendered bytes are committed with UpdateFiles, so they can be asserted
+// with GetUpdatedContents.
+func (m *MockClient) CommitTemplate(ctx context.Context, repo, branch, path, message string, signature scm.Signature, tmpl *template.Template, data interface{}) (_ string, err error) {
+	defer m.exitCall(m.enterCall(), "CommitTemplate", repo, branch, path, &err)
+	if err := m.checkMethod("CommitTemplate", repo); err != nil {
+		return "", err
+	}
+	content, err := client.RenderTemplate(tmpl, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render file %s: %w", path, err)
+	}
+	return m.UpdateFiles(ctx, repo, branch, message, signature, []client.FileChange{{Path: path, Content: content}})
+}
+
+// AddCommit is a mock method for adding a commit with its parents to the
+// commit graph that MergeBase walks.
+func (m *MockClient) AddCommit(repo, sha string, parents ...string) {
+	m.commitParents[key(repo, sha)] = parents
+}
+
+// MergeBase implements the client.GitClient interface.
+//
+// Refs that are branches with a head added with AddBranchHead are resolved to
+// the head, other refs are assumed to be SHAs, and the best common ancestor
+// is found in the commit graph added with AddCommit.
+func (m *MockClient) MergeBase(ctx context.Context, repo, ref1, ref2 string) (string, error) {
+	if err := m.checkMethod("MergeBase", repo); err != nil {
+		return "", err
+	}
+	sha1, sha2 := m.commitSHA(repo, ref1), m.commitSHA(repo, ref2)
+	ancestors1, ancestors2 := m.ancestors(repo, sha1), m.ancestors(repo, sha2)
+	var common []string
+	for sha := range ancestors1 {
+		if ancestors2[sha] {
+			common = append(common, sha)
+		}
+	}
+	sort.Strings(common)
+	for _, candidate := range common {
+		best := true
+		for _, other := range common {
+			if other != candidate && m.ancestors(repo, other)[candidate] {
+				best = false
+				break
+			}
+		}
+		if best {
+			return candidate, nil
+		}
+	}
+	return "", notFound("failed to get merge base of %s and %s in repo %s", ref1, ref2, repo)
+}
+
+// commitSHA resolves a branch with a head added with AddBranchHead to the
+// head, and returns other refs unchanged.
+func (m *MockClient) commitSHA(repo, ref string) string {
+	if head, ok := m.branchHeads[key(repo, ref)]; ok {
+		return head
+	}
+	return ref
+}
+
+// ancestors returns the commit and every commit reachable from it in the
+// commit graph added with AddCommit.
+func (m *MockClient) ancestors(repo, sha string) map[string]bool {
+	found := map[string]bool{}
+	pending := []string{sha}
+	for len(pending) > 0 {
+		sha, pending = pending[0], pending[1:]
+		if found[sha] {
+			continue
+		}
+		found[sha] = true
+		pending = append(pending, m.commitParents[key(repo, sha)]...)
+	}
+	return found
+}
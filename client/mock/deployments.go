@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListDeployments implements the client.GitClient interface.
+//
+// The deployments added with AddDeployment are returned, all of them are
+// returned regardless of the page in the options.
+func (m *MockClient) ListDeployments(ctx context.Context, repo string, opts scm.ListOptions) ([]*client.Deployment, error) {
+	if err := m.checkMethod("ListDeployments", repo); err != nil {
+		return nil, err
+	}
+	n, err := m.limitItems(len(m.deployments[repo]))
+	return m.deployments[repo][:n], err
+}
+
+// CreateDeploymentStatus implements the client.GitClient interface.
+//
+// Statuses can only be created for deployments added with AddDeployment.
+func (m *MockClient) CreateDeploymentStatus(ctx context.Context, repo string, id int, inp *client.DeploymentStatusInput) error {
+	if err := m.checkMethod("CreateDeploymentStatus", repo); err != nil {
+		return err
+	}
+	if m.deployment(repo, id) == nil {
+		return notFound("failed to create status for deployment %d in repo %s", id, repo)
+	}
+	k := key(repo, strconv.Itoa(id))
+	m.deploymentStatuses[k] = append(m.deploymentStatuses[k], inp)
+	return nil
+}
+
+// ListEnvironments implements the client.GitClient interface.
+//
+// The environments added with AddEnvironment are returned.
+func (m *MockClient) ListEnvironments(ctx context.Context, repo string) ([]*client.Environment, error) {
+	if err := m.checkMethod("ListEnvironments", repo); err != nil {
+		return nil, err
+	}
+	n, err := m.limitItems(len(m.environments[repo]))
+	return m.environments[repo][:n], err
+}
+
+// AddEnvironment is a mock method for setting up an environment returned by
+// ListEnvironments.
+func (m *MockClient) AddEnvironment(repo string, e *client.Environment) {
+	m.environments[repo] = append(m.environments[repo], e)
+}
+
+// AddDeployment is a mock method for setting up a deployment returned by
+// ListDeployments.
+func (m *MockClient) AddDeployment(repo string, d *client.Deployment) {
+	m.deployments[repo] = append(m.deployments[repo], d)
+}
+
+// GetDeploymentStatuses returns the statuses created for the deployment, in
+// the order they were created.
+func (m *MockClient) GetDeploymentStatuses(repo string, id int) []*client.DeploymentStatusInput {
+	return m.deploymentStatuses[key(repo, strconv.Itoa(id))]
+}
+
+// AssertDeploymentStatusCreated fails if no status with the state was created
+// for the deployment.
+func (m *MockClient) AssertDeploymentStatusCreated(repo string, id int, state string) {
+	m.t.Helper()
+	for _, inp := range m.GetDeploymentStatuses(repo, id) {
+		if inp.State == state {
+			return
+		}
+	}
+	m.t.Fatalf("no status %q created for deployment %d in repo %s", state, id, repo)
+}
+
+func (m *MockClient) deployment(repo string, id int) *client.Deployment {
+	for _, d := range m.deployments[repo] {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// Supports implements the client.GitClient interface.
+//
+// The mock supports every feature, unless it's turned off with SetSupported.
+func (m *MockClient) Supports(feature client.Feature) bool {
+	supported, ok := m.supported[feature]
+	return !ok || supported
+}
+
+// SetSupported is a mock method for setting whether Supports returns true for
+// the feature.
+func (m *MockClient) SetSupported(feature client.Feature, supported bool) {
+	m.supported[feature] = supported
+}
+
+// Capabilities implements the client.GitClient interface.
+//
+// The mock supports every method, the methods turned off with SetCapability
+// are returned as unsupported.
+func (m *MockClient) Capabilities() map[string]bool {
+	capabilities := map[string]bool{}
+	for method, supported := range m.capabilities {
+		capabilities[method] = supported
+	}
+	return capabilities
+}
+
+// SetCapability is a mock method for setting whether the method is supported,
+// calls to unsupported methods that only some drivers implement fail with
+// scm.ErrNotSupported.
+func (m *MockClient) SetCapability(method string, supported bool) {
+	m.capabilities[method] = supported
+}
+
+// checkMethod rejects calls to methods turned off with SetCapability, and
+// validates the repo like checkRepo.
+//
+// With ArchivedCheck, calls to methods that change the repo are rejected if
+// it's archived or disabled.
+func (m *MockClient) checkMethod(method, repo string) error {
+	if supported, ok := m.capabilities[method]; ok && !supported {
+		return scm.ErrNotSupported
+	}
+	if err := m.checkRepo(repo); err != nil {
+		return err
+	}
+	if m.ArchivedCheck && client.IsMutatingMethod(method) {
+		return m.checkArchived(repo)
+	}
+	return nil
+}
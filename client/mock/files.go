@@ -0,0 +1,371 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// GetFileNormalized implements the client.GitClient interface.
+func (m *MockClient) GetFileNormalized(ctx context.Context, repo, ref, path string, normalize func([]byte) ([]byte, error)) (_ *scm.Content, err error) {
+	defer m.exitCall(m.enterCall(), "GetFileNormalized", repo, ref, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	content, err := m.GetFile(ctx, repo, ref, path)
+	if err != nil {
+		return content, err
+	}
+	b, err := normalize(content.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize file %s: %w", path, err)
+	}
+	return &scm.Content{Path: content.Path, Data: b, Sha: content.Sha}, nil
+}
+
+// GetFileRaw implements the client.GitClient interface.
+func (m *MockClient) GetFileRaw(ctx context.Context, repo, ref, path string) (_ []byte, err error) {
+	defer m.exitCall(m.enterCall(), "GetFileRaw", repo, ref, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	if m.GetFileErr != nil {
+		return nil, m.GetFileErr
+	}
+	if b, ok := m.files[key(repo, path, ref)]; ok {
+		return b, nil
+	}
+	return nil, notFound("failed to get file %s from repo %s ref %s", path, repo, ref)
+}
+
+// GetFileStream implements the client.GitClient interface.
+//
+// The reader is over the content returned by GetFileRaw.
+func (m *MockClient) GetFileStream(ctx context.Context, repo, ref, path string) (_ io.ReadCloser, err error) {
+	defer m.exitCall(m.enterCall(), "GetFileStream", repo, ref, path, &err)
+	b, err := m.GetFileRaw(ctx, repo, ref, path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// FilesEqual implements the client.GitClient interface.
+//
+// The SHAs of the current contents of the file in the refs are compared.
+func (m *MockClient) FilesEqual(ctx context.Context, repo, path, refA, refB string) (_ bool, err error) {
+	defer m.exitCall(m.enterCall(), "FilesEqual", repo, "", path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return false, err
+	}
+	if m.GetFileErr != nil {
+		return false, m.GetFileErr
+	}
+	var shas []string
+	for _, ref := range []string{m.resolveRef(repo, refA), m.resolveRef(repo, refB)} {
+		b, ok := m.currentContents(repo, path, ref)
+		if !ok {
+			return false, client.SCMError{
+				Msg:    fmt.Sprintf("file %s not found in repo %s ref %s", path, repo, ref),
+				Status: http.StatusNotFound,
+				Err:    client.ErrNotFound,
+			}
+		}
+		shas = append(shas, bytesSha1(b))
+	}
+	return shas[0] == shas[1], nil
+}
+
+// GetFileAtCommit implements the client.GitClient interface.
+//
+// The content added with AddFileContents for the SHA is returned, files are
+// not updated at a commit, so updates to branches are not included.
+func (m *MockClient) GetFileAtCommit(ctx context.Context, repo, sha, path string) (_ *scm.Content, err error) {
+	defer m.exitCall(m.enterCall(), "GetFileAtCommit", repo, sha, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	if !client.IsCommitSHA(sha) {
+		return nil, fmt.Errorf("failed to get file %s from repo %s: %q is not a commit SHA", path, repo, sha)
+	}
+	if m.GetFileErr != nil {
+		return nil, m.GetFileErr
+	}
+	if b, ok := m.files[key(repo, path, sha)]; ok {
+		return &scm.Content{Path: path, Data: b, Sha: bytesSha1(b)}, nil
+	}
+	return nil, notFound("failed to get file %s from repo %s ref %s", path, repo, sha)
+}
+
+// GetCodeOwners implements the client.GitClient interface.
+//
+// The CODEOWNERS file is parsed from the contents added with AddFileContents,
+// at the first of the locations that the client reads it from.
+func (m *MockClient) GetCodeOwners(ctx context.Context, repo, ref string) (*client.CodeOwners, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	if m.GetFileErr != nil {
+		return nil, m.GetFileErr
+	}
+	for _, path := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		if b, ok := m.files[key(repo, path, ref)]; ok {
+			return client.ParseCodeOwners(b), nil
+		}
+	}
+	return nil, notFound("failed to find CODEOWNERS in repo %s ref %s", repo, ref)
+}
+
+// SuggestReviewers implements the client.GitClient interface.
+//
+// The owners are matched with the CODEOWNERS file added with AddFileContents
+// to the target branch of a pull request created with CreatePullRequest,
+// against the changes added with AddPullRequestChanges.
+func (m *MockClient) SuggestReviewers(ctx context.Context, repo string, number int) ([]string, error) {
+	if err := m.checkMethod("SuggestReviewers", repo); err != nil {
+		return nil, err
+	}
+	pr := m.pullRequest(repo, number)
+	if pr == nil {
+		return nil, notFound("failed to get pull request %d in repo %s", number, repo)
+	}
+	owners, err := m.GetCodeOwners(ctx, repo, pr.Target)
+	if client.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.SuggestedReviewers(owners, m.pullRequestChanges[key(repo, strconv.Itoa(number))]), nil
+}
+
+// AddPullRequestChanges sets the changes to files in the pull request that
+// SuggestReviewers finds the owners of.
+func (m *MockClient) AddPullRequestChanges(repo string, number int, changes []*scm.Change) {
+	m.pullRequestChanges[key(repo, strconv.Itoa(number))] = changes
+}
+
+// GetReadme implements the client.GitClient interface.
+//
+// The README is located among the files added with AddFileContents at the
+// root of the ref, like the client does for drivers without a readme
+// endpoint.
+func (m *MockClient) GetReadme(ctx context.Context, repo, ref string) (*scm.Content, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	entries, err := m.listFiles(repo, ref, "")
+	if err != nil && !client.IsNotFound(err) {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Kind == scm.ContentKindFile {
+			paths = append(paths, e.Path)
+		}
+	}
+	path, ok := client.FindReadme(paths)
+	if !ok {
+		return nil, client.SCMError{
+			Msg:    fmt.Sprintf("failed to find README in repo %s ref %s", repo, ref),
+			Status: http.StatusNotFound,
+			Err:    client.ErrNotFound,
+		}
+	}
+	b, _ := m.currentContents(repo, path, ref)
+	return &scm.Content{Path: path, Data: b, Sha: bytesSha1(b)}, nil
+}
+
+// GetFilePermalink implements the client.GitClient interface.
+//
+// Refs that are branches with a head added with AddBranchHead are resolved to
+// the head, other refs are assumed to be SHAs.
+func (m *MockClient) GetFilePermalink(ctx context.Context, repo, ref, path string) (_ string, err error) {
+	defer m.exitCall(m.enterCall(), "GetFilePermalink", repo, ref, path, &err)
+	if err := m.checkMethod("GetFilePermalink", repo); err != nil {
+		return "", err
+	}
+	ref = m.resolveRef(repo, ref)
+	sha := ref
+	if head, ok := m.branchHeads[key(repo, ref)]; ok {
+		sha = head
+	}
+	return fmt.Sprintf("https://example.com/%s/blob/%s/%s", repo, sha, path), nil
+}
+
+// GetBlame implements the client.GitClient interface.
+//
+// The hunks added with AddBlame for the file are returned.
+func (m *MockClient) GetBlame(ctx context.Context, repo, ref, path string) (_ []*client.BlameHunk, err error) {
+	defer m.exitCall(m.enterCall(), "GetBlame", repo, ref, path, &err)
+	if err := m.checkMethod("GetBlame", repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	hunks, ok := m.blame[key(repo, path, ref)]
+	if !ok {
+		return nil, notFound("failed to get blame for file %s from repo %s ref %s", path, repo, ref)
+	}
+	return hunks, nil
+}
+
+// AddBlame is a mock method for setting up the hunks returned by GetBlame for
+// the file at the ref.
+func (m *MockClient) AddBlame(repo, path, ref string, hunks []*client.BlameHunk) {
+	m.blame[key(repo, path, ref)] = hunks
+}
+
+// GetFilesAtPaths implements the client.GitClient interface.
+func (m *MockClient) GetFilesAtPaths(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	files := make(map[string]*scm.Content, len(paths))
+	for _, path := range paths {
+		content, err := m.GetFile(ctx, repo, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = content
+	}
+	return files, nil
+}
+
+// ListFiles implements the client.GitClient interface.
+//
+// The entries are derived from the files added with AddFileContents, with an
+// entry for each subdirectory.
+func (m *MockClient) ListFiles(ctx context.Context, repo, ref, path string) (_ []*scm.ContentInfo, err error) {
+	defer m.exitCall(m.enterCall(), "ListFiles", repo, ref, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	entries, err := m.listFiles(repo, m.resolveRef(repo, ref), path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := m.limitItems(len(entries))
+	return entries[:n], err
+}
+
+func (m *MockClient) listFiles(repo, ref, path string) ([]*scm.ContentInfo, error) {
+	if m.GetFileErr != nil {
+		return nil, m.GetFileErr
+	}
+	prefix := dirPrefix(path)
+	found := map[string]scm.ContentKind{}
+	for k := range m.files {
+		parts := splitKey(k)
+		if parts[0] != repo || parts[2] != ref || !strings.HasPrefix(parts[1], prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(parts[1], prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			found[prefix+rest[:i]] = scm.ContentKindDirectory
+			continue
+		}
+		found[parts[1]] = scm.ContentKindFile
+	}
+	if len(found) == 0 {
+		return nil, notFound("failed to list files in %s from repo %s ref %s", path, repo, ref)
+	}
+	entries := make([]*scm.ContentInfo, 0, len(found))
+	for p, kind := range found {
+		info := &scm.ContentInfo{Path: p, Kind: kind}
+		if kind != scm.ContentKindDirectory {
+			info.Sha = bytesSha1(m.files[key(repo, p, ref)])
+		}
+		entries = append(entries, info)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// ReadDir implements the client.GitClient interface.
+func (m *MockClient) ReadDir(ctx context.Context, repo, ref, path string) (_ map[string]*scm.Content, err error) {
+	defer m.exitCall(m.enterCall(), "ReadDir", repo, ref, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	entries, err := m.listFiles(repo, ref, path)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*scm.Content{}
+	for _, e := range entries {
+		if e.Kind != scm.ContentKindFile {
+			continue
+		}
+		content, err := m.GetFile(ctx, repo, ref, e.Path)
+		if err != nil {
+			return nil, err
+		}
+		files[e.Path] = content
+	}
+	return files, nil
+}
+
+// AddSymlink is a mock method for adding a symlink to the target, relative to
+// the directory of the path, which is read like a file with the target as its
+// content, unless SetFollowSymlinks is used.
+func (m *MockClient) AddSymlink(repo, path, ref, target string) {
+	m.AddFileContents(repo, path, ref, []byte(target))
+	m.symlinks[key(repo, path, ref)] = true
+}
+
+// SetFollowSymlinks makes GetFile follow symlinks up to depth times, like the
+// client.WithFollowSymlinks option.
+func (m *MockClient) SetFollowSymlinks(depth int) {
+	m.symlinkDepth = depth
+}
+
+// followSymlink returns the content of the file that the symlink at the path
+// points to, following the symlinks that it points to up to the depth set with
+// SetFollowSymlinks.
+func (m *MockClient) followSymlink(repo, ref, path, target string) (*scm.Content, error) {
+	seen := map[string]bool{}
+	for hops := 0; ; hops++ {
+		seen[path] = true
+		resolved, err := client.ResolveSymlink(path, target)
+		if err != nil {
+			return nil, err
+		}
+		if hops == m.symlinkDepth || seen[resolved] {
+			return nil, fmt.Errorf("failed to follow symlink %s in repo %s ref %s: %w", path, repo, ref, client.ErrTooManySymlinks)
+		}
+		b, ok := m.currentContents(repo, resolved, ref)
+		if !ok {
+			return nil, notFound("failed to get file %s from repo %s ref %s", resolved, repo, ref)
+		}
+		path = resolved
+		if !m.symlinks[key(repo, path, ref)] {
+			return &scm.Content{Path: path, Data: b, Sha: bytesSha1(b)}, nil
+		}
+		target = string(b)
+	}
+}
+
+// dirPrefix returns the prefix shared by the paths of the entries in the
+// directory.
+func dirPrefix(dir string) string {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// CreateGist implements the client.GitClient interface.
+//
+// The gist is given the next numeric ID, and can be read with GetGist.
+func (m *MockClient) CreateGist(ctx context.Context, inp *client.GistInput) (*client.Gist, error) {
+	if supported, ok := m.capabilities["CreateGist"]; ok && !supported {
+		return nil, scm.ErrNotSupported
+	}
+	files := make(map[string]string, len(inp.Files))
+	for name, content := range inp.Files {
+		files[name] = content
+	}
+	g := &client.Gist{
+		ID:          strconv.Itoa(len(m.gists) + 1),
+		Description: inp.Description,
+		Public:      inp.Public,
+		Files:       files,
+	}
+	m.gists = append(m.gists, g)
+	m.createdGists = append(m.createdGists, g)
+	return g, nil
+}
+
+// GetGist implements the client.GitClient interface.
+//
+// The gists added with AddGist or created with CreateGist are returned.
+func (m *MockClient) GetGist(ctx context.Context, id string) (*client.Gist, error) {
+	if supported, ok := m.capabilities["GetGist"]; ok && !supported {
+		return nil, scm.ErrNotSupported
+	}
+	for _, g := range m.gists {
+		if g.ID == id {
+			return g, nil
+		}
+	}
+	return nil, notFound("failed to get gist %s", id)
+}
+
+// AddGist is a mock method for setting up a gist returned by GetGist.
+func (m *MockClient) AddGist(g *client.Gist) {
+	m.gists = append(m.gists, g)
+}
+
+// GetCreatedGists returns the gists created with CreateGist, in the order
+// they were created.
+func (m *MockClient) GetCreatedGists() []*client.Gist {
+	return m.createdGists
+}
+
+// AssertGistCreated fails if no gist with the file was created with
+// CreateGist.
+func (m *MockClient) AssertGistCreated(name, content string) {
+	m.t.Helper()
+	for _, g := range m.createdGists {
+		if got, ok := g.Files[name]; ok && got == content {
+			return
+		}
+	}
+	m.t.Fatalf("no gist created with file %s with content %q", name, content)
+}
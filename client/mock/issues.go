@@ -0,0 +1,67 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// CreateIssue implements the client.GitClient interface.
+//
+// Issues are numbered in the order they are created in each repo.
+func (m *MockClient) CreateIssue(ctx context.Context, repo string, inp *scm.IssueInput) (*scm.Issue, error) {
+	if err := m.checkMethod("CreateIssue", repo); err != nil {
+		return nil, err
+	}
+	if m.CreateIssueErr != nil {
+		return nil, m.CreateIssueErr
+	}
+	m.createdIssues[repo] = append(m.createdIssues[repo], inp)
+	m.recordActor(ctx, repo, "CreateIssue")
+	number := len(m.createdIssues[repo])
+	return &scm.Issue{
+		Number: number,
+		Title:  inp.Title,
+		Body:   inp.Body,
+		Link:   fmt.Sprintf("https://example.com/issues/%d", number),
+	}, nil
+}
+
+// CreateIssueComment implements the client.GitClient interface.
+func (m *MockClient) CreateIssueComment(ctx context.Context, repo string, number int, body string) (*scm.Comment, error) {
+	if err := m.checkMethod("CreateIssueComment", repo); err != nil {
+		return nil, err
+	}
+	if m.CreateIssueErr != nil {
+		return nil, m.CreateIssueErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.issueComments[k] = append(m.issueComments[k], body)
+	m.recordActor(ctx, repo, "CreateIssueComment")
+	return &scm.Comment{ID: len(m.issueComments[k]), Body: body}, nil
+}
+
+// AssertIssueCreated fails if no issue with the title was created in the
+// repo.
+func (m *MockClient) AssertIssueCreated(repo, title string) {
+	m.t.Helper()
+	for _, inp := range m.createdIssues[repo] {
+		if inp.Title == title {
+			return
+		}
+	}
+	m.t.Fatalf("no issue with title %q created in repo %s", title, repo)
+}
+
+// AssertIssueCommentCreated fails if no comment with the body was added to the
+// issue.
+func (m *MockClient) AssertIssueCommentCreated(repo string, number int, body string) {
+	m.t.Helper()
+	for _, b := range m.issueComments[key(repo, fmt.Sprint(number))] {
+		if b == body {
+			return
+		}
+	}
+	m.t.Fatalf("no comment %q added to issue %d in repo %s", body, number, repo)
+}
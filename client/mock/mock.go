@@ -1,13 +1,18 @@
 package mock
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ocraviotto/go-scm/scm"
 	"github.com/ocraviotto/pkg/client"
@@ -21,79 +26,532 @@ func New(t *testing.T) *MockClient {
 		t:                   t,
 		files:               make(map[string][]byte),
 		updatedFiles:        make(map[string][]byte),
+		deletedFiles:        make(map[string]bool),
+		commits:             make(map[string][]*Commit),
 		createdBranches:     make(map[string]bool),
+		deletedBranches:     make(map[string]bool),
 		branchHeads:         make(map[string]string),
+		protectedBranches:   make(map[string]bool),
 		createdPullRequests: make(map[string][]*scm.PullRequestInput),
+		pullRequestDiffs:    make(map[string]string),
+		repositories:        make(map[string][]*scm.Repository),
+		forkedRepositories:  make(map[*scm.Repository]bool),
+		defaultBranches:     make(map[string]string),
+		starred:             make(map[string]bool),
+		mergeStates:         make(map[string]MergeState),
+		statuses:            make(map[string][]*scm.Status),
+		createdStatuses:     make(map[string][]*scm.StatusInput),
+		tags:                make(map[string][]*client.TagInfo),
+		commitFiles:         make(map[string][]*scm.Change),
+		archived:            make(map[string]bool),
+		mergedBranches:      make(map[string]bool),
+		createdIssues:       make(map[string][]*scm.IssueInput),
+		issueComments:       make(map[string][]string),
+		pullRequestsCreated: make(map[string]time.Time),
+		closedPullRequests:  make(map[string]bool),
+		deployments:         make(map[string][]*client.Deployment),
+		deploymentStatuses:  make(map[string][]*client.DeploymentStatusInput),
+		environments:        make(map[string][]*client.Environment),
+		diffs:               make(map[string]string),
+		topics:              make(map[string][]string),
+		pullRequestCommits:  make(map[string][]*scm.Commit),
+		renamedRepositories: make(map[string]string),
+		updateMessages:      make(map[string][]string),
+		supported:           make(map[client.Feature]bool),
+		capabilities:        make(map[string]bool),
+		symlinks:            make(map[string]bool),
+		variables:           make(map[string]string),
+		secrets:             make(map[string]bool),
+		commitParents:       make(map[string][]string),
+		releaseAssets:       make(map[string][]*client.ReleaseAsset),
+		assetContents:       make(map[string][]byte),
+		workflowRuns:        make(map[string][]*client.WorkflowRun),
+		forks:               make(map[string]*scm.Repository),
+		forkRequests:        make(map[string]int),
+		blame:               make(map[string][]*client.BlameHunk),
+		reviewComments:      make(map[string][]*scm.Review),
+		actors:              make(map[string][]string),
+		pullRequestChanges:  make(map[string][]*scm.Change),
+		mergedPullRequests:  make(map[string]client.MergeMethod),
+		mergesInProgress:    make(map[string]int),
+		mergeCommitMessages: make(map[string]mergeCommitMessage),
+		checkRuns:           make(map[string][]*client.Check),
+		lockedPullRequests:  make(map[string]string),
+		disabled:            make(map[string]bool),
+		releases:            make(map[string][]*scm.Release),
+		labels:              make(map[string][]string),
+		autoMerges:          make(map[string]client.MergeMethod),
+		reviewThreads:       make(map[string][]*client.ReviewThread),
+		reviews:             make(map[string][]*client.Review),
+		branchProtection:    make(map[string]*client.BranchProtection),
+		languages:           make(map[string]map[string]int),
 	}
 }
 
+// Commit is a multi-file commit recorded by the mock.
+type Commit struct {
+	Sha       string
+	Message   string
+	Signature scm.Signature
+	Changes   []client.FileChange
+}
+
 // MockClient implements the client.GitClient interface with an in-memory
 // representation of files.
+//
+// Repo names are checked with client.ValidateRepo, so malformed names fail
+// with client.ErrInvalidRepo, as they do with the WithRepoValidation option,
+// and the old names of repos renamed with RenameRepository fail with a not
+// found error.
 type MockClient struct {
 	t                    *testing.T
 	files                map[string][]byte
 	GetFileErr           error
 	updatedFiles         map[string][]byte
 	UpdateFileErr        error
+	DeleteFileErr        error
+	deletedFiles         map[string]bool
+	commits              map[string][]*Commit
 	createdBranches      map[string]bool
 	CreateBranchErr      error
+	deletedBranches      map[string]bool
+	DeleteBranchErr      error
 	branchHeads          map[string]string
+	protectedBranches    map[string]bool
+	branchProtection     map[string]*client.BranchProtection
+	defaultBranches      map[string]string
 	createdPullRequests  map[string][]*scm.PullRequestInput
 	CreatePullRequestErr error
+	pullRequestDiffs     map[string]string
+	repositories         map[string][]*scm.Repository
+	forkedRepositories   map[*scm.Repository]bool
+	ListRepositoriesErr  error
+	starred              map[string]bool
+	mergeStates          map[string]MergeState
+	statuses             map[string][]*scm.Status
+	createdStatuses      map[string][]*scm.StatusInput
+	tags                 map[string][]*client.TagInfo
+	commitFiles          map[string][]*scm.Change
+	archived             map[string]bool
+	mergedBranches       map[string]bool
+	createdIssues        map[string][]*scm.IssueInput
+	CreateIssueErr       error
+	issueComments        map[string][]string
+	pullRequestsCreated  map[string]time.Time
+	closedPullRequests   map[string]bool
+	ClosePullRequestErr  error
+	labels               map[string][]string
+	autoMerges           map[string]client.MergeMethod
+	reviewThreads        map[string][]*client.ReviewThread
+	reviews              map[string][]*client.Review
+	maxItems             int
+	AddLabelsErr         error
+	deployments          map[string][]*client.Deployment
+	deploymentStatuses   map[string][]*client.DeploymentStatusInput
+	environments         map[string][]*client.Environment
+	defaultRef           *string
+	diffs                map[string]string
+	topics               map[string][]string
+	languages            map[string]map[string]int
+	pullRequestCommits   map[string][]*scm.Commit
+	renamedRepositories  map[string]string
+	messageFormat        client.CommitMessageFormat
+	errorContext         bool
+	callDepth            int
+	updateMessages       map[string][]string
+	supported            map[client.Feature]bool
+	capabilities         map[string]bool
+	symlinks             map[string]bool
+	symlinkDepth         int
+	variables            map[string]string
+	secrets              map[string]bool
+	tokenScopes          []string
+	gists                []*client.Gist
+	createdGists         []*client.Gist
+	commitParents        map[string][]string
+	releaseAssets        map[string][]*client.ReleaseAsset
+	assetContents        map[string][]byte
+	workflowRuns         map[string][]*client.WorkflowRun
+	forks                map[string]*scm.Repository
+	forkRequests         map[string]int
+	blame                map[string][]*client.BlameHunk
+	reviewComments       map[string][]*scm.Review
+	actors               map[string][]string
+	pullRequestChanges   map[string][]*scm.Change
+	mergedPullRequests   map[string]client.MergeMethod
+	mergesInProgress     map[string]int
+	mergeCommitMessages  map[string]mergeCommitMessage
+	checkRuns            map[string][]*client.Check
+	lockedPullRequests   map[string]string
+	disabled             map[string]bool
+	releases             map[string][]*scm.Release
+	sudo                 string
+	// KeepBranchHeads disables moving the branch heads when files are
+	// written, so GetBranchHead returns the heads added with AddBranchHead.
+	KeepBranchHeads bool
+	// ArchivedCheck makes the methods that change a repo fail like they do
+	// with the client.WithArchivedCheck option, for repos archived with
+	// SetRepositoryArchived or added with AddRepository, and repos disabled
+	// with SetRepositoryDisabled.
+	ArchivedCheck bool
+	// OnGetPullRequest is called before the mock reads a pull request, which
+	// allows tests to change the state of the pull request between polls.
+	OnGetPullRequest func(repo string, number int)
 }
 
 // GetFile implements the client.GitClient interface.
-func (m *MockClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+//
+// Files written to the ref with UpdateFile or UpdateFiles are returned with
+// their new content, otherwise the content from AddFileContents is returned.
+// Symlinks added with AddSymlink are followed when SetFollowSymlinks is used.
+func (m *MockClient) GetFile(ctx context.Context, repo, ref, path string) (_ *scm.Content, err error) {
+	defer m.exitCall(m.enterCall(), "GetFile", repo, ref, path, &err)
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
 	if m.GetFileErr != nil {
 		return &scm.Content{}, m.GetFileErr
 	}
-	if b, ok := m.files[key(repo, path, ref)]; ok {
-		return &scm.Content{Data: b, Sha: bytesSha1(b)}, nil
+	b, ok := m.currentContents(repo, path, ref)
+	if !ok {
+		return nil, errors.New("not found")
 	}
-	return nil, errors.New("not found")
+	if m.symlinkDepth > 0 && m.symlinks[key(repo, path, ref)] {
+		return m.followSymlink(repo, ref, path, string(b))
+	}
+	return &scm.Content{Data: b, Sha: bytesSha1(b)}, nil
 }
 
 // UpdateFile implements the client.GitClient interface.
-func (m *MockClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
+//
+// Writing the content the file already has on the branch, either from
+// AddFileContents or an earlier update, returns client.ErrNoChange unless the
+// client.AllowEmpty option is provided.
+//
+// If the previousSHA is provided for an existing file and doesn't match the
+// SHA returned by GetFile for its current content, the update fails with
+// client.ErrConflict unless the client.Force option is provided.
+func (m *MockClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte, opts ...client.WriteOption) (err error) {
+	defer m.exitCall(m.enterCall(), "UpdateFile", repo, branch, path, &err)
+	if err := m.checkMethod("UpdateFile", repo); err != nil {
+		return err
+	}
 	if m.UpdateFileErr != nil {
 		return m.UpdateFileErr
 	}
-	// TODO: Do we need something to validate the previousSHA?
+	if m.archived[repo] {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch),
+			Status: http.StatusForbidden,
+			Err:    client.ErrArchived,
+		}
+	}
+	if m.protectedBranches[key(repo, branch)] {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch),
+			Status: http.StatusUnprocessableEntity,
+			Err:    client.ErrProtectedBranch,
+		}
+	}
+	o := writeOptions(opts)
+	if current, ok := m.currentContents(repo, path, branch); ok {
+		if !o.AllowEmpty && bytes.Equal(current, content) {
+			return client.ErrNoChange
+		}
+		if !o.Force && previousSHA != "" && previousSHA != bytesSha1(current) {
+			return client.SCMError{
+				Msg:    fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch),
+				Status: http.StatusConflict,
+				Err:    client.ErrConflict,
+			}
+		}
+	}
+	message = m.messageFormat.Apply(message)
 	m.updatedFiles[key(repo, path, branch)] = content
+	delete(m.deletedFiles, key(repo, path, branch))
+	m.recordUpdateMessage(repo, path, branch, message)
+	m.recordActor(ctx, repo, "UpdateFile")
+	m.advanceBranchHead(repo, branch, bytesSha1([]byte(fmt.Sprintf("%s:%s:%s:%s", m.branchHeads[key(repo, branch)], path, message, content))))
 	return nil
 }
 
-// DeleteFile is here for future ref
-func (c *MockClient) DeleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
-	return scm.ErrNotSupported
+// UpdateFileWithRetry implements the client.GitClient interface.
+//
+// The write is made with UpdateFile and the SHA of the content that was read,
+// so a transform that writes the file itself, simulating a concurrent update,
+// causes a conflict and a retry.
+func (m *MockClient) UpdateFileWithRetry(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) (_ string, err error) {
+	defer m.exitCall(m.enterCall(), "UpdateFileWithRetry", repo, branch, path, &err)
+	if err := m.checkMethod("UpdateFileWithRetry", repo); err != nil {
+		return "", err
+	}
+	for attempt := 0; ; attempt++ {
+		existing, _ := m.currentContents(repo, path, branch)
+		sha := ""
+		if existing != nil {
+			sha = bytesSha1(existing)
+		}
+		content, err := transform(existing)
+		if err != nil {
+			return "", fmt.Errorf("failed to transform file %s: %w", path, err)
+		}
+		err = m.UpdateFile(ctx, repo, branch, path, message, sha, signature, content)
+		if errors.Is(err, client.ErrConflict) && attempt < conflictRetries {
+			continue
+		}
+		if err != nil && !errors.Is(err, client.ErrNoChange) {
+			return "", err
+		}
+		return m.branchHeads[key(repo, branch)], err
+	}
+}
+
+// conflictRetries matches the number of retries made by the client.
+const conflictRetries = 3
+
+// SyncFile implements the client.GitClient interface.
+//
+// The file is written with UpdateFile if its content differs, and the SHA is
+// the one returned by GetFile for the content. With the client.DeleteIfEmpty
+// option, an existing file is deleted with DeleteFile if the wanted content is
+// empty.
+func (m *MockClient) SyncFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, want []byte, opts ...client.WriteOption) (_ bool, _ string, err error) {
+	defer m.exitCall(m.enterCall(), "SyncFile", repo, branch, path, &err)
+	if err := m.checkMethod("SyncFile", repo); err != nil {
+		return false, "", err
+	}
+	current, ok := m.currentContents(repo, path, branch)
+	if len(want) == 0 && writeOptions(opts).DeleteIfEmpty {
+		if !ok {
+			return false, "", nil
+		}
+		if err := m.DeleteFile(ctx, repo, branch, path, message, "", signature, nil); err != nil {
+			return false, "", err
+		}
+		return true, "", nil
+	}
+	if bytes.Equal(current, want) {
+		if !ok {
+			return false, "", nil
+		}
+		return false, bytesSha1(current), nil
+	}
+	if err := m.UpdateFile(ctx, repo, branch, path, message, "", signature, want); err != nil {
+		return false, "", err
+	}
+	return true, bytesSha1(want), nil
+}
+
+// UpdateFiles implements the client.GitClient interface.
+//
+// All the changes are applied, or none are if an error is returned, and a
+// single commit is recorded.
+//
+// Changes that don't modify the files are dropped unless the
+// client.AllowEmpty option is provided, in which case an empty commit can be
+// recorded.
+func (m *MockClient) UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []client.FileChange, opts ...client.WriteOption) (_ string, err error) {
+	defer m.exitCall(m.enterCall(), "UpdateFiles", repo, "", "", &err)
+	if err := m.checkMethod("UpdateFiles", repo); err != nil {
+		return "", err
+	}
+	if m.UpdateFileErr != nil {
+		return "", m.UpdateFileErr
+	}
+	o := writeOptions(opts)
+	if len(changes) == 0 && !o.AllowEmpty {
+		return "", errors.New("no changes to commit")
+	}
+	if m.archived[repo] {
+		return "", client.SCMError{
+			Msg:    fmt.Sprintf("failed to commit files in repo %s branch %s", repo, branch),
+			Status: http.StatusForbidden,
+			Err:    client.ErrArchived,
+		}
+	}
+	if m.protectedBranches[key(repo, branch)] {
+		return "", client.SCMError{
+			Msg:    fmt.Sprintf("failed to commit files in repo %s branch %s", repo, branch),
+			Status: http.StatusUnprocessableEntity,
+			Err:    client.ErrProtectedBranch,
+		}
+	}
+	if !o.AllowEmpty {
+		var filtered []client.FileChange
+		for _, change := range changes {
+			if current, ok := m.currentContents(repo, change.Path, branch); ok && !change.Delete && bytes.Equal(current, change.Content) {
+				continue
+			}
+			filtered = append(filtered, change)
+		}
+		if len(filtered) == 0 {
+			return m.branchHeads[key(repo, branch)], client.ErrNoChange
+		}
+		changes = filtered
+	}
+	message = m.messageFormat.Apply(message)
+	for _, change := range changes {
+		k := key(repo, change.Path, branch)
+		m.recordUpdateMessage(repo, change.Path, branch, message)
+		if change.Delete {
+			delete(m.updatedFiles, k)
+			m.deletedFiles[k] = true
+			continue
+		}
+		m.updatedFiles[k] = change.Content
+		delete(m.deletedFiles, k)
+	}
+	commits := m.commits[key(repo, branch)]
+	commit := &Commit{
+		Sha:       bytesSha1([]byte(fmt.Sprintf("%s:%s:%d:%s", repo, branch, len(commits), message))),
+		Message:   message,
+		Signature: signature,
+		Changes:   append([]client.FileChange(nil), changes...),
+	}
+	m.commits[key(repo, branch)] = append(commits, commit)
+	m.recordActor(ctx, repo, "UpdateFiles")
+	m.advanceBranchHead(repo, branch, commit.Sha)
+	return commit.Sha, nil
+}
+
+// Batch implements the client.GitClient interface.
+func (m *MockClient) Batch() *client.Batcher {
+	return client.NewBatcher(m)
+}
+
+// DeleteFile implements the client.GitClient interface.
+func (m *MockClient) DeleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) (err error) {
+	defer m.exitCall(m.enterCall(), "DeleteFile", repo, branch, path, &err)
+	if err := m.checkMethod("DeleteFile", repo); err != nil {
+		return err
+	}
+	if m.DeleteFileErr != nil {
+		return m.DeleteFileErr
+	}
+	if m.archived[repo] {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to delete file %s in repo %s branch %s", path, repo, branch),
+			Status: http.StatusForbidden,
+			Err:    client.ErrArchived,
+		}
+	}
+	if m.protectedBranches[key(repo, branch)] {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to delete file %s in repo %s branch %s", path, repo, branch),
+			Status: http.StatusUnprocessableEntity,
+			Err:    client.ErrProtectedBranch,
+		}
+	}
+	if _, ok := m.currentContents(repo, path, branch); !ok {
+		return notFound("failed to delete file %s in repo %s branch %s", path, repo, branch)
+	}
+	message = m.messageFormat.Apply(message)
+	k := key(repo, path, branch)
+	delete(m.updatedFiles, k)
+	m.deletedFiles[k] = true
+	m.recordUpdateMessage(repo, path, branch, message)
+	m.recordActor(ctx, repo, "DeleteFile")
+	m.advanceBranchHead(repo, branch, bytesSha1([]byte(fmt.Sprintf("%s:%s:%s:deleted", m.branchHeads[key(repo, branch)], path, message))))
+	return nil
 }
 
 // CreatePullRequest implements the client.GitClient interface.
-func (m *MockClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+//
+// With the client.EnsureBase option, a missing target branch is created from
+// the head of the default branch set with SetDefaultBranch, or "main".
+func (m *MockClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput, opts ...client.PullRequestOption) (*scm.PullRequest, error) {
+	if err := m.checkMethod("CreatePullRequest", repo); err != nil {
+		return nil, err
+	}
 	if m.CreatePullRequestErr != nil {
 		return nil, m.CreatePullRequestErr
 	}
+	if pullRequestOptions(opts).EnsureBase && !m.branchExists(repo, inp.Target) {
+		base := m.defaultBranch(repo)
+		head, ok := m.branchHeads[key(repo, base)]
+		if !ok {
+			return nil, notFound("failed to get head of default branch %s in repo %s", base, repo)
+		}
+		if err := m.CreateBranch(ctx, repo, inp.Target, head); err != nil {
+			return nil, err
+		}
+		m.branchHeads[key(repo, inp.Target)] = head
+	}
 	existing, ok := m.createdPullRequests[repo]
 	if !ok {
 		existing = []*scm.PullRequestInput{}
 	}
 	existing = append(existing, inp)
 	m.createdPullRequests[repo] = existing
+	m.recordActor(ctx, repo, "CreatePullRequest")
 	number := len(existing) // TODO: This is not concurrency safe!
 	return &scm.PullRequest{Number: number, Link: fmt.Sprintf("https://example.com/pull-request/%d", number)}, nil
 }
 
+// CreatePullRequestIfChanged implements the client.GitClient interface.
+//
+// The branches are compared like in IsBranchMerged, and the pull request is
+// only created with CreatePullRequest when the source branch is not merged.
+func (m *MockClient) CreatePullRequestIfChanged(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, bool, error) {
+	if err := m.checkMethod("CreatePullRequestIfChanged", repo); err != nil {
+		return nil, false, err
+	}
+	merged, err := m.IsBranchMerged(ctx, repo, inp.Source, inp.Target)
+	if err != nil {
+		return nil, false, err
+	}
+	if merged {
+		return nil, false, nil
+	}
+	pr, err := m.CreatePullRequest(ctx, repo, inp)
+	if err != nil {
+		return nil, false, err
+	}
+	return pr, true, nil
+}
+
 // CreateBranch implements the client.GitClient interface.
 func (m *MockClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	if err := m.checkMethod("CreateBranch", repo); err != nil {
+		return err
+	}
 	if m.CreateBranchErr != nil {
 		return m.CreateBranchErr
 	}
 	m.createdBranches[key(repo, branch, sha)] = true
+	delete(m.deletedBranches, key(repo, branch))
 	return nil
 }
 
+// CreateBranchIfBaseMatches implements the client.GitClient interface.
+//
+// The head of the base branch is compared with the heads added with
+// AddBranchHead, and the created branch starts at the same head.
+func (m *MockClient) CreateBranchIfBaseMatches(ctx context.Context, repo, branch, baseBranch, expectedBaseSHA string) (string, error) {
+	if err := m.checkMethod("CreateBranchIfBaseMatches", repo); err != nil {
+		return "", err
+	}
+	head, err := m.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return "", err
+	}
+	if head != expectedBaseSHA {
+		return "", fmt.Errorf("head of branch %s in repo %s is %s, not %s: %w", baseBranch, repo, head, expectedBaseSHA, client.ErrConflict)
+	}
+	if err := m.CreateBranch(ctx, repo, branch, head); err != nil {
+		return "", err
+	}
+	m.branchHeads[key(repo, branch)] = head
+	return head, nil
+}
+
 // GetBranchHead implements the client.GitClient interface.
 func (m *MockClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return "", err
+	}
+	branch = m.resolveRef(repo, branch)
 	ref, ok := m.branchHeads[key(repo, branch)]
 	if !ok {
 		return "", errors.New("not found")
@@ -101,6 +559,146 @@ func (m *MockClient) GetBranchHead(ctx context.Context, repo, branch string) (st
 	return ref, nil
 }
 
+// GetBranchHeads implements the client.GitClient interface.
+//
+// Branches without a head added with AddBranchHead have an empty head.
+func (m *MockClient) GetBranchHeads(ctx context.Context, repo string, branches []string) (map[string]string, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	heads := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		heads[branch] = m.branchHeads[key(repo, m.resolveRef(repo, branch))]
+	}
+	return heads, nil
+}
+
+// DeleteBranchesByPrefix implements the client.GitClient interface.
+//
+// Branches are known to the mock if they were added with AddBranchHead or
+// created with CreateBranch, if DeleteBranchErr is set, every deletion fails
+// with it.
+func (m *MockClient) DeleteBranchesByPrefix(ctx context.Context, repo, prefix string) (int, error) {
+	if err := m.checkMethod("DeleteBranchesByPrefix", repo); err != nil {
+		return 0, err
+	}
+	if prefix == "" {
+		return 0, errors.New("a prefix is required to delete branches")
+	}
+	var (
+		deleted int
+		errs    []error
+	)
+	for _, branch := range m.branchesWithPrefix(repo, prefix) {
+		if m.DeleteBranchErr != nil {
+			errs = append(errs, m.DeleteBranchErr)
+			continue
+		}
+		m.deleteBranch(repo, branch)
+		deleted++
+	}
+	if len(errs) > 0 {
+		return deleted, client.BulkError{Errs: errs}
+	}
+	return deleted, nil
+}
+
+// BranchesWithoutOpenPRs implements the client.GitClient interface.
+//
+// Branches are known to the mock like in DeleteBranchesByPrefix, and the open
+// pull requests are the ones created with CreatePullRequest that were not
+// closed or merged.
+func (m *MockClient) BranchesWithoutOpenPRs(ctx context.Context, repo, prefix string) ([]string, error) {
+	if err := m.checkMethod("BranchesWithoutOpenPRs", repo); err != nil {
+		return nil, err
+	}
+	heads := map[string]bool{}
+	for i, pr := range m.createdPullRequests[repo] {
+		if !m.closedPullRequests[key(repo, strconv.Itoa(i+1))] {
+			heads[pr.Source] = true
+		}
+	}
+	var names []string
+	for _, branch := range m.branchesWithPrefix(repo, prefix) {
+		if !heads[branch] {
+			names = append(names, branch)
+		}
+	}
+	return names, nil
+}
+
+// IsBranchProtected implements the client.GitClient interface.
+func (m *MockClient) IsBranchProtected(ctx context.Context, repo, branch string) (bool, error) {
+	if err := m.checkMethod("IsBranchProtected", repo); err != nil {
+		return false, err
+	}
+	branch = m.resolveRef(repo, branch)
+	return m.protectedBranches[key(repo, branch)], nil
+}
+
+// GetBranchProtection implements the client.GitClient interface.
+//
+// The protection set with SetBranchProtection is returned, branches without
+// one are not protected and return a not found error.
+func (m *MockClient) GetBranchProtection(ctx context.Context, repo, branch string) (*client.BranchProtection, error) {
+	if err := m.checkMethod("GetBranchProtection", repo); err != nil {
+		return nil, err
+	}
+	branch = m.resolveRef(repo, branch)
+	p, ok := m.branchProtection[key(repo, branch)]
+	if !ok {
+		return nil, notFound("failed to get protection of branch %s in repo %s", branch, repo)
+	}
+	return p, nil
+}
+
+// IsBranchMerged implements the client.GitClient interface.
+//
+// A branch is merged if it was marked as merged with SetBranchMerged, or it
+// has the same head as the base branch.
+func (m *MockClient) IsBranchMerged(ctx context.Context, repo, branch, baseBranch string) (bool, error) {
+	if err := m.checkMethod("IsBranchMerged", repo); err != nil {
+		return false, err
+	}
+	if merged, ok := m.mergedBranches[key(repo, branch, baseBranch)]; ok {
+		return merged, nil
+	}
+	head, err := m.GetBranchHead(ctx, repo, branch)
+	if err != nil {
+		return false, err
+	}
+	baseHead, err := m.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return false, err
+	}
+	return head == baseHead, nil
+}
+
+// SetBranchMerged records whether the branch is merged into the base branch,
+// overriding the comparison of their heads.
+func (m *MockClient) SetBranchMerged(repo, branch, baseBranch string, merged bool) {
+	m.mergedBranches[key(repo, branch, baseBranch)] = merged
+}
+
+// SetBranchProtected marks a branch as protected, writes to protected
+// branches are rejected with client.ErrProtectedBranch.
+func (m *MockClient) SetBranchProtected(repo, branch string, protected bool) {
+	m.protectedBranches[key(repo, branch)] = protected
+}
+
+// SetBranchProtection sets the protection returned by GetBranchProtection,
+// and marks the branch as protected, like SetBranchProtected, a nil protection
+// removes it.
+func (m *MockClient) SetBranchProtection(repo, branch string, p *client.BranchProtection) {
+	k := key(repo, branch)
+	m.protectedBranches[k] = p != nil
+	if p == nil {
+		delete(m.branchProtection, k)
+		return
+	}
+	m.branchProtection[k] = p
+}
+
 // AddFileContents is a mock method for setting up a fixture for
 // GetFileContents.
 func (m *MockClient) AddFileContents(repo, path, ref string, body []byte) {
@@ -114,6 +712,134 @@ func (m *MockClient) GetUpdatedContents(repo, path, ref string) []byte {
 	return c
 }
 
+// UpdatedFilesUnder returns the updated contents of the files on the branch
+// whose paths start with the prefix, keyed by path.
+func (m *MockClient) UpdatedFilesUnder(repo, branch, prefix string) map[string][]byte {
+	files := map[string][]byte{}
+	for k, v := range m.updatedFiles {
+		if parts := splitKey(k); parts[0] == repo && parts[2] == branch && strings.HasPrefix(parts[1], prefix) {
+			files[parts[1]] = v
+		}
+	}
+	return files
+}
+
+// AssertFileUpdatedUnder fails if no file on the branch whose path starts
+// with the prefix was updated.
+func (m *MockClient) AssertFileUpdatedUnder(repo, branch, prefix string) {
+	m.t.Helper()
+	if len(m.UpdatedFilesUnder(repo, branch, prefix)) == 0 {
+		m.t.Fatalf("no files under %s were updated in repo %s branch %s", prefix, repo, branch)
+	}
+}
+
+// GetCommits returns the commits recorded by UpdateFiles for the branch.
+func (m *MockClient) GetCommits(repo, branch string) []*Commit {
+	return m.commits[key(repo, branch)]
+}
+
+// AssertFileDeleted fails if the file was not deleted from the branch.
+func (m *MockClient) AssertFileDeleted(repo, path, branch string) {
+	m.t.Helper()
+	if !m.deletedFiles[key(repo, path, branch)] {
+		m.t.Fatalf("file %s not deleted in repo %s branch %s", path, repo, branch)
+	}
+}
+
+// SetCommitMessagePrefix makes the mock decorate commit messages with the
+// prefix, like the client.WithCommitMessagePrefix option.
+func (m *MockClient) SetCommitMessagePrefix(prefix string) {
+	m.messageFormat.Prefix = prefix
+}
+
+// SetErrorContext makes the mock wrap the errors returned by the methods that
+// act on a file with client.WrapErrorContext, like the client.WithErrorContext
+// option.
+func (m *MockClient) SetErrorContext() {
+	m.errorContext = true
+}
+
+// enterCall records the start of a call to a method that wraps its errors,
+// and returns true if it's not called by another such method.
+func (m *MockClient) enterCall() bool {
+	m.callDepth++
+	return m.callDepth == 1
+}
+
+// exitCall records the end of a call started with enterCall, and wraps the
+// error of outermost calls when SetErrorContext is used, so that errors are
+// only wrapped once, like the client.
+func (m *MockClient) exitCall(outermost bool, method, repo, ref, path string, err *error) {
+	m.callDepth--
+	if outermost && m.errorContext && *err != nil {
+		*err = client.WrapErrorContext(method, repo, ref, path, *err)
+	}
+}
+
+// AddCommitMessageTrailer makes the mock decorate commit messages with the
+// trailer, like the client.WithCommitMessageTrailer option.
+func (m *MockClient) AddCommitMessageTrailer(key, value string) {
+	m.messageFormat.Trailers = append(m.messageFormat.Trailers, client.CommitMessageTrailer{Key: key, Value: value})
+}
+
+// AssertUpdateMessage fails if the file was not written or deleted on the
+// branch by a commit with the message, after the decoration configured with
+// SetCommitMessagePrefix and AddCommitMessageTrailer.
+func (m *MockClient) AssertUpdateMessage(repo, path, branch, message string) {
+	m.t.Helper()
+	for _, msg := range m.updateMessages[key(repo, path, branch)] {
+		if msg == message {
+			return
+		}
+	}
+	m.t.Fatalf("file %s not updated in repo %s branch %s with message %q, got %q", path, repo, branch, message, m.updateMessages[key(repo, path, branch)])
+}
+
+func (m *MockClient) recordUpdateMessage(repo, path, branch, message string) {
+	k := key(repo, path, branch)
+	m.updateMessages[k] = append(m.updateMessages[k], message)
+}
+
+// SetMaxItems limits the number of items returned by the list methods, like
+// the client.WithMaxItems option.
+func (m *MockClient) SetMaxItems(n int) {
+	m.maxItems = n
+}
+
+// limitItems returns the number of the n items a list method returns, and
+// client.ErrTruncated if they exceed the limit set with SetMaxItems.
+func (m *MockClient) limitItems(n int) (int, error) {
+	if m.maxItems > 0 && n > m.maxItems {
+		return m.maxItems, client.ErrTruncated
+	}
+	return n, nil
+}
+
+// SetDefaultBranch sets the default branch of the repo, which is "main" if
+// it's not set.
+func (m *MockClient) SetDefaultBranch(repo, branch string) {
+	m.defaultBranches[repo] = branch
+}
+
+// SetDefaultRef makes the methods that read from a repository substitute the
+// ref for empty refs and branches, like the client.WithDefaultRef option, an
+// empty ref substitutes the default branch of the repo.
+func (m *MockClient) SetDefaultRef(ref string) {
+	m.defaultRef = &ref
+}
+
+// resolveRef returns the ref, or the default set with SetDefaultRef if the ref
+// is empty.
+func (m *MockClient) resolveRef(repo, ref string) string {
+	if ref != "" || m.defaultRef == nil {
+		return ref
+	}
+	if *m.defaultRef != "" {
+		return *m.defaultRef
+	}
+	return m.defaultBranch(repo)
+}
+
 // AddBranchHead is a mock for setting up a response for GetBranchHead.
 func (m *MockClient) AddBranchHead(repo, branch, sha string) {
 	m.branchHeads[key(repo, branch)] = sha
@@ -137,6 +863,22 @@ func (m *MockClient) RefuteBranchCreated(repo, branch, sha string) {
 	}
 }
 
+// AssertBranchDeleted fails if the branch was not deleted.
+func (m *MockClient) AssertBranchDeleted(repo, branch string) {
+	m.t.Helper()
+	if !m.deletedBranches[key(repo, branch)] {
+		m.t.Fatalf("branch %s not deleted in repo %s", branch, repo)
+	}
+}
+
+// RefuteBranchDeleted fails if the branch was deleted.
+func (m *MockClient) RefuteBranchDeleted(repo, branch string) {
+	m.t.Helper()
+	if m.deletedBranches[key(repo, branch)] {
+		m.t.Fatalf("branch %s was deleted in repo %s", branch, repo)
+	}
+}
+
 // AssertPullRequestCreated fails if no matching PullRequest was created.
 func (m *MockClient) AssertPullRequestCreated(repo string, inp *scm.PullRequestInput) {
 	m.t.Helper()
@@ -149,6 +891,32 @@ func (m *MockClient) AssertPullRequestCreated(repo string, inp *scm.PullRequestI
 	m.t.Fatalf("pullrequest not created in repo %s", repo)
 }
 
+// AssertPullRequestCreatedMatching fails if no PullRequest was created for
+// which match returns true.
+func (m *MockClient) AssertPullRequestCreatedMatching(repo string, match func(*scm.PullRequestInput) bool) {
+	m.t.Helper()
+
+	for _, pr := range m.createdPullRequests[repo] {
+		if match(pr) {
+			return
+		}
+	}
+	m.t.Fatalf("no matching pullrequest created in repo %s", repo)
+}
+
+// AssertPullRequestCreatedByBranch fails if no PullRequest was created from
+// the head (source) branch to the base (target) branch.
+func (m *MockClient) AssertPullRequestCreatedByBranch(repo, head, base string) {
+	m.t.Helper()
+
+	for _, pr := range m.createdPullRequests[repo] {
+		if pr.Source == head && pr.Target == base {
+			return
+		}
+	}
+	m.t.Fatalf("pullrequest from %s to %s not created in repo %s", head, base, repo)
+}
+
 // RefutePullRequestCreated fails if matching PullRequest was created.
 func (m *MockClient) RefutePullRequestCreated(repo string, inp *scm.PullRequestInput) {
 	m.t.Helper()
@@ -180,6 +948,10 @@ func (m *MockClient) AssertNoInteractions() {
 		m.t.Fatalf("files were updated %#v", m.updatedFiles)
 	}
 
+	if len(m.commits) != 0 {
+		m.t.Fatalf("commits were made %#v", m.commits)
+	}
+
 	if len(m.createdBranches) != 0 {
 		m.t.Fatalf("branches created %#v", m.createdBranches)
 	}
@@ -189,10 +961,116 @@ func (m *MockClient) AssertNoInteractions() {
 	}
 }
 
+// currentContents returns the content of the file on the branch, taking into
+// account any updates or deletions.
+func (m *MockClient) currentContents(repo, path, branch string) ([]byte, bool) {
+	k := key(repo, path, branch)
+	if m.deletedFiles[k] {
+		return nil, false
+	}
+	if b, ok := m.updatedFiles[k]; ok {
+		return b, true
+	}
+	b, ok := m.files[k]
+	return b, ok
+}
+
+// branchesWithPrefix returns the sorted names of the known branches in the
+// repo that start with the prefix.
+func (m *MockClient) branchesWithPrefix(repo, prefix string) []string {
+	found := map[string]bool{}
+	for k := range m.branchHeads {
+		if parts := splitKey(k); parts[0] == repo && strings.HasPrefix(parts[1], prefix) {
+			found[parts[1]] = true
+		}
+	}
+	for k := range m.createdBranches {
+		if parts := splitKey(k); parts[0] == repo && strings.HasPrefix(parts[1], prefix) && !m.deletedBranches[key(repo, parts[1])] {
+			found[parts[1]] = true
+		}
+	}
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// branchExists returns true if the branch was added with AddBranchHead or
+// created with CreateBranch, and hasn't been deleted.
+func (m *MockClient) branchExists(repo, branch string) bool {
+	if _, ok := m.branchHeads[key(repo, branch)]; ok {
+		return true
+	}
+	for _, name := range m.branchesWithPrefix(repo, branch) {
+		if name == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultBranch returns the default branch of the repo.
+func (m *MockClient) defaultBranch(repo string) string {
+	if b, ok := m.defaultBranches[repo]; ok {
+		return b
+	}
+	return "main"
+}
+
+// advanceBranchHead moves the branch to the SHA of a write, unless
+// KeepBranchHeads is set.
+func (m *MockClient) advanceBranchHead(repo, branch, sha string) {
+	if !m.KeepBranchHeads {
+		m.branchHeads[key(repo, branch)] = sha
+	}
+}
+
+func (m *MockClient) deleteBranch(repo, branch string) {
+	delete(m.branchHeads, key(repo, branch))
+	m.deletedBranches[key(repo, branch)] = true
+}
+
+func pullRequestOptions(opts []client.PullRequestOption) client.PullRequestOptions {
+	o := client.PullRequestOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+func mergeOptions(opts []client.MergeOption) client.MergeOptions {
+	o := client.MergeOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+func writeOptions(opts []client.WriteOption) client.WriteOptions {
+	o := client.WriteOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// notFound returns an error that the client.IsNotFound function recognises.
+func notFound(format string, a ...interface{}) error {
+	return client.SCMError{Msg: fmt.Sprintf(format, a...), Status: http.StatusNotFound}
+}
+
 func key(s ...string) string {
 	return strings.Join(s, ":")
 }
 
+// splitKey splits a key into its parts, this relies on ":" not being valid in
+// repo or branch names.
+func splitKey(k string) []string {
+	return strings.Split(k, ":")
+}
+
 func bytesSha1(b []byte) string {
 	h := sha1.New()
 	_, _ = h.Write([]byte(b))
@@ -0,0 +1,1826 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+	"github.com/ocraviotto/pkg/test"
+)
+
+const testRepo = "testorg/testrepo"
+
+func TestDeleteBranchesByPrefix(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha0")
+	m.AddBranchHead(testRepo, "gitops-a", "sha1")
+	if err := m.CreateBranch(context.Background(), testRepo, "gitops-b", "sha0"); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := m.DeleteBranchesByPrefix(context.Background(), testRepo, "gitops-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("got %d deleted branches, want 2", deleted)
+	}
+	m.AssertBranchDeleted(testRepo, "gitops-a")
+	m.AssertBranchDeleted(testRepo, "gitops-b")
+	m.RefuteBranchDeleted(testRepo, "main")
+	if _, err := m.GetBranchHead(context.Background(), testRepo, "gitops-a"); err == nil {
+		t.Fatal("expected the deleted branch to have no head")
+	}
+}
+
+func TestCreateBranchIfBaseMatches(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha0")
+
+	head, err := m.CreateBranchIfBaseMatches(context.Background(), testRepo, "gitops-a", "main", "sha0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != "sha0" {
+		t.Fatalf("got head %s, want sha0", head)
+	}
+	m.AssertBranchCreated(testRepo, "gitops-a", "sha0")
+
+	_, err = m.CreateBranchIfBaseMatches(context.Background(), testRepo, "gitops-b", "main", "sha1")
+	if !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("got %v, want %v", err, client.ErrConflict)
+	}
+	m.RefuteBranchCreated(testRepo, "gitops-b", "sha0")
+}
+
+func TestDeleteBranchesByPrefixWithErrors(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "gitops-a", "sha1")
+	m.AddBranchHead(testRepo, "gitops-b", "sha2")
+	m.DeleteBranchErr = errors.New("mock error")
+
+	deleted, err := m.DeleteBranchesByPrefix(context.Background(), testRepo, "gitops-")
+	if !test.MatchError(t, "2 errors occurred", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if deleted != 0 {
+		t.Fatalf("got %d deleted branches, want 0", deleted)
+	}
+}
+
+func TestUpdateFileToProtectedBranch(t *testing.T) {
+	m := New(t)
+	m.SetBranchProtected(testRepo, "main", true)
+
+	err := m.UpdateFile(context.Background(), testRepo, "main", "README.md", "update", "", scm.Signature{}, []byte("testing"))
+	if !errors.Is(err, client.ErrProtectedBranch) {
+		t.Fatalf("got %v, want ErrProtectedBranch", err)
+	}
+	if b := m.GetUpdatedContents(testRepo, "README.md", "main"); b != nil {
+		t.Fatalf("file was updated: %q", b)
+	}
+}
+
+func TestBatchCommitsOnce(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "b.yaml", "main", []byte("old"))
+
+	_, err := m.Batch().
+		Add("a.yaml", []byte("new")).
+		Delete("b.yaml").
+		Commit(context.Background(), testRepo, "main", "batched", scm.Signature{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(m.GetUpdatedContents(testRepo, "a.yaml", "main")); s != "new" {
+		t.Fatalf("got %q, want %q", s, "new")
+	}
+	m.AssertFileDeleted(testRepo, "b.yaml", "main")
+	if l := len(m.GetCommits(testRepo, "main")); l != 1 {
+		t.Fatalf("got %d commits, want 1", l)
+	}
+}
+
+func TestUpdateFileWithNoChange(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "README.md", "main", []byte("testing"))
+
+	err := m.UpdateFile(context.Background(), testRepo, "main", "README.md", "update", "", scm.Signature{}, []byte("testing"))
+	if !errors.Is(err, client.ErrNoChange) {
+		t.Fatalf("got %v, want ErrNoChange", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateFileWriteOptions(t *testing.T) {
+	staleSHA := bytesSha1([]byte("stale"))
+	tests := []struct {
+		name        string
+		content     string
+		previousSHA string
+		opts        []client.WriteOption
+		wantErr     error
+	}{
+		{"unchanged content", "testing", "", nil, client.ErrNoChange},
+		{"unchanged content allowing empty", "testing", "", []client.WriteOption{client.AllowEmpty()}, nil},
+		{"matching sha", "updated", bytesSha1([]byte("testing")), nil, nil},
+		{"conflicting sha", "updated", staleSHA, nil, client.ErrConflict},
+		{"conflicting sha with force", "updated", staleSHA, []client.WriteOption{client.Force()}, nil},
+		{"unchanged content with force", "testing", staleSHA, []client.WriteOption{client.Force()}, client.ErrNoChange},
+		{"unchanged content with both", "testing", staleSHA, []client.WriteOption{client.Force(), client.AllowEmpty()}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(t)
+			m.AddFileContents(testRepo, "README.md", "main", []byte("testing"))
+
+			err := m.UpdateFile(context.Background(), testRepo, "main", "README.md", "update", tt.previousSHA, scm.Signature{}, []byte(tt.content), tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			updated := m.GetUpdatedContents(testRepo, "README.md", "main")
+			if tt.wantErr == nil && string(updated) != tt.content {
+				t.Fatalf("got %q, want %q", updated, tt.content)
+			}
+			if tt.wantErr != nil && updated != nil {
+				t.Fatalf("file was updated: %q", updated)
+			}
+		})
+	}
+}
+
+func TestUpdateFileWithRetry(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "VERSION", "main", []byte("1"))
+
+	var reads int
+	_, err := m.UpdateFileWithRetry(context.Background(), testRepo, "main", "VERSION", "bump", scm.Signature{}, func(existing []byte) ([]byte, error) {
+		reads++
+		if reads == 1 {
+			if err := m.UpdateFile(context.Background(), testRepo, "main", "VERSION", "concurrent bump", "", scm.Signature{}, []byte("2")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return append(existing, '0'), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reads != 2 {
+		t.Fatalf("got %d reads, want 2", reads)
+	}
+	if b := m.GetUpdatedContents(testRepo, "VERSION", "main"); string(b) != "20" {
+		t.Fatalf("got %q, want the transform applied to the concurrent update", b)
+	}
+}
+
+func TestUpdateFileWithStaleSHA(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "VERSION", "main", []byte("1"))
+	if err := m.UpdateFile(context.Background(), testRepo, "main", "VERSION", "bump", "", scm.Signature{}, []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := m.GetFile(context.Background(), testRepo, "main", "VERSION")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "2" {
+		t.Fatalf("got %q, want the updated content", content.Data)
+	}
+	err = m.UpdateFile(context.Background(), testRepo, "main", "VERSION", "bump", bytesSha1([]byte("1")), scm.Signature{}, []byte("3"))
+	if !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("got %v, want ErrConflict", err)
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	m := New(t)
+
+	changed, sha, err := m.SyncFile(context.Background(), testRepo, "main", "VERSION", "bump", scm.Signature{}, []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("file was not created")
+	}
+	changed, existing, err := m.SyncFile(context.Background(), testRepo, "main", "VERSION", "bump", scm.Signature{}, []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || existing != sha {
+		t.Fatalf("got changed %v and sha %s, want the existing SHA %s", changed, existing, sha)
+	}
+	content, err := m.GetFile(context.Background(), testRepo, "main", "VERSION")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.Sha != sha {
+		t.Fatalf("got sha %s from GetFile, want %s", content.Sha, sha)
+	}
+}
+
+func TestSyncFileDeletingIfEmpty(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "VERSION", "main", []byte("1"))
+
+	for _, want := range []bool{true, false} {
+		changed, sha, err := m.SyncFile(context.Background(), testRepo, "main", "VERSION", "remove", scm.Signature{}, nil, client.DeleteIfEmpty())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != want || sha != "" {
+			t.Fatalf("got changed %v and sha %s, want changed %v", changed, sha, want)
+		}
+	}
+	m.AssertFileDeleted(testRepo, "VERSION", "main")
+}
+
+func TestCommitMessageDecoration(t *testing.T) {
+	m := New(t)
+	m.SetCommitMessagePrefix("[bot]")
+	m.AddCommitMessageTrailer("Ticket", "OPS-123")
+	want := "[bot] update\n\nTicket: OPS-123"
+
+	if err := m.UpdateFile(context.Background(), testRepo, "main", "a.yaml", "update", "", scm.Signature{}, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.UpdateFiles(context.Background(), testRepo, "main", "update", scm.Signature{}, []client.FileChange{{Path: "b.yaml", Content: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertUpdateMessage(testRepo, "a.yaml", "main", want)
+	m.AssertUpdateMessage(testRepo, "b.yaml", "main", want)
+	if msg := m.GetCommits(testRepo, "main")[0].Message; msg != want {
+		t.Fatalf("got commit message %q, want %q", msg, want)
+	}
+}
+
+func TestUpdateFilesAllowingEmpty(t *testing.T) {
+	m := New(t)
+
+	_, err := m.UpdateFiles(context.Background(), testRepo, "main", "trigger CI", scm.Signature{}, nil, client.AllowEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(m.GetCommits(testRepo, "main")); l != 1 {
+		t.Fatalf("got %d commits, want 1", l)
+	}
+}
+
+func TestChangedFilesBetween(t *testing.T) {
+	m := New(t)
+	m.AddCommit(testRepo, "sha1")
+	m.AddCommit(testRepo, "sha2", "sha1")
+	m.AddCommit(testRepo, "sha3", "sha2")
+	m.AddBranchHead(testRepo, "main", "sha3")
+	m.AddCommitFiles(testRepo, "sha1", []*scm.Change{{Path: "old.yaml"}})
+	m.AddCommitFiles(testRepo, "sha2", []*scm.Change{{Path: "b.yaml"}, {Path: "a.yaml"}})
+	m.AddCommitFiles(testRepo, "sha3", []*scm.Change{{Path: "a.yaml"}, {Path: "c.yaml", PrevFilePath: "b.yaml", Renamed: true}})
+
+	paths, err := m.ChangedFilesBetween(context.Background(), testRepo, "sha1", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.yaml,b.yaml,c.yaml"; strings.Join(paths, ",") != want {
+		t.Fatalf("got paths %q, want %q", paths, want)
+	}
+}
+
+func TestPreviewCommit(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "a.yaml", "main", []byte("a"))
+	m.AddFileContents(testRepo, "b.yaml", "main", []byte("b"))
+
+	preview, err := m.PreviewCommit(context.Background(), testRepo, "main", []client.FileChange{
+		{Path: "a.yaml", Content: []byte("a")},
+		{Path: "b.yaml", Delete: true},
+		{Path: "c.yaml", Content: []byte("c")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.yaml,c.yaml"; strings.Join(preview.Files, ",") != want {
+		t.Fatalf("got files %q, want %q", preview.Files, want)
+	}
+	if l := len(preview.Changes); l != 2 {
+		t.Fatalf("got %d changes, want 2", l)
+	}
+	if l := len(m.GetCommits(testRepo, "main")); l != 0 {
+		t.Fatalf("got %d commits, want 0", l)
+	}
+	if _, err := m.GetFile(context.Background(), testRepo, "main", "b.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPullRequestDiff(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "README.md", "main", []byte("hello world\n"))
+	if err := m.UpdateFile(context.Background(), testRepo, "feature", "README.md", "update", "", scm.Signature{}, []byte("hello there\n")); err != nil {
+		t.Fatal(err)
+	}
+	pr, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "feature", Target: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := m.GetPullRequestDiff(context.Background(), testRepo, pr.Number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n-hello world\n+hello there\n"
+	if diff != want {
+		t.Fatalf("got diff %q, want %q", diff, want)
+	}
+
+	m.SetPullRequestDiff(testRepo, pr.Number, "configured")
+	if diff, _ := m.GetPullRequestDiff(context.Background(), testRepo, pr.Number); diff != "configured" {
+		t.Fatalf("got diff %q, want %q", diff, "configured")
+	}
+	if _, err := m.GetPullRequestDiff(context.Background(), testRepo, 10); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/a.yaml", "main", []byte("a"))
+	m.AddFileContents(testRepo, "config/b.yaml", "main", []byte("b"))
+	m.AddFileContents(testRepo, "config/nested/c.yaml", "main", []byte("c"))
+	m.AddFileContents(testRepo, "config/d.yaml", "other", []byte("d"))
+
+	entries, err := m.ListFiles(context.Background(), testRepo, "main", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(entries); l != 3 {
+		t.Fatalf("got %d entries, want 3", l)
+	}
+	files, err := m.ReadDir(context.Background(), testRepo, "main", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(files); l != 2 {
+		t.Fatalf("got %d files, want 2", l)
+	}
+	if s := string(files["config/b.yaml"].Data); s != "b" {
+		t.Fatalf("got %q, want %q", s, "b")
+	}
+}
+
+func TestGetFileNormalized(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/a.yaml", "main", []byte("key: value\n"))
+
+	file, err := m.GetFileNormalized(context.Background(), testRepo, "main", "config/a.yaml", func(b []byte) ([]byte, error) {
+		return bytes.TrimSpace(b), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(file.Data); s != "key: value" {
+		t.Fatalf("got %q, want %q", s, "key: value")
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	m := New(t)
+	m.AddRepository("testorg", &scm.Repository{Name: "active"})
+	m.AddRepository("testorg", &scm.Repository{Name: "old", Archived: true})
+	m.AddForkedRepository("testorg", &scm.Repository{Name: "fork"})
+	m.AddRepository("", &scm.Repository{Name: "mine"})
+
+	repos, err := m.ListRepositories(context.Background(), "testorg", client.RepositoryListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(repos); l != 3 {
+		t.Fatalf("got %d repositories, want 3", l)
+	}
+	repos, err = m.ListRepositories(context.Background(), "testorg", client.RepositoryListOptions{ExcludeArchived: true, ExcludeForks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(repos); l != 1 || repos[0].Name != "active" {
+		t.Fatalf("got %#v, want only the active repository", repos)
+	}
+}
+
+func TestAssertPullRequestCreatedMatching(t *testing.T) {
+	m := New(t)
+	_, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{
+		Title:  "Update config",
+		Body:   "Updated at 2021-01-01T00:00:00Z\n",
+		Source: "update-config",
+		Target: "main",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreatedMatching(testRepo, func(inp *scm.PullRequestInput) bool {
+		return inp.Title == "Update config"
+	})
+	m.AssertPullRequestCreatedByBranch(testRepo, "update-config", "main")
+}
+
+func TestGetFileRaw(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "badge.svg", "main", []byte("<svg></svg>"))
+
+	b, err := m.GetFileRaw(context.Background(), testRepo, "main", "badge.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "<svg></svg>" {
+		t.Fatalf("got %q, want %q", s, "<svg></svg>")
+	}
+	if _, err := m.GetFileRaw(context.Background(), testRepo, "main", "missing.svg"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCreatePullRequestEnsuringBase(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "abc123")
+
+	_, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{
+		Source: "bootstrap",
+		Target: "production",
+	}, client.EnsureBase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertBranchCreated(testRepo, "production", "abc123")
+	if head, err := m.GetBranchHead(context.Background(), testRepo, "production"); err != nil || head != "abc123" {
+		t.Fatalf("got %q, %v, want the head of main", head, err)
+	}
+}
+
+func TestStar(t *testing.T) {
+	m := New(t)
+	if err := m.Star(context.Background(), testRepo); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertStarred(testRepo)
+	if err := m.Unstar(context.Background(), testRepo); err != nil {
+		t.Fatal(err)
+	}
+	m.RefuteStarred(testRepo)
+}
+
+func TestSetRepositoryArchived(t *testing.T) {
+	m := New(t)
+	repo := &scm.Repository{Namespace: "testorg", Name: "testrepo"}
+	m.AddRepository("testorg", repo)
+
+	if err := m.SetRepositoryArchived(context.Background(), testRepo, true); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertRepositoryArchived(testRepo, true)
+	if !repo.Archived {
+		t.Fatal("the stored repository was not archived")
+	}
+
+	err := m.UpdateFile(context.Background(), testRepo, "main", "a.yaml", "update", "", scm.Signature{}, []byte("test"))
+	if !errors.Is(err, client.ErrArchived) {
+		t.Fatalf("got %v, want %v", err, client.ErrArchived)
+	}
+}
+
+func TestCreateForkPullRequest(t *testing.T) {
+	m := New(t)
+	_, err := m.CreateForkPullRequest(context.Background(), testRepo, "forkorg/testrepo", "fix", "main", &scm.PullRequestInput{Title: "Fix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreatedByBranch(testRepo, "forkorg:fix", "main")
+}
+
+func TestWaitForMergeable(t *testing.T) {
+	m := New(t)
+	pr, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "update", Target: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetMergeState(testRepo, pr.Number, MergePending)
+	polls := 0
+	m.OnGetPullRequest = func(repo string, number int) {
+		polls++
+		if polls == 3 {
+			m.SetMergeState(repo, number, MergeConflict)
+		}
+	}
+
+	err = m.WaitForMergeable(context.Background(), testRepo, pr.Number, time.Second)
+	if !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("got %v, want %v", err, client.ErrConflict)
+	}
+	if polls != 3 {
+		t.Fatalf("got %d polls, want 3", polls)
+	}
+}
+
+func TestUpdatedFilesUnder(t *testing.T) {
+	m := New(t)
+	for _, path := range []string{"config/a.yaml", "config/nested/b.yaml", "other/c.yaml"} {
+		if err := m.UpdateFile(context.Background(), testRepo, "main", path, "update", "", scm.Signature{}, []byte(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := m.UpdatedFilesUnder(testRepo, "main", "config/")
+	if l := len(files); l != 2 {
+		t.Fatalf("got %d files, want 2", l)
+	}
+	if s := string(files["config/nested/b.yaml"]); s != "config/nested/b.yaml" {
+		t.Fatalf("got %q, want %q", s, "config/nested/b.yaml")
+	}
+	m.AssertFileUpdatedUnder(testRepo, "main", "other/")
+}
+
+func TestGetCombinedStatus(t *testing.T) {
+	m := New(t)
+	m.AddStatus(testRepo, "abc123", &scm.Status{Label: "build", State: scm.StateFailure})
+	m.AddStatus(testRepo, "abc123", &scm.Status{Label: "test", State: scm.StateSuccess})
+	m.AddStatus(testRepo, "abc123", &scm.Status{Label: "build", State: scm.StateSuccess})
+
+	status, err := m.GetCombinedStatus(context.Background(), testRepo, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != scm.StateSuccess {
+		t.Fatalf("got %v, want %v", status.State, scm.StateSuccess)
+	}
+}
+
+func TestWritesAdvanceBranchHead(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "seeded")
+	m.AddFileContents(testRepo, "README.md", "main", []byte("hello"))
+	ctx := context.Background()
+
+	heads := map[string]bool{"seeded": true}
+	assertNewHead := func() {
+		t.Helper()
+		head, err := m.GetBranchHead(ctx, testRepo, "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if heads[head] {
+			t.Fatalf("branch head %q was not advanced", head)
+		}
+		heads[head] = true
+	}
+
+	if err := m.UpdateFile(ctx, testRepo, "main", "config.yaml", "add", "", scm.Signature{}, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	assertNewHead()
+	if _, err := m.UpdateFiles(ctx, testRepo, "main", "update", scm.Signature{}, []client.FileChange{{Path: "config.yaml", Content: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	assertNewHead()
+	if err := m.DeleteFile(ctx, testRepo, "main", "README.md", "remove", "", scm.Signature{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertNewHead()
+	m.AssertFileDeleted(testRepo, "README.md", "main")
+}
+
+func TestWritesKeepingBranchHeads(t *testing.T) {
+	m := New(t)
+	m.KeepBranchHeads = true
+	m.AddBranchHead(testRepo, "main", "seeded")
+
+	if err := m.UpdateFile(context.Background(), testRepo, "main", "config.yaml", "add", "", scm.Signature{}, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := m.GetBranchHead(context.Background(), testRepo, "main"); head != "seeded" {
+		t.Fatalf("got %q, want %q", head, "seeded")
+	}
+}
+
+func TestGetCommitFiles(t *testing.T) {
+	m := New(t)
+	m.AddCommitFiles(testRepo, "sha1", []*scm.Change{{Path: "config/a.yaml", Added: true}})
+
+	changes, err := m.GetCommitFiles(context.Background(), testRepo, "sha1", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(changes); l != 1 || changes[0].Path != "config/a.yaml" {
+		t.Fatalf("got changes %#v, want the added change", changes)
+	}
+
+	_, err = m.GetCommitFiles(context.Background(), testRepo, "sha2", scm.ListOptions{})
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCommitTemplate(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config.yaml", "main", []byte("replicas: 2\n"))
+	tmpl := template.Must(template.New("config").Parse("replicas: {{ .Replicas }}\n"))
+
+	if _, err := m.CommitTemplate(context.Background(), testRepo, "main", "config.yaml", "scale", scm.Signature{}, tmpl, map[string]int{"Replicas": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if b := m.GetUpdatedContents(testRepo, "config.yaml", "main"); string(b) != "replicas: 3\n" {
+		t.Fatalf("got %q, want the rendered template", b)
+	}
+
+	_, err := m.CommitTemplate(context.Background(), testRepo, "main", "config.yaml", "scale", scm.Signature{}, tmpl, map[string]int{"Replicas": 3})
+	if !errors.Is(err, client.ErrNoChange) {
+		t.Fatalf("got %v, want ErrNoChange", err)
+	}
+	if l := len(m.GetCommits(testRepo, "main")); l != 1 {
+		t.Fatalf("got %d commits, want 1", l)
+	}
+}
+
+func TestCommitDir(t *testing.T) {
+	m := New(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"README.md": "# service", "config/app.yml": "name: service"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.CommitDir(context.Background(), testRepo, "main", "scaffold", scm.Signature{}, dir, "services/new"); err != nil {
+		t.Fatal(err)
+	}
+	if b := m.GetUpdatedContents(testRepo, "services/new/config/app.yml", "main"); string(b) != "name: service" {
+		t.Fatalf("got %q, want the file from the directory", b)
+	}
+	if l := len(m.GetCommits(testRepo, "main")); l != 1 {
+		t.Fatalf("got %d commits, want 1", l)
+	}
+}
+
+func TestDeployments(t *testing.T) {
+	m := New(t)
+	m.AddDeployment(testRepo, &client.Deployment{ID: 1, Environment: "production"})
+
+	deployments, err := m.ListDeployments(context.Background(), testRepo, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(deployments); l != 1 || deployments[0].Environment != "production" {
+		t.Fatalf("got deployments %#v, want the added deployment", deployments)
+	}
+	if err := m.CreateDeploymentStatus(context.Background(), testRepo, 1, &client.DeploymentStatusInput{State: "success"}); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertDeploymentStatusCreated(testRepo, 1, "success")
+
+	err = m.CreateDeploymentStatus(context.Background(), testRepo, 2, &client.DeploymentStatusInput{State: "success"})
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestListEnvironments(t *testing.T) {
+	m := New(t)
+	m.AddEnvironment(testRepo, &client.Environment{Name: "production", WaitTimer: 30 * time.Minute, Reviewers: []string{"octocat"}})
+
+	environments, err := m.ListEnvironments(context.Background(), testRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(environments); l != 1 || environments[0].Name != "production" || environments[0].WaitTimer != 30*time.Minute {
+		t.Fatalf("got environments %#v, want the added environment", environments)
+	}
+}
+
+func TestGetBlame(t *testing.T) {
+	m := New(t)
+	m.AddBlame(testRepo, "config/app.yaml", "main", []*client.BlameHunk{{StartLine: 1, EndLine: 3, Sha: "7fd1a60"}})
+
+	hunks, err := m.GetBlame(context.Background(), testRepo, "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 1 || hunks[0].EndLine != 3 {
+		t.Fatalf("got hunks %#v, want the added hunks", hunks)
+	}
+	if _, err := m.GetBlame(context.Background(), testRepo, "main", "missing.yaml"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestForkRepository(t *testing.T) {
+	m := New(t)
+	m.AddFork(testRepo, "", &scm.Repository{Namespace: "octocat", Name: "Hello-World"})
+
+	for i := 0; i < 2; i++ {
+		fork, err := m.ForkRepository(context.Background(), testRepo, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fork.Namespace != "octocat" {
+			t.Fatalf("got fork %#v, want the added fork", fork)
+		}
+	}
+	m.AssertForked(testRepo, "")
+
+	if _, err := m.ForkRepository(context.Background(), testRepo, "octo-org"); err == nil {
+		t.Fatal("forked into an org without a fork added")
+	}
+	repos, err := m.ListRepositories(context.Background(), "", client.RepositoryListOptions{ExcludeForks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 0 {
+		t.Fatalf("got repos %v, want the fork excluded", repos)
+	}
+}
+
+func TestWorkflowRuns(t *testing.T) {
+	m := New(t)
+	m.AddWorkflowRun(testRepo, &client.WorkflowRun{ID: 1, HeadBranch: "main", HeadSha: "7fd1a60", Status: "completed", Conclusion: "success"})
+	m.AddWorkflowRun(testRepo, &client.WorkflowRun{ID: 2, HeadBranch: "feature", HeadSha: "a84d88e", Status: "queued"})
+
+	runs, err := m.ListWorkflowRuns(context.Background(), testRepo, "main", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(runs); l != 1 || runs[0].ID != 1 {
+		t.Fatalf("got runs %#v, want the run on main", runs)
+	}
+	runs, err = m.ListWorkflowRuns(context.Background(), testRepo, "a84d88e", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(runs); l != 1 || runs[0].ID != 2 {
+		t.Fatalf("got runs %#v, want the run for the commit", runs)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	m := New(t)
+	m.SetCapability("GetLanguages", false)
+
+	if supported, ok := m.Capabilities()["GetLanguages"]; !ok || supported {
+		t.Fatal("GetLanguages is supported")
+	}
+	if _, err := m.GetLanguages(context.Background(), testRepo); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+	if err := m.Star(context.Background(), testRepo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSupports(t *testing.T) {
+	m := New(t)
+	m.SetSupported(client.FeatureDeployments, false)
+
+	if m.Supports(client.FeatureDeployments) {
+		t.Fatal("deployments are supported, want them turned off")
+	}
+	if !m.Supports(client.FeatureSquashMerge) {
+		t.Fatal("squash merges are not supported, want all features supported by default")
+	}
+}
+
+func TestSetDefaultRef(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "README.md", "main", []byte("hello"))
+	m.AddFileContents(testRepo, "README.md", "release", []byte("released"))
+
+	m.SetDefaultRef("")
+	content, err := m.GetFile(context.Background(), testRepo, "", "README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "hello" {
+		t.Fatalf("got %q, want the file on the default branch", content.Data)
+	}
+
+	m.SetDefaultRef("release")
+	b, err := m.GetFileRaw(context.Background(), testRepo, "", "README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "released" {
+		t.Fatalf("got %q, want the file on the default ref", b)
+	}
+}
+
+func TestGetDiff(t *testing.T) {
+	m := New(t)
+	m.SetDiff(testRepo, "sha1", "sha2", "diff --git a/README.md b/README.md\n")
+
+	diff, err := m.GetDiff(context.Background(), testRepo, "sha1", "sha2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "diff --git a/README.md b/README.md\n" {
+		t.Fatalf("got diff %q, want the diff that was set", diff)
+	}
+
+	_, err = m.GetDiff(context.Background(), testRepo, "sha2", "sha1")
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetLanguages(t *testing.T) {
+	m := New(t)
+	m.AddLanguages(testRepo, map[string]int{"Go": 100})
+	m.AddLanguages(testRepo, map[string]int{"Go": 20, "Shell": 5})
+
+	languages, err := m.GetLanguages(context.Background(), testRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if languages["Go"] != 120 || languages["Shell"] != 5 {
+		t.Fatalf("got languages %v, want Go 120 and Shell 5", languages)
+	}
+}
+
+func TestRepositoryTopics(t *testing.T) {
+	m := New(t)
+
+	if err := m.SetRepositoryTopics(context.Background(), testRepo, []string{"go", "scm"}); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertRepositoryTopic(testRepo, "scm")
+	topics, err := m.GetRepositoryTopics(context.Background(), testRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(topics); l != 2 {
+		t.Fatalf("got %d topics, want 2", l)
+	}
+}
+
+func TestListPullRequestCommits(t *testing.T) {
+	m := New(t)
+	pr, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "update", Source: "feature", Target: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}
+	if _, err := m.UpdateFiles(context.Background(), testRepo, "feature", "update config", sig, []client.FileChange{{Path: "config.yaml", Content: []byte("a")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := m.ListPullRequestCommits(context.Background(), testRepo, pr.Number, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(commits); l != 1 || commits[0].Message != "update config" || commits[0].Author != sig {
+		t.Fatalf("got commits %#v, want the commit on the source branch", commits)
+	}
+
+	m.SetPullRequestCommits(testRepo, pr.Number, []*scm.Commit{{Sha: "sha1"}, {Sha: "sha2"}})
+	commits, err = m.ListPullRequestCommits(context.Background(), testRepo, pr.Number, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(commits); l != 2 {
+		t.Fatalf("got %d commits, want the 2 that were set", l)
+	}
+
+	_, err = m.ListPullRequestCommits(context.Background(), testRepo, 5, scm.ListOptions{})
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestRenameRepository(t *testing.T) {
+	m := New(t)
+	m.AddRepository("testorg", &scm.Repository{Namespace: "testorg", Name: "testrepo"})
+	m.AddFileContents(testRepo, "README.md", "main", []byte("hello"))
+
+	repo, err := m.RenameRepository(context.Background(), testRepo, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Name != "renamed" {
+		t.Fatalf("got name %s, want renamed", repo.Name)
+	}
+	m.AssertRepositoryName(testRepo, "renamed")
+	if _, err := m.GetFile(context.Background(), "testorg/renamed", "main", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.GetFile(context.Background(), testRepo, "main", "README.md")
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error for the old name", err)
+	}
+}
+
+func TestAddLabelsToMatching(t *testing.T) {
+	m := New(t)
+	for _, source := range []string{"dependabot/yaml", "feature", "dependabot/scm"} {
+		if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: source, Target: "main"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.AddPullRequestLabels(testRepo, 3, "dependencies")
+
+	labelled, err := m.AddLabelsToMatching(context.Background(), testRepo, func(pr *scm.PullRequest) bool {
+		return strings.HasPrefix(pr.Source, "dependabot/")
+	}, []string{"dependencies"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labelled != 1 {
+		t.Fatalf("got %d labelled pull requests, want 1", labelled)
+	}
+	m.AssertPullRequestLabelled(testRepo, 1, "dependencies")
+	pr, err := m.GetPullRequest(context.Background(), testRepo, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.Labels) != 0 {
+		t.Fatalf("got labels %v on pull request 2, want none", pr.Labels)
+	}
+}
+
+func TestGetBranchProtection(t *testing.T) {
+	m := New(t)
+	m.SetBranchProtection(testRepo, "main", &client.BranchProtection{Branch: "main", RequiredApprovals: 1})
+
+	p, err := m.GetBranchProtection(context.Background(), testRepo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.RequiredApprovals != 1 {
+		t.Fatalf("got %d required approvals, want 1", p.RequiredApprovals)
+	}
+	if protected, _ := m.IsBranchProtected(context.Background(), testRepo, "main"); !protected {
+		t.Fatal("branch with protection is not protected")
+	}
+	if _, err := m.GetBranchProtection(context.Background(), testRepo, "feature"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error for an unprotected branch", err)
+	}
+}
+
+func TestSetMaxItems(t *testing.T) {
+	m := New(t)
+	m.SetMaxItems(1)
+	m.AddFileContents(testRepo, "config/a.yaml", "main", []byte("a"))
+	m.AddFileContents(testRepo, "config/b.yaml", "main", []byte("b"))
+
+	entries, err := m.ListFiles(context.Background(), testRepo, "main", "config")
+	if !errors.Is(err, client.ErrTruncated) {
+		t.Fatalf("got %v, want client.ErrTruncated", err)
+	}
+	if l := len(entries); l != 1 {
+		t.Fatalf("got %d entries, want 1", l)
+	}
+	files, err := m.ReadDir(context.Background(), testRepo, "main", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(files); l != 2 {
+		t.Fatalf("got %d files from ReadDir, want 2", l)
+	}
+}
+
+func TestGetReadme(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "docs/README.md", "main", []byte("docs"))
+	m.AddFileContents(testRepo, "Readme.md", "main", []byte("# testrepo"))
+
+	readme, err := m.GetReadme(context.Background(), testRepo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readme.Path != "Readme.md" || string(readme.Data) != "# testrepo" {
+		t.Fatalf("got README %s with %q, want the root README", readme.Path, readme.Data)
+	}
+	if _, err := m.GetReadme(context.Background(), testRepo, "other"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got %v, want client.ErrNotFound", err)
+	}
+}
+
+func TestEnableAutoMerge(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.EnableAutoMerge(context.Background(), testRepo, 1, client.MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertAutoMergeEnabled(testRepo, 1, client.MergeMethodSquash)
+	if err := m.EnableAutoMerge(context.Background(), testRepo, 2, client.MergeMethodSquash); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCountOpenPullRequests(t *testing.T) {
+	m := New(t)
+	for _, source := range []string{"feature-1", "feature-2"} {
+		if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: source, Target: "main"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := m.CountOpenPullRequests(context.Background(), testRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("got %d open pull requests, want 2", n)
+	}
+}
+
+func TestGetFileAtCommit(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/app.yml", "6dcb09b5", []byte("name: before\n"))
+	m.AddFileContents(testRepo, "config/app.yml", "main", []byte("name: after\n"))
+
+	content, err := m.GetFileAtCommit(context.Background(), testRepo, "6dcb09b5", "config/app.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "name: before\n" {
+		t.Fatalf("got content %q", content.Data)
+	}
+	if _, err := m.GetFileAtCommit(context.Background(), testRepo, "main", "config/app.yml"); err == nil {
+		t.Fatal("expected an error for a branch name")
+	}
+}
+
+func TestErrorContext(t *testing.T) {
+	m := New(t)
+	m.SetErrorContext()
+	m.AddFileContents(testRepo, "config/app.yml", "main", []byte("name: app\n"))
+
+	_, err := m.GetFileNormalized(context.Background(), testRepo, "main", "missing.yml", func(b []byte) ([]byte, error) { return b, nil })
+	if want := "GetFileNormalized testorg/testrepo/missing.yml@main: not found"; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+	err = m.UpdateFile(context.Background(), testRepo, "main", "config/app.yml", "update", "", scm.Signature{}, []byte("name: app\n"))
+	if !errors.Is(err, client.ErrNoChange) || !strings.HasPrefix(err.Error(), "UpdateFile testorg/testrepo/config/app.yml@main: ") {
+		t.Fatalf("got error %v, want a wrapped client.ErrNoChange", err)
+	}
+	_, err = m.UpdateFiles(context.Background(), testRepo, "main", "update", scm.Signature{}, []client.FileChange{{Path: "config/app.yml", Content: []byte("name: app\n")}})
+	if !errors.Is(err, client.ErrNoChange) || !strings.HasPrefix(err.Error(), "UpdateFiles testorg/testrepo: ") {
+		t.Fatalf("got error %v, want a wrapped client.ErrNoChange", err)
+	}
+}
+
+func TestListTagsWithCommits(t *testing.T) {
+	m := New(t)
+	m.AddTag(testRepo, &client.TagInfo{Name: "v1.0.0", Sha: "abc123", Commit: &scm.Commit{Sha: "abc123", Message: "Release v1.0.0"}})
+
+	tags, err := m.ListTagsWithCommits(context.Background(), testRepo, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Commit.Message != "Release v1.0.0" {
+		t.Fatalf("got tags %v", tags)
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	m := New(t)
+	inp := &scm.StatusInput{State: scm.StateSuccess, Label: "ci/build", Desc: "Build passed", Target: "https://ci.example.com/builds/42"}
+
+	if _, err := m.CreateStatus(context.Background(), testRepo, "main", inp); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertStatus(testRepo, "main", inp)
+	combined, err := m.GetCombinedStatus(context.Background(), testRepo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if combined.State != scm.StateSuccess || combined.Statuses[0].Target != inp.Target {
+		t.Fatalf("got combined status %+v", combined)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/app.yml", "main", []byte("name: old\n"))
+	m.AddFileContents(testRepo, "config/extra.yml", "main", []byte("extra: true\n"))
+	m.AddFileContents(testRepo, "README.md", "main", []byte("# testrepo\n"))
+	desired := map[string][]byte{"app.yml": []byte("name: new\n"), "db.yml": []byte("host: db\n")}
+
+	created, updated, deleted, _, err := m.SyncDir(context.Background(), testRepo, "main", "config", desired, scm.Signature{}, "sync config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || updated != 1 || deleted != 1 {
+		t.Fatalf("got %d created, %d updated and %d deleted, want 1 of each", created, updated, deleted)
+	}
+	if got := string(m.GetUpdatedContents(testRepo, "config/app.yml", "main")); got != "name: new\n" {
+		t.Fatalf("got config/app.yml %q", got)
+	}
+	m.AssertFileDeleted(testRepo, "config/extra.yml", "main")
+	if _, _, _, _, err := m.SyncDir(context.Background(), testRepo, "main", "config", desired, scm.Signature{}, "sync config"); err != client.ErrNoChange {
+		t.Fatalf("got %v, want client.ErrNoChange", err)
+	}
+}
+
+func TestListReviews(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddReview(testRepo, 1, &client.Review{ID: 1, State: client.ReviewStateApproved})
+
+	reviews, err := m.ListReviews(context.Background(), testRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].State != client.ReviewStateApproved {
+		t.Fatalf("got %v, want an approved review", reviews)
+	}
+	if _, err := m.ListReviews(context.Background(), testRepo, 2); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestReviewComments(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.CreateReviewComment(context.Background(), testRepo, 1, &scm.ReviewInput{Body: "unused variable", Path: "main.go", Line: 12}); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertReviewComment(testRepo, 1, "main.go", 12)
+	comments, err := m.ListReviewComments(context.Background(), testRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].Body != "unused variable" {
+		t.Fatalf("got comments %#v, want the created comment", comments)
+	}
+	if err := m.CreateReviewComment(context.Background(), testRepo, 2, &scm.ReviewInput{Path: "main.go", Line: 1}); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestReviewThreads(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddReviewThread(testRepo, 1, &client.ReviewThread{ID: "thread-1", Path: "README.md", Line: 3})
+
+	if err := m.ResolveReviewThread(context.Background(), testRepo, "thread-1"); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertReviewThreadResolved(testRepo, "thread-1")
+	threads, err := m.ListReviewThreads(context.Background(), testRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 || !threads[0].Resolved {
+		t.Fatalf("got %v, want a resolved thread", threads)
+	}
+	if err := m.ResolveReviewThread(context.Background(), testRepo, "thread-2"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetCodeOwners(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "docs/CODEOWNERS", "main", []byte("* @org/maintainers\n"))
+
+	co, err := m.GetCodeOwners(context.Background(), testRepo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners := co.OwnersFor("README.md"); len(owners) != 1 || owners[0] != "@org/maintainers" {
+		t.Fatalf("got owners %v, want @org/maintainers", owners)
+	}
+
+	if _, err := m.GetCodeOwners(context.Background(), testRepo, "missing"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetFilePermalink(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha1")
+
+	link, err := m.GetFilePermalink(context.Background(), testRepo, "main", "config/a.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/testorg/testrepo/blob/sha1/config/a.yaml"; link != want {
+		t.Fatalf("got %s, want %s", link, want)
+	}
+}
+
+func TestGetFilesAtPaths(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/a.yaml", "main", []byte("a: 1\n"))
+	m.AddFileContents(testRepo, "config/b.yaml", "main", []byte("b: 2\n"))
+
+	files, err := m.GetFilesAtPaths(context.Background(), testRepo, "main", []string{"config/a.yaml", "config/b.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(files); l != 2 || string(files["config/b.yaml"].Data) != "b: 2\n" {
+		t.Fatalf("got files %v, want both files", files)
+	}
+	if _, err := m.GetFilesAtPaths(context.Background(), testRepo, "main", []string{"config/c.yaml"}); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestIsBranchMerged(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha1")
+	m.AddBranchHead(testRepo, "gitops-a", "sha1")
+	m.AddBranchHead(testRepo, "gitops-b", "sha2")
+
+	if merged, err := m.IsBranchMerged(context.Background(), testRepo, "gitops-a", "main"); err != nil || !merged {
+		t.Fatalf("got %v, %v, want merged", merged, err)
+	}
+	if merged, err := m.IsBranchMerged(context.Background(), testRepo, "gitops-b", "main"); err != nil || merged {
+		t.Fatalf("got %v, %v, want not merged", merged, err)
+	}
+	m.SetBranchMerged(testRepo, "gitops-b", "main", true)
+	if merged, err := m.IsBranchMerged(context.Background(), testRepo, "gitops-b", "main"); err != nil || !merged {
+		t.Fatalf("got %v, %v, want merged", merged, err)
+	}
+}
+
+func TestInvalidRepo(t *testing.T) {
+	m := New(t)
+
+	if _, err := m.GetFile(context.Background(), "https://github.com/testorg/testrepo", "main", "a.yaml"); !errors.Is(err, client.ErrInvalidRepo) {
+		t.Fatalf("got %v, want %v", err, client.ErrInvalidRepo)
+	}
+	if err := m.CreateBranch(context.Background(), testRepo+"/", "b", "sha1"); !errors.Is(err, client.ErrInvalidRepo) {
+		t.Fatalf("got %v, want %v", err, client.ErrInvalidRepo)
+	}
+}
+
+func TestCreateIssue(t *testing.T) {
+	m := New(t)
+
+	issue, err := m.CreateIssue(context.Background(), testRepo, &scm.IssueInput{Title: "Manual step required"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreateIssueComment(context.Background(), testRepo, issue.Number, "Done."); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertIssueCreated(testRepo, "Manual step required")
+	m.AssertIssueCommentCreated(testRepo, issue.Number, "Done.")
+}
+
+func TestClosePullRequestsOlderThan(t *testing.T) {
+	m := New(t)
+	for _, source := range []string{"gitops-a", "gitops-b", "feature"} {
+		if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Source: source, Target: "main"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.SetPullRequestCreated(testRepo, 1, time.Now().Add(-72*time.Hour))
+	m.SetPullRequestCreated(testRepo, 2, time.Now())
+	m.SetPullRequestCreated(testRepo, 3, time.Now().Add(-72*time.Hour))
+
+	closed, err := m.ClosePullRequestsOlderThan(context.Background(), testRepo, 24*time.Hour, func(pr *scm.PullRequest) bool {
+		return pr.Source != "feature"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed != 1 {
+		t.Fatalf("got %d closed pull requests, want 1", closed)
+	}
+	m.AssertPullRequestClosed(testRepo, 1)
+	m.RefutePullRequestClosed(testRepo, 2)
+	m.RefutePullRequestClosed(testRepo, 3)
+}
+
+func TestGetBranchHeads(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha1")
+
+	heads, err := m.GetBranchHeads(context.Background(), testRepo, []string{"main", "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heads["main"] != "sha1" || heads["production"] != "" || len(heads) != 2 {
+		t.Fatalf("got heads %v, want main at sha1 and no production head", heads)
+	}
+}
+
+func TestGetBranchHeadsWithDefaultRef(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "release", "sha1")
+	m.SetDefaultRef("release")
+
+	heads, err := m.GetBranchHeads(context.Background(), testRepo, []string{""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heads[""] != "sha1" {
+		t.Fatalf("got heads %v, want the head of the default ref", heads)
+	}
+}
+
+func TestMultiClient(t *testing.T) {
+	github, gitlab := New(t), New(t)
+	m := client.NewMultiClient(map[string]client.GitClient{"github": github, "gitlab": gitlab}, nil)
+	github.AddFileContents(testRepo, "config.yaml", "main", []byte("github"))
+	gitlab.AddFileContents("group/sub/proj", "config.yaml", "main", []byte("gitlab"))
+
+	content, err := m.GetFile(context.Background(), "github:"+testRepo, "main", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "github" {
+		t.Fatalf("got %q from the github client, want %q", content.Data, "github")
+	}
+	content, err = m.GetFile(context.Background(), "gitlab:group/sub/proj", "main", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "gitlab" {
+		t.Fatalf("got %q from the gitlab client, want %q", content.Data, "gitlab")
+	}
+
+	if err := m.Star(context.Background(), "gitlab:group/sub/proj"); err != nil {
+		t.Fatal(err)
+	}
+	gitlab.AssertStarred("group/sub/proj")
+	github.RefuteStarred("group/sub/proj")
+}
+
+func TestMultiClientWithUnknownPrefix(t *testing.T) {
+	m := client.NewMultiClient(map[string]client.GitClient{"github": New(t)}, nil)
+
+	for _, repo := range []string{"gitea:" + testRepo, testRepo} {
+		_, err := m.GetFile(context.Background(), repo, "main", "config.yaml")
+		if !errors.Is(err, client.ErrInvalidRepo) {
+			t.Fatalf("got %v for repo %s, want ErrInvalidRepo", err, repo)
+		}
+	}
+}
+
+func TestMultiClientForkPullRequestAcrossClients(t *testing.T) {
+	m := client.NewMultiClient(map[string]client.GitClient{"github": New(t), "gitlab": New(t)}, nil)
+
+	_, err := m.CreateForkPullRequest(context.Background(), "github:"+testRepo, "gitlab:fork/testrepo", "feature", "main", &scm.PullRequestInput{Title: "test"})
+	if !errors.Is(err, client.ErrInvalidRepo) {
+		t.Fatalf("got %v, want ErrInvalidRepo", err)
+	}
+}
+
+func TestMultiClientCapabilities(t *testing.T) {
+	github, gitea := New(t), New(t)
+	gitea.SetCapability("UpdateFiles", false)
+	gitea.SetSupported(client.FeatureDeployments, false)
+	m := client.NewMultiClient(map[string]client.GitClient{"github": github, "gitea": gitea}, nil)
+
+	if m.Capabilities()["UpdateFiles"] {
+		t.Fatal("UpdateFiles is capable, want it unsupported by one of the clients")
+	}
+	if m.Supports(client.FeatureDeployments) {
+		t.Fatal("deployments are supported, want them unsupported by one of the clients")
+	}
+	if !m.Supports(client.FeatureSquashMerge) {
+		t.Fatal("squash merge is not supported by every client")
+	}
+}
+
+func TestGetFileFollowingSymlinks(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "shared/app.yaml", "main", []byte("name: shared\n"))
+	m.AddSymlink(testRepo, "config/app.yaml", "main", "../shared/app.yaml")
+	m.AddSymlink(testRepo, "a.yaml", "main", "b.yaml")
+	m.AddSymlink(testRepo, "b.yaml", "main", "a.yaml")
+
+	content, err := m.GetFile(context.Background(), testRepo, "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "../shared/app.yaml" {
+		t.Fatalf("got %q, want the symlink target without SetFollowSymlinks", content.Data)
+	}
+
+	m.SetFollowSymlinks(1)
+	content, err = m.GetFile(context.Background(), testRepo, "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "name: shared\n" {
+		t.Fatalf("got %q, want the content of the symlink target", content.Data)
+	}
+	_, err = m.GetFile(context.Background(), testRepo, "main", "a.yaml")
+	if !errors.Is(err, client.ErrTooManySymlinks) {
+		t.Fatalf("got %v, want ErrTooManySymlinks", err)
+	}
+}
+
+func TestRepositoryVariables(t *testing.T) {
+	m := New(t)
+	if err := m.SetRepositoryVariable(context.Background(), testRepo, "ENV", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetRepositorySecret(context.Background(), testRepo, "TOKEN", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	variables, err := m.ListRepositoryVariables(context.Background(), testRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variables) != 1 || variables["ENV"] != "prod" {
+		t.Fatalf("got variables %v, want only ENV", variables)
+	}
+	m.AssertRepositoryVariable(testRepo, "ENV", "prod")
+	m.AssertRepositorySecret(testRepo, "TOKEN")
+}
+
+func TestHasScope(t *testing.T) {
+	m := New(t)
+	m.SetTokenScopes("repo", "read:org")
+
+	ok, err := m.HasScope(context.Background(), "repo:status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("repo:status is not granted by the repo scope")
+	}
+	ok, err = m.HasScope(context.Background(), "admin:org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("admin:org is granted, want only read:org")
+	}
+}
+
+func TestCreatePullRequestFromPatches(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha0")
+	m.AddFileContents(testRepo, "config/app.yaml", "main", []byte("name: app\nreplicas: 1\n"))
+	patch := []byte("--- a/config/app.yaml\n+++ b/config/app.yaml\n@@ -1,2 +1,2 @@\n name: app\n-replicas: 1\n+replicas: 3\n")
+
+	pr, err := m.CreatePullRequestFromPatches(context.Background(), testRepo, "main", "import", [][]byte{patch}, &scm.PullRequestInput{Title: "Import changes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertBranchCreated(testRepo, "import", "sha0")
+	if b := m.GetUpdatedContents(testRepo, "config/app.yaml", "import"); string(b) != "name: app\nreplicas: 3\n" {
+		t.Fatalf("got %q, want the patched content", b)
+	}
+	if pr.Number != 1 {
+		t.Fatalf("got pull request %d, want 1", pr.Number)
+	}
+	m.AssertPullRequestCreated(testRepo, &scm.PullRequestInput{Title: "Import changes", Source: "import", Target: "main"})
+}
+
+func TestMergeBase(t *testing.T) {
+	m := New(t)
+	m.AddCommit(testRepo, "c1")
+	m.AddCommit(testRepo, "c2", "c1")
+	m.AddCommit(testRepo, "c3", "c2")
+	m.AddCommit(testRepo, "f1", "c2")
+	m.AddCommit(testRepo, "f2", "f1")
+	m.AddBranchHead(testRepo, "main", "c3")
+	m.AddBranchHead(testRepo, "feature", "f2")
+
+	sha, err := m.MergeBase(context.Background(), testRepo, "main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "c2" {
+		t.Fatalf("got merge base %s, want c2", sha)
+	}
+	if _, err := m.MergeBase(context.Background(), testRepo, "main", "unrelated"); !client.IsNotFound(err) {
+		t.Fatalf("got %v for unrelated refs, want a not found error", err)
+	}
+}
+
+func TestReleaseAssets(t *testing.T) {
+	m := New(t)
+	m.AddReleaseAsset(testRepo, 12, &client.ReleaseAsset{ID: 1, Name: "tool-linux-amd64"}, []byte("binary content"))
+
+	assets, err := m.ListReleaseAssets(context.Background(), testRepo, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].Name != "tool-linux-amd64" || assets[0].Size != 14 {
+		t.Fatalf("got assets %v, want the added asset", assets)
+	}
+	var buf bytes.Buffer
+	if err := m.DownloadReleaseAsset(context.Background(), testRepo, 1, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "binary content" {
+		t.Fatalf("got %q, want the asset content", buf.String())
+	}
+}
+
+func TestUploadReleaseAsset(t *testing.T) {
+	m := New(t)
+	m.AddReleaseAsset(testRepo, 12, &client.ReleaseAsset{ID: 1, Name: "tool-linux-amd64"}, []byte("binary content"))
+
+	asset, err := m.UploadReleaseAsset(context.Background(), testRepo, 12, "tool-darwin-amd64", "application/octet-stream", strings.NewReader("other content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.ID != 2 || asset.Size != 13 {
+		t.Fatalf("got asset %v, want a new asset with the content size", asset)
+	}
+	if b := m.AssertReleaseAsset(testRepo, 12, "tool-darwin-amd64"); string(b) != "other content" {
+		t.Fatalf("got content %q, want the uploaded content", b)
+	}
+}
+
+func TestAssertActor(t *testing.T) {
+	m := New(t)
+	m.SetSudo("automation")
+	sig := scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}
+
+	if err := m.UpdateFile(client.WithContextSudo(context.Background(), "jdoe"), testRepo, "main", "README.md", "update", "", sig, []byte("testing")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "main", Target: "release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertActor(testRepo, "UpdateFile", "jdoe")
+	m.AssertActor(testRepo, "CreatePullRequest", "automation")
+}
+
+func TestSuggestReviewers(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "CODEOWNERS", "main", []byte("*.go @org/go-reviewers\n/docs/ @org/docs\n"))
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddPullRequestChanges(testRepo, 1, []*scm.Change{{Path: "main.go"}, {Path: "docs/index.md"}, {Path: "README.md"}})
+
+	reviewers, err := m.SuggestReviewers(context.Background(), testRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(reviewers, ","); got != "@org/docs,@org/go-reviewers" {
+		t.Fatalf("got reviewers %q, want the owners of the changed files", got)
+	}
+}
+
+func TestGetFileStream(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "data/large.bin", "main", []byte("large content"))
+
+	r, err := m.GetFileStream(context.Background(), testRepo, "main", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "large content" {
+		t.Fatalf("got %q, want the file content", b)
+	}
+}
+
+func TestFilesEqual(t *testing.T) {
+	m := New(t)
+	m.AddFileContents(testRepo, "config/app.yaml", "staging", []byte("replicas: 2"))
+	m.AddFileContents(testRepo, "config/app.yaml", "prod", []byte("replicas: 2"))
+
+	equal, err := m.FilesEqual(context.Background(), testRepo, "config/app.yaml", "staging", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Fatal("got different files, want equal files")
+	}
+
+	_, err = m.FilesEqual(context.Background(), testRepo, "config/app.yaml", "staging", "dev")
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got %v, want client.ErrNotFound", err)
+	}
+}
+
+func TestMergePullRequest(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetMergeInProgress(testRepo, 1, 2)
+
+	err := m.MergePullRequest(context.Background(), testRepo, 1, client.MergeMethodSquash)
+	if !errors.Is(err, client.ErrMergeInProgress) {
+		t.Fatalf("got %v, want client.ErrMergeInProgress", err)
+	}
+	if err := m.MergePullRequest(context.Background(), testRepo, 1, client.MergeMethodSquash, client.RetryMergeInProgress(1, time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestMerged(testRepo, 1, client.MergeMethodSquash)
+}
+
+func TestMergePullRequestWithCommitMessage(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.MergePullRequest(context.Background(), testRepo, 1, client.MergeMethodSquash, client.MergeCommitMessage("Promote to staging", "Promotes v1.2.0."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertMergeCommitMessage(testRepo, 1, "Promote to staging", "Promotes v1.2.0.")
+}
+
+func TestSetPullRequestBase(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.SetPullRequestBase(context.Background(), testRepo, 1, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestBase(testRepo, 1, "staging")
+
+	err := m.SetPullRequestBase(context.Background(), testRepo, 2, "staging")
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestLockPullRequest(t *testing.T) {
+	m := New(t)
+	if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.LockPullRequest(context.Background(), testRepo, 1, "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestLocked(testRepo, 1)
+	if err := m.UnlockPullRequest(context.Background(), testRepo, 1); err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestNotLocked(testRepo, 1)
+
+	err := m.LockPullRequest(context.Background(), testRepo, 2, "resolved")
+	if !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestBranchesWithoutOpenPRs(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha0")
+	m.AddBranchHead(testRepo, "gitops-a", "sha1")
+	m.AddBranchHead(testRepo, "gitops-b", "sha2")
+	m.AddBranchHead(testRepo, "gitops-c", "sha3")
+	for _, source := range []string{"gitops-a", "gitops-b"} {
+		if _, err := m.CreatePullRequest(context.Background(), testRepo, &scm.PullRequestInput{Title: source, Source: source, Target: "main"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.MergePullRequest(context.Background(), testRepo, 2, client.MergeMethodMerge); err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := m.BranchesWithoutOpenPRs(context.Background(), testRepo, "gitops-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(branches, ","); got != "gitops-b,gitops-c" {
+		t.Fatalf("got branches %q, want the branches without open pull requests", got)
+	}
+}
+
+func TestCreatePullRequestIfChanged(t *testing.T) {
+	m := New(t)
+	m.AddBranchHead(testRepo, "main", "sha0")
+	m.AddBranchHead(testRepo, "feature", "sha0")
+	inp := &scm.PullRequestInput{Title: "testing", Source: "feature", Target: "main"}
+
+	if _, created, err := m.CreatePullRequestIfChanged(context.Background(), testRepo, inp); err != nil || created {
+		t.Fatalf("got %v, %v, want no pull request", created, err)
+	}
+	sig := scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}
+	if err := m.UpdateFile(context.Background(), testRepo, "feature", "README.md", "update", "", sig, []byte("testing")); err != nil {
+		t.Fatal(err)
+	}
+	if _, created, err := m.CreatePullRequestIfChanged(context.Background(), testRepo, inp); err != nil || !created {
+		t.Fatalf("got %v, %v, want a pull request", created, err)
+	}
+	m.AssertPullRequestCreated(testRepo, inp)
+}
+
+func TestGetChecksStatus(t *testing.T) {
+	m := New(t)
+	m.AddStatus(testRepo, "main", &scm.Status{Label: "ci/build", State: scm.StateSuccess})
+	m.AddCheckRun(testRepo, "main", &client.Check{Name: "test", State: scm.StatePending})
+	m.AddCheckRun(testRepo, "main", &client.Check{Name: "test", State: scm.StateFailure})
+
+	summary, err := m.GetChecksStatus(context.Background(), testRepo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.State != scm.StateFailure || len(summary.Checks) != 2 || !summary.Checks[1].CheckRun {
+		t.Fatalf("got %#v, want a failed status and check run", summary)
+	}
+}
+
+func TestGists(t *testing.T) {
+	m := New(t)
+	m.AddGist(&client.Gist{ID: "aa5a315d61ae9438b18d", Files: map[string]string{"a.txt": "a"}})
+
+	created, err := m.CreateGist(context.Background(), &client.GistInput{Description: "output", Files: map[string]string{"b.txt": "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertGistCreated("b.txt", "b")
+
+	for id, want := range map[string]string{"aa5a315d61ae9438b18d": "a.txt", created.ID: "b.txt"} {
+		g, err := m.GetGist(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := g.Files[want]; !ok {
+			t.Fatalf("got gist %#v, want file %s", g, want)
+		}
+	}
+	if _, err := m.GetGist(context.Background(), "unknown"); !client.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestArchivedCheck(t *testing.T) {
+	m := New(t)
+	m.ArchivedCheck = true
+	m.AddRepository("Codertocat", &scm.Repository{Namespace: "Codertocat", Name: "Archived", Archived: true})
+	m.SetRepositoryDisabled("Codertocat/Disabled", true)
+
+	err := m.CreateBranch(context.Background(), "Codertocat/Archived", "feature", "sha1")
+	if !errors.Is(err, client.ErrArchived) {
+		t.Fatalf("got %v, want client.ErrArchived", err)
+	}
+	err = m.UpdateFile(context.Background(), "Codertocat/Disabled", "main", "a.yaml", "update", "", scm.Signature{}, []byte("a"))
+	if !errors.Is(err, client.ErrRepoDisabled) {
+		t.Fatalf("got %v, want client.ErrRepoDisabled", err)
+	}
+	if err := m.SetRepositoryArchived(context.Background(), "Codertocat/Archived", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateBranch(context.Background(), "Codertocat/Archived", "feature", "sha1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	m := New(t)
+	m.AddRelease(testRepo, &scm.Release{Tag: "v1.2.0"})
+	m.AddRelease(testRepo, &scm.Release{Tag: "v1.10.0"})
+	m.AddRelease(testRepo, &scm.Release{Tag: "v2.0.0-rc.1"})
+	m.AddTag("Codertocat/Tags", &client.TagInfo{Name: "v0.1.0", Sha: "sha1"})
+
+	release, err := m.LatestRelease(context.Background(), testRepo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "v1.10.0" {
+		t.Fatalf("got release %s, want v1.10.0", release.Tag)
+	}
+	release, err = m.LatestRelease(context.Background(), "Codertocat/Tags", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "v0.1.0" {
+		t.Fatalf("got release %s, want v0.1.0", release.Tag)
+	}
+	if _, err := m.LatestRelease(context.Background(), "Codertocat/Empty", false); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got %v, want client.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,603 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// MergeState is the mergeability of a pull request in the mock.
+type MergeState int
+
+const (
+	// MergeClean is the state of pull requests that can be merged, which is
+	// the default.
+	MergeClean MergeState = iota
+	// MergePending is the state of pull requests whose mergeability is still
+	// being checked.
+	MergePending
+	// MergeConflict is the state of pull requests that have conflicts.
+	MergeConflict
+)
+
+// GetPullRequest implements the client.GitClient interface.
+func (m *MockClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	if m.OnGetPullRequest != nil {
+		m.OnGetPullRequest(repo, number)
+	}
+	inp := m.pullRequest(repo, number)
+	if inp == nil {
+		return nil, notFound("failed to get pull request %d in repo %s", number, repo)
+	}
+	return &scm.PullRequest{
+		Number:  number,
+		Title:   inp.Title,
+		Body:    inp.Body,
+		Source:  inp.Source,
+		Target:  inp.Target,
+		Link:    fmt.Sprintf("https://example.com/pull-request/%d", number),
+		Labels:  m.pullRequestLabels(repo, number),
+		Closed:  m.closedPullRequests[key(repo, strconv.Itoa(number))],
+		Created: m.pullRequestsCreated[key(repo, strconv.Itoa(number))],
+	}, nil
+}
+
+// ListPullRequestCommits implements the client.GitClient interface.
+//
+// The commits set with SetPullRequestCommits are returned if there are any,
+// otherwise the commits recorded by UpdateFiles on the source branch of the
+// pull request are returned, all of them regardless of the page in the
+// options.
+func (m *MockClient) ListPullRequestCommits(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Commit, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	pr := m.pullRequest(repo, number)
+	if pr == nil {
+		return nil, notFound("failed to list commits in pull request %d in repo %s", number, repo)
+	}
+	commits, ok := m.pullRequestCommits[key(repo, strconv.Itoa(number))]
+	if !ok {
+		for _, c := range m.commits[key(repo, pr.Source)] {
+			commits = append(commits, &scm.Commit{Sha: c.Sha, Message: c.Message, Author: c.Signature, Committer: c.Signature})
+		}
+	}
+	n, err := m.limitItems(len(commits))
+	return commits[:n], err
+}
+
+// SetPullRequestCommits sets the commits returned by ListPullRequestCommits.
+func (m *MockClient) SetPullRequestCommits(repo string, number int, commits []*scm.Commit) {
+	m.pullRequestCommits[key(repo, strconv.Itoa(number))] = commits
+}
+
+// IsPullRequestMergeable implements the client.GitClient interface.
+//
+// The state set with SetMergeState is returned, after calling the
+// OnGetPullRequest hook.
+func (m *MockClient) IsPullRequestMergeable(ctx context.Context, repo string, number int) (bool, error) {
+	if err := m.checkMethod("IsPullRequestMergeable", repo); err != nil {
+		return false, err
+	}
+	if _, err := m.GetPullRequest(ctx, repo, number); err != nil {
+		return false, err
+	}
+	switch m.mergeStates[key(repo, strconv.Itoa(number))] {
+	case MergePending:
+		return false, nil
+	case MergeConflict:
+		return false, fmt.Errorf("pull request %d in repo %s can't be merged: %w", number, repo, client.ErrConflict)
+	}
+	return true, nil
+}
+
+// WaitForMergeable implements the client.GitClient interface.
+//
+// The mock doesn't wait between polls, tests should change the state in the
+// OnGetPullRequest hook, or provide a context that will be done.
+func (m *MockClient) WaitForMergeable(ctx context.Context, repo string, number int, poll time.Duration) error {
+	if err := m.checkMethod("WaitForMergeable", repo); err != nil {
+		return err
+	}
+	for {
+		mergeable, err := m.IsPullRequestMergeable(ctx, repo, number)
+		if err != nil {
+			return err
+		}
+		if mergeable {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// SetMergeState sets the mergeability of a pull request.
+func (m *MockClient) SetMergeState(repo string, number int, state MergeState) {
+	m.mergeStates[key(repo, strconv.Itoa(number))] = state
+}
+
+// MergePullRequest implements the client.GitClient interface.
+//
+// Pull requests with the MergeConflict state fail with client.ErrConflict,
+// and merges fail with client.ErrMergeInProgress the number of times set with
+// SetMergeInProgress, including the retries with the
+// client.RetryMergeInProgress option, which doesn't wait between them.
+func (m *MockClient) MergePullRequest(ctx context.Context, repo string, number int, method client.MergeMethod, opts ...client.MergeOption) error {
+	if err := m.checkMethod("MergePullRequest", repo); err != nil {
+		return err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return notFound("failed to get pull request %d in repo %s", number, repo)
+	}
+	k := key(repo, strconv.Itoa(number))
+	if m.mergeStates[k] == MergeConflict {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to merge pull request %d in repo %s", number, repo),
+			Status: http.StatusMethodNotAllowed,
+			Err:    client.ErrConflict,
+		}
+	}
+	o := mergeOptions(opts)
+	for attempt := 0; m.mergesInProgress[k] > 0; attempt++ {
+		m.mergesInProgress[k]--
+		if attempt == o.Retries {
+			return client.SCMError{
+				Msg:    fmt.Sprintf("failed to merge pull request %d in repo %s: merge already in progress", number, repo),
+				Status: http.StatusMethodNotAllowed,
+				Err:    client.ErrMergeInProgress,
+			}
+		}
+	}
+	m.mergedPullRequests[k] = method
+	m.mergeCommitMessages[k] = mergeCommitMessage{title: o.CommitTitle, body: o.CommitMessage}
+	m.closedPullRequests[k] = true
+	m.recordActor(ctx, repo, "MergePullRequest")
+	return nil
+}
+
+// SetMergeInProgress makes the next attempts to merge the pull request fail
+// with client.ErrMergeInProgress, times times, like concurrent merges on
+// GitHub.
+func (m *MockClient) SetMergeInProgress(repo string, number, times int) {
+	m.mergesInProgress[key(repo, strconv.Itoa(number))] = times
+}
+
+// AssertPullRequestMerged fails if the pull request was not merged with the
+// method.
+func (m *MockClient) AssertPullRequestMerged(repo string, number int, method client.MergeMethod) {
+	m.t.Helper()
+	got, ok := m.mergedPullRequests[key(repo, strconv.Itoa(number))]
+	if !ok {
+		m.t.Fatalf("pull request %d in repo %s was not merged", number, repo)
+	}
+	if got != method {
+		m.t.Fatalf("pull request %d in repo %s merged with %s, want %s", number, repo, got, method)
+	}
+}
+
+// SetPullRequestBase implements the client.GitClient interface.
+//
+// The target branch of the pull request created with CreatePullRequest is
+// changed to the new base.
+func (m *MockClient) SetPullRequestBase(ctx context.Context, repo string, number int, newBase string) error {
+	if err := m.checkMethod("SetPullRequestBase", repo); err != nil {
+		return err
+	}
+	pr := m.pullRequest(repo, number)
+	if pr == nil {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to set base of pull request %d in repo %s to %s", number, repo, newBase),
+			Status: http.StatusNotFound,
+		}
+	}
+	pr.Target = newBase
+	return nil
+}
+
+// AssertPullRequestBase fails if the pull request doesn't target the base
+// branch.
+func (m *MockClient) AssertPullRequestBase(repo string, number int, base string) {
+	m.t.Helper()
+	pr := m.pullRequest(repo, number)
+	if pr == nil {
+		m.t.Fatalf("pull request %d in repo %s was not created", number, repo)
+	}
+	if pr.Target != base {
+		m.t.Fatalf("pull request %d in repo %s targets %s, want %s", number, repo, pr.Target, base)
+	}
+}
+
+// LockPullRequest implements the client.GitClient interface.
+//
+// Only pull requests created with CreatePullRequest can be locked.
+func (m *MockClient) LockPullRequest(ctx context.Context, repo string, number int, reason string) error {
+	if err := m.checkMethod("LockPullRequest", repo); err != nil {
+		return err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to lock pull request %d in repo %s", number, repo),
+			Status: http.StatusNotFound,
+		}
+	}
+	m.lockedPullRequests[key(repo, strconv.Itoa(number))] = reason
+	return nil
+}
+
+// UnlockPullRequest implements the client.GitClient interface.
+//
+// Only pull requests created with CreatePullRequest can be unlocked.
+func (m *MockClient) UnlockPullRequest(ctx context.Context, repo string, number int) error {
+	if err := m.checkMethod("UnlockPullRequest", repo); err != nil {
+		return err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return client.SCMError{
+			Msg:    fmt.Sprintf("failed to unlock pull request %d in repo %s", number, repo),
+			Status: http.StatusNotFound,
+		}
+	}
+	delete(m.lockedPullRequests, key(repo, strconv.Itoa(number)))
+	return nil
+}
+
+// AssertPullRequestLocked fails if the pull request is not locked.
+func (m *MockClient) AssertPullRequestLocked(repo string, number int) {
+	m.t.Helper()
+	if _, ok := m.lockedPullRequests[key(repo, strconv.Itoa(number))]; !ok {
+		m.t.Fatalf("pull request %d in repo %s is not locked", number, repo)
+	}
+}
+
+// AssertPullRequestNotLocked fails if the pull request is locked.
+func (m *MockClient) AssertPullRequestNotLocked(repo string, number int) {
+	m.t.Helper()
+	if _, ok := m.lockedPullRequests[key(repo, strconv.Itoa(number))]; ok {
+		m.t.Fatalf("pull request %d in repo %s is locked", number, repo)
+	}
+}
+
+// AssertMergeCommitMessage fails if the pull request was not merged with the
+// title and body set with client.MergeCommitMessage.
+func (m *MockClient) AssertMergeCommitMessage(repo string, number int, title, body string) {
+	m.t.Helper()
+	k := key(repo, strconv.Itoa(number))
+	if _, ok := m.mergedPullRequests[k]; !ok {
+		m.t.Fatalf("pull request %d in repo %s was not merged", number, repo)
+	}
+	got := m.mergeCommitMessages[k]
+	if got.title != title || got.body != body {
+		m.t.Fatalf("pull request %d in repo %s merged with commit message %q, %q, want %q, %q", number, repo, got.title, got.body, title, body)
+	}
+}
+
+// mergeCommitMessage is the commit message a pull request was merged with.
+type mergeCommitMessage struct {
+	title, body string
+}
+
+// GetPullRequestDiff implements the client.GitClient interface.
+//
+// The diff set with SetPullRequestDiff is returned if there is one, otherwise
+// a simple diff is synthesized from the files updated on the source branch of
+// the pull request.
+func (m *MockClient) GetPullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	if err := m.checkMethod("GetPullRequestDiff", repo); err != nil {
+		return "", err
+	}
+	if diff, ok := m.pullRequestDiffs[key(repo, strconv.Itoa(number))]; ok {
+		return diff, nil
+	}
+	pr := m.pullRequest(repo, number)
+	if pr == nil {
+		return "", notFound("failed to get diff for pull request %d in repo %s", number, repo)
+	}
+	return m.synthesizeDiff(repo, pr.Source, pr.Target), nil
+}
+
+// CreateForkPullRequest implements the client.GitClient interface.
+//
+// The pull request is recorded with the source in the "owner:branch" format,
+// so it can be asserted with AssertPullRequestCreatedByBranch.
+func (m *MockClient) CreateForkPullRequest(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	if err := m.checkMethod("CreateForkPullRequest", upstreamRepo); err != nil {
+		return nil, err
+	}
+	owner, _ := scm.Split(headRepo)
+	forkInp := *inp
+	forkInp.Source = owner + ":" + headBranch
+	forkInp.Target = baseBranch
+	return m.CreatePullRequest(ctx, upstreamRepo, &forkInp)
+}
+
+// SetPullRequestDiff sets the diff returned by GetPullRequestDiff.
+func (m *MockClient) SetPullRequestDiff(repo string, number int, diff string) {
+	m.pullRequestDiffs[key(repo, strconv.Itoa(number))] = diff
+}
+
+// CountOpenPullRequests implements the client.GitClient interface.
+//
+// The pull requests created with CreatePullRequest that have not been closed
+// are counted.
+func (m *MockClient) CountOpenPullRequests(ctx context.Context, repo string) (int, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return 0, err
+	}
+	var open int
+	for i := range m.createdPullRequests[repo] {
+		if !m.closedPullRequests[key(repo, strconv.Itoa(i+1))] {
+			open++
+		}
+	}
+	return open, nil
+}
+
+// ClosePullRequestsOlderThan implements the client.GitClient interface.
+//
+// Pull requests created with CreatePullRequest are closed if their creation
+// time set with SetPullRequestCreated is older than d, pull requests without a
+// creation time are never closed. If ClosePullRequestErr is set, every close
+// fails with it.
+func (m *MockClient) ClosePullRequestsOlderThan(ctx context.Context, repo string, d time.Duration, filter func(*scm.PullRequest) bool) (int, error) {
+	if err := m.checkMethod("ClosePullRequestsOlderThan", repo); err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-d)
+	var (
+		closed int
+		errs   []error
+	)
+	for i, inp := range m.createdPullRequests[repo] {
+		number := i + 1
+		k := key(repo, strconv.Itoa(number))
+		created, ok := m.pullRequestsCreated[k]
+		if m.closedPullRequests[k] || !ok || !created.Before(cutoff) {
+			continue
+		}
+		pr := &scm.PullRequest{Number: number, Title: inp.Title, Body: inp.Body, Source: inp.Source, Target: inp.Target, Created: created}
+		if filter != nil && !filter(pr) {
+			continue
+		}
+		if m.ClosePullRequestErr != nil {
+			errs = append(errs, m.ClosePullRequestErr)
+			continue
+		}
+		m.closedPullRequests[k] = true
+		closed++
+	}
+	if len(errs) > 0 {
+		return closed, client.BulkError{Errs: errs}
+	}
+	return closed, nil
+}
+
+// SetPullRequestCreated sets the creation time of a pull request created with
+// CreatePullRequest.
+func (m *MockClient) SetPullRequestCreated(repo string, number int, created time.Time) {
+	m.pullRequestsCreated[key(repo, strconv.Itoa(number))] = created
+}
+
+// AssertPullRequestClosed fails if the pull request was not closed.
+func (m *MockClient) AssertPullRequestClosed(repo string, number int) {
+	m.t.Helper()
+	if !m.closedPullRequests[key(repo, strconv.Itoa(number))] {
+		m.t.Fatalf("pull request %d in repo %s was not closed", number, repo)
+	}
+}
+
+// RefutePullRequestClosed fails if the pull request was closed.
+func (m *MockClient) RefutePullRequestClosed(repo string, number int) {
+	m.t.Helper()
+	if m.closedPullRequests[key(repo, strconv.Itoa(number))] {
+		m.t.Fatalf("pull request %d in repo %s was closed", number, repo)
+	}
+}
+
+// EnableAutoMerge implements the client.GitClient interface.
+//
+// Auto-merge can only be enabled for pull requests created with
+// CreatePullRequest, the method is recorded for AssertAutoMergeEnabled.
+func (m *MockClient) EnableAutoMerge(ctx context.Context, repo string, number int, method client.MergeMethod) error {
+	if err := m.checkMethod("EnableAutoMerge", repo); err != nil {
+		return err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return notFound("failed to enable auto-merge for pull request %d in repo %s", number, repo)
+	}
+	m.autoMerges[key(repo, strconv.Itoa(number))] = method
+	return nil
+}
+
+// AssertAutoMergeEnabled fails if auto-merge was not enabled for the pull
+// request with the method.
+func (m *MockClient) AssertAutoMergeEnabled(repo string, number int, method client.MergeMethod) {
+	m.t.Helper()
+	got, ok := m.autoMerges[key(repo, strconv.Itoa(number))]
+	if !ok {
+		m.t.Fatalf("auto-merge not enabled for pull request %d in repo %s", number, repo)
+	}
+	if got != method {
+		m.t.Fatalf("auto-merge enabled for pull request %d in repo %s with method %s, want %s", number, repo, got, method)
+	}
+}
+
+// AddLabelsToMatching implements the client.GitClient interface.
+//
+// The labels are added to the open pull requests created with
+// CreatePullRequest that are accepted by the match func, pull requests that
+// already have all the labels are skipped. If AddLabelsErr is set, labelling
+// every pull request fails with it.
+func (m *MockClient) AddLabelsToMatching(ctx context.Context, repo string, match func(*scm.PullRequest) bool, labels []string) (int, error) {
+	if err := m.checkMethod("AddLabelsToMatching", repo); err != nil {
+		return 0, err
+	}
+	if len(labels) == 0 {
+		return 0, errors.New("no labels to add")
+	}
+	var (
+		labelled int
+		errs     []error
+	)
+	for i, inp := range m.createdPullRequests[repo] {
+		number := i + 1
+		k := key(repo, strconv.Itoa(number))
+		if m.closedPullRequests[k] {
+			continue
+		}
+		pr := &scm.PullRequest{Number: number, Title: inp.Title, Body: inp.Body, Source: inp.Source, Target: inp.Target, Labels: m.pullRequestLabels(repo, number)}
+		if (match != nil && !match(pr)) || m.hasLabels(repo, number, labels) {
+			continue
+		}
+		if m.AddLabelsErr != nil {
+			errs = append(errs, m.AddLabelsErr)
+			continue
+		}
+		for _, l := range labels {
+			if !m.hasLabels(repo, number, []string{l}) {
+				m.labels[k] = append(m.labels[k], l)
+			}
+		}
+		labelled++
+	}
+	if len(errs) > 0 {
+		return labelled, client.BulkError{Errs: errs}
+	}
+	return labelled, nil
+}
+
+// AddPullRequestLabels is a mock method for setting up the labels a pull
+// request created with CreatePullRequest already has.
+func (m *MockClient) AddPullRequestLabels(repo string, number int, labels ...string) {
+	k := key(repo, strconv.Itoa(number))
+	m.labels[k] = append(m.labels[k], labels...)
+}
+
+// AssertPullRequestLabelled fails if the pull request doesn't have the label.
+func (m *MockClient) AssertPullRequestLabelled(repo string, number int, label string) {
+	m.t.Helper()
+	if !m.hasLabels(repo, number, []string{label}) {
+		m.t.Fatalf("pull request %d in repo %s does not have label %s", number, repo, label)
+	}
+}
+
+func (m *MockClient) hasLabels(repo string, number int, labels []string) bool {
+	has := map[string]bool{}
+	for _, l := range m.labels[key(repo, strconv.Itoa(number))] {
+		has[l] = true
+	}
+	for _, l := range labels {
+		if !has[l] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MockClient) pullRequestLabels(repo string, number int) []scm.Label {
+	var labels []scm.Label
+	for _, l := range m.labels[key(repo, strconv.Itoa(number))] {
+		labels = append(labels, scm.Label{Name: l})
+	}
+	return labels
+}
+
+// pullRequest returns the input for a pull request created with
+// CreatePullRequest, or nil if there is no such pull request.
+func (m *MockClient) pullRequest(repo string, number int) *scm.PullRequestInput {
+	prs := m.createdPullRequests[repo]
+	if number < 1 || number > len(prs) {
+		return nil
+	}
+	return prs[number-1]
+}
+
+// synthesizeDiff creates a diff that replaces the whole of each file that was
+// updated on the source branch.
+func (m *MockClient) synthesizeDiff(repo, source, target string) string {
+	var paths []string
+	for k := range m.updatedFiles {
+		if parts := splitKey(k); parts[0] == repo && parts[2] == source {
+			paths = append(paths, parts[1])
+		}
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+		if old, ok := m.currentContents(repo, path, target); ok {
+			writeLines(&b, "-", old)
+		}
+		writeLines(&b, "+", m.updatedFiles[key(repo, path, source)])
+	}
+	return b.String()
+}
+
+func writeLines(b *strings.Builder, prefix string, content []byte) {
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		b.WriteString(prefix + strings.TrimSuffix(line, "\n") + "\n")
+	}
+}
+
+// CreatePullRequestFromPatches implements the client.GitClient interface.
+//
+// The patches are applied to the files of the new branch, which starts with
+// the content of the base branch, and committed with UpdateFiles, before the
+// pull request is recorded like CreatePullRequest.
+func (m *MockClient) CreatePullRequestFromPatches(ctx context.Context, repo, baseBranch, newBranch string, patches [][]byte, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	if err := m.checkMethod("CreatePullRequestFromPatches", repo); err != nil {
+		return nil, err
+	}
+	parsed, err := client.ParsePatches(patches)
+	if err != nil {
+		return nil, err
+	}
+	head, err := m.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CreateBranch(ctx, repo, newBranch, head); err != nil {
+		return nil, err
+	}
+	m.branchHeads[key(repo, newBranch)] = head
+	for i, p := range parsed {
+		changes, err := p.Changes(func(path string) ([]byte, error) {
+			if b, ok := m.currentContents(repo, path, newBranch); ok {
+				return b, nil
+			}
+			if b, ok := m.currentContents(repo, path, baseBranch); ok && !m.deletedFiles[key(repo, path, newBranch)] {
+				return b, nil
+			}
+			return nil, notFound("failed to get file %s from repo %s ref %s", path, repo, newBranch)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch %d: %w", i+1, err)
+		}
+		message := p.Message
+		if message == "" {
+			message = inp.Title
+		}
+		if _, err := m.UpdateFiles(ctx, repo, newBranch, message, p.Author, changes); err != nil {
+			return nil, fmt.Errorf("failed to commit patch %d: %w", i+1, err)
+		}
+	}
+	pr := *inp
+	pr.Source, pr.Target = newBranch, baseBranch
+	return m.CreatePullRequest(ctx, repo, &pr)
+}
@@ -0,0 +1,122 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListReleaseAssets implements the client.GitClient interface.
+//
+// The assets added with AddReleaseAsset for the release are returned in the
+// order they were added.
+func (m *MockClient) ListReleaseAssets(ctx context.Context, repo string, releaseID int) ([]*client.ReleaseAsset, error) {
+	if err := m.checkMethod("ListReleaseAssets", repo); err != nil {
+		return nil, err
+	}
+	assets := m.releaseAssets[key(repo, strconv.Itoa(releaseID))]
+	n, err := m.limitItems(len(assets))
+	return assets[:n], err
+}
+
+// DownloadReleaseAsset implements the client.GitClient interface.
+func (m *MockClient) DownloadReleaseAsset(ctx context.Context, repo string, assetID int, w io.Writer) error {
+	if err := m.checkMethod("DownloadReleaseAsset", repo); err != nil {
+		return err
+	}
+	b, ok := m.assetContents[key(repo, strconv.Itoa(assetID))]
+	if !ok {
+		return notFound("failed to download asset %d from repo %s", assetID, repo)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// UploadReleaseAsset implements the client.GitClient interface.
+//
+// The asset is attached to the release like with AddReleaseAsset, with the next
+// free ID, and can be checked with AssertReleaseAsset.
+func (m *MockClient) UploadReleaseAsset(ctx context.Context, repo string, releaseID int, name, contentType string, r io.Reader) (*client.ReleaseAsset, error) {
+	if err := m.checkMethod("UploadReleaseAsset", repo); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload asset %s to repo %s: %w", name, repo, err)
+	}
+	id := len(m.assetContents) + 1
+	for {
+		if _, ok := m.assetContents[key(repo, strconv.Itoa(id))]; !ok {
+			break
+		}
+		id++
+	}
+	asset := &client.ReleaseAsset{
+		ID:          id,
+		Name:        name,
+		ContentType: contentType,
+		DownloadURL: fmt.Sprintf("https://example.com/%s/releases/download/%d/%s", repo, releaseID, name),
+	}
+	m.AddReleaseAsset(repo, releaseID, asset, b)
+	return asset, nil
+}
+
+// AssertReleaseAsset is a mock method for checking that an asset with the name
+// was attached to the release, and returns its content.
+func (m *MockClient) AssertReleaseAsset(repo string, releaseID int, name string) []byte {
+	m.t.Helper()
+	for _, a := range m.releaseAssets[key(repo, strconv.Itoa(releaseID))] {
+		if a.Name == name {
+			return m.assetContents[key(repo, strconv.Itoa(a.ID))]
+		}
+	}
+	m.t.Fatalf("asset %s not attached to release %d in repo %s", name, releaseID, repo)
+	return nil
+}
+
+// AddReleaseAsset is a mock method for attaching an asset with the content to
+// the release, the size of the asset is set to the size of the content.
+func (m *MockClient) AddReleaseAsset(repo string, releaseID int, asset *client.ReleaseAsset, content []byte) {
+	asset.Size = len(content)
+	k := key(repo, strconv.Itoa(releaseID))
+	m.releaseAssets[k] = append(m.releaseAssets[k], asset)
+	m.assetContents[key(repo, strconv.Itoa(asset.ID))] = content
+}
+
+// LatestRelease implements the client.GitClient interface.
+//
+// The release is selected with client.SelectLatestRelease from the releases
+// added with AddRelease, or from the tags added with AddTag if the repo has no
+// releases.
+func (m *MockClient) LatestRelease(ctx context.Context, repo string, includePrereleases bool, opts ...client.ReleaseOption) (*scm.Release, error) {
+	if err := m.checkMethod("LatestRelease", repo); err != nil {
+		return nil, err
+	}
+	releases := m.releases[repo]
+	if len(releases) == 0 {
+		for _, tag := range m.tags[repo] {
+			releases = append(releases, &scm.Release{Tag: tag.Name, Title: tag.Name, Commitish: tag.Sha})
+		}
+	}
+	latest := client.SelectLatestRelease(releases, includePrereleases, opts...)
+	if latest == nil {
+		return nil, client.SCMError{
+			Msg:    fmt.Sprintf("failed to find latest release in repo %s", repo),
+			Status: http.StatusNotFound,
+			Err:    client.ErrNotFound,
+		}
+	}
+	return latest, nil
+}
+
+// AddRelease is a mock method for setting up a release returned by
+// LatestRelease.
+func (m *MockClient) AddRelease(repo string, release *scm.Release) {
+	m.releases[repo] = append(m.releases[repo], release)
+}
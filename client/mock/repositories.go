@@ -0,0 +1,318 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListRepositories implements the client.GitClient interface.
+//
+// The repositories added with AddRepository and AddForkedRepository for the
+// org are returned in the order they were added.
+func (m *MockClient) ListRepositories(ctx context.Context, org string, opts client.RepositoryListOptions) ([]*scm.Repository, error) {
+	if m.ListRepositoriesErr != nil {
+		return nil, m.ListRepositoriesErr
+	}
+	var repos []*scm.Repository
+	for _, repo := range m.repositories[org] {
+		if (opts.ExcludeArchived && repo.Archived) || (opts.ExcludeForks && m.forkedRepositories[repo]) {
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	n, err := m.limitItems(len(repos))
+	return repos[:n], err
+}
+
+// AddRepository adds a repository to the org, an empty org adds it to the
+// repositories of the authenticated user.
+func (m *MockClient) AddRepository(org string, repo *scm.Repository) {
+	m.repositories[org] = append(m.repositories[org], repo)
+}
+
+// AddForkedRepository adds a repository that is a fork to the org.
+func (m *MockClient) AddForkedRepository(org string, repo *scm.Repository) {
+	m.AddRepository(org, repo)
+	m.forkedRepositories[repo] = true
+}
+
+// SetRepositoryArchived implements the client.GitClient interface.
+//
+// The Archived field of the repository is updated if it was added with
+// AddRepository, and writes to an archived repository are rejected with
+// client.ErrArchived.
+func (m *MockClient) SetRepositoryArchived(ctx context.Context, repo string, archived bool) error {
+	if err := m.checkMethod("SetRepositoryArchived", repo); err != nil {
+		return err
+	}
+	m.archived[repo] = archived
+	for _, repos := range m.repositories {
+		for _, r := range repos {
+			if scm.Join(r.Namespace, r.Name) == repo {
+				r.Archived = archived
+			}
+		}
+	}
+	return nil
+}
+
+// SetRepositoryDisabled is a mock method for disabling the repo, which is
+// only checked with ArchivedCheck.
+func (m *MockClient) SetRepositoryDisabled(repo string, disabled bool) {
+	m.disabled[repo] = disabled
+}
+
+// checkArchived returns an error like the client.WithArchivedCheck option if
+// the repo is archived or disabled.
+func (m *MockClient) checkArchived(repo string) error {
+	if m.disabled[repo] {
+		return fmt.Errorf("repo %s: %w", repo, client.ErrRepoDisabled)
+	}
+	archived := m.archived[repo]
+	for _, repos := range m.repositories {
+		for _, r := range repos {
+			if scm.Join(r.Namespace, r.Name) == repo && r.Archived {
+				archived = true
+			}
+		}
+	}
+	if archived {
+		return fmt.Errorf("repo %s: %w", repo, client.ErrArchived)
+	}
+	return nil
+}
+
+// AssertRepositoryArchived fails if the archived state of the repo set with
+// SetRepositoryArchived doesn't match.
+func (m *MockClient) AssertRepositoryArchived(repo string, archived bool) {
+	m.t.Helper()
+	if m.archived[repo] != archived {
+		m.t.Fatalf("repo %s archived is %v, want %v", repo, m.archived[repo], archived)
+	}
+}
+
+// Star implements the client.GitClient interface.
+func (m *MockClient) Star(ctx context.Context, repo string) error {
+	if err := m.checkMethod("Star", repo); err != nil {
+		return err
+	}
+	m.starred[repo] = true
+	return nil
+}
+
+// Unstar implements the client.GitClient interface.
+func (m *MockClient) Unstar(ctx context.Context, repo string) error {
+	if err := m.checkMethod("Unstar", repo); err != nil {
+		return err
+	}
+	delete(m.starred, repo)
+	return nil
+}
+
+// IsStarred implements the client.GitClient interface.
+func (m *MockClient) IsStarred(ctx context.Context, repo string) (bool, error) {
+	if err := m.checkMethod("IsStarred", repo); err != nil {
+		return false, err
+	}
+	return m.starred[repo], nil
+}
+
+// AssertStarred fails if the repo is not starred.
+func (m *MockClient) AssertStarred(repo string) {
+	m.t.Helper()
+	if !m.starred[repo] {
+		m.t.Fatalf("repo %s is not starred", repo)
+	}
+}
+
+// RefuteStarred fails if the repo is starred.
+func (m *MockClient) RefuteStarred(repo string) {
+	m.t.Helper()
+	if m.starred[repo] {
+		m.t.Fatalf("repo %s is starred", repo)
+	}
+}
+
+// GetRepositoryTopics implements the client.GitClient interface.
+func (m *MockClient) GetRepositoryTopics(ctx context.Context, repo string) ([]string, error) {
+	if err := m.checkMethod("GetRepositoryTopics", repo); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), m.topics[repo]...), nil
+}
+
+// SetRepositoryTopics implements the client.GitClient interface.
+func (m *MockClient) SetRepositoryTopics(ctx context.Context, repo string, topics []string) error {
+	if err := m.checkMethod("SetRepositoryTopics", repo); err != nil {
+		return err
+	}
+	m.topics[repo] = append([]string(nil), topics...)
+	return nil
+}
+
+// AssertRepositoryTopic fails if the repo doesn't have the topic.
+func (m *MockClient) AssertRepositoryTopic(repo, topic string) {
+	m.t.Helper()
+	for _, t := range m.topics[repo] {
+		if t == topic {
+			return
+		}
+	}
+	m.t.Fatalf("repo %s doesn't have topic %s", repo, topic)
+}
+
+// GetLanguages implements the client.GitClient interface.
+//
+// The stats added with AddLanguages are returned, repos without stats have no
+// languages.
+func (m *MockClient) GetLanguages(ctx context.Context, repo string) (map[string]int, error) {
+	if err := m.checkMethod("GetLanguages", repo); err != nil {
+		return nil, err
+	}
+	stats := map[string]int{}
+	for lang, n := range m.languages[repo] {
+		stats[lang] = n
+	}
+	return stats, nil
+}
+
+// AddLanguages is a mock method for setting up the stats returned by
+// GetLanguages, the bytes are added to any stats already added for the repo.
+func (m *MockClient) AddLanguages(repo string, stats map[string]int) {
+	if m.languages[repo] == nil {
+		m.languages[repo] = map[string]int{}
+	}
+	for lang, n := range stats {
+		m.languages[repo][lang] += n
+	}
+}
+
+// RenameRepository implements the client.GitClient interface.
+//
+// The repository added with AddRepository is renamed if there is one, and the
+// state recorded for the repo is moved to the new name, after which the old
+// name is rejected with a not found error.
+func (m *MockClient) RenameRepository(ctx context.Context, repo, newName string) (*scm.Repository, error) {
+	if err := m.checkMethod("RenameRepository", repo); err != nil {
+		return nil, err
+	}
+	namespace, _ := scm.Split(repo)
+	newRepo := scm.Join(namespace, newName)
+	renamed := &scm.Repository{Namespace: namespace, Name: newName}
+	for _, repos := range m.repositories {
+		for _, r := range repos {
+			if scm.Join(r.Namespace, r.Name) == repo {
+				r.Name = newName
+				renamed = r
+			}
+		}
+	}
+	for _, state := range m.repoState() {
+		renameKeys(state, repo, newRepo)
+	}
+	for old, renamedTo := range m.renamedRepositories {
+		if renamedTo == repo {
+			m.renamedRepositories[old] = newRepo
+		}
+	}
+	m.renamedRepositories[repo] = newRepo
+	delete(m.renamedRepositories, newRepo)
+	return renamed, nil
+}
+
+// ForkRepository implements the client.GitClient interface.
+//
+// The fork added with AddFork for the repo and org is returned, for every
+// request, as the existing fork is returned when the repo is forked again.
+// Options are ignored, the fork is always ready.
+func (m *MockClient) ForkRepository(ctx context.Context, repo, org string, opts ...client.ForkOption) (*scm.Repository, error) {
+	if err := m.checkMethod("ForkRepository", repo); err != nil {
+		return nil, err
+	}
+	fork, ok := m.forks[key(repo, org)]
+	if !ok {
+		return nil, fmt.Errorf("failed to fork repo %s into %q: no fork added with AddFork", repo, org)
+	}
+	m.forkRequests[key(repo, org)]++
+	return fork, nil
+}
+
+// AddFork is a mock method for setting up the fork returned by ForkRepository
+// for the repo and org, the fork is also added to the org like with
+// AddForkedRepository.
+func (m *MockClient) AddFork(repo, org string, fork *scm.Repository) {
+	m.forks[key(repo, org)] = fork
+	m.AddForkedRepository(org, fork)
+}
+
+// AssertForked fails if the repo was not forked into the org with
+// ForkRepository.
+func (m *MockClient) AssertForked(repo, org string) {
+	m.t.Helper()
+	if m.forkRequests[key(repo, org)] == 0 {
+		m.t.Fatalf("repo %s was not forked into %q", repo, org)
+	}
+}
+
+// AssertRepositoryName fails if the repo has not been renamed to the name with
+// RenameRepository.
+func (m *MockClient) AssertRepositoryName(repo, name string) {
+	m.t.Helper()
+	namespace, _ := scm.Split(repo)
+	if got := m.renamedRepositories[repo]; got != scm.Join(namespace, name) {
+		m.t.Fatalf("repo %s was not renamed to %s", repo, name)
+	}
+}
+
+// checkRepo validates the repo name, and rejects the old names of renamed
+// repos.
+func (m *MockClient) checkRepo(repo string) error {
+	if err := client.ValidateRepo(repo); err != nil {
+		return err
+	}
+	if newRepo, ok := m.renamedRepositories[repo]; ok {
+		return notFound("repo %s was renamed to %s", repo, newRepo)
+	}
+	return nil
+}
+
+// repoState returns the maps with state keyed by repo, new maps with keys
+// starting with the repo need to be added here so that they are renamed.
+func (m *MockClient) repoState() []interface{} {
+	return []interface{}{
+		m.files, m.updatedFiles, m.deletedFiles, m.commits, m.createdBranches,
+		m.deletedBranches, m.branchHeads, m.protectedBranches, m.branchProtection,
+		m.defaultBranches,
+		m.createdPullRequests, m.pullRequestDiffs, m.starred, m.mergeStates,
+		m.statuses, m.commitFiles, m.archived, m.mergedBranches, m.createdIssues,
+		m.issueComments, m.pullRequestsCreated, m.closedPullRequests,
+		m.deployments, m.deploymentStatuses, m.diffs, m.topics,
+		m.pullRequestCommits, m.updateMessages, m.labels, m.languages,
+		m.autoMerges, m.reviewThreads, m.reviews, m.createdStatuses,
+		m.tags, m.symlinks, m.variables, m.secrets,
+		m.commitParents, m.releaseAssets, m.assetContents, m.workflowRuns,
+		m.forks, m.forkRequests, m.blame, m.reviewComments,
+		m.actors, m.pullRequestChanges, m.mergedPullRequests, m.mergesInProgress,
+		m.checkRuns, m.mergeCommitMessages, m.environments,
+		m.lockedPullRequests, m.disabled, m.releases,
+	}
+}
+
+// renameKeys moves the entries of the map, which must have string keys, from
+// keys for the old repo to the same keys for the new repo.
+func renameKeys(state interface{}, oldRepo, newRepo string) {
+	v := reflect.ValueOf(state)
+	for _, k := range v.MapKeys() {
+		parts := splitKey(k.String())
+		if parts[0] != oldRepo {
+			continue
+		}
+		parts[0] = newRepo
+		v.SetMapIndex(reflect.ValueOf(key(parts...)), v.MapIndex(k))
+		v.SetMapIndex(k, reflect.Value{})
+	}
+}
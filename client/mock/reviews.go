@@ -0,0 +1,162 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListReviews implements the client.GitClient interface.
+//
+// The reviews added with AddReview are returned in the order they were added,
+// an unknown pull request is rejected with a 404 status.
+func (m *MockClient) ListReviews(ctx context.Context, repo string, number int) ([]*client.Review, error) {
+	if err := m.checkMethod("ListReviews", repo); err != nil {
+		return nil, err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return nil, client.SCMError{
+			Msg:    fmt.Sprintf("failed to list reviews of pull request %d in repo %s", number, repo),
+			Status: http.StatusNotFound,
+		}
+	}
+	reviews := m.reviews[key(repo, strconv.Itoa(number))]
+	n, err := m.limitItems(len(reviews))
+	return append([]*client.Review(nil), reviews[:n]...), err
+}
+
+// AddReview is a mock method for setting up a review of a pull request,
+// returned by ListReviews.
+func (m *MockClient) AddReview(repo string, number int, review *client.Review) {
+	k := key(repo, strconv.Itoa(number))
+	m.reviews[k] = append(m.reviews[k], review)
+}
+
+// ListReviewThreads implements the client.GitClient interface.
+//
+// The threads added with AddReviewThread are returned, with the state they
+// have after any calls to ResolveReviewThread.
+func (m *MockClient) ListReviewThreads(ctx context.Context, repo string, number int) ([]*client.ReviewThread, error) {
+	if err := m.checkMethod("ListReviewThreads", repo); err != nil {
+		return nil, err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return nil, notFound("failed to list review threads in pull request %d in repo %s", number, repo)
+	}
+	var threads []*client.ReviewThread
+	for _, t := range m.reviewThreads[key(repo, strconv.Itoa(number))] {
+		thread := *t
+		threads = append(threads, &thread)
+	}
+	return threads, nil
+}
+
+// ResolveReviewThread implements the client.GitClient interface.
+//
+// Only threads added with AddReviewThread can be resolved.
+func (m *MockClient) ResolveReviewThread(ctx context.Context, repo, threadID string) error {
+	if err := m.checkMethod("ResolveReviewThread", repo); err != nil {
+		return err
+	}
+	t := m.reviewThread(repo, threadID)
+	if t == nil {
+		return notFound("failed to resolve review thread %s in repo %s", threadID, repo)
+	}
+	t.Resolved = true
+	return nil
+}
+
+// AddReviewThread is a mock method for setting up a review thread on a pull
+// request, returned by ListReviewThreads.
+func (m *MockClient) AddReviewThread(repo string, number int, thread *client.ReviewThread) {
+	k := key(repo, strconv.Itoa(number))
+	t := *thread
+	m.reviewThreads[k] = append(m.reviewThreads[k], &t)
+}
+
+// AssertReviewThreadResolved fails if the review thread is not resolved.
+func (m *MockClient) AssertReviewThreadResolved(repo, threadID string) {
+	m.t.Helper()
+	t := m.reviewThread(repo, threadID)
+	if t == nil || !t.Resolved {
+		m.t.Fatalf("review thread %s in repo %s was not resolved", threadID, repo)
+	}
+}
+
+// ListReviewComments implements the client.GitClient interface.
+//
+// The comments created with CreateReviewComment and added with
+// AddReviewComment are returned in the order they were made.
+func (m *MockClient) ListReviewComments(ctx context.Context, repo string, number int) ([]*scm.Review, error) {
+	if err := m.checkMethod("ListReviewComments", repo); err != nil {
+		return nil, err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return nil, notFound("failed to list review comments of pull request %d in repo %s", number, repo)
+	}
+	comments := m.reviewComments[key(repo, strconv.Itoa(number))]
+	n, err := m.limitItems(len(comments))
+	return append([]*scm.Review(nil), comments[:n]...), err
+}
+
+// CreateReviewComment implements the client.GitClient interface.
+//
+// Comments can only be created on pull requests created with
+// CreatePullRequest, and can be checked with AssertReviewComment.
+func (m *MockClient) CreateReviewComment(ctx context.Context, repo string, number int, inp *scm.ReviewInput) error {
+	if err := m.checkMethod("CreateReviewComment", repo); err != nil {
+		return err
+	}
+	if m.pullRequest(repo, number) == nil {
+		return notFound("failed to comment on pull request %d in repo %s", number, repo)
+	}
+	k := key(repo, strconv.Itoa(number))
+	m.AddReviewComment(repo, number, &scm.Review{
+		ID:   len(m.reviewComments[k]) + 1,
+		Body: inp.Body,
+		Path: inp.Path,
+		Sha:  inp.Sha,
+		Line: inp.Line,
+	})
+	m.recordActor(ctx, repo, "CreateReviewComment")
+	return nil
+}
+
+// AddReviewComment is a mock method for setting up a review comment on a pull
+// request, returned by ListReviewComments.
+func (m *MockClient) AddReviewComment(repo string, number int, comment *scm.Review) {
+	k := key(repo, strconv.Itoa(number))
+	m.reviewComments[k] = append(m.reviewComments[k], comment)
+}
+
+// AssertReviewComment fails if no review comment was made on the line of the
+// file at the path in the pull request.
+func (m *MockClient) AssertReviewComment(repo string, number int, path string, line int) {
+	m.t.Helper()
+	for _, c := range m.reviewComments[key(repo, strconv.Itoa(number))] {
+		if c.Path == path && c.Line == line {
+			return
+		}
+	}
+	m.t.Fatalf("no review comment on line %d of %s in pull request %d in repo %s", line, path, number, repo)
+}
+
+// reviewThread returns the thread added with AddReviewThread to any pull
+// request in the repo, or nil if there is no such thread.
+func (m *MockClient) reviewThread(repo, threadID string) *client.ReviewThread {
+	for k, threads := range m.reviewThreads {
+		if splitKey(k)[0] != repo {
+			continue
+		}
+		for _, t := range threads {
+			if t.ID == threadID {
+				return t
+			}
+		}
+	}
+	return nil
+}
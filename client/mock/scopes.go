@@ -0,0 +1,34 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// GetTokenScopes implements the client.GitClient interface.
+//
+// The scopes set with SetTokenScopes are returned, the token has no scopes
+// until they are set.
+func (m *MockClient) GetTokenScopes(ctx context.Context) ([]string, error) {
+	if supported, ok := m.capabilities["GetTokenScopes"]; ok && !supported {
+		return nil, scm.ErrNotSupported
+	}
+	return append([]string(nil), m.tokenScopes...), nil
+}
+
+// HasScope implements the client.GitClient interface.
+func (m *MockClient) HasScope(ctx context.Context, scope string) (bool, error) {
+	scopes, err := m.GetTokenScopes(ctx)
+	if err != nil {
+		return false, err
+	}
+	return client.ScopesInclude(scopes, scope), nil
+}
+
+// SetTokenScopes is a mock method for setting the scopes returned by
+// GetTokenScopes.
+func (m *MockClient) SetTokenScopes(scopes ...string) {
+	m.tokenScopes = scopes
+}
@@ -0,0 +1,101 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// GetCombinedStatus implements the client.GitClient interface.
+//
+// The state is computed with client.CombinedState from the statuses added
+// with AddStatus, a later status for the same context replaces an earlier
+// one.
+func (m *MockClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*client.CombinedStatus, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	statuses := m.statuses[key(repo, ref)]
+	return &client.CombinedStatus{
+		State:    client.CombinedState(statuses),
+		Sha:      ref,
+		Statuses: append([]*scm.Status(nil), statuses...),
+	}, nil
+}
+
+// AddStatus adds a commit status for the ref.
+func (m *MockClient) AddStatus(repo, ref string, status *scm.Status) {
+	k := key(repo, ref)
+	for i, s := range m.statuses[k] {
+		if s.Label == status.Label {
+			m.statuses[k][i] = status
+			return
+		}
+	}
+	m.statuses[k] = append(m.statuses[k], status)
+}
+
+// GetChecksStatus implements the client.GitClient interface.
+//
+// The checks are the statuses added with AddStatus, followed by the check
+// runs added with AddCheckRun, and the state is computed with
+// client.ChecksState.
+func (m *MockClient) GetChecksStatus(ctx context.Context, repo, ref string) (*client.ChecksSummary, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	var checks []*client.Check
+	for _, s := range m.statuses[key(repo, ref)] {
+		checks = append(checks, &client.Check{Name: s.Label, State: s.State, Desc: s.Desc, Link: s.Target})
+	}
+	checks = append(checks, m.checkRuns[key(repo, ref)]...)
+	return &client.ChecksSummary{State: client.ChecksState(checks), Sha: ref, Checks: checks}, nil
+}
+
+// AddCheckRun adds a check run for the ref, a later check run with the same
+// name replaces an earlier one.
+func (m *MockClient) AddCheckRun(repo, ref string, check *client.Check) {
+	k := key(repo, ref)
+	run := *check
+	run.CheckRun = true
+	for i, c := range m.checkRuns[k] {
+		if c.Name == run.Name {
+			m.checkRuns[k][i] = &run
+			return
+		}
+	}
+	m.checkRuns[k] = append(m.checkRuns[k], &run)
+}
+
+// CreateStatus implements the client.GitClient interface.
+//
+// The status is added like AddStatus, so it's included in GetCombinedStatus,
+// and the input is recorded for AssertStatus.
+func (m *MockClient) CreateStatus(ctx context.Context, repo, ref string, inp *scm.StatusInput) (*scm.Status, error) {
+	if err := m.checkMethod("CreateStatus", repo); err != nil {
+		return nil, err
+	}
+	ref = m.resolveRef(repo, ref)
+	k := key(repo, ref)
+	recorded := *inp
+	m.createdStatuses[k] = append(m.createdStatuses[k], &recorded)
+	status := &scm.Status{State: inp.State, Label: inp.Label, Desc: inp.Desc, Target: inp.Target}
+	m.AddStatus(repo, ref, status)
+	return status, nil
+}
+
+// AssertStatus fails if no status matching every field of the input,
+// including the description and target URL, was created for the ref with
+// CreateStatus.
+func (m *MockClient) AssertStatus(repo, ref string, want *scm.StatusInput) {
+	m.t.Helper()
+	for _, inp := range m.createdStatuses[key(repo, ref)] {
+		if *inp == *want {
+			return
+		}
+	}
+	m.t.Fatalf("no status %+v created for ref %s in repo %s, got %v", *want, ref, repo, m.createdStatuses[key(repo, ref)])
+}
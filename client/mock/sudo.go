@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/ocraviotto/pkg/client"
+)
+
+// SetSudo makes the mock act on behalf of the user, like the client.WithSudo
+// option, a user in the context from client.WithContextSudo takes precedence.
+func (m *MockClient) SetSudo(username string) {
+	m.sudo = username
+}
+
+// recordActor records the user that the method acted on behalf of in the
+// repo, the user from the context, or the one set with SetSudo.
+func (m *MockClient) recordActor(ctx context.Context, repo, method string) {
+	actor := client.ContextSudo(ctx)
+	if actor == "" {
+		actor = m.sudo
+	}
+	m.actors[key(repo, method)] = append(m.actors[key(repo, method)], actor)
+}
+
+// AssertActor fails if the method wasn't called on the repo on behalf of the
+// user, an empty user matches calls made without impersonation.
+//
+// The users are recorded by the methods that commit files, and create pull
+// requests, issues and comments.
+func (m *MockClient) AssertActor(repo, method, username string) {
+	m.t.Helper()
+	for _, actor := range m.actors[key(repo, method)] {
+		if actor == username {
+			return
+		}
+	}
+	m.t.Fatalf("%s not called in repo %s on behalf of %q, got %q", method, repo, username, m.actors[key(repo, method)])
+}
@@ -0,0 +1,27 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListTagsWithCommits implements the client.GitClient interface.
+//
+// The tags added with AddTag are returned in the order they were added,
+// regardless of the page in the options.
+func (m *MockClient) ListTagsWithCommits(ctx context.Context, repo string, opts scm.ListOptions) ([]*client.TagInfo, error) {
+	if err := m.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	tags := m.tags[repo]
+	n, err := m.limitItems(len(tags))
+	return append([]*client.TagInfo(nil), tags[:n]...), err
+}
+
+// AddTag is a mock method for setting up a tag returned by
+// ListTagsWithCommits.
+func (m *MockClient) AddTag(repo string, tag *client.TagInfo) {
+	m.tags[repo] = append(m.tags[repo], tag)
+}
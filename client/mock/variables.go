@@ -0,0 +1,59 @@
+package mock
+
+import "context"
+
+// SetRepositoryVariable implements the client.GitClient interface.
+func (m *MockClient) SetRepositoryVariable(ctx context.Context, repo, name, value string) error {
+	if err := m.checkMethod("SetRepositoryVariable", repo); err != nil {
+		return err
+	}
+	m.variables[key(repo, name)] = value
+	return nil
+}
+
+// ListRepositoryVariables implements the client.GitClient interface.
+func (m *MockClient) ListRepositoryVariables(ctx context.Context, repo string) (map[string]string, error) {
+	if err := m.checkMethod("ListRepositoryVariables", repo); err != nil {
+		return nil, err
+	}
+	variables := map[string]string{}
+	for k, value := range m.variables {
+		if parts := splitKey(k); parts[0] == repo {
+			variables[parts[1]] = value
+		}
+	}
+	return variables, nil
+}
+
+// AssertRepositoryVariable fails if the variable of the repo was not set to
+// the value.
+func (m *MockClient) AssertRepositoryVariable(repo, name, value string) {
+	m.t.Helper()
+	got, ok := m.variables[key(repo, name)]
+	if !ok {
+		m.t.Fatalf("variable %s not set in repo %s", name, repo)
+	}
+	if got != value {
+		m.t.Fatalf("variable %s in repo %s is %q, want %q", name, repo, got, value)
+	}
+}
+
+// SetRepositorySecret implements the client.GitClient interface.
+//
+// Only the name of the secret is recorded, like the upstream services, the
+// value can't be read back.
+func (m *MockClient) SetRepositorySecret(ctx context.Context, repo, name, value string) error {
+	if err := m.checkMethod("SetRepositorySecret", repo); err != nil {
+		return err
+	}
+	m.secrets[key(repo, name)] = true
+	return nil
+}
+
+// AssertRepositorySecret fails if the secret was not set in the repo.
+func (m *MockClient) AssertRepositorySecret(repo, name string) {
+	m.t.Helper()
+	if !m.secrets[key(repo, name)] {
+		m.t.Fatalf("secret %s not set in repo %s", name, repo)
+	}
+}
@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/pkg/client"
+)
+
+// ListWorkflowRuns implements the client.GitClient interface.
+//
+// The runs added with AddWorkflowRun whose head branch or head SHA is the ref
+// are returned, or all of them if the ref is empty, regardless of the page in
+// the options.
+func (m *MockClient) ListWorkflowRuns(ctx context.Context, repo, ref string, opts scm.ListOptions) ([]*client.WorkflowRun, error) {
+	if err := m.checkMethod("ListWorkflowRuns", repo); err != nil {
+		return nil, err
+	}
+	var runs []*client.WorkflowRun
+	for _, r := range m.workflowRuns[repo] {
+		if ref == "" || r.HeadBranch == ref || r.HeadSha == ref {
+			runs = append(runs, r)
+		}
+	}
+	n, err := m.limitItems(len(runs))
+	return runs[:n], err
+}
+
+// AddWorkflowRun is a mock method for setting up a workflow run returned by
+// ListWorkflowRuns, runs are returned in the order they were added.
+func (m *MockClient) AddWorkflowRun(repo string, run *client.WorkflowRun) {
+	m.workflowRuns[repo] = append(m.workflowRuns[repo], run)
+}
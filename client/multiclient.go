@@ -0,0 +1,900 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+var _ GitClient = (*MultiClient)(nil)
+
+// MatchFunc returns the prefix of the repo that selects the client for it,
+// and the repo name without the prefix, or false if the repo has no prefix.
+type MatchFunc func(repo string) (prefix, name string, ok bool)
+
+// MatchColonPrefix is a MatchFunc for repos with the prefix separated by a
+// colon, e.g. "github:owner/repo" has the prefix "github".
+func MatchColonPrefix(repo string) (prefix, name string, ok bool) {
+	i := strings.Index(repo, ":")
+	if i < 0 {
+		return "", repo, false
+	}
+	return repo[:i], repo[i+1:], true
+}
+
+// MultiClient is a GitClient that routes each call to one of several
+// clients, selected by the prefix of the repo, and calls it with the repo
+// name without the prefix.
+//
+// ListRepositories is routed by the prefix of the org, e.g. "gitlab:group",
+// or "gitlab:" for the repositories of the authenticated user.
+type MultiClient struct {
+	clients map[string]GitClient
+	match   MatchFunc
+}
+
+// NewMultiClient creates and returns a new MultiClient that routes repos to
+// the clients keyed by the prefix that the match func returns for them, a nil
+// match func uses MatchColonPrefix.
+func NewMultiClient(clients map[string]GitClient, match MatchFunc) *MultiClient {
+	if match == nil {
+		match = MatchColonPrefix
+	}
+	return &MultiClient{clients: clients, match: match}
+}
+
+// route returns the client for the repo and the repo name to call it with,
+// or an error wrapping ErrInvalidRepo if no client matches the repo.
+func (m *MultiClient) route(repo string) (GitClient, string, error) {
+	prefix, name, ok := m.match(repo)
+	if !ok {
+		return nil, "", invalidRepo(repo, "has no client prefix")
+	}
+	c, ok := m.clients[prefix]
+	if !ok {
+		return nil, "", invalidRepo(repo, fmt.Sprintf("has no client for prefix %q", prefix))
+	}
+	return c, name, nil
+}
+
+// routeTo returns the repo name to call the client with for a repo that is
+// used along with a routed repo, repos without a prefix are used unchanged,
+// and repos with a prefix must be routed to the same client.
+func (m *MultiClient) routeTo(c GitClient, repo string) (string, error) {
+	if _, _, ok := m.match(repo); !ok {
+		return repo, nil
+	}
+	other, name, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	if other != c {
+		return "", invalidRepo(repo, "is routed to a different client")
+	}
+	return name, nil
+}
+
+// Batch returns a new Batcher that commits through this client, so the repo
+// passed to Commit is routed like the repos of the other methods.
+func (m *MultiClient) Batch() *Batcher {
+	return NewBatcher(m)
+}
+
+// Supports returns true if every client supports the feature.
+func (m *MultiClient) Supports(feature Feature) bool {
+	for _, c := range m.clients {
+		if !c.Supports(feature) {
+			return false
+		}
+	}
+	return true
+}
+
+// Capabilities returns whether every client supports each of the methods
+// that are only implemented for some drivers, keyed by the method name.
+func (m *MultiClient) Capabilities() map[string]bool {
+	capabilities := map[string]bool{}
+	for _, c := range m.clients {
+		for method, ok := range c.Capabilities() {
+			if v, seen := capabilities[method]; !seen || v {
+				capabilities[method] = ok
+			}
+		}
+	}
+	return capabilities
+}
+
+// GetTokenScopes fails with ErrNotSupported, as the token depends on the
+// client, use the client for a prefix to get the scopes of its token.
+func (m *MultiClient) GetTokenScopes(ctx context.Context) ([]string, error) {
+	return nil, scm.ErrNotSupported
+}
+
+// HasScope fails with ErrNotSupported, like GetTokenScopes.
+func (m *MultiClient) HasScope(ctx context.Context, scope string) (bool, error) {
+	return false, scm.ErrNotSupported
+}
+
+// CreateGist fails with ErrNotSupported, as gists don't belong to a repo, use
+// the client for a prefix to create gists.
+func (m *MultiClient) CreateGist(ctx context.Context, inp *GistInput) (*Gist, error) {
+	return nil, scm.ErrNotSupported
+}
+
+// GetGist fails with ErrNotSupported, like CreateGist.
+func (m *MultiClient) GetGist(ctx context.Context, id string) (*Gist, error) {
+	return nil, scm.ErrNotSupported
+}
+
+// GetFile implements the GitClient interface.
+func (m *MultiClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFile(ctx, repo, ref, path)
+}
+
+// GetFileNormalized implements the GitClient interface.
+func (m *MultiClient) GetFileNormalized(ctx context.Context, repo, ref, path string, normalize func([]byte) ([]byte, error)) (*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFileNormalized(ctx, repo, ref, path, normalize)
+}
+
+// GetFileAtCommit implements the GitClient interface.
+func (m *MultiClient) GetFileAtCommit(ctx context.Context, repo, sha, path string) (*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFileAtCommit(ctx, repo, sha, path)
+}
+
+// GetFileRaw implements the GitClient interface.
+func (m *MultiClient) GetFileRaw(ctx context.Context, repo, ref, path string) ([]byte, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFileRaw(ctx, repo, ref, path)
+}
+
+// GetFileStream implements the GitClient interface.
+func (m *MultiClient) GetFileStream(ctx context.Context, repo, ref, path string) (io.ReadCloser, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFileStream(ctx, repo, ref, path)
+}
+
+// FilesEqual implements the GitClient interface.
+func (m *MultiClient) FilesEqual(ctx context.Context, repo, path, refA, refB string) (bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, err
+	}
+	return c.FilesEqual(ctx, repo, path, refA, refB)
+}
+
+// GetFilesAtPaths implements the GitClient interface.
+func (m *MultiClient) GetFilesAtPaths(ctx context.Context, repo, ref string, paths []string) (map[string]*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFilesAtPaths(ctx, repo, ref, paths)
+}
+
+// GetReadme implements the GitClient interface.
+func (m *MultiClient) GetReadme(ctx context.Context, repo, ref string) (*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetReadme(ctx, repo, ref)
+}
+
+// GetFilePermalink implements the GitClient interface.
+func (m *MultiClient) GetFilePermalink(ctx context.Context, repo, ref, path string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.GetFilePermalink(ctx, repo, ref, path)
+}
+
+// GetBlame implements the GitClient interface.
+func (m *MultiClient) GetBlame(ctx context.Context, repo, ref, path string) ([]*BlameHunk, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBlame(ctx, repo, ref, path)
+}
+
+// ListFiles implements the GitClient interface.
+func (m *MultiClient) ListFiles(ctx context.Context, repo, ref, path string) ([]*scm.ContentInfo, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListFiles(ctx, repo, ref, path)
+}
+
+// ReadDir implements the GitClient interface.
+func (m *MultiClient) ReadDir(ctx context.Context, repo, ref, path string) (map[string]*scm.Content, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadDir(ctx, repo, ref, path)
+}
+
+// UpdateFile implements the GitClient interface.
+func (m *MultiClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte, opts ...WriteOption) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.UpdateFile(ctx, repo, branch, path, message, previousSHA, signature, content, opts...)
+}
+
+// UpdateFileWithRetry implements the GitClient interface.
+func (m *MultiClient) UpdateFileWithRetry(ctx context.Context, repo, branch, path, message string, signature scm.Signature, transform func(existing []byte) ([]byte, error)) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.UpdateFileWithRetry(ctx, repo, branch, path, message, signature, transform)
+}
+
+// SyncFile implements the GitClient interface.
+func (m *MultiClient) SyncFile(ctx context.Context, repo, branch, path, message string, signature scm.Signature, want []byte, opts ...WriteOption) (changed bool, sha string, err error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, "", err
+	}
+	return c.SyncFile(ctx, repo, branch, path, message, signature, want, opts...)
+}
+
+// UpdateFiles implements the GitClient interface.
+func (m *MultiClient) UpdateFiles(ctx context.Context, repo, branch, message string, signature scm.Signature, changes []FileChange, opts ...WriteOption) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.UpdateFiles(ctx, repo, branch, message, signature, changes, opts...)
+}
+
+// PreviewCommit implements the GitClient interface.
+func (m *MultiClient) PreviewCommit(ctx context.Context, repo, baseBranch string, changes []FileChange) (*CommitPreview, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.PreviewCommit(ctx, repo, baseBranch, changes)
+}
+
+// CommitTemplate implements the GitClient interface.
+func (m *MultiClient) CommitTemplate(ctx context.Context, repo, branch, path, message string, signature scm.Signature, tmpl *template.Template, data interface{}) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.CommitTemplate(ctx, repo, branch, path, message, signature, tmpl, data)
+}
+
+// SyncDir implements the GitClient interface.
+func (m *MultiClient) SyncDir(ctx context.Context, repo, branch, dir string, desired map[string][]byte, signature scm.Signature, message string) (created, updated, deleted int, sha string, err error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	return c.SyncDir(ctx, repo, branch, dir, desired, signature, message)
+}
+
+// CommitDir implements the GitClient interface.
+func (m *MultiClient) CommitDir(ctx context.Context, repo, branch, message string, signature scm.Signature, localDir, repoPrefix string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.CommitDir(ctx, repo, branch, message, signature, localDir, repoPrefix)
+}
+
+// DeleteFile implements the GitClient interface.
+func (m *MultiClient) DeleteFile(ctx context.Context, repo, branch, path, message, previousSHA string, signature scm.Signature, content []byte) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.DeleteFile(ctx, repo, branch, path, message, previousSHA, signature, content)
+}
+
+// CreatePullRequest implements the GitClient interface.
+func (m *MultiClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput, opts ...PullRequestOption) (*scm.PullRequest, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreatePullRequest(ctx, repo, inp, opts...)
+}
+
+// CreatePullRequestIfChanged implements the GitClient interface.
+func (m *MultiClient) CreatePullRequestIfChanged(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.CreatePullRequestIfChanged(ctx, repo, inp)
+}
+
+// CreatePullRequestFromPatches implements the GitClient interface.
+func (m *MultiClient) CreatePullRequestFromPatches(ctx context.Context, repo, baseBranch, newBranch string, patches [][]byte, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreatePullRequestFromPatches(ctx, repo, baseBranch, newBranch, patches, inp)
+}
+
+// CreateForkPullRequest implements the GitClient interface.
+func (m *MultiClient) CreateForkPullRequest(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	c, upstreamRepo, err := m.route(upstreamRepo)
+	if err != nil {
+		return nil, err
+	}
+	if headRepo, err = m.routeTo(c, headRepo); err != nil {
+		return nil, err
+	}
+	return c.CreateForkPullRequest(ctx, upstreamRepo, headRepo, headBranch, baseBranch, inp)
+}
+
+// GetPullRequest implements the GitClient interface.
+func (m *MultiClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetPullRequest(ctx, repo, number)
+}
+
+// ListPullRequestCommits implements the GitClient interface.
+func (m *MultiClient) ListPullRequestCommits(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Commit, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListPullRequestCommits(ctx, repo, number, opts)
+}
+
+// EnableAutoMerge implements the GitClient interface.
+func (m *MultiClient) EnableAutoMerge(ctx context.Context, repo string, number int, method MergeMethod) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.EnableAutoMerge(ctx, repo, number, method)
+}
+
+// MergePullRequest implements the GitClient interface.
+func (m *MultiClient) MergePullRequest(ctx context.Context, repo string, number int, method MergeMethod, opts ...MergeOption) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.MergePullRequest(ctx, repo, number, method, opts...)
+}
+
+// SetPullRequestBase implements the GitClient interface.
+func (m *MultiClient) SetPullRequestBase(ctx context.Context, repo string, number int, newBase string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.SetPullRequestBase(ctx, repo, number, newBase)
+}
+
+// LockPullRequest implements the GitClient interface.
+func (m *MultiClient) LockPullRequest(ctx context.Context, repo string, number int, reason string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.LockPullRequest(ctx, repo, number, reason)
+}
+
+// UnlockPullRequest implements the GitClient interface.
+func (m *MultiClient) UnlockPullRequest(ctx context.Context, repo string, number int) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.UnlockPullRequest(ctx, repo, number)
+}
+
+// ListReviews implements the GitClient interface.
+func (m *MultiClient) ListReviews(ctx context.Context, repo string, number int) ([]*Review, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListReviews(ctx, repo, number)
+}
+
+// ListReviewThreads implements the GitClient interface.
+func (m *MultiClient) ListReviewThreads(ctx context.Context, repo string, number int) ([]*ReviewThread, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListReviewThreads(ctx, repo, number)
+}
+
+// ResolveReviewThread implements the GitClient interface.
+func (m *MultiClient) ResolveReviewThread(ctx context.Context, repo, threadID string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.ResolveReviewThread(ctx, repo, threadID)
+}
+
+// ListReviewComments implements the GitClient interface.
+func (m *MultiClient) ListReviewComments(ctx context.Context, repo string, number int) ([]*scm.Review, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListReviewComments(ctx, repo, number)
+}
+
+// CreateReviewComment implements the GitClient interface.
+func (m *MultiClient) CreateReviewComment(ctx context.Context, repo string, number int, inp *scm.ReviewInput) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.CreateReviewComment(ctx, repo, number, inp)
+}
+
+// IsPullRequestMergeable implements the GitClient interface.
+func (m *MultiClient) IsPullRequestMergeable(ctx context.Context, repo string, number int) (bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, err
+	}
+	return c.IsPullRequestMergeable(ctx, repo, number)
+}
+
+// WaitForMergeable implements the GitClient interface.
+func (m *MultiClient) WaitForMergeable(ctx context.Context, repo string, number int, poll time.Duration) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.WaitForMergeable(ctx, repo, number, poll)
+}
+
+// GetPullRequestDiff implements the GitClient interface.
+func (m *MultiClient) GetPullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.GetPullRequestDiff(ctx, repo, number)
+}
+
+// CountOpenPullRequests implements the GitClient interface.
+func (m *MultiClient) CountOpenPullRequests(ctx context.Context, repo string) (int, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return 0, err
+	}
+	return c.CountOpenPullRequests(ctx, repo)
+}
+
+// ClosePullRequestsOlderThan implements the GitClient interface.
+func (m *MultiClient) ClosePullRequestsOlderThan(ctx context.Context, repo string, d time.Duration, filter func(*scm.PullRequest) bool) (int, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return 0, err
+	}
+	return c.ClosePullRequestsOlderThan(ctx, repo, d, filter)
+}
+
+// AddLabelsToMatching implements the GitClient interface.
+func (m *MultiClient) AddLabelsToMatching(ctx context.Context, repo string, match func(*scm.PullRequest) bool, labels []string) (int, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return 0, err
+	}
+	return c.AddLabelsToMatching(ctx, repo, match, labels)
+}
+
+// GetCodeOwners implements the GitClient interface.
+func (m *MultiClient) GetCodeOwners(ctx context.Context, repo, ref string) (*CodeOwners, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetCodeOwners(ctx, repo, ref)
+}
+
+// SuggestReviewers implements the GitClient interface.
+func (m *MultiClient) SuggestReviewers(ctx context.Context, repo string, number int) ([]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.SuggestReviewers(ctx, repo, number)
+}
+
+// CreateIssue implements the GitClient interface.
+func (m *MultiClient) CreateIssue(ctx context.Context, repo string, inp *scm.IssueInput) (*scm.Issue, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateIssue(ctx, repo, inp)
+}
+
+// CreateIssueComment implements the GitClient interface.
+func (m *MultiClient) CreateIssueComment(ctx context.Context, repo string, number int, body string) (*scm.Comment, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateIssueComment(ctx, repo, number, body)
+}
+
+// GetCombinedStatus implements the GitClient interface.
+func (m *MultiClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*CombinedStatus, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetCombinedStatus(ctx, repo, ref)
+}
+
+// GetChecksStatus implements the GitClient interface.
+func (m *MultiClient) GetChecksStatus(ctx context.Context, repo, ref string) (*ChecksSummary, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetChecksStatus(ctx, repo, ref)
+}
+
+// ListReleaseAssets implements the GitClient interface.
+func (m *MultiClient) ListReleaseAssets(ctx context.Context, repo string, releaseID int) ([]*ReleaseAsset, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListReleaseAssets(ctx, repo, releaseID)
+}
+
+// DownloadReleaseAsset implements the GitClient interface.
+func (m *MultiClient) DownloadReleaseAsset(ctx context.Context, repo string, assetID int, w io.Writer) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.DownloadReleaseAsset(ctx, repo, assetID, w)
+}
+
+// UploadReleaseAsset implements the GitClient interface.
+func (m *MultiClient) UploadReleaseAsset(ctx context.Context, repo string, releaseID int, name, contentType string, r io.Reader) (*ReleaseAsset, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.UploadReleaseAsset(ctx, repo, releaseID, name, contentType, r)
+}
+
+// LatestRelease implements the GitClient interface.
+func (m *MultiClient) LatestRelease(ctx context.Context, repo string, includePrereleases bool, opts ...ReleaseOption) (*scm.Release, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.LatestRelease(ctx, repo, includePrereleases, opts...)
+}
+
+// ListTagsWithCommits implements the GitClient interface.
+func (m *MultiClient) ListTagsWithCommits(ctx context.Context, repo string, opts scm.ListOptions) ([]*TagInfo, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListTagsWithCommits(ctx, repo, opts)
+}
+
+// CreateStatus implements the GitClient interface.
+func (m *MultiClient) CreateStatus(ctx context.Context, repo, ref string, inp *scm.StatusInput) (*scm.Status, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateStatus(ctx, repo, ref, inp)
+}
+
+// GetDiff implements the GitClient interface.
+func (m *MultiClient) GetDiff(ctx context.Context, repo, base, head string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.GetDiff(ctx, repo, base, head)
+}
+
+// ChangedFilesBetween implements the GitClient interface.
+func (m *MultiClient) ChangedFilesBetween(ctx context.Context, repo, base, head string) ([]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ChangedFilesBetween(ctx, repo, base, head)
+}
+
+// GetCommitFiles implements the GitClient interface.
+func (m *MultiClient) GetCommitFiles(ctx context.Context, repo, sha string, opts scm.ListOptions) ([]*scm.Change, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetCommitFiles(ctx, repo, sha, opts)
+}
+
+// CreateBranch implements the GitClient interface.
+func (m *MultiClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.CreateBranch(ctx, repo, branch, sha)
+}
+
+// CreateBranchIfBaseMatches implements the GitClient interface.
+func (m *MultiClient) CreateBranchIfBaseMatches(ctx context.Context, repo, branch, baseBranch, expectedBaseSHA string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.CreateBranchIfBaseMatches(ctx, repo, branch, baseBranch, expectedBaseSHA)
+}
+
+// GetBranchHead implements the GitClient interface.
+func (m *MultiClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.GetBranchHead(ctx, repo, branch)
+}
+
+// GetBranchHeads implements the GitClient interface.
+func (m *MultiClient) GetBranchHeads(ctx context.Context, repo string, branches []string) (map[string]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBranchHeads(ctx, repo, branches)
+}
+
+// DeleteBranchesByPrefix implements the GitClient interface.
+func (m *MultiClient) DeleteBranchesByPrefix(ctx context.Context, repo, prefix string) (int, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return 0, err
+	}
+	return c.DeleteBranchesByPrefix(ctx, repo, prefix)
+}
+
+// BranchesWithoutOpenPRs implements the GitClient interface.
+func (m *MultiClient) BranchesWithoutOpenPRs(ctx context.Context, repo, prefix string) ([]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.BranchesWithoutOpenPRs(ctx, repo, prefix)
+}
+
+// MergeBase implements the GitClient interface.
+func (m *MultiClient) MergeBase(ctx context.Context, repo, ref1, ref2 string) (string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return "", err
+	}
+	return c.MergeBase(ctx, repo, ref1, ref2)
+}
+
+// IsBranchMerged implements the GitClient interface.
+func (m *MultiClient) IsBranchMerged(ctx context.Context, repo, branch, baseBranch string) (bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, err
+	}
+	return c.IsBranchMerged(ctx, repo, branch, baseBranch)
+}
+
+// IsBranchProtected implements the GitClient interface.
+func (m *MultiClient) IsBranchProtected(ctx context.Context, repo, branch string) (bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, err
+	}
+	return c.IsBranchProtected(ctx, repo, branch)
+}
+
+// GetBranchProtection implements the GitClient interface.
+func (m *MultiClient) GetBranchProtection(ctx context.Context, repo, branch string) (*BranchProtection, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBranchProtection(ctx, repo, branch)
+}
+
+// ListRepositories implements the GitClient interface.
+func (m *MultiClient) ListRepositories(ctx context.Context, org string, opts RepositoryListOptions) ([]*scm.Repository, error) {
+	c, org, err := m.route(org)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListRepositories(ctx, org, opts)
+}
+
+// ListDeployments implements the GitClient interface.
+func (m *MultiClient) ListDeployments(ctx context.Context, repo string, opts scm.ListOptions) ([]*Deployment, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListDeployments(ctx, repo, opts)
+}
+
+// CreateDeploymentStatus implements the GitClient interface.
+func (m *MultiClient) CreateDeploymentStatus(ctx context.Context, repo string, id int, inp *DeploymentStatusInput) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.CreateDeploymentStatus(ctx, repo, id, inp)
+}
+
+// ListEnvironments implements the GitClient interface.
+func (m *MultiClient) ListEnvironments(ctx context.Context, repo string) ([]*Environment, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListEnvironments(ctx, repo)
+}
+
+// ListWorkflowRuns implements the GitClient interface.
+func (m *MultiClient) ListWorkflowRuns(ctx context.Context, repo, ref string, opts scm.ListOptions) ([]*WorkflowRun, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListWorkflowRuns(ctx, repo, ref, opts)
+}
+
+// SetRepositoryArchived implements the GitClient interface.
+func (m *MultiClient) SetRepositoryArchived(ctx context.Context, repo string, archived bool) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.SetRepositoryArchived(ctx, repo, archived)
+}
+
+// RenameRepository implements the GitClient interface.
+func (m *MultiClient) RenameRepository(ctx context.Context, repo, newName string) (*scm.Repository, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.RenameRepository(ctx, repo, newName)
+}
+
+// ForkRepository implements the GitClient interface.
+func (m *MultiClient) ForkRepository(ctx context.Context, repo, org string, opts ...ForkOption) (*scm.Repository, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ForkRepository(ctx, repo, org, opts...)
+}
+
+// GetLanguages implements the GitClient interface.
+func (m *MultiClient) GetLanguages(ctx context.Context, repo string) (map[string]int, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetLanguages(ctx, repo)
+}
+
+// GetRepositoryTopics implements the GitClient interface.
+func (m *MultiClient) GetRepositoryTopics(ctx context.Context, repo string) ([]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetRepositoryTopics(ctx, repo)
+}
+
+// SetRepositoryTopics implements the GitClient interface.
+func (m *MultiClient) SetRepositoryTopics(ctx context.Context, repo string, topics []string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.SetRepositoryTopics(ctx, repo, topics)
+}
+
+// SetRepositoryVariable implements the GitClient interface.
+func (m *MultiClient) SetRepositoryVariable(ctx context.Context, repo, name, value string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.SetRepositoryVariable(ctx, repo, name, value)
+}
+
+// ListRepositoryVariables implements the GitClient interface.
+func (m *MultiClient) ListRepositoryVariables(ctx context.Context, repo string) (map[string]string, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListRepositoryVariables(ctx, repo)
+}
+
+// SetRepositorySecret implements the GitClient interface.
+func (m *MultiClient) SetRepositorySecret(ctx context.Context, repo, name, value string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.SetRepositorySecret(ctx, repo, name, value)
+}
+
+// Star implements the GitClient interface.
+func (m *MultiClient) Star(ctx context.Context, repo string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.Star(ctx, repo)
+}
+
+// Unstar implements the GitClient interface.
+func (m *MultiClient) Unstar(ctx context.Context, repo string) error {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return err
+	}
+	return c.Unstar(ctx, repo)
+}
+
+// IsStarred implements the GitClient interface.
+func (m *MultiClient) IsStarred(ctx context.Context, repo string) (bool, error) {
+	c, repo, err := m.route(repo)
+	if err != nil {
+		return false, err
+	}
+	return c.IsStarred(ctx, repo)
+}
@@ -0,0 +1,238 @@
+package client
+
+import "time"
+
+// ClientFunc is an option for creating new SCMClients.
+type ClientFunc func(c *SCMClient)
+
+// WithMaxDiffSize is an option func that limits the size of the diffs that are
+// returned, diffs larger than n bytes are truncated and end with
+// DiffTruncatedMarker.
+func WithMaxDiffSize(n int) ClientFunc {
+	return func(c *SCMClient) {
+		c.maxDiffSize = n
+	}
+}
+
+// WithConcurrency is an option func that sets the maximum number of concurrent
+// requests made by methods that fetch several resources.
+func WithConcurrency(n int) ClientFunc {
+	return func(c *SCMClient) {
+		c.concurrency = n
+	}
+}
+
+// WithRetry is an option func that retries failed calls that are likely to
+// succeed on a later attempt, e.g. server errors, up to attempts times in
+// total, waiting for the backoff before the first retry, and doubling it for
+// each retry that follows.
+//
+// Retrying methods that create resources can create duplicates if the first
+// attempt succeeded upstream, use WithNoRetryMethods to exclude them.
+func WithRetry(attempts int, backoff time.Duration) ClientFunc {
+	return func(c *SCMClient) {
+		c.retry.attempts = attempts
+		c.retry.backoff = backoff
+	}
+}
+
+// WithMaxItems is an option func that stops the list methods, e.g. ListFiles
+// and ListRepositories, paging once n items have been collected, if more items
+// remain, the first n are returned along with ErrTruncated.
+func WithMaxItems(n int) ClientFunc {
+	return func(c *SCMClient) {
+		c.maxItems = n
+	}
+}
+
+// WithErrorContext is an option func that wraps every error returned by the
+// GitClient methods with the method and repo, and the path and ref for the
+// methods that act on a file, with WrapErrorContext.
+func WithErrorContext() ClientFunc {
+	return func(c *SCMClient) {
+		c.errorContext = true
+	}
+}
+
+// WithNoRetryMethods is an option func that disables retries for the named
+// GitClient methods, e.g. "CreatePullRequest".
+func WithNoRetryMethods(methods ...string) ClientFunc {
+	return func(c *SCMClient) {
+		if c.retry.noRetry == nil {
+			c.retry.noRetry = map[string]bool{}
+		}
+		for _, m := range methods {
+			c.retry.noRetry[m] = true
+		}
+	}
+}
+
+// WriteOptions configures a single write to a repository.
+type WriteOptions struct {
+	AllowEmpty bool // commit even if the content is unchanged
+	Force      bool // write even if the file or branch changed since it was read
+	// DeleteIfEmpty makes SyncFile delete the file when the wanted content is
+	// empty, rather than leave an empty file.
+	DeleteIfEmpty bool
+}
+
+// WriteOption is an option func for writes to a repository.
+type WriteOption func(o *WriteOptions)
+
+// AllowEmpty is a WriteOption that disables the detection of unchanged
+// content, so that a commit is made regardless.
+func AllowEmpty() WriteOption {
+	return func(o *WriteOptions) {
+		o.AllowEmpty = true
+	}
+}
+
+// Force is a WriteOption that ignores conflicts with the previous SHA of a file,
+// or with concurrent updates to the branch for multi-file commits.
+func Force() WriteOption {
+	return func(o *WriteOptions) {
+		o.Force = true
+	}
+}
+
+// DeleteIfEmpty is a WriteOption for SyncFile that treats empty wanted content,
+// including nil, as the file being absent, so an existing file is deleted,
+// and a file that doesn't exist is left alone.
+func DeleteIfEmpty() WriteOption {
+	return func(o *WriteOptions) {
+		o.DeleteIfEmpty = true
+	}
+}
+
+func makeWriteOptions(opts []WriteOption) WriteOptions {
+	o := WriteOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// PullRequestOptions configures the creation of a pull request.
+type PullRequestOptions struct {
+	EnsureBase bool // create the target branch if it doesn't exist
+}
+
+// PullRequestOption is an option func for creating pull requests.
+type PullRequestOption func(o *PullRequestOptions)
+
+// EnsureBase is a PullRequestOption that creates the target branch of the pull
+// request from the default branch of the repository if it doesn't exist.
+func EnsureBase() PullRequestOption {
+	return func(o *PullRequestOptions) {
+		o.EnsureBase = true
+	}
+}
+
+func makePullRequestOptions(opts []PullRequestOption) PullRequestOptions {
+	o := PullRequestOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// ForkOptions configures the forking of a repository.
+type ForkOptions struct {
+	Poll time.Duration // wait for the fork to be ready, checking every Poll
+}
+
+// ForkOption is an option func for forking repositories.
+type ForkOption func(o *ForkOptions)
+
+// WaitForFork is a ForkOption that waits for the fork to be ready, checking
+// whether its default branch can be read every poll interval, as forks are
+// created asynchronously on GitHub and GitLab.
+func WaitForFork(poll time.Duration) ForkOption {
+	return func(o *ForkOptions) {
+		o.Poll = poll
+	}
+}
+
+func makeForkOptions(opts []ForkOption) ForkOptions {
+	o := ForkOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// MergeOptions configures the merging of a pull request.
+type MergeOptions struct {
+	Retries int           // retry merges that fail with ErrMergeInProgress up to Retries times
+	Wait    time.Duration // wait between the retries
+
+	CommitTitle   string // the title of the commit created by the merge
+	CommitMessage string // the body of the commit created by the merge
+}
+
+// MergeOption is an option func for merging pull requests.
+type MergeOption func(o *MergeOptions)
+
+// RetryMergeInProgress is a MergeOption that retries a merge that fails with
+// ErrMergeInProgress up to retries times, waiting between the attempts, which
+// happens when concurrent merges race into the same branch.
+func RetryMergeInProgress(retries int, wait time.Duration) MergeOption {
+	return func(o *MergeOptions) {
+		o.Retries = retries
+		o.Wait = wait
+	}
+}
+
+// MergeCommitMessage is a MergeOption that sets the title and body of the
+// commit created by squash and merge-commit merges, rather than letting the
+// upstream service generate one from the commits of the pull request.
+//
+// GitLab takes a single message, so the title and body are joined by a blank
+// line.
+func MergeCommitMessage(title, body string) MergeOption {
+	return func(o *MergeOptions) {
+		o.CommitTitle = title
+		o.CommitMessage = body
+	}
+}
+
+func makeMergeOptions(opts []MergeOption) MergeOptions {
+	o := MergeOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// ReleaseOptions configures the selection of the latest release.
+type ReleaseOptions struct {
+	NonSemverFallback bool // select a release that isn't a semantic version if there's no other
+}
+
+// ReleaseOption is an option func for selecting the latest release.
+type ReleaseOption func(o *ReleaseOptions)
+
+// FallbackToNonSemver is a ReleaseOption that selects the most recently
+// published release whose tag isn't a semantic version, when no release has a
+// semantic version, rather than finding no release.
+func FallbackToNonSemver() ReleaseOption {
+	return func(o *ReleaseOptions) {
+		o.NonSemverFallback = true
+	}
+}
+
+func makeReleaseOptions(opts []ReleaseOption) ReleaseOptions {
+	o := ReleaseOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return o
+}
+
+// RepositoryListOptions filters the repositories returned by ListRepositories.
+//
+// The zero value lists all repositories.
+type RepositoryListOptions struct {
+	ExcludeArchived bool // skip repositories that are archived
+	ExcludeForks    bool // skip repositories that are forks, GitHub and GitLab only
+}
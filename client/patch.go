@@ -0,0 +1,390 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// hunkHeader matches the header of a hunk of a unified diff, the line counts
+// are omitted when they are 1.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// subjectPrefix matches the prefix added to the subject by git format-patch,
+// e.g. "[PATCH 1/2] ".
+var subjectPrefix = regexp.MustCompile(`^\[PATCH[^\]]*\]\s*`)
+
+// Patch is a commit parsed from the output of git format-patch, or from a
+// plain unified diff, which has no message or author.
+type Patch struct {
+	Message string
+	Author  scm.Signature
+	Files   []FilePatch
+}
+
+// FilePatch is the change to a single file in a Patch, the OldPath is empty
+// for files that are created, and the NewPath is empty for files that are
+// deleted.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	hunks   []hunk
+}
+
+type hunk struct {
+	oldStart int
+	oldLines int
+	newLines int
+	lines    []hunkLine
+}
+
+// hunkLine is a line of a hunk, the text includes the line ending, unless the
+// line is at the end of a file without a trailing newline.
+type hunkLine struct {
+	op   byte
+	text string
+}
+
+// CreatePullRequestFromPatches creates the new branch from the head of the
+// base branch, applies each of the patches to it as a commit, in order, and
+// opens a pull request from the new branch to the base branch.
+//
+// The patches are in the format of git format-patch, the message and author
+// of each commit are taken from the patch, plain diffs are committed with the
+// title of the pull request as the message. Every patch is parsed before the
+// branch is created, if a patch doesn't apply, the error wraps ErrConflict,
+// and the branch is left with the commits of the patches that applied.
+//
+// Patches are only supported on GitHub and GitLab, as the commits are made
+// with UpdateFiles.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreatePullRequestFromPatches(ctx context.Context, repo, baseBranch, newBranch string, patches [][]byte, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	var out *scm.PullRequest
+	err := c.call(ctx, "CreatePullRequestFromPatches", repo, func(ctx context.Context) (err error) {
+		out, err = c.createPullRequestFromPatches(ctx, repo, baseBranch, newBranch, patches, inp)
+		return err
+	})
+	c.emit(Event{Type: "CreatePullRequestFromPatches", Repo: repo, Branch: newBranch, Number: prNumber(out), Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createPullRequestFromPatches(ctx context.Context, repo, baseBranch, newBranch string, patches [][]byte, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	parsed, err := ParsePatches(patches)
+	if err != nil {
+		return nil, err
+	}
+	head, err := c.GetBranchHead(ctx, repo, baseBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch head: %w", err)
+	}
+	if err := c.CreateBranch(ctx, repo, newBranch, head); err != nil {
+		return nil, fmt.Errorf("failed to create branch %s: %w", newBranch, err)
+	}
+	for i, p := range parsed {
+		changes, err := p.Changes(func(path string) ([]byte, error) {
+			content, err := c.getFile(ctx, repo, newBranch, path)
+			if err != nil {
+				return nil, err
+			}
+			return content.Data, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch %d: %w", i+1, err)
+		}
+		message := p.Message
+		if message == "" {
+			message = inp.Title
+		}
+		if _, err := c.UpdateFiles(ctx, repo, newBranch, message, p.Author, changes); err != nil {
+			return nil, fmt.Errorf("failed to commit patch %d: %w", i+1, err)
+		}
+	}
+	pr := *inp
+	pr.Source, pr.Target = newBranch, baseBranch
+	return c.CreatePullRequest(ctx, repo, &pr)
+}
+
+// ParsePatches parses each of the patches with ParsePatch.
+func ParsePatches(patches [][]byte) ([]*Patch, error) {
+	if len(patches) == 0 {
+		return nil, errors.New("no patches to apply")
+	}
+	parsed := make([]*Patch, len(patches))
+	for i, b := range patches {
+		p, err := ParsePatch(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse patch %d: %w", i+1, err)
+		}
+		parsed[i] = p
+	}
+	return parsed, nil
+}
+
+// ParsePatch parses a patch in the format of git format-patch, or a plain
+// unified diff.
+//
+// Binary patches are not supported.
+func ParsePatch(b []byte) (*Patch, error) {
+	lines := strings.SplitAfter(string(b), "\n")
+	p := &Patch{}
+	i := p.parseHeader(lines)
+	var current *FilePatch
+	for i < len(lines) {
+		line := strings.TrimRight(lines[i], "\n")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			p.Files = append(p.Files, FilePatch{})
+			current = &p.Files[len(p.Files)-1]
+			if fields := strings.Fields(line); len(fields) == 4 {
+				current.OldPath = strings.TrimPrefix(fields[2], "a/")
+				current.NewPath = strings.TrimPrefix(fields[3], "b/")
+			}
+		case strings.HasPrefix(line, "--- ") && (i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")):
+			if current == nil || len(current.hunks) > 0 {
+				p.Files = append(p.Files, FilePatch{})
+				current = &p.Files[len(p.Files)-1]
+			}
+			current.OldPath = patchPath(strings.TrimPrefix(line, "--- "), "a/")
+			current.NewPath = patchPath(strings.TrimRight(strings.TrimPrefix(lines[i+1], "+++ "), "\n"), "b/")
+			i++
+		case current == nil && isFileHeader(line):
+			return nil, fmt.Errorf("file header at line %d is not in a file diff", i+1)
+		case strings.HasPrefix(line, "new file mode"):
+			current.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.NewPath = ""
+		case strings.HasPrefix(line, "rename from "):
+			current.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			current.NewPath = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			return nil, errors.New("binary patches are not supported")
+		case strings.HasPrefix(line, "@@ "):
+			if current == nil {
+				return nil, fmt.Errorf("hunk at line %d is not in a file diff", i+1)
+			}
+			h, n, err := parseHunk(lines[i:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse hunk at line %d: %w", i+1, err)
+			}
+			current.hunks = append(current.hunks, h)
+			i += n
+			continue
+		}
+		i++
+	}
+	if len(p.Files) == 0 {
+		return nil, errors.New("patch has no file diffs")
+	}
+	return p, nil
+}
+
+// isFileHeader returns true for the lines of the extended header of a file
+// diff that change its paths.
+func isFileHeader(line string) bool {
+	for _, prefix := range []string{"new file mode", "deleted file mode", "rename from ", "rename to "} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHeader parses the email headers and message of a patch created by git
+// format-patch, and returns the index of the line that follows the message.
+func (p *Patch) parseHeader(lines []string) int {
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "From ") {
+		return 0
+	}
+	var (
+		subject string
+		body    []string
+		i       = 1
+	)
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		if line == "" {
+			i++
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "From: "):
+			if addr, err := mail.ParseAddress(strings.TrimPrefix(line, "From: ")); err == nil {
+				p.Author = scm.Signature{Name: addr.Name, Email: addr.Address}
+			}
+		case strings.HasPrefix(line, "Subject: "):
+			subject = strings.TrimPrefix(line, "Subject: ")
+		case strings.HasPrefix(line, " ") && subject != "":
+			subject += line
+		}
+	}
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		if line == "---" || strings.HasPrefix(line, "diff --git ") {
+			break
+		}
+		body = append(body, line)
+	}
+	p.Message = strings.TrimSpace(subjectPrefix.ReplaceAllString(subject, "") + "\n\n" + strings.Join(body, "\n"))
+	return i
+}
+
+// patchPath returns the path of a "---" or "+++" line without the prefix, or
+// an empty path for /dev/null.
+func patchPath(s, prefix string) string {
+	if i := strings.Index(s, "\t"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+// parseHunk parses the hunk that starts at the first line, and returns the
+// number of lines it spans, the lines are counted with the header, so that
+// lines that follow the hunk, e.g. the "-- " signature, are not included.
+func parseHunk(lines []string) (hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[0])
+	if m == nil {
+		return hunk{}, 0, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(lines[0]))
+	}
+	h := hunk{oldStart: atoiOr(m[1], 0), oldLines: atoiOr(m[2], 1), newLines: atoiOr(m[4], 1)}
+	oldLeft, newLeft := h.oldLines, h.newLines
+	n := 1
+	for ; n < len(lines) && (oldLeft > 0 || newLeft > 0 || strings.HasPrefix(lines[n], `\`)); n++ {
+		line := lines[n]
+		if strings.HasPrefix(line, `\`) {
+			if len(h.lines) > 0 {
+				last := &h.lines[len(h.lines)-1]
+				last.text = strings.TrimSuffix(last.text, "\n")
+			}
+			continue
+		}
+		op, text := byte(' '), ""
+		if line != "\n" && line != "" {
+			op, text = line[0], line[1:]
+		} else {
+			text = "\n"
+		}
+		switch op {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		default:
+			return hunk{}, 0, fmt.Errorf("invalid hunk line %q", strings.TrimSpace(line))
+		}
+		h.lines = append(h.lines, hunkLine{op: op, text: text})
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return hunk{}, 0, errors.New("hunk is truncated")
+	}
+	return h, n, nil
+}
+
+func atoiOr(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Apply returns the content with the hunks of the file patch applied, the
+// context and removed lines of each hunk must match the content at the lines
+// that the hunk starts at, or an error wrapping ErrConflict is returned.
+func (f FilePatch) Apply(content []byte) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var (
+		out bytes.Buffer
+		pos int
+	)
+	for n, h := range f.hunks {
+		start := h.oldStart - 1
+		if h.oldLines == 0 {
+			start = h.oldStart
+		}
+		if start < pos || start > len(lines) {
+			return nil, fmt.Errorf("hunk %d of %s doesn't apply at line %d: %w", n+1, f.path(), h.oldStart, ErrConflict)
+		}
+		for _, l := range lines[pos:start] {
+			out.WriteString(l)
+		}
+		i := start
+		for _, l := range h.lines {
+			if l.op == '+' {
+				out.WriteString(l.text)
+				continue
+			}
+			if i >= len(lines) || lines[i] != l.text {
+				return nil, fmt.Errorf("hunk %d of %s doesn't apply at line %d: %w", n+1, f.path(), i+1, ErrConflict)
+			}
+			if l.op == ' ' {
+				out.WriteString(l.text)
+			}
+			i++
+		}
+		pos = i
+	}
+	for _, l := range lines[pos:] {
+		out.WriteString(l)
+	}
+	return out.Bytes(), nil
+}
+
+func (f FilePatch) path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Changes returns the file changes that apply the patch, reading the current
+// content of the files that it changes with read.
+//
+// Renamed files are deleted from their old path, and written to the new path.
+func (p *Patch) Changes(read func(path string) ([]byte, error)) ([]FileChange, error) {
+	var changes []FileChange
+	for _, f := range p.Files {
+		if f.NewPath == "" {
+			changes = append(changes, FileChange{Path: f.OldPath, Delete: true})
+			continue
+		}
+		var current []byte
+		if f.OldPath != "" {
+			b, err := read(f.OldPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s to patch: %w", f.OldPath, err)
+			}
+			current = b
+		}
+		content, err := f.Apply(current)
+		if err != nil {
+			return nil, err
+		}
+		if f.OldPath != "" && f.OldPath != f.NewPath {
+			changes = append(changes, FileChange{Path: f.OldPath, Delete: true})
+		}
+		changes = append(changes, FileChange{Path: f.NewPath, Content: content})
+	}
+	return changes, nil
+}
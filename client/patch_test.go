@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testFormatPatch = `From 0123456789abcdef0123456789abcdef01234567 Mon Sep 17 00:00:00 2001
+From: John Doe <john.doe@example.com>
+Date: Mon, 1 Nov 2021 12:00:00 +0000
+Subject: [PATCH 1/2] Bump the replicas
+
+The service needs more capacity.
+---
+ config/app.yaml | 2 +-
+ config/new.yaml | 1 +
+ 2 files changed, 2 insertions(+), 1 deletion(-)
+
+diff --git a/config/app.yaml b/config/app.yaml
+index 1111111..2222222 100644
+--- a/config/app.yaml
++++ b/config/app.yaml
+@@ -1,3 +1,3 @@
+ name: app
+-replicas: 1
++replicas: 3
+ image: app:v1
+diff --git a/config/new.yaml b/config/new.yaml
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/config/new.yaml
+@@ -0,0 +1 @@
++name: new
+-- 
+2.30.0
+`
+
+func TestParsePatch(t *testing.T) {
+	p, err := ParsePatch([]byte(testFormatPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Bump the replicas\n\nThe service needs more capacity."; p.Message != want {
+		t.Errorf("got message %q, want %q", p.Message, want)
+	}
+	if diff := cmp.Diff(scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, p.Author); diff != "" {
+		t.Errorf("author doesn't match:\n%s", diff)
+	}
+	changes, err := p.Changes(func(path string) ([]byte, error) {
+		return []byte("name: app\nreplicas: 1\nimage: app:v1\n"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{
+		{Path: "config/app.yaml", Content: []byte("name: app\nreplicas: 3\nimage: app:v1\n")},
+		{Path: "config/new.yaml", Content: []byte("name: new\n")},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Fatalf("changes don't match:\n%s", diff)
+	}
+}
+
+func TestFilePatchApply(t *testing.T) {
+	applyTests := []struct {
+		name    string
+		diff    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "offset hunks",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -4 +4,2 @@\n d\n+e\n",
+			content: "a\nb\nc\nd\n",
+			want:    "A\nb\nc\nd\ne\n",
+		},
+		{
+			name:    "no newline at end of file",
+			diff:    "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n",
+			content: "a",
+			want:    "b",
+		},
+		{
+			name:    "deleted file",
+			diff:    "--- a/f\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+			content: "a\n",
+		},
+		{
+			name:    "conflict",
+			diff:    "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+b\n",
+			content: "changed\n",
+			wantErr: "hunk 1 of f doesn't apply at line 1",
+		},
+	}
+
+	for _, tt := range applyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePatch([]byte(tt.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Files[0].NewPath == "" {
+				return
+			}
+			got, err := p.Files[0].Apply([]byte(tt.content))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrConflict) {
+					t.Fatalf("got %v, want ErrConflict", err)
+				}
+				test.MatchError(t, tt.wantErr, err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatchRejectsBinaryPatches(t *testing.T) {
+	_, err := ParsePatch([]byte("diff --git a/logo.png b/logo.png\nindex 1111111..2222222 100644\nBinary files a/logo.png and b/logo.png differ\n"))
+	test.MatchError(t, "binary patches are not supported", err)
+}
+
+func TestParsePatchRejectsFileHeadersOutsideFileDiffs(t *testing.T) {
+	for _, header := range []string{"new file mode 100644", "deleted file mode 100644", "rename from a.yaml", "rename to b.yaml"} {
+		_, err := ParsePatch([]byte(header + "\n"))
+		test.MatchError(t, "file header at line 1 is not in a file diff", err)
+	}
+}
+
+func TestCreatePullRequestFromPatches(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "commit": map[string]string{"id": "base-sha"}})
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/branches").
+		JSON(map[string]string{"branch": "import", "ref": "base-sha"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"name": "import"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		Persist().
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/app.yaml", "content": base64.StdEncoding.EncodeToString([]byte("name: app\nreplicas: 1\nimage: app:v1\n")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/new.yaml").
+		Persist().
+		Reply(http.StatusNotFound)
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		JSON(map[string]interface{}{
+			"branch":         "import",
+			"commit_message": "Bump the replicas\n\nThe service needs more capacity.",
+			"author_name":    "John Doe",
+			"author_email":   "john.doe@example.com",
+			"actions": []map[string]string{
+				{"action": "update", "file_path": "config/app.yaml", "content": base64.StdEncoding.EncodeToString([]byte("name: app\nreplicas: 3\nimage: app:v1\n")), "encoding": "base64"},
+				{"action": "create", "file_path": "config/new.yaml", "content": base64.StdEncoding.EncodeToString([]byte("name: new\n")), "encoding": "base64"},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"id": "new-commit"})
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/merge_requests").
+		MatchParam("source_branch", "^import$").
+		MatchParam("target_branch", "^main$").
+		MatchParam("title", "^Import changes$").
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"iid": 7, "source_branch": "import", "target_branch": "main"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	pr, err := client.CreatePullRequestFromPatches(context.Background(), "Codertocat/Hello-World", "main", "import",
+		[][]byte{[]byte(testFormatPatch)}, &scm.PullRequestInput{Title: "Import changes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 7 {
+		t.Fatalf("got pull request %d, want 7", pr.Number)
+	}
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WithTreePrefetch is an option func that makes GetFile read the whole tree of
+// a ref the first time a file is read from it, and find the blobs of the
+// files read from the ref after that in the tree, so that reading many files,
+// e.g. when reconciling a directory, doesn't make a request per file to find
+// each of them.
+//
+// The content of each file is still read when it's requested, from its blob.
+// Files that aren't in the tree, and symlinks, are read like they are without
+// the tree.
+//
+// The tree of a branch is dropped when the client changes the branch, changes
+// made by others are not seen until then, so this is intended for clients
+// that read a ref for a short time, or that are the only writer of the
+// branches they read.
+//
+// Trees are only prefetched on GitHub, other drivers, and trees too large
+// for GitHub to return in full, read each file separately.
+func WithTreePrefetch() ClientFunc {
+	return func(c *SCMClient) {
+		c.trees = &treeCache{}
+	}
+}
+
+// treeCache holds the blobs of the prefetched trees of each ref, by repo.
+type treeCache struct {
+	mu    sync.Mutex
+	trees map[string]map[string]*treeFetch
+}
+
+// treeFetch is the fetch of a tree, which is shared by the calls that read the
+// ref while it's in progress.
+type treeFetch struct {
+	done    chan struct{}
+	entries map[string]ghTreeEntry // nil if the tree can't be prefetched
+	err     error
+}
+
+// get returns the entries of the tree of the ref by path, fetching the tree
+// if it's not cached or being fetched.
+//
+// Failed fetches are not cached, so the tree is fetched again by the next
+// call.
+func (t *treeCache) get(ctx context.Context, repo, ref string, fetch func() (map[string]ghTreeEntry, error)) (map[string]ghTreeEntry, error) {
+	t.mu.Lock()
+	f, ok := t.trees[repo][ref]
+	if !ok {
+		f = &treeFetch{done: make(chan struct{})}
+		if t.trees == nil {
+			t.trees = map[string]map[string]*treeFetch{}
+		}
+		if t.trees[repo] == nil {
+			t.trees[repo] = map[string]*treeFetch{}
+		}
+		t.trees[repo][ref] = f
+	}
+	t.mu.Unlock()
+	if ok {
+		select {
+		case <-f.done:
+			return f.entries, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f.entries, f.err = fetch()
+	if f.err != nil {
+		t.mu.Lock()
+		if t.trees[repo][ref] == f {
+			delete(t.trees[repo], ref)
+		}
+		t.mu.Unlock()
+	}
+	close(f.done)
+	return f.entries, f.err
+}
+
+// invalidate drops the tree of the ref, or of every ref of the repo if the ref
+// is empty.
+func (t *treeCache) invalidate(repo, ref string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ref == "" {
+		delete(t.trees, repo)
+		return
+	}
+	delete(t.trees[repo], ref)
+}
+
+// invalidateFor drops the trees of the refs that the call described by the
+// event may have changed, whether or not it succeeded.
+func (t *treeCache) invalidateFor(e Event) {
+	switch {
+	case e.Branch != "":
+		t.invalidate(e.Repo, e.Branch)
+	case e.Type == "DeleteBranchesByPrefix" || e.Type == "RenameRepository":
+		t.invalidate(e.Repo, "")
+	}
+}
+
+// getFilePrefetched reads the file from its blob in the prefetched tree of the
+// ref, falling back to getFile if the tree can't be prefetched.
+func (c *SCMClient) getFilePrefetched(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+	if c.trees == nil || c.scmClient.Driver != scm.DriverGithub {
+		return c.getFile(ctx, repo, ref, path)
+	}
+	entries, err := c.trees.get(ctx, repo, ref, func() (map[string]ghTreeEntry, error) {
+		return c.getTreeGitHub(ctx, repo, ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		return c.getFile(ctx, repo, ref, path)
+	}
+	// Files that aren't in the tree, directories, submodules and symlinks are
+	// read like they are without the tree, as the blob of a symlink is the
+	// path of its target.
+	e, ok := entries[path]
+	if !ok || e.Type != "blob" || e.Mode == "120000" || e.Sha == nil {
+		return c.getFile(ctx, repo, ref, path)
+	}
+	b, err := c.getBlobByIDGitHub(ctx, repo, ref, path, *e.Sha)
+	if err != nil {
+		return nil, err
+	}
+	return &scm.Content{Path: path, Data: b, BlobID: *e.Sha}, nil
+}
+
+// getTreeGitHub returns the entries of the recursive tree of the ref by path,
+// or nil if the tree is truncated because it's too large.
+func (c *SCMClient) getTreeGitHub(ctx context.Context, repo, ref string) (map[string]ghTreeEntry, error) {
+	var tree struct {
+		Tree      []ghTreeEntry `json:"tree"`
+		Truncated bool          `json:"truncated"`
+	}
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/trees/%s?recursive=1", repo, ref), nil, &tree)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get tree of repo %s ref %s", repo, ref), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tree.Truncated {
+		return nil, nil
+	}
+	entries := make(map[string]ghTreeEntry, len(tree.Tree))
+	for _, e := range tree.Tree {
+		entries[e.Path] = e
+	}
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func mockTreeGitHub(truncated bool) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/main").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"sha": "9fb037999f264ba9a7fc6274d15fa3ae2ab98312",
+			"tree": []map[string]interface{}{
+				{"path": "config", "mode": "040000", "type": "tree", "sha": "f484d249c660418515fb01c2b9662073663c242e"},
+				{"path": "config/a.yaml", "mode": "100644", "type": "blob", "sha": "3d21ec53a331a6f037a91c368710b99387d012c1"},
+				{"path": "config/b.yaml", "mode": "100644", "type": "blob", "sha": "45b983be36b73c0788dc9cbcb76cbb80fc7bb057"},
+				{"path": "config/link.yaml", "mode": "120000", "type": "blob", "sha": "ee9f0c38e2e5a7e0bd0b4d3f4bb3d0c1ee2ae2f9"},
+			},
+			"truncated": truncated,
+		})
+}
+
+func TestGetFileWithTreePrefetch(t *testing.T) {
+	mockTreeGitHub(false)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/blobs/3d21ec53a331a6f037a91c368710b99387d012c1").
+		MatchHeader("Accept", rawMediaTypeGitHub).
+		Reply(http.StatusOK).
+		BodyString("a: 1\n")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/blobs/45b983be36b73c0788dc9cbcb76cbb80fc7bb057").
+		MatchHeader("Accept", rawMediaTypeGitHub).
+		Reply(http.StatusOK).
+		BodyString("b: 2\n")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/c.yaml").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithTreePrefetch())
+
+	for path, want := range map[string]string{"config/a.yaml": "a: 1\n", "config/b.yaml": "b: 2\n"} {
+		content, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content.Data) != want || content.Path != path {
+			t.Fatalf("got %s with %q, want %s with %q", content.Path, content.Data, path, want)
+		}
+	}
+	_, err = client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/c.yaml")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the tree or the blobs were not read")
+	}
+}
+
+func TestGetFileWithTreePrefetchReadsSymlinks(t *testing.T) {
+	mockTreeGitHub(false)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/link.yaml").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithTreePrefetch())
+
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/link.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the symlink was not read from the contents API")
+	}
+}
+
+func TestGetFileWithTreePrefetchInvalidatedByWrite(t *testing.T) {
+	mockTreeGitHub(false)
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/c.yaml").
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/content.json")
+	mockTreeGitHub(false)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/c.yaml").
+		Times(3).
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithTreePrefetch())
+
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/c.yaml"); !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	err = client.UpdateFile(context.Background(), "Codertocat/Hello-World", "main", "config/c.yaml", "create", "", scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, []byte("c: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/c.yaml"); !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the tree was not fetched again after the write")
+	}
+}
+
+func TestGetFileWithTreePrefetchTruncated(t *testing.T) {
+	mockTreeGitHub(true)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "main").
+		Times(2).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithTreePrefetch())
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/my/file.yaml"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("the files were not read from the contents API")
+	}
+}
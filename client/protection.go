@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// BranchProtection is the protection configured for a branch, go-scm has no
+// representation for it, so the fields are the ones shared by the drivers.
+type BranchProtection struct {
+	Branch                  string
+	RequiredApprovals       int
+	RequireCodeOwnerReviews bool
+	DismissStaleReviews     bool
+	RequiredStatusChecks    []string
+	StrictStatusChecks      bool // the branch must be up to date before merging
+	EnforceAdmins           bool
+	AllowForcePushes        bool
+	AllowDeletions          bool
+	RestrictPushes          bool // only some users can push to the branch
+}
+
+// GetBranchProtection gets the protection configured for the branch.
+//
+// Reading branch protection is only supported on GitHub, GitLab and Gitea, and
+// usually requires an admin token, if the token is rejected, the error wraps
+// ErrUnauthorized. If the branch is not protected, a not found error is
+// returned.
+func (c *SCMClient) GetBranchProtection(ctx context.Context, repo, branch string) (*BranchProtection, error) {
+	var out *BranchProtection
+	err := c.call(ctx, "GetBranchProtection", repo, func(ctx context.Context) (err error) {
+		if branch, err = c.resolveRef(ctx, repo, branch); err != nil {
+			return err
+		}
+		out, err = c.getBranchProtection(ctx, repo, branch)
+		return err
+	})
+	return out, err
+}
+
+type ghEnabled struct {
+	Enabled bool `json:"enabled"`
+}
+
+type ghBranchProtection struct {
+	RequiredStatusChecks *struct {
+		Strict   bool     `json:"strict"`
+		Contexts []string `json:"contexts"`
+	} `json:"required_status_checks"`
+	RequiredPullRequestReviews *struct {
+		DismissStaleReviews          bool `json:"dismiss_stale_reviews"`
+		RequireCodeOwnerReviews      bool `json:"require_code_owner_reviews"`
+		RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+	} `json:"required_pull_request_reviews"`
+	EnforceAdmins    ghEnabled        `json:"enforce_admins"`
+	AllowForcePushes ghEnabled        `json:"allow_force_pushes"`
+	AllowDeletions   ghEnabled        `json:"allow_deletions"`
+	Restrictions     *json.RawMessage `json:"restrictions"`
+}
+
+func (p ghBranchProtection) convert(branch string) *BranchProtection {
+	out := &BranchProtection{
+		Branch:           branch,
+		EnforceAdmins:    p.EnforceAdmins.Enabled,
+		AllowForcePushes: p.AllowForcePushes.Enabled,
+		AllowDeletions:   p.AllowDeletions.Enabled,
+		RestrictPushes:   p.Restrictions != nil,
+	}
+	if checks := p.RequiredStatusChecks; checks != nil {
+		out.RequiredStatusChecks, out.StrictStatusChecks = checks.Contexts, checks.Strict
+	}
+	if reviews := p.RequiredPullRequestReviews; reviews != nil {
+		out.RequiredApprovals = reviews.RequiredApprovingReviewCount
+		out.RequireCodeOwnerReviews = reviews.RequireCodeOwnerReviews
+		out.DismissStaleReviews = reviews.DismissStaleReviews
+	}
+	return out
+}
+
+type glProtectedBranch struct {
+	AllowForcePush            bool `json:"allow_force_push"`
+	CodeOwnerApprovalRequired bool `json:"code_owner_approval_required"`
+}
+
+// convert returns the protection of a GitLab protected branch, which always
+// restricts pushes to some access level.
+func (p glProtectedBranch) convert(branch string) *BranchProtection {
+	return &BranchProtection{
+		Branch:                  branch,
+		RequireCodeOwnerReviews: p.CodeOwnerApprovalRequired,
+		AllowForcePushes:        p.AllowForcePush,
+		RestrictPushes:          true,
+	}
+}
+
+type giteaBranchProtection struct {
+	EnablePush            bool     `json:"enable_push"`
+	EnablePushWhitelist   bool     `json:"enable_push_whitelist"`
+	EnableStatusCheck     bool     `json:"enable_status_check"`
+	StatusCheckContexts   []string `json:"status_check_contexts"`
+	RequiredApprovals     int      `json:"required_approvals"`
+	DismissStaleApprovals bool     `json:"dismiss_stale_approvals"`
+	BlockOnOutdatedBranch bool     `json:"block_on_outdated_branch"`
+}
+
+func (p giteaBranchProtection) convert(branch string) *BranchProtection {
+	out := &BranchProtection{
+		Branch:              branch,
+		RequiredApprovals:   p.RequiredApprovals,
+		DismissStaleReviews: p.DismissStaleApprovals,
+		StrictStatusChecks:  p.BlockOnOutdatedBranch,
+		RestrictPushes:      !p.EnablePush || p.EnablePushWhitelist,
+	}
+	if p.EnableStatusCheck {
+		out.RequiredStatusChecks = p.StatusCheckContexts
+	}
+	return out
+}
+
+// branchProtectionResponse is the driver's representation of the protection
+// of a branch.
+type branchProtectionResponse interface {
+	convert(branch string) *BranchProtection
+}
+
+func (c *SCMClient) getBranchProtection(ctx context.Context, repo, branch string) (*BranchProtection, error) {
+	var (
+		path string
+		out  branchProtectionResponse
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path, out = fmt.Sprintf("repos/%s/branches/%s/protection", repo, branch), &ghBranchProtection{}
+	case scm.DriverGitlab:
+		path, out = fmt.Sprintf("api/v4/projects/%s/protected_branches/%s", encodeRepo(repo), url.PathEscape(branch)), &glProtectedBranch{}
+	case scm.DriverGitea:
+		path, out = fmt.Sprintf("api/v1/repos/%s/branch_protections/%s", repo, branch), &giteaBranchProtection{}
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, out)
+	if r != nil && isErrorStatus(r.Status) {
+		e := SCMError{Msg: fmt.Sprintf("failed to get protection of branch %s in repo %s", branch, repo), Status: r.Status}
+		if r.Status == http.StatusUnauthorized || r.Status == http.StatusForbidden {
+			e.Err = ErrUnauthorized
+		}
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.convert(branch), nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetBranchProtection(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/main/protection").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"required_status_checks":        map[string]interface{}{"strict": true, "contexts": []string{"ci/test"}},
+			"required_pull_request_reviews": map[string]interface{}{"required_approving_review_count": 2, "require_code_owner_reviews": true},
+			"enforce_admins":                map[string]bool{"enabled": true},
+			"allow_force_pushes":            map[string]bool{"enabled": false},
+			"restrictions":                  map[string]interface{}{"users": []string{}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	p, err := client.GetBranchProtection(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &BranchProtection{
+		Branch:                  "main",
+		RequiredApprovals:       2,
+		RequireCodeOwnerReviews: true,
+		RequiredStatusChecks:    []string{"ci/test"},
+		StrictStatusChecks:      true,
+		EnforceAdmins:           true,
+		RestrictPushes:          true,
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("got %#v, want %#v", p, want)
+	}
+}
+
+func TestGetBranchProtectionInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/protected_branches/main").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "allow_force_push": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	p, err := client.GetBranchProtection(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.AllowForcePushes || !p.RestrictPushes {
+		t.Fatalf("got %#v, want force pushes allowed and pushes restricted", p)
+	}
+}
+
+func TestGetBranchProtectionWithoutAdminToken(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/main/protection").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "Resource not accessible by integration"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetBranchProtection(context.Background(), "Codertocat/Hello-World", "main")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("got %v, want ErrUnauthorized", err)
+	}
+	if !test.MatchError(t, `failed to get protection of branch main.*\(403\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetBranchProtectionWithUnprotectedBranch(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/main/protection").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Branch not protected"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetBranchProtection(context.Background(), "Codertocat/Hello-World", "main")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,828 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// DiffTruncatedMarker is appended to diffs that exceed the maximum size
+// configured with WithMaxDiffSize.
+const DiffTruncatedMarker = "\n... diff truncated ...\n"
+
+// GetPullRequest returns the pull request with the number.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	var out *scm.PullRequest
+	err := c.call(ctx, "GetPullRequest", repo, func(ctx context.Context) (err error) {
+		out, err = c.getPullRequest(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	pr, r, err := c.scmClient.PullRequests.Find(ctx, repo, number)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get pull request %d in repo %s", number, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// ListPullRequestCommits returns the commits in the pull request, paging
+// through the commits from the page in the options.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, IsNotFound returns true for an unknown
+// pull request.
+func (c *SCMClient) ListPullRequestCommits(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Commit, error) {
+	var out []*scm.Commit
+	err := c.call(ctx, "ListPullRequestCommits", repo, func(ctx context.Context) (err error) {
+		out, err = c.listPullRequestCommits(ctx, repo, number, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) listPullRequestCommits(ctx context.Context, repo string, number int, opts scm.ListOptions) ([]*scm.Commit, error) {
+	var all []*scm.Commit
+	for {
+		commits, r, err := c.scmClient.PullRequests.ListCommits(ctx, repo, number, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list commits in pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, commits...)
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// MergeMethod is the way the commits of a pull request are merged.
+type MergeMethod string
+
+const (
+	// MergeMethodMerge merges the commits with a merge commit.
+	MergeMethodMerge MergeMethod = "MERGE"
+	// MergeMethodSquash squashes the commits into a single commit.
+	MergeMethodSquash MergeMethod = "SQUASH"
+	// MergeMethodRebase rebases the commits onto the target branch.
+	MergeMethodRebase MergeMethod = "REBASE"
+)
+
+// EnableAutoMerge queues the pull request to be merged with the method once
+// the required checks pass, rather than merging it immediately.
+//
+// Auto-merge is only supported on GitHub, where it uses the GraphQL API, and
+// it must be allowed in the settings of the repo.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) EnableAutoMerge(ctx context.Context, repo string, number int, method MergeMethod) error {
+	err := c.call(ctx, "EnableAutoMerge", repo, func(ctx context.Context) error {
+		return c.enableAutoMerge(ctx, repo, number, method)
+	})
+	c.emit(Event{Type: "EnableAutoMerge", Repo: repo, Number: number, Err: err})
+	return err
+}
+
+func (c *SCMClient) enableAutoMerge(ctx context.Context, repo string, number int, method MergeMethod) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return scm.ErrNotSupported
+	}
+	owner, name := scm.Split(repo)
+	msg := fmt.Sprintf("failed to enable auto-merge for pull request %d in repo %s", number, repo)
+	var pr struct {
+		Repository *struct {
+			PullRequest *struct {
+				ID string `json:"id"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	err := c.doGraphQL(ctx, msg, "query($owner: String!, $name: String!, $number: Int!) { repository(owner: $owner, name: $name) { pullRequest(number: $number) { id } } }",
+		map[string]interface{}{"owner": owner, "name": name, "number": number}, &pr)
+	if err != nil {
+		return err
+	}
+	if pr.Repository == nil || pr.Repository.PullRequest == nil {
+		return SCMError{Msg: msg, Status: http.StatusNotFound}
+	}
+	return c.doGraphQL(ctx, msg, "mutation($id: ID!, $method: PullRequestMergeMethod!) { enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId } }",
+		map[string]interface{}{"id": pr.Repository.PullRequest.ID, "method": method}, nil)
+}
+
+// MergePullRequest merges the pull request with the method, at the head that
+// it has when the merge is made, so that commits pushed while merging are not
+// merged unseen.
+//
+// If another merge into the target branch is in progress, or the target
+// branch was modified while merging, an error wrapping ErrMergeInProgress is
+// returned, and the merge is retried from the current head of the pull
+// request with the RetryMergeInProgress option. If the pull request can't be
+// merged, or its head changed while merging, an error wrapping ErrConflict is
+// returned.
+//
+// The message of the commit created by the merge can be set with the
+// MergeCommitMessage option.
+//
+// Merges are only supported on GitHub and GitLab, where rebasing is not
+// supported.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) MergePullRequest(ctx context.Context, repo string, number int, method MergeMethod, opts ...MergeOption) error {
+	o := makeMergeOptions(opts)
+	err := c.call(ctx, "MergePullRequest", repo, func(ctx context.Context) error {
+		return c.mergePullRequest(ctx, repo, number, method, o)
+	})
+	c.emit(Event{Type: "MergePullRequest", Repo: repo, Number: number, Err: err})
+	return err
+}
+
+func (c *SCMClient) mergePullRequest(ctx context.Context, repo string, number int, method MergeMethod, o MergeOptions) error {
+	for attempt := 0; ; attempt++ {
+		err := c.mergePullRequestOnce(ctx, repo, number, method, o)
+		if !errors.Is(err, ErrMergeInProgress) || attempt == o.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.getClock().After(o.Wait):
+		}
+	}
+}
+
+func (c *SCMClient) mergePullRequestOnce(ctx context.Context, repo string, number int, method MergeMethod, o MergeOptions) error {
+	var (
+		path string
+		in   map[string]interface{}
+	)
+	pr, err := c.getPullRequest(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/pulls/%d/merge", repo, number)
+		in = map[string]interface{}{"merge_method": strings.ToLower(string(method)), "sha": pr.Sha}
+		if o.CommitTitle != "" {
+			in["commit_title"] = o.CommitTitle
+		}
+		if o.CommitMessage != "" {
+			in["commit_message"] = o.CommitMessage
+		}
+	case scm.DriverGitlab:
+		if method == MergeMethodRebase {
+			return scm.ErrNotSupported
+		}
+		path = fmt.Sprintf("api/v4/projects/%s/merge_requests/%d/merge", encodeRepo(repo), number)
+		in = map[string]interface{}{"squash": method == MergeMethodSquash, "sha": pr.Sha}
+		if message := joinCommitMessage(o.CommitTitle, o.CommitMessage); message != "" {
+			param := "merge_commit_message"
+			if method == MergeMethodSquash {
+				param = "squash_commit_message"
+			}
+			in[param] = message
+		}
+	default:
+		return scm.ErrNotSupported
+	}
+	var out struct {
+		Message string `json:"message"`
+	}
+	r, err := c.doWithErrorBody(ctx, http.MethodPut, path, in, &out)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("failed to merge pull request %d in repo %s", number, repo)
+	switch {
+	case !isErrorStatus(r.Status):
+		return nil
+	case r.Status == http.StatusMethodNotAllowed && isMergeInProgressMessage(out.Message):
+		return SCMError{Msg: fmt.Sprintf("%s: %s", msg, out.Message), Status: r.Status, Err: ErrMergeInProgress}
+	case r.Status == http.StatusMethodNotAllowed, r.Status == http.StatusNotAcceptable, r.Status == http.StatusConflict:
+		return SCMError{Msg: msg, Status: r.Status, Err: ErrConflict}
+	}
+	return SCMError{Msg: msg, Status: r.Status}
+}
+
+// joinCommitMessage returns the title and body as a single commit message.
+func joinCommitMessage(title, body string) string {
+	if body == "" {
+		return title
+	}
+	return title + "\n\n" + body
+}
+
+// isMergeInProgressMessage returns true for the messages that GitHub rejects
+// merges that race with other merges into the target branch with.
+func isMergeInProgressMessage(m string) bool {
+	m = strings.ToLower(m)
+	return strings.Contains(m, "base branch was modified") || strings.Contains(m, "merge already in progress")
+}
+
+// SetPullRequestBase changes the branch that the pull request targets to
+// newBase, so open pull requests can follow a rebased branch rather than
+// being recreated.
+//
+// Retargeting is only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetPullRequestBase(ctx context.Context, repo string, number int, newBase string) error {
+	err := c.call(ctx, "SetPullRequestBase", repo, func(ctx context.Context) error {
+		return c.setPullRequestBase(ctx, repo, number, newBase)
+	})
+	c.emit(Event{Type: "SetPullRequestBase", Repo: repo, Branch: newBase, Number: number, Err: err})
+	return err
+}
+
+func (c *SCMClient) setPullRequestBase(ctx context.Context, repo string, number int, newBase string) error {
+	var (
+		method, path string
+		in           map[string]string
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		method, path = http.MethodPatch, fmt.Sprintf("repos/%s/pulls/%d", repo, number)
+		in = map[string]string{"base": newBase}
+	case scm.DriverGitlab:
+		method, path = http.MethodPut, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d", encodeRepo(repo), number)
+		in = map[string]string{"target_branch": newBase}
+	case scm.DriverGitea:
+		method, path = http.MethodPatch, fmt.Sprintf("api/v1/repos/%s/pulls/%d", repo, number)
+		in = map[string]string{"base": newBase}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, method, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to set base of pull request %d in repo %s to %s", number, repo, newBase), Status: r.Status}
+	}
+	return err
+}
+
+// LockPullRequest locks the conversation of the pull request, so only
+// collaborators can comment on it.
+//
+// The reason is one of the lock reasons accepted by GitHub, e.g. "resolved" or
+// "off-topic", and may be empty, it's ignored by GitLab.
+//
+// Locking is only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, the number of an issue is rejected with a
+// 404 status like an unknown pull request.
+func (c *SCMClient) LockPullRequest(ctx context.Context, repo string, number int, reason string) error {
+	err := c.call(ctx, "LockPullRequest", repo, func(ctx context.Context) error {
+		return c.setPullRequestLocked(ctx, repo, number, true, reason)
+	})
+	c.emit(Event{Type: "LockPullRequest", Repo: repo, Number: number, Err: err})
+	return err
+}
+
+// UnlockPullRequest unlocks the conversation of a pull request that was
+// locked with LockPullRequest.
+//
+// Locking is only supported on GitHub and GitLab.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, the number of an issue is rejected with a
+// 404 status like an unknown pull request.
+func (c *SCMClient) UnlockPullRequest(ctx context.Context, repo string, number int) error {
+	err := c.call(ctx, "UnlockPullRequest", repo, func(ctx context.Context) error {
+		return c.setPullRequestLocked(ctx, repo, number, false, "")
+	})
+	c.emit(Event{Type: "UnlockPullRequest", Repo: repo, Number: number, Err: err})
+	return err
+}
+
+func (c *SCMClient) setPullRequestLocked(ctx context.Context, repo string, number int, locked bool, reason string) error {
+	var (
+		method, path string
+		in           interface{}
+	)
+	action := "lock"
+	if !locked {
+		action = "unlock"
+	}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		// Pull requests are locked through the issues API, which also locks
+		// issues, so the number is checked to be a pull request first.
+		var issue struct {
+			PullRequest interface{} `json:"pull_request"`
+		}
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/issues/%d", repo, number), nil, &issue)
+		notFound := SCMError{Msg: fmt.Sprintf("failed to %s pull request %d in repo %s", action, number, repo), Status: http.StatusNotFound}
+		if r != nil && r.Status == http.StatusNotFound {
+			return notFound
+		}
+		if r != nil && isErrorStatus(r.Status) {
+			return SCMError{Msg: fmt.Sprintf("failed to get pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return err
+		}
+		if issue.PullRequest == nil {
+			return notFound
+		}
+		method, path = http.MethodDelete, fmt.Sprintf("repos/%s/issues/%d/lock", repo, number)
+		if locked {
+			method = http.MethodPut
+			if reason != "" {
+				in = map[string]string{"lock_reason": reason}
+			}
+		}
+	case scm.DriverGitlab:
+		method, path = http.MethodPut, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d", encodeRepo(repo), number)
+		in = map[string]bool{"discussion_locked": locked}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, method, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to %s pull request %d in repo %s", action, number, repo), Status: r.Status}
+	}
+	return err
+}
+
+// IsPullRequestMergeable returns true if the pull request can be merged, and
+// false if the upstream service is still checking.
+//
+// If the pull request has conflicts with the target branch, an error
+// wrapping ErrConflict is returned.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) IsPullRequestMergeable(ctx context.Context, repo string, number int) (bool, error) {
+	var out bool
+	err := c.call(ctx, "IsPullRequestMergeable", repo, func(ctx context.Context) (err error) {
+		out, err = c.isPullRequestMergeable(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) isPullRequestMergeable(ctx context.Context, repo string, number int) (bool, error) {
+	var (
+		mergeable, conflict bool
+		path                string
+		out                 struct {
+			Mergeable      *bool  `json:"mergeable"`
+			MergeableState string `json:"mergeable_state"`
+			MergeStatus    string `json:"merge_status"`
+			HasConflicts   bool   `json:"has_conflicts"`
+		}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/pulls/%d", repo, number)
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s/merge_requests/%d", encodeRepo(repo), number)
+	default:
+		return false, scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return false, SCMError{Msg: fmt.Sprintf("failed to get pull request %d in repo %s", number, repo), Status: r.Status}
+	}
+	if err != nil {
+		return false, err
+	}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		// GitHub returns a null mergeable while it's computed in the background.
+		mergeable = out.Mergeable != nil && *out.Mergeable
+		conflict = (out.Mergeable != nil && !*out.Mergeable) || out.MergeableState == "dirty"
+	case scm.DriverGitlab:
+		mergeable = out.MergeStatus == "can_be_merged" && !out.HasConflicts
+		conflict = out.MergeStatus == "cannot_be_merged" || out.HasConflicts
+	}
+	if conflict {
+		return false, fmt.Errorf("pull request %d in repo %s can't be merged: %w", number, repo, ErrConflict)
+	}
+	return mergeable, nil
+}
+
+// WaitForMergeable checks whether the pull request can be merged every poll
+// interval, until it can, it has conflicts, or the context is done.
+//
+// If the pull request has conflicts, an error wrapping ErrConflict is
+// returned, and if the context is done, the context error is returned.
+func (c *SCMClient) WaitForMergeable(ctx context.Context, repo string, number int, poll time.Duration) error {
+	return c.call(ctx, "WaitForMergeable", repo, func(ctx context.Context) error {
+		return c.waitForMergeable(ctx, repo, number, poll)
+	})
+}
+
+func (c *SCMClient) waitForMergeable(ctx context.Context, repo string, number int, poll time.Duration) error {
+	for {
+		mergeable, err := c.IsPullRequestMergeable(ctx, repo, number)
+		if err != nil {
+			return err
+		}
+		if mergeable {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.getClock().After(poll):
+		}
+	}
+}
+
+// GetPullRequestDiff returns the unified diff of the changes in a pull
+// request.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetPullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	var out string
+	err := c.call(ctx, "GetPullRequestDiff", repo, func(ctx context.Context) (err error) {
+		out, err = c.getPullRequestDiff(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getPullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		header := http.Header{"Accept": {"application/vnd.github.v3.diff"}}
+		r, body, truncated, err := c.doRaw(ctx, http.MethodGet, fmt.Sprintf("repos/%s/pulls/%d", repo, number), header, c.maxDiffSize)
+		if r != nil && isErrorStatus(r.Status) {
+			return "", SCMError{Msg: fmt.Sprintf("failed to get diff for pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return "", err
+		}
+		diff := string(body)
+		if truncated {
+			diff += DiffTruncatedMarker
+		}
+		return diff, nil
+	case scm.DriverGitlab:
+		return c.getMergeRequestDiffGitLab(ctx, repo, number)
+	default:
+		return "", scm.ErrNotSupported
+	}
+}
+
+// getMergeRequestDiffGitLab assembles a unified diff from the per-file diffs
+// in the merge request changes, GitLab has no endpoint for the whole diff.
+func (c *SCMClient) getMergeRequestDiffGitLab(ctx context.Context, repo string, number int) (string, error) {
+	out := struct {
+		Changes []glDiff `json:"changes"`
+	}{}
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d/changes", encodeRepo(repo), number), nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: fmt.Sprintf("failed to get diff for merge request %d in repo %s", number, repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.joinDiffsGitLab(out.Changes), nil
+}
+
+// glDiff is the diff of a single file in GitLab's changes and compare
+// responses.
+type glDiff struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Diff    string `json:"diff"`
+}
+
+// joinDiffsGitLab assembles a unified diff from the per-file diffs, truncated
+// to the maximum diff size.
+func (c *SCMClient) joinDiffsGitLab(diffs []glDiff) string {
+	var b strings.Builder
+	for _, change := range diffs {
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n%s", change.OldPath, change.NewPath, change.OldPath, change.NewPath, change.Diff)
+	}
+	diff := b.String()
+	if c.maxDiffSize > 0 && len(diff) > c.maxDiffSize {
+		diff = diff[:c.maxDiffSize] + DiffTruncatedMarker
+	}
+	return diff
+}
+
+// CountOpenPullRequests returns the number of open pull requests in the repo.
+//
+// GitHub and GitLab report the count without listing the pull requests, for
+// other drivers all the open pull requests are listed to count them.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CountOpenPullRequests(ctx context.Context, repo string) (int, error) {
+	var out int
+	err := c.call(ctx, "CountOpenPullRequests", repo, func(ctx context.Context) (err error) {
+		out, err = c.countOpenPullRequests(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) countOpenPullRequests(ctx context.Context, repo string) (int, error) {
+	msg := fmt.Sprintf("failed to count open pull requests in repo %s", repo)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		var out struct {
+			TotalCount int `json:"total_count"`
+		}
+		q := url.Values{"q": {fmt.Sprintf("repo:%s is:pr is:open", repo)}, "per_page": {"1"}}
+		r, err := c.do(ctx, http.MethodGet, "search/issues?"+q.Encode(), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return 0, SCMError{Msg: msg, Status: r.Status}
+		}
+		if err != nil {
+			return 0, err
+		}
+		return out.TotalCount, nil
+	case scm.DriverGitlab:
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/projects/%s/merge_requests?state=opened&per_page=1", encodeRepo(repo)), nil, nil)
+		if r != nil && isErrorStatus(r.Status) {
+			return 0, SCMError{Msg: msg, Status: r.Status}
+		}
+		if err != nil {
+			return 0, err
+		}
+		// GitLab leaves out the total for very large results.
+		if n, err := strconv.Atoi(r.Header.Get("X-Total")); err == nil {
+			return n, nil
+		}
+	}
+	prs, err := c.listOpenPullRequests(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	return len(prs), nil
+}
+
+// ClosePullRequestsOlderThan closes the open pull requests in the repo that
+// were created more than d ago, and are accepted by the filter, if it's not
+// nil, and returns the number of pull requests that were closed.
+//
+// A failure to close a pull request doesn't stop the remaining pull requests
+// from being closed, all the failures are returned together in a BulkError.
+func (c *SCMClient) ClosePullRequestsOlderThan(ctx context.Context, repo string, d time.Duration, filter func(*scm.PullRequest) bool) (int, error) {
+	var out int
+	err := c.call(ctx, "ClosePullRequestsOlderThan", repo, func(ctx context.Context) (err error) {
+		out, err = c.closePullRequestsOlderThan(ctx, repo, d, filter)
+		return err
+	})
+	c.emit(Event{Type: "ClosePullRequestsOlderThan", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) closePullRequestsOlderThan(ctx context.Context, repo string, d time.Duration, filter func(*scm.PullRequest) bool) (int, error) {
+	prs, err := c.listOpenPullRequests(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := c.getClock().Now().Add(-d)
+	var (
+		closed int
+		errs   []error
+	)
+	for _, pr := range prs {
+		if !pr.Created.Before(cutoff) || (filter != nil && !filter(pr)) {
+			continue
+		}
+		r, err := c.scmClient.PullRequests.Close(ctx, repo, pr.Number)
+		if r != nil && isErrorStatus(r.Status) {
+			err = SCMError{Msg: fmt.Sprintf("failed to close pull request %d in repo %s", pr.Number, repo), Status: r.Status}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		closed++
+	}
+	if len(errs) > 0 {
+		return closed, BulkError{Errs: errs}
+	}
+	return closed, nil
+}
+
+// AddLabelsToMatching adds the labels to the open pull requests in the repo
+// that are accepted by the match func, or all of them if it's nil, and returns
+// the number of pull requests that were labelled, pull requests that already
+// have all the labels are skipped.
+//
+// Labelling pull requests is only supported on GitHub and GitLab.
+//
+// A failure to label a pull request doesn't stop the remaining pull requests
+// from being labelled, all the failures are returned together in a BulkError.
+func (c *SCMClient) AddLabelsToMatching(ctx context.Context, repo string, match func(*scm.PullRequest) bool, labels []string) (int, error) {
+	var out int
+	err := c.call(ctx, "AddLabelsToMatching", repo, func(ctx context.Context) (err error) {
+		out, err = c.addLabelsToMatching(ctx, repo, match, labels)
+		return err
+	})
+	c.emit(Event{Type: "AddLabelsToMatching", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) addLabelsToMatching(ctx context.Context, repo string, match func(*scm.PullRequest) bool, labels []string) (int, error) {
+	if len(labels) == 0 {
+		return 0, errors.New("no labels to add")
+	}
+	if c.scmClient.Driver != scm.DriverGithub && c.scmClient.Driver != scm.DriverGitlab {
+		return 0, scm.ErrNotSupported
+	}
+	prs, err := c.listOpenPullRequests(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		labelled int
+		errs     []error
+	)
+	for _, pr := range prs {
+		if (match != nil && !match(pr)) || hasLabels(pr, labels) {
+			continue
+		}
+		if err := c.addLabels(ctx, repo, pr.Number, labels); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		labelled++
+	}
+	if len(errs) > 0 {
+		return labelled, BulkError{Errs: errs}
+	}
+	return labelled, nil
+}
+
+// addLabels adds the labels to a single pull request, go-scm provides no way
+// to do this, so the request is made directly to the driver's API.
+func (c *SCMClient) addLabels(ctx context.Context, repo string, number int, labels []string) error {
+	var (
+		method, path string
+		in           interface{}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		method, path = http.MethodPost, fmt.Sprintf("repos/%s/issues/%d/labels", repo, number)
+		in = map[string][]string{"labels": labels}
+	case scm.DriverGitlab:
+		method, path = http.MethodPut, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d", encodeRepo(repo), number)
+		in = map[string]string{"add_labels": strings.Join(labels, ",")}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, method, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to add labels to pull request %d in repo %s", number, repo), Status: r.Status}
+	}
+	return err
+}
+
+// hasLabels returns true if the pull request has all the labels.
+func hasLabels(pr *scm.PullRequest, labels []string) bool {
+	for _, want := range labels {
+		found := false
+		for _, l := range pr.Labels {
+			if l.Name == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// listOpenPullRequests pages through all the open pull requests in the repo.
+func (c *SCMClient) listOpenPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error) {
+	var (
+		all  []*scm.PullRequest
+		opts = scm.PullRequestListOptions{Open: true, Size: 100, Page: 1}
+	)
+	for {
+		prs, r, err := c.scmClient.PullRequests.List(ctx, repo, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list pull requests in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, prs...)
+		if r == nil || r.Page.Next == 0 {
+			return all, nil
+		}
+		opts.Page = r.Page.Next
+	}
+}
+
+// CreateForkPullRequest creates a pull request from a branch in the headRepo,
+// usually a fork, to the base branch in the upstream repo.
+//
+// The title and body are taken from the input, and the source and target are
+// set from the branches in the format required by the driver.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateForkPullRequest(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	var out *scm.PullRequest
+	err := c.call(ctx, "CreateForkPullRequest", upstreamRepo, func(ctx context.Context) (err error) {
+		out, err = c.createForkPullRequest(ctx, upstreamRepo, headRepo, headBranch, baseBranch, inp)
+		return err
+	})
+	c.emit(Event{Type: "CreateForkPullRequest", Repo: upstreamRepo, Branch: headBranch, Number: prNumber(out), Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createForkPullRequest(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub, scm.DriverGitea:
+		owner, _ := scm.Split(headRepo)
+		forkInp := *inp
+		forkInp.Source = owner + ":" + headBranch
+		forkInp.Target = baseBranch
+		pr, _, err := c.scmClient.PullRequests.Create(ctx, upstreamRepo, &forkInp)
+		return pr, err
+	case scm.DriverGitlab:
+		return c.createForkMergeRequestGitLab(ctx, upstreamRepo, headRepo, headBranch, baseBranch, inp)
+	default:
+		return nil, scm.ErrNotSupported
+	}
+}
+
+// createForkMergeRequestGitLab creates the merge request in the fork, with
+// the upstream project as the target, which go-scm doesn't support.
+func (c *SCMClient) createForkMergeRequestGitLab(ctx context.Context, upstreamRepo, headRepo, headBranch, baseBranch string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	upstream, r, err := c.scmClient.Repositories.Find(ctx, upstreamRepo)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get repo %s", upstreamRepo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		SHA          string `json:"sha"`
+		WebURL       string `json:"web_url"`
+	}{}
+	r, err = c.do(ctx, http.MethodPost, fmt.Sprintf("api/v4/projects/%s/merge_requests", encodeRepo(headRepo)), map[string]string{
+		"title":             inp.Title,
+		"description":       inp.Body,
+		"source_branch":     headBranch,
+		"target_branch":     baseBranch,
+		"target_project_id": upstream.ID,
+	}, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to create merge request from %s to repo %s", headRepo, upstreamRepo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scm.PullRequest{
+		Number: out.IID,
+		Title:  out.Title,
+		Body:   out.Description,
+		Sha:    out.SHA,
+		Source: out.SourceBranch,
+		Target: out.TargetBranch,
+		Fork:   headRepo,
+		Link:   out.WebURL,
+	}, nil
+}
+
+// splitForkSource splits a source in the "owner:branch" format used for
+// pull requests from forks.
+func splitForkSource(source string) (owner, branch string, ok bool) {
+	i := strings.Index(source, ":")
+	if i < 0 {
+		return "", source, false
+	}
+	return source[:i], source[i+1:], true
+}
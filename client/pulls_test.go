@@ -0,0 +1,1050 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testDiff = `diff --git a/README.md b/README.md
+index 3b18e51..a042389 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-hello world
++hello there
+`
+
+func TestGetPullRequestDiff(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		MatchHeader("Accept", "application/vnd.github.v3.diff").
+		Reply(http.StatusOK).
+		BodyString(testDiff)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	diff, err := client.GetPullRequestDiff(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != testDiff {
+		t.Fatalf("got diff %q, want %q", diff, testDiff)
+	}
+}
+
+func TestGetPullRequestDiffWithMaxDiffSize(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		BodyString(testDiff)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithMaxDiffSize(20))
+
+	diff, err := client.GetPullRequestDiff(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testDiff[:20] + DiffTruncatedMarker; diff != want {
+		t.Fatalf("got diff %q, want %q", diff, want)
+	}
+}
+
+func TestGetPullRequestDiffInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests/2/changes").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"changes": []map[string]string{
+				{"old_path": "README.md", "new_path": "README.md", "diff": "@@ -1 +1 @@\n-hello world\n+hello there\n"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	diff, err := client.GetPullRequestDiff(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-hello world\n+hello there\n"
+	if diff != want {
+		t.Fatalf("got diff %q, want %q", diff, want)
+	}
+}
+
+func TestGetPullRequestDiffWithMissingPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetPullRequestDiff(context.Background(), "Codertocat/Hello-World", 2)
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCreatePullRequestEnsuringBase(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/production").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Branch not found"})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "name": "Hello-World", "default_branch": "master"})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		MatchType("json").
+		JSON(map[string]string{"ref": "refs/heads/production", "sha": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"}).
+		Reply(http.StatusCreated).
+		File("testdata/created_ref.json")
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusCreated).
+		File("testdata/pr_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreatePullRequest(context.Background(), "Codertocat/Hello-World", &scm.PullRequestInput{
+		Title:  "Bootstrap production",
+		Source: "bootstrap",
+		Target: "production",
+	}, EnsureBase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("base branch was not created")
+	}
+}
+
+func TestCreatePullRequestEnsuringExistingBase(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusCreated).
+		File("testdata/pr_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreatePullRequest(context.Background(), "Codertocat/Hello-World", &scm.PullRequestInput{
+		Title:  "Update config",
+		Source: "update",
+		Target: "master",
+	}, EnsureBase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not created")
+	}
+}
+
+func TestCreateForkPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		MatchType("json").
+		JSON(map[string]string{"title": "Fix typo", "body": "", "head": "octocat:fix-typo", "base": "master"}).
+		Reply(http.StatusCreated).
+		File("testdata/pr_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreateForkPullRequest(context.Background(), "Codertocat/Hello-World", "octocat/Hello-World", "fix-typo", "master", &scm.PullRequestInput{Title: "Fix typo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not created")
+	}
+}
+
+func TestCreatePullRequestFromForkInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 42, "path": "Hello-World", "namespace": map[string]string{"path": "Codertocat"}})
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/octocat/Hello-World/merge_requests").
+		MatchType("json").
+		JSON(map[string]string{"title": "Fix typo", "description": "", "source_branch": "fix-typo", "target_branch": "master", "target_project_id": "42"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"iid": 7, "source_branch": "fix-typo", "target_branch": "master", "web_url": "https://gitlab.com/Codertocat/Hello-World/-/merge_requests/7"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	pr, err := client.CreatePullRequest(context.Background(), "Codertocat/Hello-World", &scm.PullRequestInput{
+		Title:  "Fix typo",
+		Source: "octocat:fix-typo",
+		Target: "master",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 7 || pr.Fork != "octocat/Hello-World" {
+		t.Fatalf("got %#v", pr)
+	}
+}
+
+func TestWaitForMergeable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "mergeable": nil, "mergeable_state": "unknown"})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "mergeable": true, "mergeable_state": "clean"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock))
+
+	if err := client.WaitForMergeable(context.Background(), "Codertocat/Hello-World", 2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if l := len(clock.waits); l != 1 || clock.waits[0] != time.Minute {
+		t.Fatalf("got waits %v, want one wait of a minute", clock.waits)
+	}
+}
+
+func TestWaitForMergeableWithConflict(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "mergeable": false, "mergeable_state": "dirty"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{}))
+
+	err = client.WaitForMergeable(context.Background(), "Codertocat/Hello-World", 2, time.Minute)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want %v", err, ErrConflict)
+	}
+}
+
+func TestWaitForMergeableWithCancelledContext(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "mergeable": nil})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client := New(scmClient, WithClock(&fakeClock{onAfter: cancel, block: true}))
+
+	err = client.WaitForMergeable(ctx, "Codertocat/Hello-World", 2, time.Minute)
+	if err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+// fakeClock records the waits, and returns immediately unless block is set.
+type fakeClock struct {
+	now     time.Time
+	waits   []time.Duration
+	block   bool
+	onAfter func()
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.waits = append(f.waits, d)
+	if f.onAfter != nil {
+		f.onAfter()
+	}
+	ch := make(chan time.Time, 1)
+	if !f.block {
+		f.now = f.now.Add(d)
+		ch <- f.now
+	}
+	return ch
+}
+
+func TestClosePullRequestsOlderThan(t *testing.T) {
+	now := time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"number": 1, "title": "Stale", "created_at": now.Add(-72 * time.Hour), "head": map[string]string{"ref": "gitops-a"}},
+			{"number": 2, "title": "Recent", "created_at": now.Add(-time.Hour), "head": map[string]string{"ref": "gitops-b"}},
+			{"number": 3, "title": "Stale, not a bot", "created_at": now.Add(-72 * time.Hour), "head": map[string]string{"ref": "feature"}},
+			{"number": 4, "title": "Stale, fails", "created_at": now.Add(-72 * time.Hour), "head": map[string]string{"ref": "gitops-c"}},
+		})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/1").
+		JSON(map[string]string{"state": "closed"}).
+		Reply(http.StatusOK)
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/4").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{now: now}))
+
+	closed, err := client.ClosePullRequestsOlderThan(context.Background(), "Codertocat/Hello-World", 24*time.Hour, func(pr *scm.PullRequest) bool {
+		return strings.HasPrefix(pr.Source, "gitops-")
+	})
+	if !test.MatchError(t, `failed to close pull request 4.*\(403\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if closed != 1 {
+		t.Fatalf("got %d closed pull requests, want 1", closed)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull requests were not closed")
+	}
+}
+
+func TestAddLabelsToMatching(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"number": 1, "title": "Bump yaml", "head": map[string]string{"ref": "dependabot/go_modules/yaml"}},
+			{"number": 2, "title": "Add feature", "head": map[string]string{"ref": "feature"}},
+			{"number": 3, "title": "Bump scm", "head": map[string]string{"ref": "dependabot/go_modules/scm"}, "labels": []map[string]string{{"name": "dependencies"}}},
+			{"number": 4, "title": "Bump logr", "head": map[string]string{"ref": "dependabot/go_modules/logr"}},
+		})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/1/labels").
+		MatchType("json").
+		JSON(map[string][]string{"labels": {"dependencies"}}).
+		Reply(http.StatusOK)
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/4/labels").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	labelled, err := client.AddLabelsToMatching(context.Background(), "Codertocat/Hello-World", func(pr *scm.PullRequest) bool {
+		return strings.HasPrefix(pr.Source, "dependabot/")
+	}, []string{"dependencies"})
+	if !test.MatchError(t, `failed to add labels to pull request 4.*\(403\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if labelled != 1 {
+		t.Fatalf("got %d labelled pull requests, want 1", labelled)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull requests were not labelled")
+	}
+}
+
+func TestAddLabelsToMatchingInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{{"iid": 7, "title": "Bump yaml", "source_branch": "dependabot/yaml"}})
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/7").
+		MatchType("json").
+		JSON(map[string]string{"add_labels": "dependencies,bot"}).
+		Reply(http.StatusOK)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	labelled, err := client.AddLabelsToMatching(context.Background(), "Codertocat/Hello-World", nil, []string{"dependencies", "bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labelled != 1 {
+		t.Fatalf("got %d labelled pull requests, want 1", labelled)
+	}
+	if !gock.IsDone() {
+		t.Fatal("merge request was not labelled")
+	}
+}
+
+func TestListPullRequestCommits(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/commits").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/pulls/2/commits?page=2>; rel="next"`).
+		JSON([]map[string]interface{}{
+			{"sha": "7fd1a60", "commit": map[string]interface{}{"message": "first", "author": map[string]string{"name": "John Doe"}}},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/commits").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"sha": "a84d88e", "commit": map[string]interface{}{"message": "second"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	commits, err := client.ListPullRequestCommits(context.Background(), "Codertocat/Hello-World", 2, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(commits); l != 2 {
+		t.Fatalf("got %d commits, want 2", l)
+	}
+	if commits[0].Message != "first" || commits[0].Author.Name != "John Doe" || commits[1].Sha != "a84d88e" {
+		t.Fatalf("got commits %#v %#v, want the commits from both pages", commits[0], commits[1])
+	}
+}
+
+func TestListPullRequestCommitsWithMaxItems(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/commits").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/pulls/2/commits?page=2>; rel="next"`).
+		JSON([]map[string]interface{}{{"sha": "7fd1a60"}, {"sha": "a84d88e"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithMaxItems(1))
+
+	commits, err := client.ListPullRequestCommits(context.Background(), "Codertocat/Hello-World", 2, scm.ListOptions{})
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("got %v, want ErrTruncated", err)
+	}
+	if l := len(commits); l != 1 || commits[0].Sha != "7fd1a60" {
+		t.Fatalf("got %d commits, want the first commit", l)
+	}
+}
+
+func TestEnableAutoMerge(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"number":2`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]string{"id": "PR_kwDOA"}}}})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"variables":{"id":"PR_kwDOA","method":"SQUASH"}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"enablePullRequestAutoMerge": map[string]interface{}{"clientMutationId": nil}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.EnableAutoMerge(context.Background(), "Codertocat/Hello-World", 2, MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("auto-merge was not enabled")
+	}
+}
+
+func TestEnableAutoMergeWithGraphQLError(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]string{"id": "PR_kwDOA"}}}})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"errors": []map[string]string{{"message": "Pull request Auto merge is not allowed for this repository"}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.EnableAutoMerge(context.Background(), "Codertocat/Hello-World", 2, MergeMethodMerge)
+	if !test.MatchError(t, `failed to enable auto-merge for pull request 2.*not allowed`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestEnableAutoMergeInGitLab(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.EnableAutoMerge(context.Background(), "Codertocat/Hello-World", 2, MergeMethodMerge); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+}
+
+func TestListPullRequestCommitsWithUnknownPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/3/commits").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListPullRequestCommits(context.Background(), "Codertocat/Hello-World", 3, scm.ListOptions{})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCountOpenPullRequests(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		MatchParam("q", `^repo:Codertocat/Hello-World is:pr is:open$`).
+		MatchParam("per_page", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"total_count": 42, "items": []interface{}{}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	n, err := client.CountOpenPullRequests(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Fatalf("got %d open pull requests, want 42", n)
+	}
+}
+
+func TestCountOpenPullRequestsInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests").
+		MatchParam("state", "opened").
+		Reply(http.StatusOK).
+		SetHeader("X-Total", "7").
+		JSON([]interface{}{})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	n, err := client.CountOpenPullRequests(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 {
+		t.Fatalf("got %d open pull requests, want 7", n)
+	}
+}
+
+func TestCountOpenPullRequestsInGitea(t *testing.T) {
+	gock.New("https://gitea.example.com").
+		Get("/api/v1/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{{"number": 1, "state": "open"}, {"number": 2, "state": "open"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	n, err := client.CountOpenPullRequests(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("got %d open pull requests, want 2", n)
+	}
+}
+
+func TestMergePullRequestRetriesMergeInProgress(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1").
+		Times(2).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/1/merge").
+		Reply(http.StatusMethodNotAllowed).
+		JSON(map[string]string{"message": "Base branch was modified. Review and try the merge again."})
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/1/merge").
+		BodyString(`{"merge_method":"squash","sha":"6dcb09b5b57875f334f61aebed695e2e4193db5e"}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"merged": true, "message": "Pull Request successfully merged"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock))
+
+	err = client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodSquash, RetryMergeInProgress(2, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clock.waits) != 1 || clock.waits[0] != time.Second {
+		t.Fatalf("got waits %v, want one wait of a second", clock.waits)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the merge was not retried")
+	}
+}
+
+func TestMergePullRequestWithMergeInProgress(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/1/merge").
+		Reply(http.StatusMethodNotAllowed).
+		JSON(map[string]string{"message": "Merge already in progress"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodMerge)
+	if !errors.Is(err, ErrMergeInProgress) {
+		t.Fatalf("got %v, want ErrMergeInProgress", err)
+	}
+}
+
+func TestMergePullRequestWithConflict(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/1/merge").
+		Reply(http.StatusMethodNotAllowed).
+		JSON(map[string]string{"message": "Pull Request is not mergeable"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock))
+
+	err = client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodMerge, RetryMergeInProgress(2, time.Second))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want ErrConflict", err)
+	}
+	if len(clock.waits) != 0 {
+		t.Fatalf("got waits %v, want none", clock.waits)
+	}
+}
+
+func TestMergePullRequestInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests/1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 1, "sha": "abc123", "source_branch": "feature", "target_branch": "main"})
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/1/merge").
+		BodyString(`{"sha":"abc123","squash":true}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 1, "state": "merged"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergePullRequestWithCommitMessage(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/1/merge").
+		BodyString(`{"commit_message":"Promotes v1.2.0.","commit_title":"Promote to staging","merge_method":"squash","sha":"6dcb09b5b57875f334f61aebed695e2e4193db5e"}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"merged": true, "message": "Pull Request successfully merged"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodSquash, MergeCommitMessage("Promote to staging", "Promotes v1.2.0."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not merged")
+	}
+}
+
+func TestMergePullRequestWithCommitMessageInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests/1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 1, "sha": "abc123", "source_branch": "feature", "target_branch": "main"})
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/1/merge").
+		BodyString(`{"sha":"abc123","squash":true,"squash_commit_message":"Promote to staging\n\nPromotes v1.2.0."}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 1, "state": "merged"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.MergePullRequest(context.Background(), "Codertocat/Hello-World", 1, MergeMethodSquash, MergeCommitMessage("Promote to staging", "Promotes v1.2.0."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("merge request was not merged")
+	}
+}
+
+func TestSetPullRequestBase(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/2").
+		MatchType("json").
+		JSON(map[string]string{"base": "staging"}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetPullRequestBase(context.Background(), "Codertocat/Hello-World", 2, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not retargeted")
+	}
+}
+
+func TestSetPullRequestBaseInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/2").
+		MatchType("json").
+		JSON(map[string]string{"target_branch": "staging"}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 2})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetPullRequestBase(context.Background(), "Codertocat/Hello-World", 2, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("merge request was not retargeted")
+	}
+}
+
+func TestSetPullRequestBaseNotFound(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/9").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.SetPullRequestBase(context.Background(), "Codertocat/Hello-World", 9, "staging")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestLockPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/issues/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "pull_request": map[string]string{"url": "https://api.github.com/repos/Codertocat/Hello-World/pulls/2"}})
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/issues/2/lock").
+		MatchType("json").
+		JSON(map[string]string{"lock_reason": "resolved"}).
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.LockPullRequest(context.Background(), "Codertocat/Hello-World", 2, "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not locked")
+	}
+}
+
+func TestUnlockPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/issues/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "pull_request": map[string]string{"url": "https://api.github.com/repos/Codertocat/Hello-World/pulls/2"}})
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/issues/2/lock").
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.UnlockPullRequest(context.Background(), "Codertocat/Hello-World", 2); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not unlocked")
+	}
+}
+
+func TestLockPullRequestInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/2").
+		MatchType("json").
+		JSON(map[string]bool{"discussion_locked": true}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"iid": 2})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.LockPullRequest(context.Background(), "Codertocat/Hello-World", 2, "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("merge request was not locked")
+	}
+}
+
+func TestLockPullRequestNotFound(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/issues/9").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.LockPullRequest(context.Background(), "Codertocat/Hello-World", 9, "resolved")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestLockPullRequestWithIssue(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/issues/3").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 3})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.LockPullRequest(context.Background(), "Codertocat/Hello-World", 3, "resolved")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the issue was not checked")
+	}
+}
+
+func TestCreatePullRequestIfChanged(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/master...new-feature").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"status": "ahead", "ahead_by": 1, "behind_by": 0})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		MatchType("json").
+		JSON(map[string]string{"title": "New feature", "body": "", "head": "new-feature", "base": "master"}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/pr_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	pr, created, err := client.CreatePullRequestIfChanged(context.Background(), "Codertocat/Hello-World", &scm.PullRequestInput{Title: "New feature", Source: "new-feature", Target: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || pr.Number != 1347 {
+		t.Fatalf("got %v, %v, want pull request 1347 to be created", pr, created)
+	}
+}
+
+func TestCreatePullRequestIfChangedWithoutChanges(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/master...new-feature").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"status": "identical", "ahead_by": 0, "behind_by": 0})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	pr, created, err := client.CreatePullRequestIfChanged(context.Background(), "Codertocat/Hello-World", &scm.PullRequestInput{Title: "New feature", Source: "new-feature", Target: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || pr != nil {
+		t.Fatalf("got %v, %v, want no pull request", pr, created)
+	}
+}
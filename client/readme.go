@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// readmeExtensions are the extensions of README files, in order of preference
+// when a repo has more than one README.
+var readmeExtensions = []string{".md", ".markdown", ".rst", ".txt", ".adoc", ".org", ""}
+
+// GetReadme gets the README file at the root of the repository, the name is
+// matched case-insensitively, with any of the common extensions.
+//
+// On GitHub the README is located by the readme endpoint, for other drivers
+// the root directory is listed to find it.
+//
+// If the repo has no README, an error wrapping ErrNotFound is returned, for
+// which IsNotFound returns true. If an HTTP error is returned by the upstream
+// service, an error with the response status code is returned.
+func (c *SCMClient) GetReadme(ctx context.Context, repo, ref string) (*scm.Content, error) {
+	var out *scm.Content
+	err := c.call(ctx, "GetReadme", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getReadme(ctx, repo, ref)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getReadme(ctx context.Context, repo, ref string) (*scm.Content, error) {
+	if c.scmClient.Driver == scm.DriverGithub {
+		return c.getReadmeGitHub(ctx, repo, ref)
+	}
+	entries, err := c.listFiles(ctx, repo, ref, "", 0)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Kind == scm.ContentKindFile {
+			names = append(names, e.Path)
+		}
+	}
+	name, ok := FindReadme(names)
+	if !ok {
+		return nil, readmeNotFound(repo, ref)
+	}
+	return c.getFile(ctx, repo, ref, name)
+}
+
+func (c *SCMClient) getReadmeGitHub(ctx context.Context, repo, ref string) (*scm.Content, error) {
+	path := fmt.Sprintf("repos/%s/readme", repo)
+	if ref != "" {
+		path += "?ref=" + url.QueryEscape(ref)
+	}
+	var out struct {
+		Path     string `json:"path"`
+		Sha      string `json:"sha"`
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && r.Status == http.StatusNotFound {
+		return nil, readmeNotFound(repo, ref)
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get README from repo %s ref %s", repo, ref), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(out.Content)
+	if out.Encoding == "base64" {
+		// GitHub wraps the base64 content in lines.
+		if data, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(out.Content, "\n", "")); err != nil {
+			return nil, fmt.Errorf("failed to decode README %s: %w", out.Path, err)
+		}
+	}
+	return &scm.Content{Path: out.Path, Data: data, BlobID: out.Sha}, nil
+}
+
+// FindReadme returns the README among the paths of the files at the root of a
+// repo, preferring Markdown if there are several.
+func FindReadme(paths []string) (string, bool) {
+	for _, ext := range readmeExtensions {
+		for _, p := range paths {
+			name := pathpkg.Base(p)
+			if strings.EqualFold(name, "readme"+ext) {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+func readmeNotFound(repo, ref string) error {
+	return SCMError{Msg: fmt.Sprintf("failed to find README in repo %s ref %s", repo, ref), Status: http.StatusNotFound, Err: ErrNotFound}
+}
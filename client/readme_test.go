@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetReadme(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/readme").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"path": "README.md", "sha": "3d21ec5", "content": "IyBIZWxs\nbyBXb3JsZAo=\n", "encoding": "base64"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	readme, err := client.GetReadme(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readme.Path != "README.md" || string(readme.Data) != "# Hello World\n" || readme.BlobID != "3d21ec5" {
+		t.Fatalf("got %#v, want the decoded README", readme)
+	}
+}
+
+func TestGetReadmeWithNoReadme(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/readme").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetReadme(context.Background(), "Codertocat/Hello-World", "main")
+	if !errors.Is(err, ErrNotFound) || !IsNotFound(err) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetReadmeInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"path": "docs", "type": "tree", "mode": "040000"},
+			{"path": "readme.txt", "type": "blob", "mode": "100644"},
+			{"path": "Readme.MD", "type": "blob", "mode": "100644"},
+		})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/Readme.MD").
+		MatchParam("ref", "main").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "Readme.MD", "content": "IyBIZWxsbyBXb3JsZAo=", "encoding": "base64"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	readme, err := client.GetReadme(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readme.Path != "Readme.MD" || string(readme.Data) != "# Hello World\n" {
+		t.Fatalf("got %#v, want the Markdown README", readme)
+	}
+}
+
+func TestFindReadme(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"main.go", "README"}, "README"},
+		{[]string{"README", "readme.rst", "README.md"}, "README.md"},
+		{[]string{"README.txt", "readme.RST"}, "readme.RST"},
+		{[]string{"READMEFIRST.md", "main.go"}, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := FindReadme(tt.paths); got != tt.want {
+			t.Errorf("FindReadme(%v) got %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
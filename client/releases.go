@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// ReleaseAsset is a file attached to a release.
+//
+// go-scm has no release asset API, so assets are read directly from the
+// upstream service.
+type ReleaseAsset struct {
+	ID          int
+	Name        string
+	Label       string
+	ContentType string
+	Size        int
+	DownloadURL string
+	Created     time.Time
+	Updated     time.Time
+}
+
+type ghReleaseAsset struct {
+	ID                 int       `json:"id"`
+	Name               string    `json:"name"`
+	Label              string    `json:"label"`
+	ContentType        string    `json:"content_type"`
+	Size               int       `json:"size"`
+	BrowserDownloadURL string    `json:"browser_download_url"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+func (a ghReleaseAsset) convert() *ReleaseAsset {
+	return &ReleaseAsset{
+		ID:          a.ID,
+		Name:        a.Name,
+		Label:       a.Label,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		DownloadURL: a.BrowserDownloadURL,
+		Created:     a.CreatedAt,
+		Updated:     a.UpdatedAt,
+	}
+}
+
+// ListReleaseAssets returns the assets attached to the release.
+//
+// Release assets are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListReleaseAssets(ctx context.Context, repo string, releaseID int) ([]*ReleaseAsset, error) {
+	var out []*ReleaseAsset
+	err := c.call(ctx, "ListReleaseAssets", repo, func(ctx context.Context) (err error) {
+		out, err = c.listReleaseAssets(ctx, repo, releaseID)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) listReleaseAssets(ctx context.Context, repo string, releaseID int) ([]*ReleaseAsset, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	opts := scm.ListOptions{Size: 100}
+	var all []*ReleaseAsset
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var assets []ghReleaseAsset
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/releases/%d/assets?%s", repo, releaseID, params.Encode()), nil, &assets)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list assets of release %d in repo %s", releaseID, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assets {
+			all = append(all, a.convert())
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// DownloadReleaseAsset writes the content of the release asset to w, the
+// content is streamed, so large assets are never held in memory.
+//
+// Failed downloads are only retried if nothing was written to w.
+//
+// Release assets are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) DownloadReleaseAsset(ctx context.Context, repo string, assetID int, w io.Writer) error {
+	return c.call(ctx, "DownloadReleaseAsset", repo, func(ctx context.Context) error {
+		return c.downloadReleaseAsset(ctx, repo, assetID, w)
+	})
+}
+
+func (c *SCMClient) downloadReleaseAsset(ctx context.Context, repo string, assetID int, w io.Writer) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return scm.ErrNotSupported
+	}
+	header := http.Header{}
+	header.Set("Accept", "application/octet-stream")
+	res, err := c.scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("repos/%s/releases/assets/%d", repo, assetID),
+		Header: header,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to download asset %d from repo %s", assetID, repo), Status: res.Status}
+	}
+	n, err := io.Copy(w, res.Body)
+	if err != nil && n > 0 {
+		// The cause is not wrapped, so that the partial download is not
+		// retried into the same writer.
+		return fmt.Errorf("failed to download asset %d from repo %s after %d bytes: %v", assetID, repo, n, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download asset %d from repo %s: %w", assetID, repo, err)
+	}
+	return nil
+}
+
+// UploadReleaseAsset attaches an asset with the name and content type to the
+// release, streaming the content from r, so large assets are never held in
+// memory.
+//
+// The size of the content must be known before it's sent, so r must have a
+// Len method, e.g. a bytes.Reader, or be an io.Seeker, e.g. an os.File, which
+// is uploaded from its current offset. Uploads are never retried, as the
+// content of r is consumed.
+//
+// Release assets are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) UploadReleaseAsset(ctx context.Context, repo string, releaseID int, name, contentType string, r io.Reader) (*ReleaseAsset, error) {
+	var out *ReleaseAsset
+	err := c.call(ctx, "UploadReleaseAsset", repo, func(ctx context.Context) (err error) {
+		out, err = c.uploadReleaseAsset(ctx, repo, releaseID, name, contentType, r)
+		return err
+	})
+	c.emit(Event{Type: "UploadReleaseAsset", Repo: repo, Path: name, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) uploadReleaseAsset(ctx context.Context, repo string, releaseID int, name, contentType string, r io.Reader) (*ReleaseAsset, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	size, err := readerSize(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload asset %s to repo %s: %w", name, repo, err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+	res, err := c.scmClient.Do(ctx, &scm.Request{
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("%srepos/%s/releases/%d/assets?%s", c.uploadURL(), repo, releaseID, url.Values{"name": {name}}.Encode()),
+		Header: header,
+		Body:   r,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to upload asset %s to release %d in repo %s", name, releaseID, repo), Status: res.Status}
+	}
+	var asset ghReleaseAsset
+	if err := json.NewDecoder(res.Body).Decode(&asset); err != nil {
+		return nil, err
+	}
+	return asset.convert(), nil
+}
+
+// uploadURL returns the base URL of the GitHub uploads API, which is on a
+// separate host from the API on github.com, and under /api/uploads/ on
+// GitHub Enterprise Server.
+func (c *SCMClient) uploadURL() string {
+	base := *c.scmClient.BaseURL
+	if base.Host == "api.github.com" {
+		return "https://uploads.github.com/"
+	}
+	base.Path = strings.TrimSuffix(strings.TrimSuffix(base.Path, "/"), "/v3") + "/uploads/"
+	return base.String()
+}
+
+// readerSize returns the number of bytes left to read from r.
+func readerSize(r io.Reader) (int64, error) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len()), nil
+	case io.Seeker:
+		current, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := v.Seek(current, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return end - current, nil
+	}
+	return 0, errors.New("the size of the content is unknown, the reader must have a Len method or be an io.Seeker")
+}
+
+// LatestRelease returns the release of the repo with the highest semantic
+// version, e.g. "v1.2.3", drafts are skipped, and prereleases are only
+// selected if includePrereleases is true, whether they are marked as
+// prereleases or have a prerelease version, e.g. "v1.3.0-rc.1".
+//
+// Releases whose tags aren't semantic versions are skipped, unless the
+// FallbackToNonSemver option is provided. If the repo has no releases, its
+// tags are used instead, returned as releases with only the tag, title and
+// commit set.
+//
+// If no release is selected, an error wrapping ErrNotFound is returned. If an
+// HTTP error is returned by the upstream service, an error with the response
+// status code is returned.
+func (c *SCMClient) LatestRelease(ctx context.Context, repo string, includePrereleases bool, opts ...ReleaseOption) (*scm.Release, error) {
+	var out *scm.Release
+	err := c.call(ctx, "LatestRelease", repo, func(ctx context.Context) (err error) {
+		out, err = c.latestRelease(ctx, repo, includePrereleases, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) latestRelease(ctx context.Context, repo string, includePrereleases bool, opts []ReleaseOption) (*scm.Release, error) {
+	releases, err := c.listReleases(ctx, repo)
+	if err != nil && err != ErrTruncated {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		tags, terr := c.listTags(ctx, repo, scm.ListOptions{Size: 100})
+		if terr != nil && terr != ErrTruncated {
+			return nil, terr
+		}
+		for _, tag := range tags {
+			releases = append(releases, &scm.Release{Tag: tag.Name, Title: tag.Name, Commitish: tag.Sha})
+		}
+		err = terr
+	}
+	latest := SelectLatestRelease(releases, includePrereleases, opts...)
+	if latest == nil {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to find latest release in repo %s", repo), Status: http.StatusNotFound, Err: ErrNotFound}
+	}
+	return latest, err
+}
+
+func (c *SCMClient) listReleases(ctx context.Context, repo string) ([]*scm.Release, error) {
+	opts := scm.ListOptions{Size: 100}
+	var all []*scm.Release
+	for {
+		releases, r, err := c.scmClient.Releases.List(ctx, repo, scm.ReleaseListOptions{Page: opts.Page, Size: opts.Size})
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list releases in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, releases...)
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// SelectLatestRelease returns the release with the highest semantic version,
+// like LatestRelease does, or nil if no release is selected.
+func SelectLatestRelease(releases []*scm.Release, includePrereleases bool, opts ...ReleaseOption) *scm.Release {
+	o := makeReleaseOptions(opts)
+	var (
+		latest        *scm.Release
+		latestVersion semver
+		fallback      *scm.Release
+	)
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && !includePrereleases) {
+			continue
+		}
+		v, ok := parseSemver(r.Tag)
+		if !ok {
+			if fallback == nil || releaseTime(r).After(releaseTime(fallback)) {
+				fallback = r
+			}
+			continue
+		}
+		if len(v.prerelease) > 0 && !includePrereleases {
+			continue
+		}
+		if latest == nil || v.compare(latestVersion) > 0 {
+			latest, latestVersion = r, v
+		}
+	}
+	if latest == nil && o.NonSemverFallback {
+		return fallback
+	}
+	return latest
+}
+
+// releaseTime returns when the release was published, or created if it was
+// never published.
+func releaseTime(r *scm.Release) time.Time {
+	if r.Published.IsZero() {
+		return r.Created
+	}
+	return r.Published
+}
@@ -0,0 +1,297 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListReleaseAssets(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases/12/assets").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 1, "name": "tool-linux-amd64", "content_type": "application/octet-stream", "size": 1024, "browser_download_url": "https://github.com/Codertocat/Hello-World/releases/download/v1.0.0/tool-linux-amd64"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	assets, err := client.ListReleaseAssets(context.Background(), "Codertocat/Hello-World", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ReleaseAsset{{
+		ID:          1,
+		Name:        "tool-linux-amd64",
+		ContentType: "application/octet-stream",
+		Size:        1024,
+		DownloadURL: "https://github.com/Codertocat/Hello-World/releases/download/v1.0.0/tool-linux-amd64",
+	}}
+	if diff := cmp.Diff(want, assets); diff != "" {
+		t.Fatalf("assets don't match:\n%s", diff)
+	}
+}
+
+func TestDownloadReleaseAsset(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases/assets/1").
+		MatchHeader("Accept", "application/octet-stream").
+		Reply(http.StatusOK).
+		BodyString("binary content")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	var buf bytes.Buffer
+	if err := client.DownloadReleaseAsset(context.Background(), "Codertocat/Hello-World", 1, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "binary content" {
+		t.Fatalf("got %q, want the asset content", buf.String())
+	}
+}
+
+func TestDownloadReleaseAssetNotFound(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases/assets/1").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	var buf bytes.Buffer
+	err = client.DownloadReleaseAsset(context.Background(), "Codertocat/Hello-World", 1, &buf)
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("got %d bytes written for a missing asset", buf.Len())
+	}
+}
+
+func TestUploadReleaseAsset(t *testing.T) {
+	gock.New("https://uploads.github.com").
+		Post("/repos/Codertocat/Hello-World/releases/12/assets").
+		MatchParam("name", "tool.tar.gz").
+		MatchHeader("Content-Type", "application/gzip").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			b, err := ioutil.ReadAll(req.Body)
+			return req.ContentLength == 14 && string(b) == "binary content", err
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": 3, "name": "tool.tar.gz", "content_type": "application/gzip", "size": 14})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	// A reader without a Len method, to check that the size comes from Seek.
+	r := io.NewSectionReader(strings.NewReader("binary content"), 0, 14)
+	asset, err := client.UploadReleaseAsset(context.Background(), "Codertocat/Hello-World", 12, "tool.tar.gz", "application/gzip", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ReleaseAsset{ID: 3, Name: "tool.tar.gz", ContentType: "application/gzip", Size: 14}
+	if diff := cmp.Diff(want, asset); diff != "" {
+		t.Fatalf("asset doesn't match:\n%s", diff)
+	}
+}
+
+func TestUploadReleaseAssetUnknownSize(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	r := ioutil.NopCloser(strings.NewReader("binary content"))
+	_, err = client.UploadReleaseAsset(context.Background(), "Codertocat/Hello-World", 12, "tool.tar.gz", "application/gzip", r)
+	if !test.MatchError(t, `failed to upload asset tool.tar.gz.*size of the content is unknown`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUploadReleaseAssetNotRetried(t *testing.T) {
+	gock.New("https://uploads.github.com").
+		Post("/repos/Codertocat/Hello-World/releases/12/assets").
+		Times(2).
+		Reply(http.StatusBadGateway)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithClock(&fakeClock{}), WithRetry(3, time.Second))
+
+	_, err = client.UploadReleaseAsset(context.Background(), "Codertocat/Hello-World", 12, "tool.tar.gz", "application/gzip", strings.NewReader("binary content"))
+	if !test.MatchError(t, `failed to upload asset tool.tar.gz.*(502)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if !gock.IsPending() {
+		t.Fatal("the upload was retried")
+	}
+}
+
+func TestUploadReleaseAssetEnterprise(t *testing.T) {
+	gock.New("https://ghe.example.com").
+		Post("/api/uploads/repos/Codertocat/Hello-World/releases/12/assets").
+		MatchParam("name", "tool.tar.gz").
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": 3, "name": "tool.tar.gz"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "https://ghe.example.com/api/v3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if _, err := client.UploadReleaseAsset(context.Background(), "Codertocat/Hello-World", 12, "tool.tar.gz", "application/gzip", strings.NewReader("binary content")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 4, "tag_name": "v2.0.0-rc.1"},
+			{"id": 3, "tag_name": "v1.10.0"},
+			{"id": 2, "tag_name": "v1.9.0"},
+			{"id": 1, "tag_name": "nightly"},
+			{"id": 5, "tag_name": "v3.0.0", "draft": true},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	release, err := client.LatestRelease(context.Background(), "Codertocat/Hello-World", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.ID != 3 || release.Tag != "v1.10.0" {
+		t.Fatalf("got release %d %s, want 3 v1.10.0", release.ID, release.Tag)
+	}
+}
+
+func TestLatestReleaseFromTags(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/tags").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"name": "v0.2.0", "commit": map[string]string{"sha": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"}},
+			{"name": "v0.10.0", "commit": map[string]string{"sha": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	release, err := client.LatestRelease(context.Background(), "Codertocat/Hello-World", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &scm.Release{Tag: "v0.10.0", Title: "v0.10.0", Commitish: "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d"}
+	if diff := cmp.Diff(want, release); diff != "" {
+		t.Fatalf("got different release: %s", diff)
+	}
+}
+
+func TestLatestReleaseNotFound(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{{"id": 1, "tag_name": "nightly"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.LatestRelease(context.Background(), "Codertocat/Hello-World", false)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSelectLatestRelease(t *testing.T) {
+	day := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	releases := []*scm.Release{
+		{Tag: "v1.2.0"},
+		{Tag: "v1.3.0-beta.1"},
+		{Tag: "v1.2.1", Prerelease: true},
+		{Tag: "v1.4.0", Draft: true},
+	}
+	nonSemver := []*scm.Release{
+		{Tag: "nightly-1", Published: day},
+		{Tag: "nightly-2", Published: day.Add(24 * time.Hour)},
+	}
+	tests := []struct {
+		name               string
+		releases           []*scm.Release
+		includePrereleases bool
+		opts               []ReleaseOption
+		want               string
+	}{
+		{"stable", releases, false, nil, "v1.2.0"},
+		{"prereleases", releases, true, nil, "v1.3.0-beta.1"},
+		{"non-semver skipped", nonSemver, false, nil, ""},
+		{"non-semver fallback", nonSemver, false, []ReleaseOption{FallbackToNonSemver()}, "nightly-2"},
+		{"semver preferred to fallback", append(nonSemver, releases...), false, []ReleaseOption{FallbackToNonSemver()}, "v1.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if r := SelectLatestRelease(tt.releases, tt.includePrereleases, tt.opts...); r != nil {
+				got = r.Tag
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,711 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// ListRepositories lists the repositories in an org, or the repositories of
+// the authenticated user if the org is empty, fetching all the pages.
+//
+// Listing the repositories in an org, or excluding forks is only supported on
+// GitHub and GitLab, the other drivers that go-scm can list repositories with
+// only support listing the repositories of the authenticated user, and fail
+// with ErrNotSupported for an org or the ExcludeForks option.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListRepositories(ctx context.Context, org string, opts RepositoryListOptions) ([]*scm.Repository, error) {
+	var out []*scm.Repository
+	err := c.call(ctx, "ListRepositories", org, func(ctx context.Context) (err error) {
+		out, err = c.listRepositories(ctx, org, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) listRepositories(ctx context.Context, org string, opts RepositoryListOptions) ([]*scm.Repository, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path := "user/repos"
+		if org != "" {
+			path = fmt.Sprintf("orgs/%s/repos", org)
+		}
+		return c.listRepositoriesGitHub(ctx, org, path, opts)
+	case scm.DriverGitlab:
+		query := url.Values{}
+		path := "api/v4/projects"
+		if org != "" {
+			path = fmt.Sprintf("api/v4/groups/%s/projects", encodeRepo(org))
+		} else {
+			query.Set("membership", "true")
+		}
+		if opts.ExcludeArchived {
+			query.Set("archived", "false")
+		}
+		return c.listRepositoriesGitLab(ctx, org, path, query, opts)
+	}
+	if org != "" || opts.ExcludeForks {
+		return nil, scm.ErrNotSupported
+	}
+	var (
+		all      []*scm.Repository
+		listOpts = scm.ListOptions{Size: 100}
+	)
+	for {
+		repos, r, err := c.scmClient.Repositories.List(ctx, listOpts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: "failed to list repositories", Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if !(opts.ExcludeArchived && repo.Archived) {
+				all = append(all, repo)
+			}
+		}
+		more := nextPage(&listOpts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+type ghRepository struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranch string    `json:"default_branch"`
+	Archived      bool      `json:"archived"`
+	Private       bool      `json:"private"`
+	Fork          bool      `json:"fork"`
+	Visibility    string    `json:"visibility"`
+	CloneURL      string    `json:"clone_url"`
+	SSHURL        string    `json:"ssh_url"`
+	HTMLURL       string    `json:"html_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (c *SCMClient) listRepositoriesGitHub(ctx context.Context, org, path string, opts RepositoryListOptions) ([]*scm.Repository, error) {
+	var all []*scm.Repository
+	for page := 1; page != 0; {
+		var repos []ghRepository
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", path, page), nil, &repos)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, listRepositoriesError(org, r.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if (opts.ExcludeArchived && repo.Archived) || (opts.ExcludeForks && repo.Fork) {
+				continue
+			}
+			all = append(all, repo.convert())
+		}
+		page = r.Page.Next
+		if exceedsLimit(c.maxItems, len(all), page != 0) {
+			return all[:c.maxItems], ErrTruncated
+		}
+	}
+	return all, nil
+}
+
+func (repo ghRepository) convert() *scm.Repository {
+	return &scm.Repository{
+		ID:         strconv.Itoa(repo.ID),
+		Namespace:  repo.Owner.Login,
+		Name:       repo.Name,
+		Branch:     repo.DefaultBranch,
+		Archived:   repo.Archived,
+		Private:    repo.Private,
+		Visibility: convertVisibility(repo.Visibility, repo.Private),
+		Clone:      repo.CloneURL,
+		CloneSSH:   repo.SSHURL,
+		Link:       repo.HTMLURL,
+		Created:    repo.CreatedAt,
+		Updated:    repo.UpdatedAt,
+	}
+}
+
+type glProject struct {
+	ID        int    `json:"id"`
+	Path      string `json:"path"`
+	Namespace struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	DefaultBranch     string      `json:"default_branch"`
+	Archived          bool        `json:"archived"`
+	Visibility        string      `json:"visibility"`
+	ForkedFromProject interface{} `json:"forked_from_project"`
+	HTTPURL           string      `json:"http_url_to_repo"`
+	SSHURL            string      `json:"ssh_url_to_repo"`
+	WebURL            string      `json:"web_url"`
+	CreatedAt         time.Time   `json:"created_at"`
+	LastActivityAt    time.Time   `json:"last_activity_at"`
+}
+
+func (c *SCMClient) listRepositoriesGitLab(ctx context.Context, org, path string, query url.Values, opts RepositoryListOptions) ([]*scm.Repository, error) {
+	var all []*scm.Repository
+	query.Set("per_page", "100")
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var projects []glProject
+		r, err := c.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &projects)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, listRepositoriesError(org, r.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			if (opts.ExcludeArchived && p.Archived) || (opts.ExcludeForks && p.ForkedFromProject != nil) {
+				continue
+			}
+			all = append(all, p.convert())
+		}
+		page = r.Page.Next
+		if exceedsLimit(c.maxItems, len(all), page != 0) {
+			return all[:c.maxItems], ErrTruncated
+		}
+	}
+	return all, nil
+}
+
+func (p glProject) convert() *scm.Repository {
+	return &scm.Repository{
+		ID:         strconv.Itoa(p.ID),
+		Namespace:  p.Namespace.FullPath,
+		Name:       p.Path,
+		Branch:     p.DefaultBranch,
+		Archived:   p.Archived,
+		Private:    p.Visibility != "public",
+		Visibility: convertVisibility(p.Visibility, p.Visibility != "public"),
+		Clone:      p.HTTPURL,
+		CloneSSH:   p.SSHURL,
+		Link:       p.WebURL,
+		Created:    p.CreatedAt,
+		Updated:    p.LastActivityAt,
+	}
+}
+
+func listRepositoriesError(org string, status int) error {
+	if org == "" {
+		return SCMError{Msg: "failed to list repositories", Status: status}
+	}
+	return SCMError{Msg: fmt.Sprintf("failed to list repositories in %s", org), Status: status}
+}
+
+// convertVisibility converts the visibility returned by the upstream service,
+// falling back to the private flag if the visibility is not returned.
+func convertVisibility(s string, private bool) scm.Visibility {
+	switch s {
+	case "public":
+		return scm.VisibilityPublic
+	case "internal":
+		return scm.VisibilityInternal
+	case "private":
+		return scm.VisibilityPrivate
+	}
+	if private {
+		return scm.VisibilityPrivate
+	}
+	return scm.VisibilityPublic
+}
+
+// SetRepositoryArchived archives the repository, or unarchives it.
+//
+// Archiving is only supported on GitHub, GitLab and Gitea, on GitHub, writes
+// to an archived repository fail with an error wrapping ErrArchived.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetRepositoryArchived(ctx context.Context, repo string, archived bool) error {
+	err := c.call(ctx, "SetRepositoryArchived", repo, func(ctx context.Context) error {
+		return c.setRepositoryArchived(ctx, repo, archived)
+	})
+	c.emit(Event{Type: "SetRepositoryArchived", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) setRepositoryArchived(ctx context.Context, repo string, archived bool) error {
+	var (
+		method = http.MethodPatch
+		path   string
+		in     interface{}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s", repo)
+		in = map[string]bool{"archived": archived}
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s", repo)
+		in = map[string]bool{"archived": archived}
+	case scm.DriverGitlab:
+		method = http.MethodPost
+		path = fmt.Sprintf("api/v4/projects/%s/archive", encodeRepo(repo))
+		if !archived {
+			path = fmt.Sprintf("api/v4/projects/%s/unarchive", encodeRepo(repo))
+		}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, method, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to update archived state of repo %s", repo), Status: r.Status}
+	}
+	return err
+}
+
+// RenameRepository renames the repo, keeping its namespace, and returns the
+// renamed repository.
+//
+// Requests for the old name may be redirected by the upstream service, or
+// fail, so callers should use the name of the returned repository from then
+// on.
+//
+// Renaming is only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) RenameRepository(ctx context.Context, repo, newName string) (*scm.Repository, error) {
+	var out *scm.Repository
+	err := c.call(ctx, "RenameRepository", repo, func(ctx context.Context) (err error) {
+		out, err = c.renameRepository(ctx, repo, newName)
+		return err
+	})
+	c.emit(Event{Type: "RenameRepository", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) renameRepository(ctx context.Context, repo, newName string) (*scm.Repository, error) {
+	var (
+		r   *scm.Response
+		err error
+		out *scm.Repository
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub, scm.DriverGitea:
+		path := fmt.Sprintf("repos/%s", repo)
+		if c.scmClient.Driver == scm.DriverGitea {
+			path = "api/v1/" + path
+		}
+		var renamed ghRepository
+		r, err = c.do(ctx, http.MethodPatch, path, map[string]string{"name": newName}, &renamed)
+		out = renamed.convert()
+	case scm.DriverGitlab:
+		var renamed glProject
+		r, err = c.do(ctx, http.MethodPut, fmt.Sprintf("api/v4/projects/%s", encodeRepo(repo)), map[string]string{"name": newName, "path": newName}, &renamed)
+		out = renamed.convert()
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to rename repo %s to %s", repo, newName), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForkRepository forks the repo into the org, or into the namespace of the
+// authenticated user if the org is empty, and returns the fork.
+//
+// If the repo was already forked into the namespace, the existing fork is
+// returned. Forks are created asynchronously on GitHub and GitLab, the
+// returned fork may not be ready to be read or written until the WaitForFork
+// option is used.
+//
+// Forking is only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ForkRepository(ctx context.Context, repo, org string, opts ...ForkOption) (*scm.Repository, error) {
+	var out *scm.Repository
+	err := c.call(ctx, "ForkRepository", repo, func(ctx context.Context) (err error) {
+		out, err = c.forkRepository(ctx, repo, org, makeForkOptions(opts))
+		return err
+	})
+	c.emit(Event{Type: "ForkRepository", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) forkRepository(ctx context.Context, repo, org string, o ForkOptions) (*scm.Repository, error) {
+	var (
+		fork *scm.Repository
+		err  error
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub, scm.DriverGitea:
+		fork, err = c.forkRepositoryGitHub(ctx, repo, org)
+	case scm.DriverGitlab:
+		fork, err = c.forkRepositoryGitLab(ctx, repo, org)
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	if err != nil || o.Poll == 0 {
+		return fork, err
+	}
+	return fork, c.waitForFork(ctx, fork, o.Poll)
+}
+
+// forkRepositoryGitHub creates the fork with the GitHub or Gitea API, GitHub
+// returns the existing fork if there is one, and Gitea rejects the request
+// with a 409, so the existing fork is read instead.
+func (c *SCMClient) forkRepositoryGitHub(ctx context.Context, repo, org string) (*scm.Repository, error) {
+	prefix := ""
+	if c.scmClient.Driver == scm.DriverGitea {
+		prefix = "api/v1/"
+	}
+	in := map[string]string{}
+	if org != "" {
+		in["organization"] = org
+	}
+	var fork ghRepository
+	r, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%srepos/%s/forks", prefix, repo), in, &fork)
+	if r != nil && r.Status == http.StatusConflict {
+		namespace, nerr := c.forkNamespace(ctx, org)
+		if nerr != nil {
+			return nil, nerr
+		}
+		_, name := scm.Split(repo)
+		r, err = c.do(ctx, http.MethodGet, fmt.Sprintf("%srepos/%s", prefix, scm.Join(namespace, name)), nil, &fork)
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to fork repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fork.convert(), nil
+}
+
+// forkRepositoryGitLab creates the fork with the GitLab API, which rejects the
+// request with a 409 if the namespace already has a project with the name, in
+// which case the fork in the namespace is looked up among the forks of the
+// project.
+func (c *SCMClient) forkRepositoryGitLab(ctx context.Context, repo, org string) (*scm.Repository, error) {
+	in := map[string]string{}
+	if org != "" {
+		in["namespace_path"] = org
+	}
+	var fork glProject
+	r, err := c.do(ctx, http.MethodPost, fmt.Sprintf("api/v4/projects/%s/fork", encodeRepo(repo)), in, &fork)
+	if r != nil && r.Status == http.StatusConflict {
+		return c.findForkGitLab(ctx, repo, org)
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to fork repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fork.convert(), nil
+}
+
+func (c *SCMClient) findForkGitLab(ctx context.Context, repo, org string) (*scm.Repository, error) {
+	namespace, err := c.forkNamespace(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	for page := 1; page != 0; {
+		var forks []glProject
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/projects/%s/forks?owned=true&per_page=100&page=%d", encodeRepo(repo), page), nil, &forks)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list forks of repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range forks {
+			if p.Namespace.FullPath == namespace {
+				return p.convert(), nil
+			}
+		}
+		page = r.Page.Next
+	}
+	return nil, SCMError{
+		Msg:    fmt.Sprintf("failed to fork repo %s: the namespace %s has a project with the same name that is not a fork", repo, namespace),
+		Status: http.StatusConflict,
+		Err:    ErrConflict,
+	}
+}
+
+// forkNamespace returns the namespace that a repo is forked into, the org, or
+// the login of the authenticated user if the org is empty.
+func (c *SCMClient) forkNamespace(ctx context.Context, org string) (string, error) {
+	if org != "" {
+		return org, nil
+	}
+	user, r, err := c.scmClient.Users.Find(ctx)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", SCMError{Msg: "failed to get the authenticated user", Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+// waitForFork reads the default branch of the fork every poll interval until
+// it exists, which is once the upstream service has copied the repository.
+func (c *SCMClient) waitForFork(ctx context.Context, fork *scm.Repository, poll time.Duration) error {
+	repo := scm.Join(fork.Namespace, fork.Name)
+	for {
+		_, r, err := c.scmClient.Git.FindBranch(ctx, repo, fork.Branch)
+		notReady := r != nil && (r.Status == http.StatusNotFound || r.Status == http.StatusConflict)
+		if !notReady {
+			if r != nil && isErrorStatus(r.Status) {
+				return SCMError{Msg: fmt.Sprintf("failed to check whether fork %s is ready", repo), Status: r.Status}
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.getClock().After(poll):
+		}
+	}
+}
+
+// Star stars the repo for the authenticated user.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) Star(ctx context.Context, repo string) error {
+	err := c.call(ctx, "Star", repo, func(ctx context.Context) error {
+		return c.star(ctx, repo)
+	})
+	c.emit(Event{Type: "Star", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) star(ctx context.Context, repo string) error {
+	return c.setStarred(ctx, repo, true)
+}
+
+// Unstar removes the star from the repo for the authenticated user.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) Unstar(ctx context.Context, repo string) error {
+	err := c.call(ctx, "Unstar", repo, func(ctx context.Context) error {
+		return c.unstar(ctx, repo)
+	})
+	c.emit(Event{Type: "Unstar", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) unstar(ctx context.Context, repo string) error {
+	return c.setStarred(ctx, repo, false)
+}
+
+// IsStarred returns true if the authenticated user has starred the repo.
+//
+// This is not supported on GitLab, which has no endpoint for it.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) IsStarred(ctx context.Context, repo string) (bool, error) {
+	var out bool
+	err := c.call(ctx, "IsStarred", repo, func(ctx context.Context) (err error) {
+		out, err = c.isStarred(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) isStarred(ctx context.Context, repo string) (bool, error) {
+	path, ok := c.starredPath(repo)
+	if !ok || c.scmClient.Driver == scm.DriverGitlab {
+		return false, scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, nil)
+	if r != nil && r.Status == http.StatusNotFound {
+		return false, nil
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return false, SCMError{Msg: fmt.Sprintf("failed to check star for repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setStarred stars or unstars the repo, GitLab responds with a 304 if the
+// repo is already in the requested state, which is not an error.
+func (c *SCMClient) setStarred(ctx context.Context, repo string, starred bool) error {
+	path, ok := c.starredPath(repo)
+	if !ok {
+		return scm.ErrNotSupported
+	}
+	method := http.MethodPut
+	if !starred {
+		method = http.MethodDelete
+	}
+	if c.scmClient.Driver == scm.DriverGitlab {
+		method = http.MethodPost
+		if !starred {
+			path = fmt.Sprintf("api/v4/projects/%s/unstar", encodeRepo(repo))
+		}
+	}
+	r, err := c.do(ctx, method, path, nil, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to update star for repo %s", repo), Status: r.Status}
+	}
+	return err
+}
+
+// starredPath returns the path of the endpoint that stars the repo.
+func (c *SCMClient) starredPath(repo string) (string, bool) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return fmt.Sprintf("user/starred/%s", repo), true
+	case scm.DriverGitea:
+		return fmt.Sprintf("api/v1/user/starred/%s", repo), true
+	case scm.DriverGitlab:
+		return fmt.Sprintf("api/v4/projects/%s/star", encodeRepo(repo)), true
+	}
+	return "", false
+}
+
+// GetRepositoryTopics returns the topics of the repo.
+//
+// Topics are only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetRepositoryTopics(ctx context.Context, repo string) ([]string, error) {
+	var out []string
+	err := c.call(ctx, "GetRepositoryTopics", repo, func(ctx context.Context) (err error) {
+		out, err = c.getRepositoryTopics(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getRepositoryTopics(ctx context.Context, repo string) ([]string, error) {
+	var (
+		path string
+		out  struct {
+			Names  []string `json:"names"`
+			Topics []string `json:"topics"`
+		}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/topics", repo)
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/topics", repo)
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s", encodeRepo(repo))
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get topics of repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.scmClient.Driver == scm.DriverGithub {
+		return out.Names, nil
+	}
+	return out.Topics, nil
+}
+
+// GetLanguages returns the number of bytes of code in the repo for each
+// language detected by the upstream service.
+//
+// Languages are only supported on GitHub and Gitea, GitLab only reports the
+// percentage of each language, so it's not supported.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetLanguages(ctx context.Context, repo string) (map[string]int, error) {
+	var out map[string]int
+	err := c.call(ctx, "GetLanguages", repo, func(ctx context.Context) (err error) {
+		out, err = c.getLanguages(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getLanguages(ctx context.Context, repo string) (map[string]int, error) {
+	var path string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/languages", repo)
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/languages", repo)
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	out := map[string]int{}
+	r, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get languages of repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetRepositoryTopics replaces the topics of the repo.
+//
+// Topics are only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetRepositoryTopics(ctx context.Context, repo string, topics []string) error {
+	err := c.call(ctx, "SetRepositoryTopics", repo, func(ctx context.Context) error {
+		return c.setRepositoryTopics(ctx, repo, topics)
+	})
+	c.emit(Event{Type: "SetRepositoryTopics", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) setRepositoryTopics(ctx context.Context, repo string, topics []string) error {
+	if topics == nil {
+		topics = []string{}
+	}
+	var (
+		path string
+		in   interface{}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/topics", repo)
+		in = map[string][]string{"names": topics}
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/topics", repo)
+		in = map[string][]string{"topics": topics}
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s", encodeRepo(repo))
+		in = map[string][]string{"topics": topics}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodPut, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to update topics of repo %s", repo), Status: r.Status}
+	}
+	return err
+}
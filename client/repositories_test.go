@@ -0,0 +1,534 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListRepositories(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/orgs/Codertocat/repos").
+		MatchParam("page", "1").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/orgs/Codertocat/repos?per_page=100&page=2>; rel="next"`).
+		JSON([]map[string]interface{}{
+			{"id": 1, "name": "Hello-World", "owner": map[string]string{"login": "Codertocat"}, "default_branch": "main"},
+			{"id": 2, "name": "old", "owner": map[string]string{"login": "Codertocat"}, "archived": true},
+		})
+	gock.New("https://api.github.com").
+		Get("/orgs/Codertocat/repos").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 3, "name": "forked", "owner": map[string]string{"login": "Codertocat"}, "fork": true},
+			{"id": 4, "name": "private", "owner": map[string]string{"login": "Codertocat"}, "private": true},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	repos, err := client.ListRepositories(context.Background(), "Codertocat", RepositoryListOptions{ExcludeArchived: true, ExcludeForks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(repos); l != 2 {
+		t.Fatalf("got %d repositories, want 2", l)
+	}
+	if r := repos[0]; r.ID != "1" || r.Namespace != "Codertocat" || r.Name != "Hello-World" || r.Branch != "main" {
+		t.Fatalf("got %#v", r)
+	}
+	if r := repos[1]; r.Name != "private" || !r.Private {
+		t.Fatalf("got %#v", r)
+	}
+	if !gock.IsDone() {
+		t.Fatal("not all pages were requested")
+	}
+}
+
+func TestListRepositoriesWithMaxItems(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/orgs/Codertocat/repos").
+		MatchParam("page", "1").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/orgs/Codertocat/repos?per_page=100&page=2>; rel="next"`).
+		JSON([]map[string]interface{}{
+			{"id": 1, "name": "Hello-World", "owner": map[string]string{"login": "Codertocat"}},
+			{"id": 2, "name": "old", "owner": map[string]string{"login": "Codertocat"}, "archived": true},
+		})
+	gock.New("https://api.github.com").
+		Get("/orgs/Codertocat/repos").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 3, "name": "private", "owner": map[string]string{"login": "Codertocat"}, "private": true},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithMaxItems(2))
+
+	repos, err := client.ListRepositories(context.Background(), "Codertocat", RepositoryListOptions{ExcludeArchived: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(repos); l != 2 {
+		t.Fatalf("got %d repositories, want 2", l)
+	}
+}
+
+func TestListRepositoriesInGitLabGroup(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/groups/my-group/projects").
+		MatchParam("archived", "false").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 1, "path": "project", "namespace": map[string]string{"full_path": "my-group"}, "visibility": "internal"},
+			{"id": 2, "path": "fork", "namespace": map[string]string{"full_path": "my-group"}, "forked_from_project": map[string]int{"id": 5}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	repos, err := client.ListRepositories(context.Background(), "my-group", RepositoryListOptions{ExcludeArchived: true, ExcludeForks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(repos); l != 1 {
+		t.Fatalf("got %d repositories, want 1", l)
+	}
+	if r := repos[0]; r.Name != "project" || r.Namespace != "my-group" || !r.Private {
+		t.Fatalf("got %#v", r)
+	}
+}
+
+func TestListRepositoriesWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/user/repos").
+		Reply(http.StatusUnauthorized)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListRepositories(context.Background(), "", RepositoryListOptions{})
+	if e, ok := err.(SCMError); !ok || e.Status != http.StatusUnauthorized {
+		t.Fatalf("got %v, want an SCMError with status 401", err)
+	}
+}
+
+func TestStar(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/user/starred/Codertocat/Hello-World").
+		Reply(http.StatusNoContent)
+	gock.New("https://api.github.com").
+		Get("/user/starred/Codertocat/Hello-World").
+		Reply(http.StatusNoContent)
+	gock.New("https://api.github.com").
+		Delete("/user/starred/Codertocat/Hello-World").
+		Reply(http.StatusNoContent)
+	gock.New("https://api.github.com").
+		Get("/user/starred/Codertocat/Hello-World").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+	ctx := context.Background()
+
+	if err := client.Star(ctx, "Codertocat/Hello-World"); err != nil {
+		t.Fatal(err)
+	}
+	if starred, err := client.IsStarred(ctx, "Codertocat/Hello-World"); err != nil || !starred {
+		t.Fatalf("got %v, %v, want starred", starred, err)
+	}
+	if err := client.Unstar(ctx, "Codertocat/Hello-World"); err != nil {
+		t.Fatal(err)
+	}
+	if starred, err := client.IsStarred(ctx, "Codertocat/Hello-World"); err != nil || starred {
+		t.Fatalf("got %v, %v, want not starred", starred, err)
+	}
+}
+
+func TestStarInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/star").
+		Reply(http.StatusNotModified)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.Star(context.Background(), "Codertocat/Hello-World"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.IsStarred(context.Background(), "Codertocat/Hello-World"); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want %v", err, scm.ErrNotSupported)
+	}
+}
+
+func TestSetRepositoryArchived(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World").
+		MatchType("json").
+		JSON(map[string]bool{"archived": true}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"archived": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryArchived(context.Background(), "Codertocat/Hello-World", true); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("repository was not archived")
+	}
+}
+
+func TestSetRepositoryArchivedInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/unarchive").
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"archived": false})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryArchived(context.Background(), "Codertocat/Hello-World", false); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("repository was not unarchived")
+	}
+}
+
+func TestGetRepositoryTopics(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/topics").
+		Reply(http.StatusOK).
+		JSON(map[string][]string{"names": {"go", "scm"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	topics, err := client.GetRepositoryTopics(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(topics, []string{"go", "scm"}) {
+		t.Fatalf("got topics %v, want [go scm]", topics)
+	}
+}
+
+func TestGetRepositoryTopicsInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "topics": []string{"go"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	topics, err := client.GetRepositoryTopics(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(topics, []string{"go"}) {
+		t.Fatalf("got topics %v, want [go]", topics)
+	}
+}
+
+func TestSetRepositoryTopics(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/topics").
+		MatchType("json").
+		JSON(map[string][]string{"names": {}}).
+		Reply(http.StatusOK).
+		JSON(map[string][]string{"names": {}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryTopics(context.Background(), "Codertocat/Hello-World", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("topics were not cleared")
+	}
+}
+
+func TestSetRepositoryTopicsWithUnsupportedDriver(t *testing.T) {
+	scmClient, err := factory.NewClient("bitbucket", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryTopics(context.Background(), "Codertocat/Hello-World", []string{"go"}); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want %v", err, scm.ErrNotSupported)
+	}
+}
+
+func TestRenameRepository(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World").
+		MatchType("json").
+		JSON(map[string]string{"name": "Hello-Universe"}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "name": "Hello-Universe", "owner": map[string]string{"login": "Codertocat"}, "default_branch": "main"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	repo, err := client.RenameRepository(context.Background(), "Codertocat/Hello-World", "Hello-Universe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scm.Join(repo.Namespace, repo.Name); got != "Codertocat/Hello-Universe" {
+		t.Fatalf("got repo %s, want Codertocat/Hello-Universe", got)
+	}
+}
+
+func TestRenameRepositoryInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World").
+		MatchType("json").
+		JSON(map[string]string{"name": "Hello-Universe", "path": "Hello-Universe"}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "path": "Hello-Universe", "namespace": map[string]string{"full_path": "Codertocat"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	repo, err := client.RenameRepository(context.Background(), "Codertocat/Hello-World", "Hello-Universe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scm.Join(repo.Namespace, repo.Name); got != "Codertocat/Hello-Universe" {
+		t.Fatalf("got repo %s, want Codertocat/Hello-Universe", got)
+	}
+}
+
+func TestRenameRepositoryWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "name already exists on this account"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.RenameRepository(context.Background(), "Codertocat/Hello-World", "Hello-Universe")
+	if !test.MatchError(t, `failed to rename repo Codertocat/Hello-World.*\(422\)$`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetLanguages(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/languages").
+		Reply(http.StatusOK).
+		JSON(map[string]int{"Go": 12345, "Shell": 678})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	languages, err := client.GetLanguages(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"Go": 12345, "Shell": 678}; !reflect.DeepEqual(languages, want) {
+		t.Fatalf("got languages %v, want %v", languages, want)
+	}
+}
+
+func TestGetLanguagesInGitLab(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetLanguages(context.Background(), "Codertocat/Hello-World")
+	if err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+}
+
+func TestForkRepository(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/forks").
+		MatchType("json").
+		JSON(map[string]string{"organization": "octo-org"}).
+		Reply(http.StatusAccepted).
+		JSON(map[string]interface{}{"id": 2, "name": "Hello-World", "owner": map[string]string{"login": "octo-org"}, "default_branch": "main", "fork": true})
+	gock.New("https://api.github.com").
+		Get("/repos/octo-org/Hello-World/branches/main").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Get("/repos/octo-org/Hello-World/branches/main").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	client := New(scmClient, WithClock(clock))
+
+	fork, err := client.ForkRepository(context.Background(), "Codertocat/Hello-World", "octo-org", WaitForFork(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scm.Join(fork.Namespace, fork.Name); got != "octo-org/Hello-World" {
+		t.Fatalf("got fork %s, want octo-org/Hello-World", got)
+	}
+	if want := []time.Duration{time.Second}; !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("got waits %v, want %v", clock.waits, want)
+	}
+}
+
+func TestForkRepositoryInGitLabAlreadyForked(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/fork").
+		Reply(http.StatusConflict).
+		JSON(map[string]interface{}{"message": map[string][]string{"name": {"has already been taken"}}})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/user").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "username": "octocat"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/forks").
+		MatchParam("owned", "true").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 3, "path": "Hello-World", "namespace": map[string]string{"full_path": "octo-org"}},
+			{"id": 2, "path": "Hello-World", "namespace": map[string]string{"full_path": "octocat"}},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	fork, err := client.ForkRepository(context.Background(), "Codertocat/Hello-World", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.ID != "2" || scm.Join(fork.Namespace, fork.Name) != "octocat/Hello-World" {
+		t.Fatalf("got fork %#v, want the existing fork of the user", fork)
+	}
+}
+
+func TestForkRepositoryInGiteaAlreadyForked(t *testing.T) {
+	gock.New("https://gitea.example.com").
+		Post("/api/v1/repos/Codertocat/Hello-World/forks").
+		Reply(http.StatusConflict)
+	gock.New("https://gitea.example.com").
+		Get("/api/v1/repos/octo-org/Hello-World").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 2, "name": "Hello-World", "owner": map[string]string{"login": "octo-org"}, "fork": true})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	fork, err := client.ForkRepository(context.Background(), "Codertocat/Hello-World", "octo-org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scm.Join(fork.Namespace, fork.Name); got != "octo-org/Hello-World" {
+		t.Fatalf("got fork %s, want octo-org/Hello-World", got)
+	}
+}
+
+func TestForkRepositoryWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/forks").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "forking is disabled"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ForkRepository(context.Background(), "Codertocat/Hello-World", "")
+	if !test.MatchError(t, `failed to fork repo Codertocat/Hello-World.*(403)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// do makes a request to the upstream API for endpoints that go-scm doesn't
+// expose.
+//
+// The path is relative to the base URL of the wrapped scm.Client, in is
+// encoded as JSON if provided, and out is decoded from the response body when
+// the response status is not an error status.
+//
+// Error statuses are not converted to errors, callers are expected to check
+// the status of the returned response.
+func (c *SCMClient) do(ctx context.Context, method, path string, in, out interface{}) (*scm.Response, error) {
+	req := &scm.Request{
+		Method: method,
+		Path:   path,
+		Header: http.Header{},
+	}
+	if in != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Body = buf
+	}
+	res, err := c.scmClient.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) || out == nil {
+		return res, nil
+	}
+	return res, json.NewDecoder(res.Body).Decode(out)
+}
+
+// doWithErrorBody makes a request like do, but decodes out from the response
+// body for error statuses too, for endpoints that explain errors in the body.
+//
+// Bodies that can't be decoded are ignored for error statuses.
+func (c *SCMClient) doWithErrorBody(ctx context.Context, method, path string, in, out interface{}) (*scm.Response, error) {
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(in); err != nil {
+		return nil, err
+	}
+	res, err := c.scmClient.Do(ctx, &scm.Request{
+		Method: method,
+		Path:   path,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   buf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	err = json.NewDecoder(res.Body).Decode(out)
+	if isErrorStatus(res.Status) || err == io.EOF {
+		return res, nil
+	}
+	return res, err
+}
+
+// maxErrorBodySize is the maximum size of the body of error responses that's
+// read to find the cause of the error.
+const maxErrorBodySize = 64 * 1024
+
+// doRaw makes a request like do, but returns the undecoded response body.
+//
+// If limit is greater than zero, at most limit bytes of the body are read, and
+// truncated reports whether there was more to read. The body of error
+// responses is returned up to maxErrorBodySize.
+func (c *SCMClient) doRaw(ctx context.Context, method, path string, header http.Header, limit int) (res *scm.Response, body []byte, truncated bool, err error) {
+	if header == nil {
+		header = http.Header{}
+	}
+	res, err = c.scmClient.Do(ctx, &scm.Request{Method: method, Path: path, Header: header})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) {
+		body, _ = ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+		return res, body, false, nil
+	}
+	if limit <= 0 {
+		body, err = ioutil.ReadAll(res.Body)
+		return res, body, false, err
+	}
+	body, err = ioutil.ReadAll(io.LimitReader(res.Body, int64(limit)+1))
+	if len(body) > limit {
+		return res, body[:limit], true, err
+	}
+	return res, body, false, err
+}
+
+// doWithStatus makes a request like do, but returns an SCMError if the
+// response has an error status.
+func (c *SCMClient) doWithStatus(ctx context.Context, method, path string, in, out interface{}) error {
+	r, err := c.do(ctx, method, path, in, out)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to %s %s", method, path), Status: r.Status}
+	}
+	return err
+}
+
+// exceedsLimit returns true if the n items collected by a list method, with
+// more items remaining if more is true, must be truncated to the limit, a
+// limit of 0 doesn't truncate.
+func exceedsLimit(limit, n int, more bool) bool {
+	return limit > 0 && (n > limit || (n == limit && more))
+}
+
+// nextPage updates the options to request the page that follows the response,
+// and returns false if there are no more pages.
+func nextPage(opts *scm.ListOptions, res *scm.Response) bool {
+	if res == nil || res.Page.Next == 0 {
+		return false
+	}
+	opts.Page = res.Page.Next
+	return true
+}
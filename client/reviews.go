@@ -0,0 +1,413 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// ReviewState is the outcome of a review of a pull request.
+type ReviewState string
+
+const (
+	// ReviewStateApproved is a review approving the changes.
+	ReviewStateApproved ReviewState = "APPROVED"
+	// ReviewStateChangesRequested is a review requesting changes before the
+	// pull request can be merged.
+	ReviewStateChangesRequested ReviewState = "CHANGES_REQUESTED"
+	// ReviewStateCommented is a review with comments only.
+	ReviewStateCommented ReviewState = "COMMENTED"
+	// ReviewStateDismissed is a review that was dismissed, and no longer
+	// counts towards approval.
+	ReviewStateDismissed ReviewState = "DISMISSED"
+	// ReviewStatePending is a review that was started but not submitted.
+	ReviewStatePending ReviewState = "PENDING"
+)
+
+// Review is a review of a pull request.
+//
+// go-scm only has review comments, so reviews are read directly from the
+// upstream service.
+type Review struct {
+	ID        int
+	Author    scm.User
+	State     ReviewState
+	Body      string
+	Sha       string // the commit that was reviewed
+	Link      string
+	Submitted time.Time
+}
+
+// ListReviews returns the reviews of the pull request, paging through all the
+// reviews.
+//
+// Reviews are only supported on GitHub, GitLab and Gitea. GitLab has no
+// reviews, so its approvals are returned as approved reviews.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListReviews(ctx context.Context, repo string, number int) ([]*Review, error) {
+	var out []*Review
+	err := c.call(ctx, "ListReviews", repo, func(ctx context.Context) (err error) {
+		out, err = c.listReviews(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+type ghReview struct {
+	ID   int `json:"id"`
+	User struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	State       string    `json:"state"`
+	Body        string    `json:"body"`
+	CommitID    string    `json:"commit_id"`
+	HTMLURL     string    `json:"html_url"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// giteaReviewStates are the review states of Gitea that have different names
+// on GitHub.
+var giteaReviewStates = map[string]ReviewState{
+	"REQUEST_CHANGES": ReviewStateChangesRequested,
+	"COMMENT":         ReviewStateCommented,
+}
+
+func (r ghReview) convert(driver scm.Driver) *Review {
+	state := ReviewState(r.State)
+	if s, ok := giteaReviewStates[r.State]; ok && driver == scm.DriverGitea {
+		state = s
+	}
+	return &Review{
+		ID:        r.ID,
+		Author:    scm.User{Login: r.User.Login, Avatar: r.User.AvatarURL},
+		State:     state,
+		Body:      r.Body,
+		Sha:       r.CommitID,
+		Link:      r.HTMLURL,
+		Submitted: r.SubmittedAt,
+	}
+}
+
+func (c *SCMClient) listReviews(ctx context.Context, repo string, number int) ([]*Review, error) {
+	var path, sizeParam string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path, sizeParam = fmt.Sprintf("repos/%s/pulls/%d/reviews", repo, number), "per_page"
+	case scm.DriverGitea:
+		path, sizeParam = fmt.Sprintf("api/v1/repos/%s/pulls/%d/reviews", repo, number), "limit"
+	case scm.DriverGitlab:
+		return c.listApprovalsGitLab(ctx, repo, number)
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	opts := scm.ListOptions{Size: 100}
+	var all []*Review
+	for {
+		params := url.Values{sizeParam: {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var reviews []ghReview
+		r, err := c.do(ctx, http.MethodGet, path+"?"+params.Encode(), nil, &reviews)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, reviewsError(repo, number, r.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, review := range reviews {
+			all = append(all, review.convert(c.scmClient.Driver))
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+func (c *SCMClient) listApprovalsGitLab(ctx context.Context, repo string, number int) ([]*Review, error) {
+	var out struct {
+		ApprovedBy []struct {
+			User struct {
+				Username  string `json:"username"`
+				Name      string `json:"name"`
+				AvatarURL string `json:"avatar_url"`
+			} `json:"user"`
+		} `json:"approved_by"`
+	}
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d/approvals", encodeRepo(repo), number), nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, reviewsError(repo, number, r.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reviews []*Review
+	for _, a := range out.ApprovedBy {
+		reviews = append(reviews, &Review{
+			Author: scm.User{Login: a.User.Username, Name: a.User.Name, Avatar: a.User.AvatarURL},
+			State:  ReviewStateApproved,
+		})
+	}
+	return reviews, nil
+}
+
+func reviewsError(repo string, number, status int) error {
+	return SCMError{Msg: fmt.Sprintf("failed to list reviews of pull request %d in repo %s", number, repo), Status: status}
+}
+
+// ReviewThread is a conversation started by a review comment on a pull
+// request.
+type ReviewThread struct {
+	ID       string
+	Path     string
+	Line     int
+	Resolved bool
+	Outdated bool // the lines the thread is on have changed
+}
+
+// ListReviewThreads returns all the review threads in the pull request.
+//
+// Review threads are only supported on GitHub, where they use the GraphQL API.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned, IsNotFound returns true for an unknown
+// pull request.
+func (c *SCMClient) ListReviewThreads(ctx context.Context, repo string, number int) ([]*ReviewThread, error) {
+	var out []*ReviewThread
+	err := c.call(ctx, "ListReviewThreads", repo, func(ctx context.Context) (err error) {
+		out, err = c.listReviewThreads(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+type ghReviewThread struct {
+	ID         string `json:"id"`
+	Path       string `json:"path"`
+	Line       int    `json:"line"`
+	IsResolved bool   `json:"isResolved"`
+	IsOutdated bool   `json:"isOutdated"`
+}
+
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $cursor) {
+        nodes { id path line isResolved isOutdated }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+func (c *SCMClient) listReviewThreads(ctx context.Context, repo string, number int) ([]*ReviewThread, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	owner, name := scm.Split(repo)
+	msg := fmt.Sprintf("failed to list review threads in pull request %d in repo %s", number, repo)
+	var (
+		all       []*ReviewThread
+		variables = map[string]interface{}{"owner": owner, "name": name, "number": number}
+	)
+	for {
+		var out struct {
+			Repository *struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						Nodes    []ghReviewThread `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := c.doGraphQL(ctx, msg, reviewThreadsQuery, variables, &out); err != nil {
+			return nil, err
+		}
+		if out.Repository == nil || out.Repository.PullRequest == nil {
+			return nil, SCMError{Msg: msg, Status: http.StatusNotFound}
+		}
+		threads := out.Repository.PullRequest.ReviewThreads
+		for _, t := range threads.Nodes {
+			all = append(all, &ReviewThread{ID: t.ID, Path: t.Path, Line: t.Line, Resolved: t.IsResolved, Outdated: t.IsOutdated})
+		}
+		if !threads.PageInfo.HasNextPage {
+			return all, nil
+		}
+		variables["cursor"] = threads.PageInfo.EndCursor
+	}
+}
+
+// ResolveReviewThread marks the review thread with the ID returned by
+// ListReviewThreads as resolved.
+//
+// Review threads are only supported on GitHub, where they use the GraphQL API.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ResolveReviewThread(ctx context.Context, repo, threadID string) error {
+	err := c.call(ctx, "ResolveReviewThread", repo, func(ctx context.Context) error {
+		return c.resolveReviewThread(ctx, repo, threadID)
+	})
+	c.emit(Event{Type: "ResolveReviewThread", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) resolveReviewThread(ctx context.Context, repo, threadID string) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return scm.ErrNotSupported
+	}
+	return c.doGraphQL(ctx, fmt.Sprintf("failed to resolve review thread %s in repo %s", threadID, repo),
+		"mutation($id: ID!) { resolveReviewThread(input: {threadId: $id}) { thread { id } } }",
+		map[string]interface{}{"id": threadID}, nil)
+}
+
+// ListReviewComments returns the inline review comments on the files of the
+// pull request, paging through all the comments, distinct from the comments
+// on the pull request itself.
+//
+// The Line of each comment is the line of the file that it's on, in the
+// version of the file that was commented on if the line has since changed.
+//
+// Review comments are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListReviewComments(ctx context.Context, repo string, number int) ([]*scm.Review, error) {
+	var out []*scm.Review
+	err := c.call(ctx, "ListReviewComments", repo, func(ctx context.Context) (err error) {
+		out, err = c.listReviewComments(ctx, repo, number)
+		return err
+	})
+	return out, err
+}
+
+type ghReviewComment struct {
+	ID       int    `json:"id"`
+	Body     string `json:"body"`
+	Path     string `json:"path"`
+	CommitID string `json:"commit_id"`
+	// Line is null when the line is no longer in the diff, when the line
+	// that was commented on is in OriginalLine.
+	Line         *int   `json:"line"`
+	OriginalLine int    `json:"original_line"`
+	HTMLURL      string `json:"html_url"`
+	User         struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r ghReviewComment) convert() *scm.Review {
+	line := r.OriginalLine
+	if r.Line != nil {
+		line = *r.Line
+	}
+	return &scm.Review{
+		ID:      r.ID,
+		Body:    r.Body,
+		Path:    r.Path,
+		Sha:     r.CommitID,
+		Line:    line,
+		Link:    r.HTMLURL,
+		Author:  scm.User{Login: r.User.Login, Avatar: r.User.AvatarURL},
+		Created: r.CreatedAt,
+		Updated: r.UpdatedAt,
+	}
+}
+
+func (c *SCMClient) listReviewComments(ctx context.Context, repo string, number int) ([]*scm.Review, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	opts := scm.ListOptions{Size: 100}
+	var all []*scm.Review
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var comments []ghReviewComment
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/pulls/%d/comments?%s", repo, number, params.Encode()), nil, &comments)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list review comments of pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			all = append(all, comment.convert())
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
+
+// CreateReviewComment comments on the line of the file at the path in the
+// pull request, the line is the line of the file, not a position in the diff,
+// and must be part of the diff.
+//
+// The comment is made on the commit with the Sha of the input, or on the head
+// of the pull request if it's empty.
+//
+// Review comments are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateReviewComment(ctx context.Context, repo string, number int, inp *scm.ReviewInput) error {
+	err := c.call(ctx, "CreateReviewComment", repo, func(ctx context.Context) error {
+		return c.createReviewComment(ctx, repo, number, inp)
+	})
+	c.emit(Event{Type: "CreateReviewComment", Repo: repo, Path: inp.Path, Number: number, Err: err})
+	return err
+}
+
+func (c *SCMClient) createReviewComment(ctx context.Context, repo string, number int, inp *scm.ReviewInput) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return scm.ErrNotSupported
+	}
+	sha := inp.Sha
+	if sha == "" {
+		pr, r, err := c.scmClient.PullRequests.Find(ctx, repo, number)
+		if r != nil && isErrorStatus(r.Status) {
+			return SCMError{Msg: fmt.Sprintf("failed to get pull request %d in repo %s", number, repo), Status: r.Status}
+		}
+		if err != nil {
+			return err
+		}
+		sha = pr.Sha
+	}
+	r, err := c.do(ctx, http.MethodPost, fmt.Sprintf("repos/%s/pulls/%d/comments", repo, number), map[string]interface{}{
+		"body":      inp.Body,
+		"path":      inp.Path,
+		"line":      inp.Line,
+		"side":      "RIGHT",
+		"commit_id": sha,
+	}, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to comment on line %d of file %s in pull request %d in repo %s", inp.Line, inp.Path, number, repo), Status: r.Status}
+	}
+	return err
+}
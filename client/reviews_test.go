@@ -0,0 +1,316 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListReviews(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/reviews").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/pulls/2/reviews?per_page=100&page=2>; rel="next"`).
+		JSON([]map[string]interface{}{
+			{"id": 80, "user": map[string]string{"login": "octocat"}, "state": "CHANGES_REQUESTED", "commit_id": "ecdd80b"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/reviews").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 81, "user": map[string]string{"login": "hubot"}, "state": "APPROVED", "body": "LGTM", "commit_id": "6dcb09b"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	reviews, err := client.ListReviews(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Review{
+		{ID: 80, Author: scm.User{Login: "octocat"}, State: ReviewStateChangesRequested, Sha: "ecdd80b"},
+		{ID: 81, Author: scm.User{Login: "hubot"}, State: ReviewStateApproved, Body: "LGTM", Sha: "6dcb09b"},
+	}
+	if diff := cmp.Diff(want, reviews); diff != "" {
+		t.Fatalf("failed to list reviews:\n%s", diff)
+	}
+}
+
+func TestListReviewsInGitea(t *testing.T) {
+	gock.New("https://gitea.example.com").
+		Get("/api/v1/repos/Codertocat/Hello-World/pulls/2/reviews").
+		MatchParam("limit", "100").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 3, "user": map[string]string{"login": "octocat"}, "state": "REQUEST_CHANGES"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	reviews, err := client.ListReviews(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].State != ReviewStateChangesRequested {
+		t.Fatalf("got %v, want a review requesting changes", reviews)
+	}
+}
+
+func TestListReviewsInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/merge_requests/2/approvals").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"approved_by": []map[string]interface{}{
+				{"user": map[string]string{"username": "octocat", "name": "The Octocat"}},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	reviews, err := client.ListReviews(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Review{{Author: scm.User{Login: "octocat", Name: "The Octocat"}, State: ReviewStateApproved}}
+	if diff := cmp.Diff(want, reviews); diff != "" {
+		t.Fatalf("failed to list reviews:\n%s", diff)
+	}
+}
+
+func TestListReviewsWithUnknownPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/3/reviews").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListReviews(context.Background(), "Codertocat/Hello-World", 3)
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func reviewThreadsPage(hasNextPage bool, cursor string, threads ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": map[string]interface{}{
+		"reviewThreads": map[string]interface{}{
+			"nodes":    threads,
+			"pageInfo": map[string]interface{}{"hasNextPage": hasNextPage, "endCursor": cursor},
+		},
+	}}}}
+}
+
+func TestListReviewThreads(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"variables":\{"name"`).
+		Reply(http.StatusOK).
+		JSON(reviewThreadsPage(true, "Y3Vyc29yOjE=",
+			map[string]interface{}{"id": "PRRT_1", "path": "README.md", "line": 3, "isResolved": true}))
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"cursor":"Y3Vyc29yOjE="`).
+		Reply(http.StatusOK).
+		JSON(reviewThreadsPage(false, "",
+			map[string]interface{}{"id": "PRRT_2", "path": "main.go", "line": 10, "isOutdated": true}))
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	threads, err := client.ListReviewThreads(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ReviewThread{
+		{ID: "PRRT_1", Path: "README.md", Line: 3, Resolved: true},
+		{ID: "PRRT_2", Path: "main.go", Line: 10, Outdated: true},
+	}
+	if diff := cmp.Diff(want, threads); diff != "" {
+		t.Fatalf("failed to list review threads:\n%s", diff)
+	}
+}
+
+func TestListReviewThreadsWithUnknownPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"pullRequest": nil}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListReviewThreads(context.Background(), "Codertocat/Hello-World", 2)
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestResolveReviewThread(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"variables":{"id":"PRRT_1"}`).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"data": map[string]interface{}{"resolveReviewThread": map[string]interface{}{"thread": map[string]string{"id": "PRRT_1"}}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.ResolveReviewThread(context.Background(), "Codertocat/Hello-World", "PRRT_1"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("review thread was not resolved")
+	}
+}
+
+func TestResolveReviewThreadWithGraphQLError(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"errors": []map[string]string{{"message": "Could not resolve to a node with the global id of 'PRRT_1'"}}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.ResolveReviewThread(context.Background(), "Codertocat/Hello-World", "PRRT_1")
+	if !test.MatchError(t, `failed to resolve review thread PRRT_1.*Could not resolve`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestReviewThreadsInGitLab(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if _, err := client.ListReviewThreads(context.Background(), "Codertocat/Hello-World", 2); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+	if err := client.ResolveReviewThread(context.Background(), "Codertocat/Hello-World", "PRRT_1"); err != scm.ErrNotSupported {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+}
+
+func TestListReviewComments(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2/comments").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"id": 1, "body": "unused variable", "path": "main.go", "commit_id": "a84d88e", "line": 12, "original_line": 10, "user": map[string]string{"login": "lint-bot"}},
+			{"id": 2, "body": "typo", "path": "README.md", "commit_id": "7fd1a60", "line": nil, "original_line": 3},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	comments, err := client.ListReviewComments(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*scm.Review{
+		{ID: 1, Body: "unused variable", Path: "main.go", Sha: "a84d88e", Line: 12, Author: scm.User{Login: "lint-bot"}},
+		{ID: 2, Body: "typo", Path: "README.md", Sha: "7fd1a60", Line: 3},
+	}
+	if diff := cmp.Diff(want, comments); diff != "" {
+		t.Fatalf("comments don't match:\n%s", diff)
+	}
+}
+
+func TestCreateReviewComment(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"number": 2, "head": map[string]string{"ref": "feature", "sha": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d"}})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls/2/comments").
+		MatchType("json").
+		JSON(map[string]interface{}{"body": "unused variable", "path": "main.go", "line": 12, "side": "RIGHT", "commit_id": "a84d88e7554fc1fa21bcbc4efae3c782a70d2b9d"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": 1})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateReviewComment(context.Background(), "Codertocat/Hello-World", 2, &scm.ReviewInput{Body: "unused variable", Path: "main.go", Line: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the comment was not created")
+	}
+}
+
+func TestCreateReviewCommentWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls/2/comments").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "line must be part of the diff"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateReviewComment(context.Background(), "Codertocat/Hello-World", 2, &scm.ReviewInput{Body: "style", Path: "main.go", Line: 400, Sha: "a84d88e"})
+	if !test.MatchError(t, `failed to comment on line 400 of file main.go in pull request 2.*(422)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
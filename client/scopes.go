@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// impliedScopes are the scopes that are granted by a broader scope, e.g. a
+// GitHub token with the "repo" scope can also set commit statuses.
+var impliedScopes = map[string][]string{
+	"repo":             {"repo:status", "repo_deployment", "public_repo", "repo:invite", "security_events"},
+	"admin:org":        {"write:org", "read:org"},
+	"write:org":        {"read:org"},
+	"admin:public_key": {"write:public_key", "read:public_key"},
+	"write:public_key": {"read:public_key"},
+	"admin:repo_hook":  {"write:repo_hook", "read:repo_hook"},
+	"write:repo_hook":  {"read:repo_hook"},
+	"admin:gpg_key":    {"write:gpg_key", "read:gpg_key"},
+	"write:gpg_key":    {"read:gpg_key"},
+	"user":             {"read:user", "user:email", "user:follow"},
+	"write:packages":   {"read:packages"},
+	"api":              {"read_api"},
+	"write_repository": {"read_repository"},
+	"write_registry":   {"read_registry"},
+}
+
+// GetTokenScopes returns the scopes granted to the token that the client
+// authenticates with.
+//
+// Scopes are only supported on GitHub, from the X-OAuth-Scopes header, which
+// is not returned for fine-grained tokens, and on GitLab, for personal access
+// tokens.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetTokenScopes(ctx context.Context) ([]string, error) {
+	var out []string
+	err := c.call(ctx, "GetTokenScopes", "", func(ctx context.Context) (err error) {
+		out, err = c.getTokenScopes(ctx)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getTokenScopes(ctx context.Context) ([]string, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		r, err := c.do(ctx, http.MethodGet, "user", nil, nil)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: "failed to get token scopes", Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		header, ok := r.Header["X-Oauth-Scopes"]
+		if !ok {
+			return nil, scm.ErrNotSupported
+		}
+		var scopes []string
+		for _, s := range strings.Split(strings.Join(header, ","), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes, nil
+	case scm.DriverGitlab:
+		var out struct {
+			Scopes []string `json:"scopes"`
+		}
+		r, err := c.do(ctx, http.MethodGet, "api/v4/personal_access_tokens/self", nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: "failed to get token scopes", Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out.Scopes, nil
+	default:
+		return nil, scm.ErrNotSupported
+	}
+}
+
+// HasScope returns true if the token that the client authenticates with has
+// the scope, or a broader scope that grants it, e.g. "repo" grants
+// "repo:status", so that callers can fail fast before privileged operations.
+//
+// Scopes are only supported on GitHub and GitLab, like GetTokenScopes.
+func (c *SCMClient) HasScope(ctx context.Context, scope string) (bool, error) {
+	var out bool
+	err := c.call(ctx, "HasScope", "", func(ctx context.Context) error {
+		scopes, err := c.GetTokenScopes(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check scope %s: %w", scope, err)
+		}
+		out = ScopesInclude(scopes, scope)
+		return nil
+	})
+	return out, err
+}
+
+// ScopesInclude returns true if the scopes include the scope, or a broader
+// scope that grants it.
+func ScopesInclude(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+		for _, implied := range impliedScopes[s] {
+			if implied == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
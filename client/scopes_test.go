@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetTokenScopes(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/user").
+		Reply(http.StatusOK).
+		SetHeader("X-OAuth-Scopes", "repo, read:org").
+		JSON(map[string]string{"login": "octocat"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	scopes, err := client.GetTokenScopes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"repo", "read:org"}, scopes); diff != "" {
+		t.Fatalf("scopes don't match:\n%s", diff)
+	}
+}
+
+func TestGetTokenScopesWithoutScopesHeader(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/user").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"login": "octocat"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetTokenScopes(context.Background())
+	if !errors.Is(err, scm.ErrNotSupported) {
+		t.Fatalf("got %v, want ErrNotSupported", err)
+	}
+}
+
+func TestHasScopeGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/personal_access_tokens/self").
+		Times(2).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"scopes": []string{"api", "write_repository"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	for scope, want := range map[string]bool{"read_repository": true, "sudo": false} {
+		got, err := client.HasScope(context.Background(), scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("HasScope(%q) got %v, want %v", scope, got, want)
+		}
+	}
+}
+
+func TestScopesInclude(t *testing.T) {
+	includeTests := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{[]string{"repo"}, "repo", true},
+		{[]string{"repo"}, "repo:status", true},
+		{[]string{"admin:org"}, "read:org", true},
+		{[]string{"read:org"}, "admin:org", false},
+		{nil, "repo", false},
+	}
+
+	for _, tt := range includeTests {
+		if got := ScopesInclude(tt.scopes, tt.scope); got != tt.want {
+			t.Errorf("ScopesInclude(%v, %q) got %v, want %v", tt.scopes, tt.scope, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver is a semantic version parsed from a tag, e.g. "v1.2.3-rc.1".
+type semver struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// parseSemver parses the tag as a semantic version, with an optional "v"
+// prefix, build metadata is ignored.
+func parseSemver(tag string) (semver, bool) {
+	s := strings.TrimPrefix(tag, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v semver
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.prerelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+		for _, id := range v.prerelease {
+			if id == "" {
+				return semver{}, false
+			}
+		}
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, ok := parseNumericID(p)
+		if !ok {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	v.major, v.minor, v.patch = nums[0], nums[1], nums[2]
+	return v, true
+}
+
+// parseNumericID parses a version number, which can't have leading zeros.
+func parseNumericID(s string) (int, bool) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// compare returns -1, 0 or 1 if the version has a lower, equal or higher
+// precedence than the other version.
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	// A version without a prerelease has a higher precedence than the same
+	// version with one.
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		if c := comparePrereleaseID(v.prerelease[i], o.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(v.prerelease) - len(o.prerelease))
+}
+
+// comparePrereleaseID compares identifiers of prereleases, numeric identifiers
+// are compared numerically and have a lower precedence than others.
+func comparePrereleaseID(a, b string) int {
+	na, aNumeric := parseNumericID(a)
+	nb, bNumeric := parseNumericID(b)
+	switch {
+	case aNumeric && bNumeric:
+		return sign(na - nb)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package client
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"v1.2.3", true},
+		{"1.2.3", true},
+		{"v1.2.3-rc.1", true},
+		{"v1.2.3+build.5", true},
+		{"v1.2", false},
+		{"v01.2.3", false},
+		{"v1.2.3-", false},
+		{"v1.2.3-rc..1", false},
+		{"release-2021", false},
+		{"latest", false},
+	}
+	for _, tt := range tests {
+		if _, ok := parseSemver(tt.tag); ok != tt.want {
+			t.Errorf("parseSemver(%q) got %v, want %v", tt.tag, ok, tt.want)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Each version has a lower precedence than the one that follows it.
+	ordered := []string{
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc.1",
+		"v1.0.0",
+		"v1.2.0",
+		"v1.10.0",
+		"v2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := parseSemver(ordered[i-1])
+		b, _ := parseSemver(ordered[i])
+		if a.compare(b) != -1 || b.compare(a) != 1 {
+			t.Errorf("%s is not lower than %s", ordered[i-1], ordered[i])
+		}
+	}
+	a, _ := parseSemver("v1.0.0+build.1")
+	b, _ := parseSemver("1.0.0")
+	if a.compare(b) != 0 {
+		t.Errorf("build metadata changed the precedence")
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WithSingleflight is an option func that coalesces concurrent identical
+// GetFile and GetBranchHead calls, so that they share a single request to the
+// upstream service, and all receive its result.
+//
+// Results are not kept once the request completes, a call made after it
+// always makes a new request. Calls with different tokens from
+// WithContextToken, or users from WithContextSudo, are never coalesced.
+//
+// Coalesced calls receive the same *scm.Content from GetFile, which must not
+// be modified, and share the context of the first call, if it's cancelled,
+// all the calls fail.
+func WithSingleflight() ClientFunc {
+	return func(c *SCMClient) {
+		c.flights = &flightGroup{}
+	}
+}
+
+// flightGroup tracks the in-flight calls by their key.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	wg   sync.WaitGroup
+	val  interface{}
+	err  error
+	dups int // the number of calls waiting for the result
+}
+
+// do calls fn, unless a call with the same key is in flight, in which case it
+// waits for that call and returns its result.
+//
+// A nil group calls fn directly.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	if g == nil {
+		return fn()
+	}
+	g.mu.Lock()
+	if f, ok := g.calls[key]; ok {
+		f.dups++
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.val, f.err
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	if g.calls == nil {
+		g.calls = map[string]*flight{}
+	}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		f.wg.Done()
+	}()
+	// If fn panics, the waiting calls return this error, and the panic is
+	// propagated to this call.
+	f.err = errFlightPanicked
+	f.val, f.err = fn()
+	return f.val, f.err
+}
+
+// errFlightPanicked is returned to the calls coalesced with a call that
+// panicked.
+var errFlightPanicked = errors.New("coalesced call panicked")
+
+// flightKey identifies a call by the method, its arguments, and the token,
+// sudo user and installation in the context, if there are any.
+func flightKey(ctx context.Context, method string, args ...string) string {
+	var token string
+	if t, ok := ctx.Value(scm.TokenKey{}).(*scm.Token); ok && t != nil {
+		token = t.Token
+	}
+	return strings.Join(append([]string{method, token, ContextSudo(ctx), strconv.FormatInt(ContextInstallation(ctx), 10)}, args...), "\x00")
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestFlightGroupCoalescesCalls(t *testing.T) {
+	g := &flightGroup{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = g.do("key", func() (interface{}, error) {
+			calls++
+			close(started)
+			<-release
+			return "sha1", nil
+		})
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.do("key", func() (interface{}, error) {
+				t.Error("the call was not coalesced")
+				return nil, nil
+			})
+		}(i)
+	}
+	for {
+		// Wait for the other calls to be waiting for the first call.
+		g.mu.Lock()
+		dups := g.calls["key"].dups
+		g.mu.Unlock()
+		if dups == len(results)-1 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+	for i, r := range results {
+		if r != "sha1" {
+			t.Errorf("result %d: got %v, want sha1", i, r)
+		}
+	}
+}
+
+func TestFlightGroupWithPanic(t *testing.T) {
+	g := &flightGroup{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { _ = recover() }()
+		_, _ = g.do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("failed")
+		})
+	}()
+	<-started
+	done := make(chan error)
+	go func() {
+		_, err := g.do("key", func() (interface{}, error) {
+			return nil, nil
+		})
+		done <- err
+	}()
+	for {
+		// Wait for the other call to be waiting for the first call.
+		g.mu.Lock()
+		dups := g.calls["key"].dups
+		g.mu.Unlock()
+		if dups == 1 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+
+	if err := <-done; err != errFlightPanicked {
+		t.Fatalf("got %v, want errFlightPanicked", err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.calls["key"]; ok {
+		t.Fatal("the call that panicked is still in flight")
+	}
+}
+
+func TestGetBranchHeadWithSingleflightDoesNotCache(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Times(2).
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithSingleflight())
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetBranchHead(context.Background(), "Codertocat/Hello-World", "master"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("the second call didn't make a request")
+	}
+}
+
+func TestFlightKeyWithContextToken(t *testing.T) {
+	ctx := context.Background()
+	a := flightKey(WithContextToken(ctx, "token-a"), "GetFile", "org/repo", "main", "a.yaml")
+	b := flightKey(WithContextToken(ctx, "token-b"), "GetFile", "org/repo", "main", "a.yaml")
+	if a == b {
+		t.Fatal("calls with different tokens have the same key")
+	}
+}
+
+func TestFlightKeyWithInstallation(t *testing.T) {
+	ctx := context.Background()
+	a := flightKey(WithInstallationContext(ctx, 1), "GetFile", "org/repo", "main", "a.yaml")
+	b := flightKey(WithInstallationContext(ctx, 2), "GetFile", "org/repo", "main", "a.yaml")
+	if a == b {
+		t.Fatal("calls with different installations have the same key")
+	}
+}
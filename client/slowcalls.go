@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// WithSlowCallLogging is an option func that logs every call to a GitClient
+// method that takes longer than the threshold, with the method, the repo and
+// the time it took.
+//
+// To help identify the slow resource, the log also includes the requests made
+// to the upstream service during the call, e.g. "GET
+// /repos/org/repo/contents/a.yaml?ref=main (2.1s)", request and response
+// bodies, and so any file content, are never logged.
+func WithSlowCallLogging(threshold time.Duration, log logr.Logger) ClientFunc {
+	return func(c *SCMClient) {
+		c.slowCalls = &slowCallLogger{threshold: threshold, log: log}
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &traceTransport{clock: c.getClock, next: rt}
+		})
+	}
+}
+
+// slowCallLogger logs calls that take longer than the threshold.
+type slowCallLogger struct {
+	threshold time.Duration
+	log       logr.Logger
+}
+
+// traceKey is the context key for the callTrace of a call in progress.
+type traceKey struct{}
+
+// callTrace records the requests made during a call.
+type callTrace struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (t *callTrace) add(request string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = append(t.requests, request)
+}
+
+func (t *callTrace) list() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.requests...)
+}
+
+// observeSlowCall runs the call, and logs it if it takes longer than the
+// threshold.
+func (c *SCMClient) observeSlowCall(ctx context.Context, method, repo string, fn func(ctx context.Context) error) error {
+	if c.slowCalls == nil {
+		return fn(ctx)
+	}
+	trace := &callTrace{}
+	clock := c.getClock()
+	start := clock.Now()
+	err := fn(context.WithValue(ctx, traceKey{}, trace))
+	if elapsed := clock.Now().Sub(start); elapsed > c.slowCalls.threshold {
+		c.slowCalls.log.Info("slow call", "method", method, "repo", repo, "duration", elapsed.String(), "requests", trace.list())
+	}
+	return err
+}
+
+// traceTransport records each request in the callTrace from the request
+// context, with the time it took.
+type traceTransport struct {
+	clock func() Clock
+	next  http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace, ok := req.Context().Value(traceKey{}).(*callTrace)
+	if !ok {
+		return transportOrDefault(t.next).RoundTrip(req)
+	}
+	clock := t.clock()
+	start := clock.Now()
+	res, err := transportOrDefault(t.next).RoundTrip(req)
+	trace.add(fmt.Sprintf("%s %s (%s)", req.Method, req.URL.RequestURI(), clock.Now().Sub(start)))
+	return res, err
+}
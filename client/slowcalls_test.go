@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWithSlowCallLogging(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &recordingLogger{}
+	client := New(scmClient, WithSlowCallLogging(0, log))
+
+	err = client.UpdateFile(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml",
+		"update", "", scm.Signature{}, []byte("secret content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(log.entries); l != 1 {
+		t.Fatalf("got %d log entries, want 1", l)
+	}
+	entry := log.entries[0]
+	if entry["method"] != "UpdateFile" || entry["repo"] != "Codertocat/Hello-World" {
+		t.Fatalf("got entry %v, want the method and repo", entry)
+	}
+	requests, _ := entry["requests"].([]string)
+	var methods []string
+	for _, r := range requests {
+		methods = append(methods, strings.SplitN(r, " ", 2)[0])
+		if strings.Contains(r, "secret") {
+			t.Fatalf("request %q includes the content", r)
+		}
+	}
+	if !reflect.DeepEqual(methods, []string{"GET", "PUT"}) {
+		t.Fatalf("got requests %v, want the read and the write", requests)
+	}
+	if !strings.HasPrefix(requests[0], "GET /repos/Codertocat/Hello-World/contents/config/my/file.yaml?ref=master (") {
+		t.Fatalf("got request %q, want the path and the duration", requests[0])
+	}
+}
+
+func TestWithSlowCallLoggingUnderThreshold(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &recordingLogger{}
+	client := New(scmClient, WithSlowCallLogging(time.Hour, log))
+
+	if _, err := client.GetBranchHead(context.Background(), "Codertocat/Hello-World", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if l := len(log.entries); l != 0 {
+		t.Fatalf("got %d log entries, want none", l)
+	}
+}
+
+// recordingLogger records the key/value pairs of the messages logged with
+// Info.
+type recordingLogger struct {
+	entries []map[string]interface{}
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	entry := map[string]interface{}{"msg": msg}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		entry[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordingLogger) Enabled() bool { return true }
+func (l *recordingLogger) Error(err error, msg string, kv ...interface{}) {
+	l.Info(msg, append(kv, "error", err)...)
+}
+func (l *recordingLogger) V(level int) logr.InfoLogger                         { return l }
+func (l *recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger { return l }
+func (l *recordingLogger) WithName(name string) logr.Logger                    { return l }
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// CombinedStatus is the overall state of the statuses for a ref.
+type CombinedStatus struct {
+	State    scm.State
+	Sha      string
+	Statuses []*scm.Status
+}
+
+// GetCombinedStatus returns the combined state of the latest status for each
+// context on the ref.
+//
+// GitHub provides the combined status, for other drivers it's computed from
+// the statuses with CombinedState.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*CombinedStatus, error) {
+	var out *CombinedStatus
+	err := c.call(ctx, "GetCombinedStatus", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getCombinedStatus(ctx, repo, ref)
+		return err
+	})
+	return out, err
+}
+
+// CreateStatus records a commit status for the ref, with the state, context
+// label, description and target URL of the input, the target URL is sent
+// unchanged so that it links to the details of the status.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateStatus(ctx context.Context, repo, ref string, inp *scm.StatusInput) (*scm.Status, error) {
+	var out *scm.Status
+	err := c.call(ctx, "CreateStatus", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.createStatus(ctx, repo, ref, inp)
+		return err
+	})
+	c.emit(Event{Type: "CreateStatus", Repo: repo, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) createStatus(ctx context.Context, repo, ref string, inp *scm.StatusInput) (*scm.Status, error) {
+	status, r, err := c.scmClient.Repositories.CreateStatus(ctx, repo, ref, inp)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to create status %s for ref %s in repo %s", inp.Label, ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (c *SCMClient) getCombinedStatus(ctx context.Context, repo, ref string) (*CombinedStatus, error) {
+	if c.scmClient.Driver == scm.DriverGithub {
+		return c.getCombinedStatusGitHub(ctx, repo, ref)
+	}
+	var (
+		statuses []*scm.Status
+		seen     = map[string]bool{}
+		opts     = scm.ListOptions{Size: 100}
+	)
+	for {
+		page, r, err := c.scmClient.Repositories.ListStatus(ctx, repo, ref, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list statuses for ref %s in repo %s", ref, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The statuses are listed newest first, only the latest status for
+		// each context counts.
+		for _, s := range page {
+			if !seen[s.Label] {
+				seen[s.Label] = true
+				statuses = append(statuses, s)
+			}
+		}
+		if !nextPage(&opts, r) {
+			break
+		}
+	}
+	return &CombinedStatus{State: CombinedState(statuses), Sha: ref, Statuses: statuses}, nil
+}
+
+// CombinedState rolls up the states of the statuses into a single state, any
+// failure takes precedence over pending statuses, and the state is only
+// successful if all the statuses are.
+//
+// With no statuses the state is pending, like GitHub's combined status.
+func CombinedState(statuses []*scm.Status) scm.State {
+	if len(statuses) == 0 {
+		return scm.StatePending
+	}
+	state := scm.StateSuccess
+	for _, s := range statuses {
+		switch s.State {
+		case scm.StateFailure, scm.StateError, scm.StateCanceled:
+			return scm.StateFailure
+		case scm.StateSuccess:
+		default:
+			state = scm.StatePending
+		}
+	}
+	return state
+}
+
+func (c *SCMClient) getCombinedStatusGitHub(ctx context.Context, repo, ref string) (*CombinedStatus, error) {
+	out := struct {
+		State    string `json:"state"`
+		Sha      string `json:"sha"`
+		Statuses []struct {
+			State       string `json:"state"`
+			Context     string `json:"context"`
+			Description string `json:"description"`
+			TargetURL   string `json:"target_url"`
+		} `json:"statuses"`
+	}{}
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/commits/%s/status", repo, ref), nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, SCMError{Msg: fmt.Sprintf("failed to get combined status for ref %s in repo %s", ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	combined := &CombinedStatus{State: convertStateGitHub(out.State), Sha: out.Sha}
+	for _, s := range out.Statuses {
+		combined.Statuses = append(combined.Statuses, &scm.Status{
+			State:  convertStateGitHub(s.State),
+			Label:  s.Context,
+			Desc:   s.Description,
+			Target: s.TargetURL,
+		})
+	}
+	return combined, nil
+}
+
+func convertStateGitHub(s string) scm.State {
+	switch s {
+	case "pending":
+		return scm.StatePending
+	case "success":
+		return scm.StateSuccess
+	case "failure":
+		return scm.StateFailure
+	case "error":
+		return scm.StateError
+	default:
+		return scm.StateUnknown
+	}
+}
+
+// Check is a single check on a ref, either a commit status or a GitHub check
+// run.
+type Check struct {
+	Name     string
+	State    scm.State
+	Desc     string
+	Link     string
+	CheckRun bool // whether the check is a check run rather than a status
+}
+
+// ChecksSummary is the overall state of the commit statuses and check runs
+// for a ref.
+type ChecksSummary struct {
+	State  scm.State
+	Sha    string
+	Checks []*Check
+}
+
+// GetChecksStatus returns the state of the ref rolled up from the latest
+// commit status for each context, and on GitHub, the latest check run for
+// each name, with ChecksState.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetChecksStatus(ctx context.Context, repo, ref string) (*ChecksSummary, error) {
+	var out *ChecksSummary
+	err := c.call(ctx, "GetChecksStatus", repo, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getChecksStatus(ctx, repo, ref)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getChecksStatus(ctx context.Context, repo, ref string) (*ChecksSummary, error) {
+	combined, err := c.getCombinedStatus(ctx, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	var checks []*Check
+	for _, s := range combined.Statuses {
+		checks = append(checks, &Check{Name: s.Label, State: s.State, Desc: s.Desc, Link: s.Target})
+	}
+	if c.scmClient.Driver == scm.DriverGithub {
+		runs, err := c.listCheckRunsGitHub(ctx, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, runs...)
+	}
+	return &ChecksSummary{State: ChecksState(checks), Sha: combined.Sha, Checks: checks}, nil
+}
+
+// ChecksState rolls up the states of the checks like CombinedState.
+func ChecksState(checks []*Check) scm.State {
+	statuses := make([]*scm.Status, len(checks))
+	for i, check := range checks {
+		statuses[i] = &scm.Status{State: check.State}
+	}
+	return CombinedState(statuses)
+}
+
+// listCheckRunsGitHub lists the latest check run for each name on the ref.
+func (c *SCMClient) listCheckRunsGitHub(ctx context.Context, repo, ref string) ([]*Check, error) {
+	opts := scm.ListOptions{Size: 100}
+	var all []*Check
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var out struct {
+			CheckRuns []struct {
+				Name       string `json:"name"`
+				Status     string `json:"status"`
+				Conclusion string `json:"conclusion"`
+				HTMLURL    string `json:"html_url"`
+				Output     struct {
+					Title string `json:"title"`
+				} `json:"output"`
+			} `json:"check_runs"`
+		}
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/commits/%s/check-runs?%s", repo, url.PathEscape(ref), params.Encode()), nil, &out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list check runs for ref %s in repo %s", ref, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range out.CheckRuns {
+			all = append(all, &Check{
+				Name:     run.Name,
+				State:    convertCheckRunStateGitHub(run.Status, run.Conclusion),
+				Desc:     run.Output.Title,
+				Link:     run.HTMLURL,
+				CheckRun: true,
+			})
+		}
+		if !nextPage(&opts, r) {
+			return all, nil
+		}
+	}
+}
+
+// convertCheckRunStateGitHub converts the status and conclusion of a check
+// run to a state, check runs that are not completed are pending, and neutral
+// and skipped check runs don't block like successful ones.
+func convertCheckRunStateGitHub(status, conclusion string) scm.State {
+	if status != "completed" {
+		return scm.StatePending
+	}
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return scm.StateSuccess
+	case "cancelled":
+		return scm.StateCanceled
+	case "stale":
+		return scm.StatePending
+	default:
+		return scm.StateFailure
+	}
+}
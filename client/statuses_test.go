@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCombinedState(t *testing.T) {
+	status := func(states ...scm.State) []*scm.Status {
+		var statuses []*scm.Status
+		for _, s := range states {
+			statuses = append(statuses, &scm.Status{State: s})
+		}
+		return statuses
+	}
+	tests := []struct {
+		name     string
+		statuses []*scm.Status
+		want     scm.State
+	}{
+		{"no statuses", nil, scm.StatePending},
+		{"all successful", status(scm.StateSuccess, scm.StateSuccess), scm.StateSuccess},
+		{"pending", status(scm.StateSuccess, scm.StateRunning), scm.StatePending},
+		{"failure over pending", status(scm.StatePending, scm.StateError, scm.StateSuccess), scm.StateFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombinedState(tt.statuses); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCombinedStatus(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/main/status").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"state": "failure",
+			"sha":   "abc123",
+			"statuses": []map[string]string{
+				{"state": "success", "context": "ci/build"},
+				{"state": "failure", "context": "ci/test", "target_url": "https://example.com/test"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	status, err := client.GetCombinedStatus(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != scm.StateFailure || status.Sha != "abc123" || len(status.Statuses) != 2 {
+		t.Fatalf("got %#v", status)
+	}
+	if s := status.Statuses[1]; s.Label != "ci/test" || s.Target != "https://example.com/test" {
+		t.Fatalf("got %#v", s)
+	}
+}
+
+func TestGetCombinedStatusFromGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/commits/abc123/statuses").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"name": "build", "status": "success"},
+			{"name": "test", "status": "running"},
+			{"name": "build", "status": "failed"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	status, err := client.GetCombinedStatus(context.Background(), "Codertocat/Hello-World", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != scm.StatePending || len(status.Statuses) != 2 {
+		t.Fatalf("got %#v, want a pending state from the latest statuses", status)
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+		BodyString(`"state":"success","target_url":"https://ci.example.com/builds/42\?tab=logs","description":"Build passed","context":"ci/build"`).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"state": "success", "context": "ci/build", "description": "Build passed", "target_url": "https://ci.example.com/builds/42?tab=logs"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	status, err := client.CreateStatus(context.Background(), "Codertocat/Hello-World", "6dcb09b5b57875f334f61aebed695e2e4193db5e", &scm.StatusInput{
+		State:  scm.StateSuccess,
+		Label:  "ci/build",
+		Desc:   "Build passed",
+		Target: "https://ci.example.com/builds/42?tab=logs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Target != "https://ci.example.com/builds/42?tab=logs" || status.Desc != "Build passed" {
+		t.Fatalf("got status %+v", status)
+	}
+}
+
+func TestCreateStatusWithError(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/statuses/unknown").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "No commit found for SHA: unknown"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.CreateStatus(context.Background(), "Codertocat/Hello-World", "unknown", &scm.StatusInput{State: scm.StateSuccess, Label: "ci/build"})
+	if !test.MatchError(t, `failed to create status ci/build for ref unknown in repo Codertocat/Hello-World`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetChecksStatus(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/main/status").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"state":    "success",
+			"sha":      "abc123",
+			"statuses": []map[string]string{{"state": "success", "context": "ci/build"}},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/main/check-runs").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"total_count": 2,
+			"check_runs": []map[string]interface{}{
+				{"name": "lint", "status": "completed", "conclusion": "skipped"},
+				{"name": "test", "status": "in_progress", "html_url": "https://github.com/Codertocat/Hello-World/runs/4"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	summary, err := client.GetChecksStatus(context.Background(), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ChecksSummary{
+		State: scm.StatePending,
+		Sha:   "abc123",
+		Checks: []*Check{
+			{Name: "ci/build", State: scm.StateSuccess},
+			{Name: "lint", State: scm.StateSuccess, CheckRun: true},
+			{Name: "test", State: scm.StatePending, Link: "https://github.com/Codertocat/Hello-World/runs/4", CheckRun: true},
+		},
+	}
+	if diff := cmp.Diff(want, summary); diff != "" {
+		t.Fatalf("got different summary: %s", diff)
+	}
+}
+
+func TestConvertCheckRunStateGitHub(t *testing.T) {
+	tests := []struct {
+		status     string
+		conclusion string
+		want       scm.State
+	}{
+		{"queued", "", scm.StatePending},
+		{"completed", "success", scm.StateSuccess},
+		{"completed", "neutral", scm.StateSuccess},
+		{"completed", "cancelled", scm.StateCanceled},
+		{"completed", "timed_out", scm.StateFailure},
+		{"completed", "action_required", scm.StateFailure},
+	}
+	for _, tt := range tests {
+		if got := convertCheckRunStateGitHub(tt.status, tt.conclusion); got != tt.want {
+			t.Errorf("convertCheckRunStateGitHub(%q, %q) got %v, want %v", tt.status, tt.conclusion, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// maxStreamResumes is the number of times that a stream that fails while it's
+// being read is resumed from the offset it reached.
+const maxStreamResumes = 3
+
+// GetFileStream reads the specific revision of a file from a repository like
+// GetFileRaw, but returns a reader that streams the bytes of the file from the
+// upstream service, so large files are never held in memory.
+//
+// The caller must Close the reader, even if it's not read to the end, to
+// release the connection to the upstream service.
+//
+// On GitHub, files that are too large for the contents API are read from the
+// blob API. If the upstream service supports range requests, a stream that
+// fails after part of the file was read is resumed from where it failed, up
+// to three times. Responses are never cached by WithETagCache.
+//
+// On drivers other than GitHub, GitLab and Gitea, the file is read with
+// GetFile, and the reader is over the bytes in memory.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetFileStream(ctx context.Context, repo, ref, path string) (io.ReadCloser, error) {
+	var out io.ReadCloser
+	err := c.callAt(ctx, "GetFileStream", repo, ref, path, func(ctx context.Context) (err error) {
+		if ref, err = c.resolveRef(ctx, repo, ref); err != nil {
+			return err
+		}
+		out, err = c.getFileStream(ctx, repo, ref, path)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) getFileStream(ctx context.Context, repo, ref, path string) (io.ReadCloser, error) {
+	var urlPath string
+	header := http.Header{}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		urlPath = fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, path, url.QueryEscape(ref))
+		header.Set("Accept", rawMediaTypeGitHub)
+	case scm.DriverGitlab:
+		urlPath = fmt.Sprintf("api/v4/projects/%s/repository/files/%s/raw?ref=%s", encodeRepo(repo), url.PathEscape(path), url.QueryEscape(ref))
+	case scm.DriverGitea:
+		urlPath = fmt.Sprintf("api/v1/repos/%s/raw/%s?ref=%s", repo, path, url.QueryEscape(ref))
+	default:
+		content, err := c.GetFile(ctx, repo, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(content.Data)), nil
+	}
+	msg := fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref)
+	s, status, errBody, err := c.openStream(ctx, urlPath, header, msg)
+	if c.scmClient.Driver == scm.DriverGithub && isTooLarge(status, errBody) {
+		return c.getBlobStreamGitHub(ctx, repo, ref, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// getBlobStreamGitHub streams the blob for the file, found in its directory
+// listing like getBlobGitHub.
+func (c *SCMClient) getBlobStreamGitHub(ctx context.Context, repo, ref, path string) (io.ReadCloser, error) {
+	dir := pathpkg.Dir(path)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := c.listFiles(ctx, repo, ref, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Path == path {
+			s, _, _, err := c.openStream(ctx, fmt.Sprintf("repos/%s/git/blobs/%s", repo, e.BlobID), http.Header{"Accept": {rawMediaTypeGitHub}},
+				fmt.Sprintf("failed to get blob for file %s from repo %s ref %s", path, repo, ref))
+			if err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+	}
+	return nil, SCMError{Msg: fmt.Sprintf("failed to get file %s from repo %s ref %s", path, repo, ref), Status: http.StatusNotFound}
+}
+
+// openStream makes a GET request to the path, and returns a stream over the
+// response body, or an SCMError with the msg and the status of an error
+// response, which is returned with the status and body of the response.
+func (c *SCMClient) openStream(ctx context.Context, path string, header http.Header, msg string) (*fileStream, int, []byte, error) {
+	s := &fileStream{ctx: withoutETagCache(ctx), c: c, path: path, header: header, msg: msg}
+	res, err := s.open(0)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if isErrorStatus(res.Status) {
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+		return nil, res.Status, body, SCMError{Msg: msg, Status: res.Status}
+	}
+	s.body = res.Body
+	s.resumable = res.Header.Get("Accept-Ranges") == "bytes"
+	return s, res.Status, nil, nil
+}
+
+// fileStream is a response body that's resumed with a range request if it
+// fails while it's read.
+type fileStream struct {
+	ctx       context.Context
+	c         *SCMClient
+	path      string
+	header    http.Header
+	msg       string
+	body      io.ReadCloser
+	offset    int64
+	resumable bool
+	resumes   int
+	err       error
+}
+
+func (s *fileStream) open(offset int64) (*scm.Response, error) {
+	header := s.header.Clone()
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	return s.c.scmClient.Do(s.ctx, &scm.Request{Method: http.MethodGet, Path: s.path, Header: header})
+}
+
+func (s *fileStream) Read(p []byte) (int, error) {
+	for s.err == nil {
+		n, err := s.body.Read(p)
+		s.offset += int64(n)
+		if err == nil || err == io.EOF || !s.resumable || s.resumes == maxStreamResumes || s.ctx.Err() != nil {
+			return n, err
+		}
+		if rerr := s.resume(); rerr != nil {
+			s.err = fmt.Errorf("%s after %d bytes: %v", s.msg, s.offset, err)
+			return n, s.err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, s.err
+}
+
+// resume replaces the body with the rest of the file from the offset.
+func (s *fileStream) resume() error {
+	s.resumes++
+	s.body.Close()
+	res, err := s.open(s.offset)
+	if err != nil {
+		return err
+	}
+	s.body = res.Body
+	if res.Status != http.StatusPartialContent {
+		return errors.New("range request not satisfied")
+	}
+	return nil
+}
+
+func (s *fileStream) Close() error {
+	return s.body.Close()
+}
+
+type noETagCacheKey struct{}
+
+// withoutETagCache returns a copy of the context that makes WithETagCache pass
+// requests through, so streamed bodies are not read into memory.
+func withoutETagCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noETagCacheKey{}, true)
+}
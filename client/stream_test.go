@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetFileStream(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data/large.bin").
+		MatchParam("ref", "master").
+		MatchHeader("Accept", "application/vnd.github.raw").
+		Reply(http.StatusOK).
+		BodyString("large content")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	r, err := client.GetFileStream(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "large content" {
+		t.Fatalf("got %q, want %q", s, "large content")
+	}
+}
+
+func TestGetFileStreamFallsBackToBlob(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data/large.bin").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "This API returns blobs up to 100 MB in size."})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"name": "large.bin", "path": "data/large.bin", "sha": "blob-sha", "type": "file"},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/blobs/blob-sha").
+		MatchHeader("Accept", "application/vnd.github.raw").
+		Reply(http.StatusOK).
+		BodyString("large content")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	r, err := client.GetFileStream(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "large content" {
+		t.Fatalf("got %q, want %q", s, "large content")
+	}
+}
+
+func TestGetFileStreamForbidden(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/data/large.bin").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "Resource not accessible by integration"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetFileStream(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	var scmErr SCMError
+	if !errors.As(err, &scmErr) || scmErr.Status != http.StatusForbidden {
+		t.Fatalf("got %v, want a forbidden error", err)
+	}
+}
+
+func TestGetFileStreamFromGitLabNotFound(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml/raw").
+		MatchParam("ref", "main").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetFileStream(context.Background(), "Codertocat/Hello-World", "main", "config/app.yaml")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetFileStreamResumesWithRange(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &interruptedTransport{content: "large content", failAt: 6}
+	scmClient.Client = &http.Client{Transport: rt}
+	client := New(scmClient)
+
+	r, err := client.GetFileStream(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "large content" {
+		t.Fatalf("got %q, want %q", s, "large content")
+	}
+	if want := []string{"", "bytes=6-"}; strings.Join(rt.ranges, ",") != strings.Join(want, ",") {
+		t.Fatalf("got ranges %q, want %q", rt.ranges, want)
+	}
+}
+
+func TestGetFileStreamWithoutRangeSupport(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &interruptedTransport{content: "large content", failAt: 6, noRanges: true}
+	scmClient.Client = &http.Client{Transport: rt}
+	client := New(scmClient)
+
+	r, err := client.GetFileStream(context.Background(), "Codertocat/Hello-World", "master", "data/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	_, err = ioutil.ReadAll(r)
+	if !test.MatchError(t, "connection reset", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if len(rt.ranges) != 1 {
+		t.Fatalf("got %d requests, want 1", len(rt.ranges))
+	}
+}
+
+// interruptedTransport serves the content, failing the first response after
+// failAt bytes, and serves range requests for the rest.
+type interruptedTransport struct {
+	content  string
+	failAt   int
+	noRanges bool
+	ranges   []string
+}
+
+func (t *interruptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.ranges = append(t.ranges, req.Header.Get("Range"))
+	header := http.Header{}
+	if !t.noRanges {
+		header.Set("Accept-Ranges", "bytes")
+	}
+	if r := req.Header.Get("Range"); r != "" {
+		return &http.Response{
+			StatusCode: http.StatusPartialContent,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader(t.content[t.failAt:])),
+			Request:    req,
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       ioutil.NopCloser(io.MultiReader(strings.NewReader(t.content[:t.failAt]), &failingReader{err: errors.New("connection reset")})),
+		Request:    req,
+	}, nil
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
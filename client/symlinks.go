@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	pathpkg "path"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WithFollowSymlinks is an option func that makes GetFile return the content
+// of the file that a symlink points to, rather than the path of the target,
+// following up to depth symlinks that point to other symlinks.
+//
+// Symlinks are detected from the entries of the directory of the file, which
+// takes an extra request for each file read. On GitHub, the contents API
+// already follows symlinks to files, so no extra requests are made.
+func WithFollowSymlinks(depth int) ClientFunc {
+	return func(c *SCMClient) {
+		c.symlinkDepth = depth
+	}
+}
+
+// followSymlinks returns the content of the file that the path points to if
+// it's a symlink, and the content unchanged otherwise.
+func (c *SCMClient) followSymlinks(ctx context.Context, repo, ref, path string, content *scm.Content) (*scm.Content, error) {
+	seen := map[string]bool{}
+	for hops := 0; ; hops++ {
+		link, err := c.isSymlink(ctx, repo, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		if !link {
+			return content, nil
+		}
+		seen[path] = true
+		target, err := ResolveSymlink(path, string(content.Data))
+		if err != nil {
+			return nil, err
+		}
+		if hops == c.symlinkDepth || seen[target] {
+			return nil, fmt.Errorf("failed to follow symlink %s in repo %s ref %s: %w", path, repo, ref, ErrTooManySymlinks)
+		}
+		if content, err = c.getFile(ctx, repo, ref, target); err != nil {
+			return nil, err
+		}
+		path = target
+	}
+}
+
+// isSymlink returns true if the entry for the path in its directory is a
+// symlink.
+func (c *SCMClient) isSymlink(ctx context.Context, repo, ref, path string) (bool, error) {
+	path = strings.Trim(path, "/")
+	dir := pathpkg.Dir(path)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := c.listFiles(ctx, repo, ref, dir, 0)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if strings.Trim(e.Path, "/") == path {
+			return e.Kind == scm.ContentKindSymlink, nil
+		}
+	}
+	return false, nil
+}
+
+// ResolveSymlink returns the path in the repo of the target of the symlink at
+// the path, the target is relative to the directory of the symlink, and must
+// not point outside of the repo.
+func ResolveSymlink(path, target string) (string, error) {
+	if strings.HasPrefix(target, "/") {
+		return "", fmt.Errorf("symlink %s points to absolute path %s", path, target)
+	}
+	resolved := pathpkg.Join(pathpkg.Dir(strings.Trim(path, "/")), target)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "", fmt.Errorf("symlink %s points outside of the repo to %s", path, target)
+	}
+	return resolved, nil
+}
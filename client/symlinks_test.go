@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetFileFollowingSymlinks(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yaml").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/app.yaml", "content": base64.StdEncoding.EncodeToString([]byte("../shared/app.yaml")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		MatchParam("path", "^config$").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{{"path": "config/app.yaml", "type": "blob", "mode": "120000"}})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/shared/app.yaml").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "shared/app.yaml", "content": base64.StdEncoding.EncodeToString([]byte("name: shared\n")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		MatchParam("path", "^shared$").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{{"path": "shared/app.yaml", "type": "blob", "mode": "100644"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithFollowSymlinks(1))
+
+	content, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "config/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content.Data) != "name: shared\n" {
+		t.Fatalf("got %q, want the content of the symlink target", content.Data)
+	}
+	if !gock.IsDone() {
+		t.Fatal("symlink was not followed")
+	}
+}
+
+func TestGetFileWithSymlinkLoop(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/a.yaml").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "a.yaml", "content": base64.StdEncoding.EncodeToString([]byte("b.yaml")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/b.yaml").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "b.yaml", "content": base64.StdEncoding.EncodeToString([]byte("a.yaml")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		Times(2).
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"path": "a.yaml", "type": "blob", "mode": "120000"},
+			{"path": "b.yaml", "type": "blob", "mode": "120000"},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithFollowSymlinks(5))
+
+	_, err = client.GetFile(context.Background(), "Codertocat/Hello-World", "main", "a.yaml")
+	if !errors.Is(err, ErrTooManySymlinks) {
+		t.Fatalf("got %v, want ErrTooManySymlinks", err)
+	}
+}
+
+func TestResolveSymlink(t *testing.T) {
+	resolveTests := []struct {
+		path    string
+		target  string
+		want    string
+		wantErr string
+	}{
+		{"config/app.yaml", "../shared/app.yaml", "shared/app.yaml", ""},
+		{"config/app.yaml", "base.yaml", "config/base.yaml", ""},
+		{"app.yaml", "../app.yaml", "", "points outside of the repo"},
+		{"app.yaml", "/etc/app.yaml", "", "points to absolute path"},
+	}
+
+	for _, tt := range resolveTests {
+		got, err := ResolveSymlink(tt.path, tt.target)
+		if tt.wantErr != "" {
+			test.MatchError(t, tt.wantErr, err)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveSymlink(%q, %q) got %q, want %q", tt.path, tt.target, got, tt.want)
+		}
+	}
+}
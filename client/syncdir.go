@@ -0,0 +1,125 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// SyncDir makes the files in the directory of the branch, and its
+// subdirectories, match the desired files, keyed by their path relative to
+// the directory, in a single commit, returning the number of files created,
+// updated and deleted, and the SHA of the commit.
+//
+// Files that are missing are created, files with different content are
+// updated, and files that are not desired are deleted. If the directory
+// already matches, no commit is made and the SHA of the branch head is
+// returned along with ErrNoChange.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SyncDir(ctx context.Context, repo, branch, dir string, desired map[string][]byte, signature scm.Signature, message string) (created, updated, deleted int, sha string, err error) {
+	err = c.call(ctx, "SyncDir", repo, func(ctx context.Context) (err error) {
+		created, updated, deleted, sha, err = c.syncDir(ctx, repo, branch, dir, desired, signature, message)
+		return err
+	})
+	c.emit(Event{Type: "SyncDir", Repo: repo, Branch: branch, Path: dir, Err: err})
+	return created, updated, deleted, sha, err
+}
+
+func (c *SCMClient) syncDir(ctx context.Context, repo, branch, dir string, desired map[string][]byte, signature scm.Signature, message string) (created, updated, deleted int, sha string, err error) {
+	paths, err := c.listFilesRecursive(ctx, repo, branch, dir)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	files, err := c.getFiles(ctx, repo, branch, paths)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	current := make(map[string][]byte, len(files))
+	for p, file := range files {
+		current[p] = file.Data
+	}
+	if c.lineEnding != LineEndingNone {
+		normalized := make(map[string][]byte, len(desired))
+		for p, content := range desired {
+			normalized[p] = normalizeLineEndings(content, c.lineEnding)
+		}
+		desired = normalized
+	}
+	changes, created, updated, deleted := SyncChanges(dir, current, desired)
+	if len(changes) == 0 {
+		head, err := c.GetBranchHead(ctx, repo, branch)
+		if err != nil {
+			return 0, 0, 0, "", fmt.Errorf("failed to get branch head: %w", err)
+		}
+		return 0, 0, 0, head, ErrNoChange
+	}
+	sha, err = c.UpdateFiles(ctx, repo, branch, message, signature, changes)
+	if err != nil {
+		return 0, 0, 0, sha, err
+	}
+	return created, updated, deleted, sha, nil
+}
+
+// listFilesRecursive returns the paths of the files in the directory and its
+// subdirectories, a directory that doesn't exist has no files.
+func (c *SCMClient) listFilesRecursive(ctx context.Context, repo, ref, dir string) ([]string, error) {
+	entries, err := c.listFiles(ctx, repo, ref, dir, 0)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		switch e.Kind {
+		case scm.ContentKindFile:
+			paths = append(paths, e.Path)
+		case scm.ContentKindDirectory:
+			sub, err := c.listFilesRecursive(ctx, repo, ref, e.Path)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, sub...)
+		}
+	}
+	return paths, nil
+}
+
+// SyncChanges returns the changes that make the files under the directory
+// match the desired files, keyed by their path relative to the directory,
+// given the current files, keyed by their path in the repo, in lexical order
+// of the paths, along with the number of files created, updated and deleted.
+func SyncChanges(dir string, current, desired map[string][]byte) (changes []FileChange, created, updated, deleted int) {
+	prefix := strings.Trim(dir, "/")
+	wanted := make(map[string]bool, len(desired))
+	for rel, content := range desired {
+		p := path.Join(prefix, rel)
+		wanted[p] = true
+		existing, ok := current[p]
+		switch {
+		case !ok:
+			created++
+		case !bytes.Equal(existing, content):
+			updated++
+		default:
+			continue
+		}
+		changes = append(changes, FileChange{Path: p, Content: content})
+	}
+	for p := range current {
+		if !wanted[p] && (prefix == "" || strings.HasPrefix(p, prefix+"/")) {
+			deleted++
+			changes = append(changes, FileChange{Path: p, Delete: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, created, updated, deleted
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSyncChanges(t *testing.T) {
+	current := map[string][]byte{
+		"config/app.yml":         []byte("name: old\n"),
+		"config/extra.yml":       []byte("extra: true\n"),
+		"config/nested/keep.yml": []byte("keep: true\n"),
+		"other/app.yml":          []byte("name: other\n"),
+	}
+	desired := map[string][]byte{
+		"app.yml":         []byte("name: new\n"),
+		"db.yml":          []byte("host: db\n"),
+		"nested/keep.yml": []byte("keep: true\n"),
+	}
+
+	changes, created, updated, deleted := SyncChanges("config/", current, desired)
+	want := []FileChange{
+		{Path: "config/app.yml", Content: []byte("name: new\n")},
+		{Path: "config/db.yml", Content: []byte("host: db\n")},
+		{Path: "config/extra.yml", Delete: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("got changes %v, want %v", changes, want)
+	}
+	if created != 1 || updated != 1 || deleted != 1 {
+		t.Fatalf("got %d created, %d updated and %d deleted, want 1 of each", created, updated, deleted)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		MatchParam("path", "^config$").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{
+			{"path": "config/app.yml", "type": "blob", "mode": "100644"},
+			{"path": "config/extra.yml", "type": "blob", "mode": "100644"},
+		})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/app.yml").
+		Times(3).
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/app.yml", "content": base64.StdEncoding.EncodeToString([]byte("name: old\n")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/extra.yml").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"file_path": "config/extra.yml", "content": base64.StdEncoding.EncodeToString([]byte("extra: true\n")), "encoding": "base64"})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/files/config/db.yml").
+		Times(2).
+		Reply(http.StatusNotFound)
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/commits").
+		JSON(map[string]interface{}{
+			"branch":         "main",
+			"commit_message": "sync config",
+			"author_name":    "John Doe",
+			"author_email":   "john.doe@example.com",
+			"actions": []map[string]string{
+				{"action": "update", "file_path": "config/app.yml", "content": base64.StdEncoding.EncodeToString([]byte("name: new\n")), "encoding": "base64"},
+				{"action": "create", "file_path": "config/db.yml", "content": base64.StdEncoding.EncodeToString([]byte("host: db\n")), "encoding": "base64"},
+				{"action": "delete", "file_path": "config/extra.yml"},
+			},
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"id": "new-commit"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	desired := map[string][]byte{"app.yml": []byte("name: new\n"), "db.yml": []byte("host: db\n")}
+	created, updated, deleted, sha, err := client.SyncDir(context.Background(), "Codertocat/Hello-World", "main", "config", desired,
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, "sync config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || updated != 1 || deleted != 1 {
+		t.Fatalf("got %d created, %d updated and %d deleted, want 1 of each", created, updated, deleted)
+	}
+	if sha != "new-commit" {
+		t.Fatalf("got sha %s, want new-commit", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("directory was not synced")
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// TagInfo is a tag of a repository with the commit that it points to.
+type TagInfo struct {
+	Name   string
+	Sha    string // the SHA of the commit, not of an annotated tag object
+	Commit *scm.Commit
+}
+
+// ListTagsWithCommits lists the tags of the repo, paging through the tags from
+// the page in the options, with the commit that each tag points to.
+//
+// Annotated tags are dereferenced to their commit, so the SHA is always the
+// SHA of a commit, for both annotated and lightweight tags. The commits are
+// fetched with a request for each tag.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListTagsWithCommits(ctx context.Context, repo string, opts scm.ListOptions) ([]*TagInfo, error) {
+	var out []*TagInfo
+	err := c.call(ctx, "ListTagsWithCommits", repo, func(ctx context.Context) (err error) {
+		out, err = c.listTagsWithCommits(ctx, repo, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) listTagsWithCommits(ctx context.Context, repo string, opts scm.ListOptions) ([]*TagInfo, error) {
+	tags, err := c.listTags(ctx, repo, opts)
+	if err != nil && err != ErrTruncated {
+		return nil, err
+	}
+	infos := make([]*TagInfo, 0, len(tags))
+	for _, tag := range tags {
+		// The commits endpoints peel annotated tags, so the commit is found
+		// whether the tag refers to a commit or to a tag object.
+		commit, r, ferr := c.scmClient.Git.FindCommit(ctx, repo, tag.Sha)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to get commit %s for tag %s in repo %s", tag.Sha, tag.Name, repo), Status: r.Status}
+		}
+		if ferr != nil {
+			return nil, ferr
+		}
+		infos = append(infos, &TagInfo{Name: tag.Name, Sha: commit.Sha, Commit: commit})
+	}
+	return infos, err
+}
+
+func (c *SCMClient) listTags(ctx context.Context, repo string, opts scm.ListOptions) ([]*scm.Reference, error) {
+	var all []*scm.Reference
+	for {
+		tags, r, err := c.scmClient.Git.ListTags(ctx, repo, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list tags in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tags...)
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListTagsWithCommits(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/tags").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"name": "v1.1.0", "commit": map[string]string{"sha": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"}},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/7fd1a60b01f91b314f59955a4e4d4e80d8edf11d").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"sha": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d",
+			"commit": map[string]interface{}{
+				"message": "Release v1.1.0",
+				"author":  map[string]string{"name": "Monalisa Octocat", "email": "support@github.com", "date": "2021-04-20T10:00:00Z"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	tags, err := client.ListTagsWithCommits(context.Background(), "Codertocat/Hello-World", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Fatalf("got %d tags, want 1", len(tags))
+	}
+	tag := tags[0]
+	if tag.Name != "v1.1.0" || tag.Sha != "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d" {
+		t.Fatalf("got tag %s at %s", tag.Name, tag.Sha)
+	}
+	if tag.Commit.Message != "Release v1.1.0" || tag.Commit.Author.Name != "Monalisa Octocat" ||
+		!tag.Commit.Author.Date.Equal(time.Date(2021, 4, 20, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got commit %+v", tag.Commit)
+	}
+}
+
+func TestListTagsWithCommitsDereferencesAnnotatedTags(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tags").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{
+			{"name": "v2.0.0", "target": "a43b9c7e8d1c6f8ffb3b7c35bd67ffa1e7f4c52c", "commit": map[string]string{"id": "6104942438c14ec7bd21c6cd5bd995272b3faff6"}},
+		})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/commits/6104942438c14ec7bd21c6cd5bd995272b3faff6").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": "6104942438c14ec7bd21c6cd5bd995272b3faff6", "message": "Release v2.0.0", "author_name": "John Doe"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	tags, err := client.ListTagsWithCommits(context.Background(), "Codertocat/Hello-World", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Sha != "6104942438c14ec7bd21c6cd5bd995272b3faff6" {
+		t.Fatalf("got tags %v, want the commit of the annotated tag", tags)
+	}
+}
+
+func TestListTagsWithCommitsWithUnknownCommit(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/tags").
+		Reply(http.StatusOK).
+		JSON([]map[string]interface{}{{"name": "v1.0.0", "commit": map[string]string{"sha": "unknown"}}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/unknown").
+		Reply(http.StatusNotFound).
+		JSON(map[string]string{"message": "Not Found"})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListTagsWithCommits(context.Background(), "Codertocat/Hello-World", scm.ListOptions{})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// CommitTemplate executes the template with the data, and commits the result
+// to the file at the path on the branch, returning the SHA of the commit.
+//
+// If the file on the branch already has the rendered content, no commit is
+// made, and the SHA of the branch head is returned along with ErrNoChange.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CommitTemplate(ctx context.Context, repo, branch, path, message string, signature scm.Signature, tmpl *template.Template, data interface{}) (string, error) {
+	var out string
+	err := c.callAt(ctx, "CommitTemplate", repo, branch, path, func(ctx context.Context) (err error) {
+		out, err = c.commitTemplate(ctx, repo, branch, path, message, signature, tmpl, data)
+		return err
+	})
+	c.emit(Event{Type: "CommitTemplate", Repo: repo, Branch: branch, Path: path, Err: err})
+	return out, err
+}
+
+func (c *SCMClient) commitTemplate(ctx context.Context, repo, branch, path, message string, signature scm.Signature, tmpl *template.Template, data interface{}) (string, error) {
+	content, err := RenderTemplate(tmpl, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render file %s: %w", path, err)
+	}
+	return c.UpdateFiles(ctx, repo, branch, message, signature, []FileChange{{Path: path, Content: content}})
+}
+
+// RenderTemplate executes the template with the data and returns the rendered
+// bytes.
+func RenderTemplate(tmpl *template.Template, data interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"text/template"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCommitTemplate(t *testing.T) {
+	head := "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/a.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/commits/" + head).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"sha": head, "tree": map[string]string{"sha": "base-tree"}})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/base-tree").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"tree": []map[string]string{
+				{"path": "README.md", "mode": "100644", "type": "blob", "sha": "readme-blob"},
+			},
+		})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/blobs").
+		JSON(map[string]string{"content": "cmVwbGljYXM6IDMK", "encoding": "base64"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-blob"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/trees").
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-tree"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/commits").
+		Reply(http.StatusCreated).
+		JSON(map[string]string{"sha": "new-commit"})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/git/refs/heads/master").
+		Reply(http.StatusOK)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+	tmpl := template.Must(template.New("config").Parse("replicas: {{ .Replicas }}\n"))
+
+	sha, err := client.CommitTemplate(context.Background(), "Codertocat/Hello-World", "master", "config/a.yaml", "scale",
+		scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}, tmpl, map[string]int{"Replicas": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "new-commit" {
+		t.Fatalf("got sha %s, want new-commit", sha)
+	}
+	if !gock.IsDone() {
+		t.Fatal("commit was not created")
+	}
+}
+
+func TestCommitTemplateWithNoChanges(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		MatchParam("ref", "master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/branches/master").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/github_get_branch.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+	tmpl := template.Must(template.New("file").Parse("body:\n  key:\n    env:\n      val: {{ . }}\n"))
+
+	sha, err := client.CommitTemplate(context.Background(), "Codertocat/Hello-World", "master", "config/my/file.yaml", "update",
+		scm.Signature{}, tmpl, "testing")
+	if !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got %v, want ErrNoChange", err)
+	}
+	if sha != "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d" {
+		t.Fatalf("got sha %s, want the branch head", sha)
+	}
+}
+
+func TestCommitTemplateWithFailingTemplate(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+	tmpl := template.Must(template.New("file").Option("missingkey=error").Parse("{{ .missing }}"))
+
+	_, err = client.CommitTemplate(context.Background(), "Codertocat/Hello-World", "master", "config/a.yaml", "update",
+		scm.Signature{}, tmpl, map[string]string{})
+	var execErr template.ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("got %v, want a template execution error", err)
+	}
+}
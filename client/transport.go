@@ -0,0 +1,307 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/driver/bitbucket"
+	"github.com/ocraviotto/go-scm/scm/driver/gitea"
+	"github.com/ocraviotto/go-scm/scm/driver/gitee"
+	"github.com/ocraviotto/go-scm/scm/driver/github"
+	"github.com/ocraviotto/go-scm/scm/driver/gitlab"
+	"github.com/ocraviotto/go-scm/scm/driver/gogs"
+	"github.com/ocraviotto/go-scm/scm/driver/stash"
+	"github.com/ocraviotto/go-scm/scm/transport"
+	"golang.org/x/oauth2"
+)
+
+// wrapTransport replaces the transport of the HTTP client used by the
+// SCMClient's copy of the scm.Client with the one returned by wrap.
+//
+// The HTTP client is copied before it's modified, so the HTTP client of the
+// scm.Client passed to New is never changed.
+func (c *SCMClient) wrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	hc := &http.Client{}
+	if c.scmClient.Client != nil {
+		copied := *c.scmClient.Client
+		hc = &copied
+	}
+	hc.Transport = wrap(hc.Transport)
+	c.scmClient.Client = hc
+}
+
+// driverConstructors create an scm.Client for the driver with its services
+// bound to it.
+var driverConstructors = map[scm.Driver]func(string) (*scm.Client, error){
+	scm.DriverBitbucket: bitbucket.New,
+	scm.DriverGitea:     gitea.New,
+	scm.DriverGitee:     gitee.New,
+	scm.DriverGithub:    github.New,
+	scm.DriverGitlab:    gitlab.New,
+	scm.DriverGogs:      gogs.New,
+	scm.DriverStash:     stash.New,
+}
+
+// copyClient returns a copy of the scm.Client that the SCMClient can change
+// the transport of without changing the caller's scm.Client.
+//
+// The go-scm services make requests with the scm.Client they were created
+// with, so the copy is created with the driver, rather than by copying the
+// struct. If the driver is unknown, the services of the copy still make
+// requests with the caller's HTTP client.
+func copyClient(c *scm.Client) *scm.Client {
+	if newClient, ok := driverConstructors[c.Driver]; ok && c.BaseURL != nil {
+		if copied, err := newClient(c.BaseURL.String()); err == nil {
+			copied.Client = c.Client
+			copied.BaseURL = c.BaseURL
+			copied.Username = c.Username
+			copied.Linker = c.Linker
+			copied.DumpResponse = c.DumpResponse
+			copied.SetRate(c.Rate())
+			return copied
+		}
+	}
+	return &scm.Client{
+		Client:        c.Client,
+		BaseURL:       c.BaseURL,
+		Username:      c.Username,
+		Driver:        c.Driver,
+		Linker:        c.Linker,
+		Contents:      c.Contents,
+		Git:           c.Git,
+		Organizations: c.Organizations,
+		Issues:        c.Issues,
+		Milestones:    c.Milestones,
+		PullRequests:  c.PullRequests,
+		Repositories:  c.Repositories,
+		Releases:      c.Releases,
+		Reviews:       c.Reviews,
+		Users:         c.Users,
+		Webhooks:      c.Webhooks,
+		DumpResponse:  c.DumpResponse,
+	}
+}
+
+// transportOrDefault returns the transport, or http.DefaultTransport if it's
+// nil.
+//
+// This is resolved when each request is made rather than when the transport
+// is wrapped, so that replacements of the default transport are respected.
+func transportOrDefault(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		return http.DefaultTransport
+	}
+	return rt
+}
+
+// TokenSource provides the token used to authenticate requests, it's called
+// before each request, so implementations should cache tokens that are
+// expensive to create until they expire.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to the TokenSource interface.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token implements the TokenSource interface.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource returns a TokenSource that always returns the token.
+func StaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// WithTokenSource is an option func that authenticates each request with a
+// bearer token from the TokenSource, e.g. GitHub App installation tokens that
+// need to be refreshed.
+//
+// The scm.Client should be created without a token, otherwise the transport
+// that adds it replaces the token from the TokenSource.
+func WithTokenSource(ts TokenSource) ClientFunc {
+	return func(c *SCMClient) {
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &tokenTransport{source: ts, next: rt}
+		})
+	}
+}
+
+type tokenTransport struct {
+	source TokenSource
+	next   http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return transportOrDefault(t.next).RoundTrip(req)
+}
+
+// WithContextToken returns a copy of the context that carries the token, the
+// requests made with the context are authenticated with the token instead of
+// the credentials of the client.
+//
+// This allows a single client to be shared between callers with different
+// credentials.
+func WithContextToken(ctx context.Context, token string) context.Context {
+	return scm.WithContext(ctx, &scm.Token{Token: token})
+}
+
+// contextTokenTransport authenticates requests with the token from the
+// request context if there is one.
+//
+// The transports created by go-scm's factory add the configured credentials,
+// some unconditionally, so when there's a token in the context, they are
+// bypassed and the request is sent to the transport they wrap.
+type contextTokenTransport struct {
+	next http.RoundTripper
+}
+
+func (t *contextTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, ok := req.Context().Value(scm.TokenKey{}).(*scm.Token)
+	if !ok || token == nil || token.Token == "" {
+		return transportOrDefault(t.next).RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del("Private-Token")
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	return transportOrDefault(withoutCredentials(t.next)).RoundTrip(req)
+}
+
+// WithSudo is an option func that makes the requests to GitLab on behalf of
+// the user with the Sudo header, so that commits, pull requests and comments
+// are attributed to the user, this requires an administrator token.
+//
+// A user in the context from WithContextSudo takes precedence, on other
+// drivers this is a no-op.
+func WithSudo(username string) ClientFunc {
+	return func(c *SCMClient) {
+		if c.scmClient.Driver != scm.DriverGitlab {
+			return
+		}
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &sudoTransport{user: username, next: rt}
+		})
+	}
+}
+
+type sudoKey struct{}
+
+// WithContextSudo returns a copy of the context that carries the user, the
+// requests made to GitLab with the context are made on behalf of the user, like
+// with WithSudo.
+func WithContextSudo(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, sudoKey{}, username)
+}
+
+// ContextSudo returns the user from WithContextSudo, or an empty string if
+// there's none.
+func ContextSudo(ctx context.Context) string {
+	user, _ := ctx.Value(sudoKey{}).(string)
+	return user
+}
+
+// sudoTransport sets the Sudo header to the user from the request context, or
+// the configured user if there's none.
+type sudoTransport struct {
+	user string
+	next http.RoundTripper
+}
+
+func (t *sudoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	user := ContextSudo(req.Context())
+	if user == "" {
+		user = t.user
+	}
+	if user == "" {
+		return transportOrDefault(t.next).RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Sudo", user)
+	return transportOrDefault(t.next).RoundTrip(req)
+}
+
+// withoutCredentials returns the transport wrapped by the transports that add
+// credentials to requests.
+func withoutCredentials(rt http.RoundTripper) http.RoundTripper {
+	for {
+		switch t := rt.(type) {
+		case *oauth2.Transport:
+			rt = t.Base
+		case *transport.Authorization:
+			rt = t.Base
+		case *transport.BearerToken:
+			rt = t.Base
+		case *transport.PrivateToken:
+			rt = t.Base
+		case *transport.BasicAuth:
+			rt = t.Base
+		default:
+			return rt
+		}
+	}
+}
+
+// WithHeaders is an option func that adds the headers to every request made
+// to the upstream service, e.g. headers required by a proxy.
+//
+// Headers that are already set on a request, by go-scm or for authentication,
+// are not replaced.
+func WithHeaders(h map[string]string) ClientFunc {
+	headers := make(map[string]string, len(h))
+	for k, v := range h {
+		headers[k] = v
+	}
+	return func(c *SCMClient) {
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &headerTransport{headers: headers, next: rt}
+		})
+	}
+}
+
+// headerTransport sets static headers on requests.
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	return transportOrDefault(t.next).RoundTrip(req)
+}
+
+// contentLengthTransport sets the length of streamed request bodies from the
+// Content-Length header, as go-scm only sets the length of in-memory bodies,
+// and some upload endpoints reject bodies without a length.
+type contentLengthTransport struct {
+	next http.RoundTripper
+}
+
+func (t *contentLengthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.ContentLength > 0 || req.Header.Get("Content-Length") == "" {
+		return transportOrDefault(t.next).RoundTrip(req)
+	}
+	n, err := strconv.ParseInt(req.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.ContentLength = n
+	return transportOrDefault(t.next).RoundTrip(req)
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestWithTokenSource(t *testing.T) {
+	for _, token := range []string{"token-1", "token-2"} {
+		gock.New("https://api.github.com").
+			Get("/repos/Codertocat/Hello-World/contents/README.md").
+			MatchHeader("Authorization", "Bearer "+token).
+			Reply(http.StatusOK).
+			File("testdata/content.json")
+	}
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	client := New(scmClient, WithTokenSource(TokenSourceFunc(func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "token-1", nil
+		}
+		return "token-2", nil
+	})))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("requests were not made with fresh tokens")
+	}
+}
+
+func TestWithTokenSourceFailure(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("failed to mint token")
+	client := New(scmClient, WithTokenSource(TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return "", failure
+	})))
+
+	_, err = client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md")
+	if !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+}
+
+func TestWithContextToken(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/README.md").
+		MatchHeader("Authorization", "Bearer tenant-token").
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/contents/README.md").
+		MatchHeader("Authorization", "Bearer default-token").
+		Reply(http.StatusOK).
+		File("testdata/content.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "default-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	ctx := WithContextToken(context.Background(), "tenant-token")
+	if _, err := client.GetFile(ctx, "Codertocat/Hello-World", "master", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetFile(context.Background(), "Codertocat/Hello-World", "master", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("requests were not made with the expected tokens")
+	}
+}
+
+func TestWithContextTokenInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		MatchHeader("Authorization", "Bearer tenant-token").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return req.Header.Get("Private-Token") == "", nil
+		}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "commit": map[string]string{"id": "abc123"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "default-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	head, err := client.GetBranchHead(WithContextToken(context.Background(), "tenant-token"), "Codertocat/Hello-World", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != "abc123" {
+		t.Fatalf("got %q, want %q", head, "abc123")
+	}
+}
+
+func TestWithSudo(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		MatchHeader("Sudo", "jdoe").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "commit": map[string]string{"id": "abc123"}})
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/branches/main").
+		MatchHeader("Sudo", "automation").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"name": "main", "commit": map[string]string{"id": "abc123"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "admin-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithSudo("automation"))
+
+	if _, err := client.GetBranchHead(WithContextSudo(context.Background(), "jdoe"), "Codertocat/Hello-World", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBranchHead(context.Background(), "Codertocat/Hello-World", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("requests were not made on behalf of the expected users")
+	}
+}
+
+func TestWithSudoInGitHub(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &headerRecorder{}
+	scmClient.Client = &http.Client{Transport: recorder}
+	client := New(scmClient, WithSudo("automation"))
+
+	_, _ = client.GetFile(WithContextSudo(context.Background(), "jdoe"), "Codertocat/Hello-World", "main", "README.md")
+	if len(recorder.requests) == 0 {
+		t.Fatal("no request was made")
+	}
+	if sudo := recorder.requests[0].Header.Get("Sudo"); sudo != "" {
+		t.Fatalf("got Sudo header %q, want none", sudo)
+	}
+}
+
+func TestNewDoesNotChangeSharedClient(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &headerRecorder{}
+	hc := &http.Client{Transport: recorder}
+	scmClient.Client = hc
+	tenant1 := New(scmClient, WithHeaders(map[string]string{"X-Tenant-ID": "tenant-1"}))
+	tenant2 := New(scmClient)
+
+	if scmClient.Client != hc || hc.Transport != recorder {
+		t.Fatal("the HTTP client of the scm.Client was changed")
+	}
+	_, _ = tenant1.GetFile(context.Background(), "Codertocat/Hello-World", "main", "README.md")
+	_, _ = tenant2.GetFile(context.Background(), "Codertocat/Hello-World", "main", "README.md")
+	if len(recorder.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(recorder.requests))
+	}
+	if tenant := recorder.requests[0].Header.Get("X-Tenant-ID"); tenant != "tenant-1" {
+		t.Fatalf("got X-Tenant-ID header %q, want %q", tenant, "tenant-1")
+	}
+	if tenant := recorder.requests[1].Header.Get("X-Tenant-ID"); tenant != "" {
+		t.Fatalf("got X-Tenant-ID header %q from another client, want none", tenant)
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &headerRecorder{}
+	scmClient.Client = &http.Client{Transport: recorder}
+	client := New(scmClient, WithHeaders(map[string]string{"X-Tenant-ID": "tenant-1", "Content-Type": "text/plain"}))
+
+	ctx := context.Background()
+	repo := "Codertocat/Hello-World"
+	sig := scm.Signature{Name: "John Doe", Email: "john.doe@example.com"}
+	calls := map[string]func() error{
+		"GetFile": func() error { _, err := client.GetFile(ctx, repo, "main", "a.yaml"); return err },
+		"GetFileNormalized": func() error {
+			_, err := client.GetFileNormalized(ctx, repo, "main", "a.yaml", func(b []byte) ([]byte, error) { return b, nil })
+			return err
+		},
+		"GetFileAtCommit": func() error {
+			_, err := client.GetFileAtCommit(ctx, repo, "6dcb09b5b57875f334f61aebed695e2e4193db5e", "README.md")
+			return err
+		},
+		"GetFileRaw": func() error { _, err := client.GetFileRaw(ctx, repo, "main", "a.yaml"); return err },
+		"FilesEqual": func() error {
+			_, err := client.FilesEqual(ctx, repo, "a.yaml", "main", "release")
+			return err
+		},
+		"GetFileStream": func() error {
+			_, err := client.GetFileStream(ctx, repo, "main", "a.yaml")
+			return err
+		},
+		"GetFilesAtPaths":  func() error { _, err := client.GetFilesAtPaths(ctx, repo, "main", []string{"a.yaml"}); return err },
+		"GetReadme":        func() error { _, err := client.GetReadme(ctx, repo, "main"); return err },
+		"GetFilePermalink": func() error { _, err := client.GetFilePermalink(ctx, repo, "main", "a.yaml"); return err },
+		"GetBlame":         func() error { _, err := client.GetBlame(ctx, repo, "main", "a.yaml"); return err },
+		"ListFiles":        func() error { _, err := client.ListFiles(ctx, repo, "main", "config"); return err },
+		"ReadDir":          func() error { _, err := client.ReadDir(ctx, repo, "main", "config"); return err },
+		"UpdateFile":       func() error { return client.UpdateFile(ctx, repo, "main", "a.yaml", "update", "", sig, []byte("a")) },
+		"SyncFile": func() error {
+			_, _, err := client.SyncFile(ctx, repo, "main", "a.yaml", "update", sig, []byte("a"))
+			return err
+		},
+		"UpdateFileWithRetry": func() error {
+			_, err := client.UpdateFileWithRetry(ctx, repo, "main", "a.yaml", "update", sig, func([]byte) ([]byte, error) { return []byte("a"), nil })
+			return err
+		},
+		"PreviewCommit": func() error {
+			_, err := client.PreviewCommit(ctx, repo, "main", []FileChange{{Path: "a.yaml", Content: []byte("a")}})
+			return err
+		},
+		"UpdateFiles": func() error {
+			_, err := client.UpdateFiles(ctx, repo, "main", "update", sig, []FileChange{{Path: "a.yaml", Content: []byte("a")}})
+			return err
+		},
+		"CommitTemplate": func() error {
+			_, err := client.CommitTemplate(ctx, repo, "main", "a.yaml", "update", sig, template.Must(template.New("a").Parse("a")), nil)
+			return err
+		},
+		"CommitDir": func() error {
+			_, err := client.CommitDir(ctx, repo, "main", "scaffold", sig, "testdata", "services/new")
+			return err
+		},
+		"GetDiff":                 func() error { _, err := client.GetDiff(ctx, repo, "a", "b"); return err },
+		"ChangedFilesBetween":     func() error { _, err := client.ChangedFilesBetween(ctx, repo, "a", "b"); return err },
+		"RenameRepository":        func() error { _, err := client.RenameRepository(ctx, repo, "renamed"); return err },
+		"ForkRepository":          func() error { _, err := client.ForkRepository(ctx, repo, ""); return err },
+		"GetLanguages":            func() error { _, err := client.GetLanguages(ctx, repo); return err },
+		"GetRepositoryTopics":     func() error { _, err := client.GetRepositoryTopics(ctx, repo); return err },
+		"SetRepositoryTopics":     func() error { return client.SetRepositoryTopics(ctx, repo, []string{"go"}) },
+		"SetRepositoryVariable":   func() error { return client.SetRepositoryVariable(ctx, repo, "ENV", "prod") },
+		"ListRepositoryVariables": func() error { _, err := client.ListRepositoryVariables(ctx, repo); return err },
+		"CreatePullRequestFromPatches": func() error {
+			_, err := client.CreatePullRequestFromPatches(ctx, repo, "main", "import", [][]byte{[]byte("--- a/a.yaml\n+++ b/a.yaml\n@@ -1 +1 @@\n-a\n+b\n")}, &scm.PullRequestInput{Title: "test"})
+			return err
+		},
+		"GetTokenScopes":         func() error { _, err := client.GetTokenScopes(ctx); return err },
+		"HasScope":               func() error { _, err := client.HasScope(ctx, "repo"); return err },
+		"CreateGist":             func() error { _, err := client.CreateGist(ctx, &GistInput{}); return err },
+		"GetGist":                func() error { _, err := client.GetGist(ctx, "aa5a315d61ae9438b18d"); return err },
+		"SetRepositorySecret":    func() error { return client.SetRepositorySecret(ctx, repo, "TOKEN", "secret") },
+		"ListPullRequestCommits": func() error { _, err := client.ListPullRequestCommits(ctx, repo, 1, scm.ListOptions{}); return err },
+		"ListDeployments":        func() error { _, err := client.ListDeployments(ctx, repo, scm.ListOptions{}); return err },
+		"CreateDeploymentStatus": func() error {
+			return client.CreateDeploymentStatus(ctx, repo, 1, &DeploymentStatusInput{State: "success"})
+		},
+		"ListEnvironments": func() error { _, err := client.ListEnvironments(ctx, repo); return err },
+		"ListWorkflowRuns": func() error { _, err := client.ListWorkflowRuns(ctx, repo, "main", scm.ListOptions{}); return err },
+		"DeleteFile":       func() error { return client.DeleteFile(ctx, repo, "main", "a.yaml", "delete", "", sig, nil) },
+		"CreatePullRequest": func() error {
+			_, err := client.CreatePullRequest(ctx, repo, &scm.PullRequestInput{Source: "feature", Target: "main"})
+			return err
+		},
+		"CreatePullRequestIfChanged": func() error {
+			_, _, err := client.CreatePullRequestIfChanged(ctx, repo, &scm.PullRequestInput{Source: "feature", Target: "main"})
+			return err
+		},
+		"CreateForkPullRequest": func() error {
+			_, err := client.CreateForkPullRequest(ctx, repo, "fork/Hello-World", "feature", "main", &scm.PullRequestInput{})
+			return err
+		},
+		"GetPullRequest":     func() error { _, err := client.GetPullRequest(ctx, repo, 1); return err },
+		"EnableAutoMerge":    func() error { return client.EnableAutoMerge(ctx, repo, 1, MergeMethodSquash) },
+		"MergePullRequest":   func() error { return client.MergePullRequest(ctx, repo, 1, MergeMethodSquash) },
+		"SetPullRequestBase": func() error { return client.SetPullRequestBase(ctx, repo, 1, "main") },
+		"LockPullRequest":    func() error { return client.LockPullRequest(ctx, repo, 1, "resolved") },
+		"UnlockPullRequest":  func() error { return client.UnlockPullRequest(ctx, repo, 1) },
+		"SyncDir": func() error {
+			_, _, _, _, err := client.SyncDir(ctx, repo, "main", "config", map[string][]byte{"app.yml": []byte("name: app\n")}, scm.Signature{}, "sync config")
+			return err
+		},
+		"ListReviews":         func() error { _, err := client.ListReviews(ctx, repo, 1); return err },
+		"ListReviewThreads":   func() error { _, err := client.ListReviewThreads(ctx, repo, 1); return err },
+		"ResolveReviewThread": func() error { return client.ResolveReviewThread(ctx, repo, "PRRT_kwDOA") },
+		"ListReviewComments":  func() error { _, err := client.ListReviewComments(ctx, repo, 1); return err },
+		"CreateReviewComment": func() error {
+			return client.CreateReviewComment(ctx, repo, 1, &scm.ReviewInput{Body: "lint", Path: "a.go", Line: 1, Sha: "a84d88e"})
+		},
+		"IsPullRequestMergeable": func() error { _, err := client.IsPullRequestMergeable(ctx, repo, 1); return err },
+		"WaitForMergeable":       func() error { return client.WaitForMergeable(ctx, repo, 1, time.Millisecond) },
+		"GetPullRequestDiff":     func() error { _, err := client.GetPullRequestDiff(ctx, repo, 1); return err },
+		"CountOpenPullRequests":  func() error { _, err := client.CountOpenPullRequests(ctx, repo); return err },
+		"ClosePullRequestsOlderThan": func() error {
+			_, err := client.ClosePullRequestsOlderThan(ctx, repo, time.Hour, nil)
+			return err
+		},
+		"AddLabelsToMatching": func() error {
+			_, err := client.AddLabelsToMatching(ctx, repo, nil, []string{"bot"})
+			return err
+		},
+		"GetCodeOwners":    func() error { _, err := client.GetCodeOwners(ctx, repo, "main"); return err },
+		"SuggestReviewers": func() error { _, err := client.SuggestReviewers(ctx, repo, 1); return err },
+		"CreateIssue": func() error {
+			_, err := client.CreateIssue(ctx, repo, &scm.IssueInput{Title: "issue"})
+			return err
+		},
+		"CreateIssueComment":   func() error { _, err := client.CreateIssueComment(ctx, repo, 1, "comment"); return err },
+		"GetCombinedStatus":    func() error { _, err := client.GetCombinedStatus(ctx, repo, "main"); return err },
+		"GetChecksStatus":      func() error { _, err := client.GetChecksStatus(ctx, repo, "main"); return err },
+		"ListReleaseAssets":    func() error { _, err := client.ListReleaseAssets(ctx, repo, 1); return err },
+		"DownloadReleaseAsset": func() error { return client.DownloadReleaseAsset(ctx, repo, 1, ioutil.Discard) },
+		"UploadReleaseAsset": func() error {
+			_, err := client.UploadReleaseAsset(ctx, repo, 1, "asset.zip", "application/zip", strings.NewReader("content"))
+			return err
+		},
+		"ListTagsWithCommits": func() error { _, err := client.ListTagsWithCommits(ctx, repo, scm.ListOptions{}); return err },
+		"LatestRelease":       func() error { _, err := client.LatestRelease(ctx, repo, false); return err },
+		"CreateStatus": func() error {
+			_, err := client.CreateStatus(ctx, repo, "main", &scm.StatusInput{State: scm.StateSuccess, Label: "ci"})
+			return err
+		},
+		"GetCommitFiles": func() error {
+			_, err := client.GetCommitFiles(ctx, repo, "sha", scm.ListOptions{})
+			return err
+		},
+		"CreateBranch": func() error { return client.CreateBranch(ctx, repo, "feature", "sha") },
+		"CreateBranchIfBaseMatches": func() error {
+			_, err := client.CreateBranchIfBaseMatches(ctx, repo, "feature", "main", "sha")
+			return err
+		},
+		"GetBranchHead": func() error { _, err := client.GetBranchHead(ctx, repo, "main"); return err },
+		"GetBranchHeads": func() error {
+			_, err := client.GetBranchHeads(ctx, repo, []string{"main"})
+			return err
+		},
+		"DeleteBranchesByPrefix": func() error { _, err := client.DeleteBranchesByPrefix(ctx, repo, "gitops-"); return err },
+		"BranchesWithoutOpenPRs": func() error { _, err := client.BranchesWithoutOpenPRs(ctx, repo, "gitops-"); return err },
+		"MergeBase":              func() error { _, err := client.MergeBase(ctx, repo, "main", "feature"); return err },
+		"IsBranchMerged":         func() error { _, err := client.IsBranchMerged(ctx, repo, "feature", "main"); return err },
+		"GetBranchProtection":    func() error { _, err := client.GetBranchProtection(ctx, repo, "main"); return err },
+		"IsBranchProtected":      func() error { _, err := client.IsBranchProtected(ctx, repo, "main"); return err },
+		"ListRepositories": func() error {
+			_, err := client.ListRepositories(ctx, "Codertocat", RepositoryListOptions{})
+			return err
+		},
+		"SetRepositoryArchived": func() error { return client.SetRepositoryArchived(ctx, repo, true) },
+		"Star":                  func() error { return client.Star(ctx, repo) },
+		"Unstar":                func() error { return client.Unstar(ctx, repo) },
+		"IsStarred":             func() error { _, err := client.IsStarred(ctx, repo); return err },
+	}
+	for name, call := range calls {
+		recorder.requests = nil
+		call()
+		if len(recorder.requests) == 0 {
+			t.Errorf("%s: no requests were made", name)
+		}
+		for _, req := range recorder.requests {
+			if v := req.Header.Get("X-Tenant-ID"); v != "tenant-1" {
+				t.Errorf("%s: %s %s got X-Tenant-ID %q, want tenant-1", name, req.Method, req.URL, v)
+			}
+			if v := req.Header.Get("Content-Type"); req.Body != nil && v == "text/plain" {
+				t.Errorf("%s: %s %s replaced the Content-Type header", name, req.Method, req.URL)
+			}
+		}
+	}
+}
+
+// headerRecorder records requests, and responds with a NotFound status.
+type headerRecorder struct {
+	requests []*http.Request
+}
+
+func (r *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "Not Found"}`)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WithRepoValidation is an option func that checks the repo name before each
+// call, and fails with an error wrapping ErrInvalidRepo if it's malformed,
+// rather than making a request that fails with a NotFound status.
+//
+// On GitLab, repos can be in nested groups, on other drivers, the repo must
+// be in the "owner/name" form.
+func WithRepoValidation() ClientFunc {
+	return func(c *SCMClient) {
+		c.validateRepos = true
+	}
+}
+
+// ValidateRepo returns an error wrapping ErrInvalidRepo if the repo is not a
+// repo name in the "owner/name" form, or "group/subgroup/name" for nested
+// GitLab groups, e.g. it's a URL, or has a trailing slash.
+func ValidateRepo(repo string) error {
+	_, err := repoSegments(repo)
+	return err
+}
+
+// validateRepo checks the repo name for the driver of the client.
+func (c *SCMClient) validateRepo(repo string) error {
+	n, err := repoSegments(repo)
+	if err != nil {
+		return err
+	}
+	if n != 2 && c.scmClient.Driver != scm.DriverGitlab {
+		return invalidRepo(repo, "must be in the owner/name form")
+	}
+	return nil
+}
+
+// repoSegments returns the number of segments in the repo name.
+func repoSegments(repo string) (int, error) {
+	switch {
+	case repo == "":
+		return 0, invalidRepo(repo, "is empty")
+	case strings.Contains(repo, "://"):
+		return 0, invalidRepo(repo, "is a URL, not a repo name")
+	case strings.HasPrefix(repo, "/") || strings.HasSuffix(repo, "/"):
+		return 0, invalidRepo(repo, "has a leading or trailing slash")
+	case strings.ContainsAny(repo, " \t\r\n"):
+		return 0, invalidRepo(repo, "contains whitespace")
+	}
+	segments := strings.Split(repo, "/")
+	if len(segments) < 2 {
+		return 0, invalidRepo(repo, "must be in the owner/name form")
+	}
+	for _, s := range segments {
+		if s == "" {
+			return 0, invalidRepo(repo, "has an empty path segment")
+		}
+	}
+	return len(segments), nil
+}
+
+func invalidRepo(repo, reason string) error {
+	return fmt.Errorf("repo %q %s: %w", repo, reason, ErrInvalidRepo)
+}
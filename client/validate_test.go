@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ocraviotto/go-scm/scm/factory"
+)
+
+func TestValidateRepo(t *testing.T) {
+	tests := []struct {
+		repo  string
+		valid bool
+	}{
+		{"owner/repo", true},
+		{"group/subgroup/repo", true},
+		{"", false},
+		{"repo", false},
+		{"owner/repo/", false},
+		{"/owner/repo", false},
+		{"owner//repo", false},
+		{"https://github.com/owner/repo", false},
+		{"owner/my repo", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			err := ValidateRepo(tt.repo)
+			if tt.valid && err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidRepo) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidRepo)
+			}
+		})
+	}
+}
+
+func TestGetFileWithRepoValidation(t *testing.T) {
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, WithRepoValidation())
+
+	_, err = client.GetFile(context.Background(), "Codertocat/Hello-World/", "master", "config/my/file.yaml")
+	if !errors.Is(err, ErrInvalidRepo) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidRepo)
+	}
+	_, err = client.GetFile(context.Background(), "group/subgroup/repo", "master", "config/my/file.yaml")
+	if !errors.Is(err, ErrInvalidRepo) {
+		t.Fatalf("got %v, want %v for a nested repo on GitHub", err, ErrInvalidRepo)
+	}
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ocraviotto/go-scm/scm"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// SetRepositoryVariable creates the CI variable of the repo with the value,
+// or updates it if it already exists, e.g. a GitHub Actions variable or a
+// GitLab CI/CD variable.
+//
+// Variables are only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetRepositoryVariable(ctx context.Context, repo, name, value string) error {
+	err := c.call(ctx, "SetRepositoryVariable", repo, func(ctx context.Context) error {
+		return c.setRepositoryVariable(ctx, repo, name, value)
+	})
+	c.emit(Event{Type: "SetRepositoryVariable", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) setRepositoryVariable(ctx context.Context, repo, name, value string) error {
+	var (
+		updatePath, createPath string
+		update, create         interface{}
+		updateMethod           = http.MethodPut
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		updatePath = fmt.Sprintf("repos/%s/actions/variables/%s", repo, url.PathEscape(name))
+		createPath = fmt.Sprintf("repos/%s/actions/variables", repo)
+		update = map[string]string{"name": name, "value": value}
+		create = update
+		updateMethod = http.MethodPatch
+	case scm.DriverGitlab:
+		updatePath = fmt.Sprintf("api/v4/projects/%s/variables/%s", encodeRepo(repo), url.PathEscape(name))
+		createPath = fmt.Sprintf("api/v4/projects/%s/variables", encodeRepo(repo))
+		update = map[string]string{"value": value}
+		create = map[string]string{"key": name, "value": value}
+	case scm.DriverGitea:
+		updatePath = fmt.Sprintf("api/v1/repos/%s/actions/variables/%s", repo, url.PathEscape(name))
+		createPath = updatePath
+		update = map[string]string{"name": name, "value": value}
+		create = map[string]string{"value": value}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, updateMethod, updatePath, update, nil)
+	if r != nil && r.Status == http.StatusNotFound {
+		r, err = c.do(ctx, http.MethodPost, createPath, create, nil)
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to set variable %s in repo %s", name, repo), Status: r.Status}
+	}
+	return err
+}
+
+// ListRepositoryVariables returns the values of the CI variables of the repo,
+// keyed by their name.
+//
+// Variables are only supported on GitHub, GitLab and Gitea.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListRepositoryVariables(ctx context.Context, repo string) (map[string]string, error) {
+	var out map[string]string
+	err := c.call(ctx, "ListRepositoryVariables", repo, func(ctx context.Context) (err error) {
+		out, err = c.listRepositoryVariables(ctx, repo)
+		return err
+	})
+	return out, err
+}
+
+// repoVariable is a CI variable, the name is the key on GitLab, and Gitea
+// returns the value as the data.
+type repoVariable struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Data  string `json:"data"`
+}
+
+func (c *SCMClient) listRepositoryVariables(ctx context.Context, repo string) (map[string]string, error) {
+	var path, sizeParam string
+	opts := scm.ListOptions{Size: 100}
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path, sizeParam, opts.Size = fmt.Sprintf("repos/%s/actions/variables", repo), "per_page", 30
+	case scm.DriverGitlab:
+		path, sizeParam = fmt.Sprintf("api/v4/projects/%s/variables", encodeRepo(repo)), "per_page"
+	case scm.DriverGitea:
+		path, sizeParam = fmt.Sprintf("api/v1/repos/%s/actions/variables", repo), "limit"
+	default:
+		return nil, scm.ErrNotSupported
+	}
+	variables := map[string]string{}
+	for {
+		params := url.Values{sizeParam: {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		var (
+			page    []repoVariable
+			wrapped struct {
+				Variables []repoVariable `json:"variables"`
+			}
+			out interface{} = &page
+		)
+		if c.scmClient.Driver == scm.DriverGithub {
+			out = &wrapped
+		}
+		r, err := c.do(ctx, http.MethodGet, path+"?"+params.Encode(), nil, out)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list variables of repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range append(page, wrapped.Variables...) {
+			switch c.scmClient.Driver {
+			case scm.DriverGitlab:
+				variables[v.Key] = v.Value
+			case scm.DriverGitea:
+				variables[v.Name] = v.Data
+			default:
+				variables[v.Name] = v.Value
+			}
+		}
+		if !nextPage(&opts, r) {
+			return variables, nil
+		}
+	}
+}
+
+// SetRepositorySecret creates the CI secret of the repo with the value, or
+// updates it if it already exists, secrets can't be read back once they are
+// set.
+//
+// Secrets are only supported on GitHub and Gitea, on GitHub, the value is
+// encrypted with the public key of the repo before it's sent.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetRepositorySecret(ctx context.Context, repo, name, value string) error {
+	err := c.call(ctx, "SetRepositorySecret", repo, func(ctx context.Context) error {
+		return c.setRepositorySecret(ctx, repo, name, value)
+	})
+	c.emit(Event{Type: "SetRepositorySecret", Repo: repo, Err: err})
+	return err
+}
+
+func (c *SCMClient) setRepositorySecret(ctx context.Context, repo, name, value string) error {
+	var (
+		path string
+		in   interface{}
+	)
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		keyID, encrypted, err := c.encryptSecretGitHub(ctx, repo, value)
+		if err != nil {
+			return err
+		}
+		path = fmt.Sprintf("repos/%s/actions/secrets/%s", repo, url.PathEscape(name))
+		in = map[string]string{"encrypted_value": encrypted, "key_id": keyID}
+	case scm.DriverGitea:
+		path = fmt.Sprintf("api/v1/repos/%s/actions/secrets/%s", repo, url.PathEscape(name))
+		in = map[string]string{"data": value}
+	default:
+		return scm.ErrNotSupported
+	}
+	r, err := c.do(ctx, http.MethodPut, path, in, nil)
+	if r != nil && isErrorStatus(r.Status) {
+		return SCMError{Msg: fmt.Sprintf("failed to set secret %s in repo %s", name, repo), Status: r.Status}
+	}
+	return err
+}
+
+// encryptSecretGitHub returns the ID of the public key of the repo, and the
+// value sealed with the key, encoded in base64, as required by the secrets
+// API.
+func (c *SCMClient) encryptSecretGitHub(ctx context.Context, repo, value string) (keyID, encrypted string, err error) {
+	var out struct {
+		KeyID string `json:"key_id"`
+		Key   string `json:"key"`
+	}
+	r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/actions/secrets/public-key", repo), nil, &out)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", "", SCMError{Msg: fmt.Sprintf("failed to get the secrets public key of repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(out.Key)
+	if err != nil || len(b) != 32 {
+		return "", "", fmt.Errorf("invalid secrets public key of repo %s", repo)
+	}
+	var key [32]byte
+	copy(key[:], b)
+	sealed, err := box.SealAnonymous(nil, []byte(value), &key, rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt secret for repo %s: %w", repo, err)
+	}
+	return out.KeyID, base64.StdEncoding.EncodeToString(sealed), nil
+}
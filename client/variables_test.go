@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"golang.org/x/crypto/nacl/box"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSetRepositoryVariableCreatesMissingVariable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/actions/variables/ENV").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/actions/variables").
+		JSON(map[string]string{"name": "ENV", "value": "prod"}).
+		Reply(http.StatusCreated)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryVariable(context.Background(), "Codertocat/Hello-World", "ENV", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("variable was not created")
+	}
+}
+
+func TestSetRepositoryVariableGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/variables/ENV").
+		JSON(map[string]string{"value": "prod"}).
+		Reply(http.StatusOK)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositoryVariable(context.Background(), "Codertocat/Hello-World", "ENV", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("variable was not updated")
+	}
+}
+
+func TestListRepositoryVariables(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/variables").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"total_count": 2,
+			"variables": []map[string]string{
+				{"name": "ENV", "value": "prod"},
+				{"name": "REGION", "value": "eu"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	variables, err := client.ListRepositoryVariables(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"ENV": "prod", "REGION": "eu"}, variables); diff != "" {
+		t.Fatalf("variables don't match:\n%s", diff)
+	}
+}
+
+func TestListRepositoryVariablesGitea(t *testing.T) {
+	gock.New("https://gitea.example.com").
+		Get("/api/v1/repos/Codertocat/Hello-World/actions/variables").
+		Reply(http.StatusOK).
+		JSON([]map[string]string{{"name": "ENV", "data": "prod"}})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("gitea", "https://gitea.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	variables, err := client.ListRepositoryVariables(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"ENV": "prod"}, variables); diff != "" {
+		t.Fatalf("variables don't match:\n%s", diff)
+	}
+}
+
+func TestSetRepositorySecretEncryptsValue(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/secrets/public-key").
+		Reply(http.StatusOK).
+		JSON(map[string]string{"key_id": "key-1", "key": base64.StdEncoding.EncodeToString(publicKey[:])})
+	var body map[string]string
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/actions/secrets/TOKEN").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			return true, json.Unmarshal(b, &body)
+		}).
+		Reply(http.StatusCreated)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	if err := client.SetRepositorySecret(context.Background(), "Codertocat/Hello-World", "TOKEN", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if body["key_id"] != "key-1" {
+		t.Fatalf("got key_id %q, want key-1", body["key_id"])
+	}
+	sealed, err := base64.StdEncoding.DecodeString(body["encrypted_value"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, ok := box.OpenAnonymous(nil, sealed, publicKey, privateKey)
+	if !ok {
+		t.Fatal("failed to decrypt the secret")
+	}
+	if string(value) != "s3cret" {
+		t.Fatalf("got secret %q, want s3cret", value)
+	}
+}
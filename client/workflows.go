@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ocraviotto/go-scm/scm"
+)
+
+// WorkflowRun is a run of a GitHub Actions workflow.
+//
+// go-scm has no Actions API, so runs are read directly from the upstream
+// service.
+type WorkflowRun struct {
+	ID         int
+	WorkflowID int
+	Name       string
+	Number     int
+	Event      string
+	HeadBranch string
+	HeadSha    string
+	// Status is one of "queued", "in_progress" or "completed", or another
+	// status reported by GitHub, e.g. "waiting".
+	Status string
+	// Conclusion is empty until the run is completed, and then one of e.g.
+	// "success", "failure", "cancelled" or "skipped".
+	Conclusion string
+	Link       string
+	Created    time.Time
+	Updated    time.Time
+}
+
+type ghWorkflowRuns struct {
+	WorkflowRuns []ghWorkflowRun `json:"workflow_runs"`
+}
+
+type ghWorkflowRun struct {
+	ID         int       `json:"id"`
+	WorkflowID int       `json:"workflow_id"`
+	Name       string    `json:"name"`
+	RunNumber  int       `json:"run_number"`
+	Event      string    `json:"event"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSha    string    `json:"head_sha"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	HTMLURL    string    `json:"html_url"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ListWorkflowRuns lists the workflow runs of the repo for the ref, newest
+// first, paging through the runs from the page in the options.
+//
+// The ref is either a commit SHA, which matches the runs for the commit, or a
+// branch, which matches the runs for any commit on the branch. If the ref is
+// empty, the runs for every ref are listed.
+//
+// Workflow runs are only supported on GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListWorkflowRuns(ctx context.Context, repo, ref string, opts scm.ListOptions) ([]*WorkflowRun, error) {
+	var out []*WorkflowRun
+	err := c.call(ctx, "ListWorkflowRuns", repo, func(ctx context.Context) (err error) {
+		out, err = c.listWorkflowRuns(ctx, repo, ref, opts)
+		return err
+	})
+	return out, err
+}
+
+func (c *SCMClient) listWorkflowRuns(ctx context.Context, repo, ref string, opts scm.ListOptions) ([]*WorkflowRun, error) {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return nil, scm.ErrNotSupported
+	}
+	if opts.Size == 0 {
+		opts.Size = 100
+	}
+	var all []*WorkflowRun
+	for {
+		params := url.Values{"per_page": {strconv.Itoa(opts.Size)}}
+		if opts.Page > 0 {
+			params.Set("page", strconv.Itoa(opts.Page))
+		}
+		switch {
+		case IsCommitSHA(ref):
+			params.Set("head_sha", ref)
+		case ref != "":
+			params.Set("branch", ref)
+		}
+		var runs ghWorkflowRuns
+		r, err := c.do(ctx, http.MethodGet, fmt.Sprintf("repos/%s/actions/runs?%s", repo, params.Encode()), nil, &runs)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, SCMError{Msg: fmt.Sprintf("failed to list workflow runs for ref %s in repo %s", ref, repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range runs.WorkflowRuns {
+			all = append(all, &WorkflowRun{
+				ID:         w.ID,
+				WorkflowID: w.WorkflowID,
+				Name:       w.Name,
+				Number:     w.RunNumber,
+				Event:      w.Event,
+				HeadBranch: w.HeadBranch,
+				HeadSha:    w.HeadSha,
+				Status:     w.Status,
+				Conclusion: w.Conclusion,
+				Link:       w.HTMLURL,
+				Created:    w.CreatedAt,
+				Updated:    w.UpdatedAt,
+			})
+		}
+		more := nextPage(&opts, r)
+		if exceedsLimit(c.maxItems, len(all), more) {
+			return all[:c.maxItems], ErrTruncated
+		}
+		if !more {
+			return all, nil
+		}
+	}
+}
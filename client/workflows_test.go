@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ocraviotto/go-scm/scm"
+	"github.com/ocraviotto/go-scm/scm/factory"
+	"github.com/ocraviotto/pkg/test"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestListWorkflowRuns(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/runs").
+		MatchParam("branch", "main").
+		MatchParam("per_page", "100").
+		Reply(http.StatusOK).
+		SetHeader("Link", `<https://api.github.com/repos/Codertocat/Hello-World/actions/runs?branch=main&per_page=100&page=2>; rel="next"`).
+		JSON(map[string]interface{}{
+			"total_count": 2,
+			"workflow_runs": []map[string]interface{}{
+				{"id": 2, "workflow_id": 10, "name": "CI", "run_number": 8, "event": "push", "head_branch": "main", "head_sha": "a84d88e", "status": "in_progress"},
+			},
+		})
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/runs").
+		MatchParam("branch", "main").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"total_count": 2,
+			"workflow_runs": []map[string]interface{}{
+				{"id": 1, "workflow_id": 10, "name": "CI", "run_number": 7, "event": "push", "head_branch": "main", "head_sha": "7fd1a60", "status": "completed", "conclusion": "success"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	runs, err := client.ListWorkflowRuns(context.Background(), "Codertocat/Hello-World", "main", scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*WorkflowRun{
+		{ID: 2, WorkflowID: 10, Name: "CI", Number: 8, Event: "push", HeadBranch: "main", HeadSha: "a84d88e", Status: "in_progress"},
+		{ID: 1, WorkflowID: 10, Name: "CI", Number: 7, Event: "push", HeadBranch: "main", HeadSha: "7fd1a60", Status: "completed", Conclusion: "success"},
+	}
+	if diff := cmp.Diff(want, runs); diff != "" {
+		t.Fatalf("runs don't match:\n%s", diff)
+	}
+}
+
+func TestListWorkflowRunsForCommit(t *testing.T) {
+	sha := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/runs").
+		MatchParam("head_sha", sha).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"workflow_runs": []map[string]interface{}{
+				{"id": 1, "name": "CI", "head_sha": sha, "status": "completed", "conclusion": "failure"},
+			},
+		})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	runs, err := client.ListWorkflowRuns(context.Background(), "Codertocat/Hello-World", sha, scm.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Conclusion != "failure" {
+		t.Fatalf("got runs %v, want the failed run", runs)
+	}
+}
+
+func TestListWorkflowRunsWithError(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/runs").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListWorkflowRuns(context.Background(), "Codertocat/Hello-World", "main", scm.ListOptions{})
+	if !test.MatchError(t, `failed to list workflow runs for ref main in repo Codertocat/Hello-World.*(404)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestListWorkflowRunsNotSupported(t *testing.T) {
+	scmClient, err := factory.NewClient("gitlab", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.ListWorkflowRuns(context.Background(), "Codertocat/Hello-World", "main", scm.ListOptions{})
+	if !errors.Is(err, scm.ErrNotSupported) {
+		t.Fatalf("got %v, want scm.ErrNotSupported", err)
+	}
+}
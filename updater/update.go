@@ -1,6 +1,7 @@
 package updater
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/rand"
@@ -69,6 +70,10 @@ type Updater struct {
 
 // ApplyUpdateToFile does the job of fetching a file, passing it to a
 // user-provided function if not deleting it, and optionally creating a PR.
+//
+// If the function doesn't change the file, nothing is committed and no branch
+// is created, and the input branch, which already has the content, is returned
+// with a nil error.
 func (u *Updater) ApplyUpdateToFile(ctx context.Context, input CommitInput, f ContentUpdater) (string, error) {
 	var (
 		updated         []byte
@@ -101,6 +106,10 @@ func (u *Updater) ApplyUpdateToFile(ctx context.Context, input CommitInput, f Co
 	if err != nil {
 		return "", fmt.Errorf("failed to apply update: %v", err)
 	}
+	if !isNotFoundError && !input.RemoveFile && bytes.Equal(current.Data, updated) {
+		u.log.Info("file already up to date", "filename", input.Filename)
+		return input.Branch, nil
+	}
 
 	return u.applyUpdate(ctx, input, currentSHA, updated)
 }
@@ -100,6 +100,26 @@ func TestApplyUpdateToFileCommittingDirectly(t *testing.T) {
 	m.AssertNoBranchesCreated()
 }
 
+func TestApplyUpdateToFileWithNoChange(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	body := []byte("test:\n  image: old-image\n")
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, body)
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), ReplaceContents(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != testBranch {
+		t.Fatalf("got branch %#v, want %#v", branch, testBranch)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoInteractions()
+}
+
 func TestApplyUpdateToFileMissingFile(t *testing.T) {
 	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
 	m := mock.New(t)